@@ -0,0 +1,140 @@
+package main
+
+import (
+	"draco/models"
+	"encoding/json"
+	"testing"
+)
+
+// characterWithScores returns a character generated by `method` with
+// `scores` in the order of models.Abilities.
+func characterWithScores(method models.AbilityMethodType, scores ...int) *models.Character {
+	c := &models.Character{AbilityMethod: method}
+	for i, ability := range models.Abilities {
+		c.SetAbilityScore(ability, scores[i])
+	}
+	return c
+}
+
+func TestValidateAbilityMethod(t *testing.T) {
+	roll := &models.AbilityRoll{Scores: []int{16, 12, 11, 9, 14, 7}}
+
+	tests := []struct {
+		name      string
+		character *models.Character
+		valid     bool
+	}{
+		{"manual", characterWithScores(models.AbilityMethodManual, 30, 3, 18, 18, 18, 1), true},
+		{"point-buy within budget", characterWithScores(models.AbilityMethodPointBuy, 8, 8, 8, 8, 8, 8), true},
+		{"point-buy whole budget", characterWithScores(models.AbilityMethodPointBuy, 15, 15, 15, 8, 8, 8), true},
+		{"point-buy mixed costs", characterWithScores(models.AbilityMethodPointBuy, 14, 13, 12, 11, 10, 9), true},
+		{"point-buy over budget", characterWithScores(models.AbilityMethodPointBuy, 15, 15, 15, 9, 8, 8), false},
+		{"point-buy above 15", characterWithScores(models.AbilityMethodPointBuy, 16, 8, 8, 8, 8, 8), false},
+		{"point-buy below 8", characterWithScores(models.AbilityMethodPointBuy, 15, 15, 15, 10, 8, 7), false},
+		{"standard array", characterWithScores(models.AbilityMethodStandardArray, 15, 14, 13, 12, 10, 8), true},
+		{"standard array reordered", characterWithScores(models.AbilityMethodStandardArray, 8, 10, 15, 13, 12, 14), true},
+		{"standard array repeated", characterWithScores(models.AbilityMethodStandardArray, 15, 15, 13, 12, 10, 8), false},
+		{"standard array changed", characterWithScores(models.AbilityMethodStandardArray, 15, 14, 13, 12, 10, 9), false},
+		{"rolled", characterWithScores(models.AbilityMethodRolled, 7, 9, 11, 12, 14, 16), true},
+		{"rolled repeated", characterWithScores(models.AbilityMethodRolled, 16, 16, 11, 9, 14, 7), false},
+		{"rolled changed", characterWithScores(models.AbilityMethodRolled, 16, 12, 11, 9, 14, 8), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := validateAbilityMethod(tt.character, roll)
+			if (msg == "") != tt.valid {
+				t.Errorf("validateAbilityMethod(%v) = %q, want valid %v", tt.character.AbilityScores(), msg, tt.valid)
+			}
+		})
+	}
+}
+
+// fakeRegistry holds the races of the racial bonus tests.
+type fakeRegistry struct {
+	entries map[string]models.RegistryEntry
+}
+
+func (f *fakeRegistry) Insert(entry models.RegistryEntry) (int, error) { return 0, nil }
+func (f *fakeRegistry) Get(id int) (*models.RegistryEntry, error)      { return nil, models.ErrNoRecord }
+func (f *fakeRegistry) GetAllVisible(username string, kind models.RegistryKindType) (*[]models.RegistryEntry, error) {
+	return &[]models.RegistryEntry{}, nil
+}
+func (f *fakeRegistry) FindVisible(username string, kind models.RegistryKindType, name string, classID *int) (*models.RegistryEntry, error) {
+	entry, ok := f.entries[name]
+	if !ok || entry.Kind != kind {
+		return nil, models.ErrNoRecord
+	}
+	return &entry, nil
+}
+func (f *fakeRegistry) Update(entry models.RegistryEntry) error { return nil }
+func (f *fakeRegistry) Delete(id int) error                     { return nil }
+
+func TestApplyRacialBonuses(t *testing.T) {
+	race := func(name, bonuses string, choices int) models.RegistryEntry {
+		return models.RegistryEntry{Kind: models.RegistryRace, Name: name,
+			AbilityBonuses: json.RawMessage(bonuses), AbilityBonusChoices: &choices}
+	}
+	app := &application{registry: &fakeRegistry{entries: map[string]models.RegistryEntry{
+		"Human":          race("Human", `{"strength": 1, "dexterity": 1, "constitution": 1, "intelligence": 1, "wisdom": 1, "charisma": 1}`, 0),
+		"Mountain Dwarf": race("Mountain Dwarf", `{"strength": 2, "constitution": 2}`, 0),
+		"Half-Elf":       race("Half-Elf", `{"charisma": 2}`, 2),
+	}}}
+
+	tests := []struct {
+		name   string
+		race   string
+		scores []int
+		chosen []models.AbilityType
+		want   []int // Empty if the choices are refused
+	}{
+		{"every ability", "Human", []int{15, 14, 13, 12, 10, 8}, nil, []int{16, 15, 14, 13, 11, 9}},
+		{"fixed bonuses", "Mountain Dwarf", []int{15, 14, 13, 12, 10, 8}, nil, []int{17, 14, 15, 12, 10, 8}},
+		{"chosen bonuses", "Half-Elf", []int{8, 14, 13, 12, 10, 15},
+			[]models.AbilityType{models.AbilityDexterity, models.AbilityConstitution}, []int{8, 15, 14, 12, 10, 17}},
+		{"choices not allowed", "Mountain Dwarf", []int{15, 14, 13, 12, 10, 8},
+			[]models.AbilityType{models.AbilityWisdom}, nil},
+		{"too few choices", "Half-Elf", []int{8, 14, 13, 12, 10, 15},
+			[]models.AbilityType{models.AbilityDexterity}, nil},
+		{"too many choices", "Half-Elf", []int{8, 14, 13, 12, 10, 15},
+			[]models.AbilityType{models.AbilityDexterity, models.AbilityWisdom, models.AbilityStrength}, nil},
+		{"choice repeated", "Half-Elf", []int{8, 14, 13, 12, 10, 15},
+			[]models.AbilityType{models.AbilityDexterity, models.AbilityDexterity}, nil},
+		{"choice of a fixed bonus", "Half-Elf", []int{8, 14, 13, 12, 10, 15},
+			[]models.AbilityType{models.AbilityDexterity, models.AbilityCharisma}, nil},
+		{"above 30", "Mountain Dwarf", []int{29, 14, 13, 12, 10, 8}, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := characterWithScores(models.AbilityMethodManual, tt.scores...)
+			c.Race = models.RaceType(tt.race)
+
+			msg, err := app.applyRacialBonuses(c, "player", tt.chosen)
+			if err != nil {
+				t.Fatalf("applyRacialBonuses() error: %v", err)
+			}
+			if len(tt.want) == 0 {
+				if msg == "" {
+					t.Errorf("applyRacialBonuses(%v) = %v, want the choices refused", tt.chosen, c.AbilityScores())
+				}
+				return
+			}
+			if msg != "" {
+				t.Fatalf("applyRacialBonuses(%v) refused: %s", tt.chosen, msg)
+			}
+			for i, score := range c.AbilityScores() {
+				if score != tt.want[i] {
+					t.Errorf("applyRacialBonuses(%v) = %v, want %v", tt.chosen, c.AbilityScores(), tt.want)
+					break
+				}
+			}
+		})
+	}
+
+	c := characterWithScores(models.AbilityMethodManual, 10, 10, 10, 10, 10, 10)
+	c.Race = "Tortle"
+	if _, err := app.applyRacialBonuses(c, "player", nil); err != models.ErrNoRecord {
+		t.Errorf("applyRacialBonuses() of an unknown race error = %v, want %v", err, models.ErrNoRecord)
+	}
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"draco/events"
 	"draco/models"
 	"draco/models/postgresql"
+	"encoding/json"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/labstack/echo/v4"
@@ -13,12 +16,48 @@ import (
 type application struct {
 	echoInstance  *echo.Echo
 	jwtSigningKey string
+	oidc          *oidcProvider // nil when single sign-on is not configured
+	events        *events.Hub
 	players       interface {
 		Insert(username string, password string, name string) error
 		Authenticate(username string, password string) (string, error)
 		Get(username string) (*models.Player, error)
 		UpdatePassword(username string, newPassword string) error
 		Delete(username string) error
+		Search(query string, limit, offset int) (*[]models.Player, error)
+		SetDisabled(username string, disabled bool) error
+		SetRole(username string, role models.RoleType) error
+		RequirePasswordReset(username string) error
+		UpdateProfile(username string, name, avatarURL, bio *string) error
+		ChangeUsername(username, newUsername string) error
+	}
+	twoFactor interface {
+		GetSecret(username string) (string, bool, error)
+		SetPendingSecret(username, secret string) error
+		Enable(username string, recoveryCodes []string) error
+		Disable(username string) error
+		ClaimCounter(username string, counter int64) error
+		UseRecoveryCode(username, code string) error
+		CountUnusedRecoveryCodes(username string) (int, error)
+		CreateChallenge(username, challengeHash string, expiresAt time.Time) error
+		GetChallenge(challengeHash string) (string, error)
+		RecordFailedAttempt(challengeHash string) error
+		DeleteChallenge(challengeHash string) error
+	}
+	personalTokens interface {
+		Insert(username, name, tokenHash string, scopes []models.TokenScope) (int, error)
+		GetAllForPlayer(username string) (*[]models.PersonalAccessToken, error)
+		Revoke(username string, id int) error
+		Authenticate(tokenHash string) (string, []models.TokenScope, error)
+	}
+	identities interface {
+		CreateLoginState(stateHash, sessionKeyHash, nonce, codeVerifier, linkUsername string, expiresAt time.Time) error
+		ConsumeLoginState(stateHash, sessionKeyHash string) (string, string, string, error)
+		GetPlayer(issuer, subject string) (string, error)
+		GetAllForPlayer(username string) (*[]models.PlayerIdentity, error)
+		Link(issuer, subject, username string) error
+		Provision(issuer, subject, username, password, name string) error
+		Unlink(username, issuer string) error
 	}
 	characters interface {
 		Insert(c models.Character) (int, error)
@@ -27,6 +66,52 @@ type application struct {
 		Update(c models.Character) error
 		Delete(id int) error
 	}
+	characterClasses interface {
+		GetAll(characterID int) (*[]models.CharacterClass, error)
+		Replace(characterID int, classes []models.CharacterClass) error
+	}
+	proficiencies interface {
+		Set(p models.CharacterProficiency) error
+		GetAll(characterID int) (*[]models.CharacterProficiency, error)
+		Delete(characterID int, kind models.ProficiencyKindType, name string) error
+	}
+	conditions interface {
+		Set(characterID int, condition models.AppliedCondition) error
+		GetAll(characterID int) (*[]models.AppliedCondition, error)
+		Delete(characterID int, condition models.ConditionType) error
+		Rest(characterID int, rest models.RestType) error
+	}
+	hitPoints interface {
+		AdjustHP(characterID, damage, healing int) (*models.Character, error)
+		DeathSave(characterID, roll int) (*models.Character, error)
+		Stabilize(characterID int) (*models.Character, error)
+	}
+	levels interface {
+		LevelUp(l models.LevelUp) (*models.Character, *models.LevelUp, error)
+		GetHistory(characterID int) (*[]models.LevelUp, error)
+	}
+	features interface {
+		GetAll(source *models.FeatureSourceType, race, class string) (*[]models.Feature, error)
+		FindFeat(name string) (*models.Feature, error)
+		GetForCharacter(characterID, featureID int) (*models.CharacterFeature, error)
+		GetAllForCharacter(characterID int) (*[]models.CharacterFeature, error)
+		AddFeat(characterID int, name string) error
+		RemoveFeat(characterID, featureID int) error
+		Use(characterID, featureID, uses, maxUses int) (int, error)
+	}
+	abilityRolls interface {
+		Insert(username string, scores []int, rolls json.RawMessage) (int, error)
+		Get(id int) (*models.AbilityRoll, error)
+		GetAllForPlayer(username string, limit, offset int) (*[]models.AbilityRoll, error)
+	}
+	registry interface {
+		Insert(entry models.RegistryEntry) (int, error)
+		Get(id int) (*models.RegistryEntry, error)
+		GetAllVisible(username string, kind models.RegistryKindType) (*[]models.RegistryEntry, error)
+		FindVisible(username string, kind models.RegistryKindType, name string, classID *int) (*models.RegistryEntry, error)
+		Update(entry models.RegistryEntry) error
+		Delete(id int) error
+	}
 	spells interface {
 		Insert(s models.Spell) error
 		Get(characterID int, spellName string) (*models.Spell, error)
@@ -42,38 +127,135 @@ type application struct {
 		GetItemStats(characterID int) (*models.ItemStats, error)
 	}
 	campaigns interface {
-		Insert(c models.Campaign, characterIDs []int) (int, error)
+		Insert(c models.Campaign, characterIDs []int, invitees []string, expiresAt *time.Time) (int, error)
 		Get(id int) (*models.Campaign, error)
-		Update(id int, state string, location string) error
+		Update(id int, statusNote string, location string) error
 		Delete(id int) error
 		GetPlayersCreatedCampaigns(dungeonMaster string) (*[]models.Campaign, error)
 		GetAllCharacterCampaigns(characterID int) (*[]models.Campaign, error)
 		GetCampaignParticpants(id int) (*[]models.CampaignParticipants, error)
 		GetPlayersAttendedAll(dungeonMaster string) (*[]string, error)
+		GetOrphaned() (*[]models.Campaign, error)
+		SetDungeonMaster(id int, username string) error
+		Transition(id int, to models.CampaignStateType, changedBy string) error
+		GetStateHistory(id int) (*[]models.CampaignStateTransition, error)
 	}
 	milestones interface {
 		Insert(campaignID int, milestone string) error
-		GetAllForCampaign(campaignID int) (*[]string, error)
+		Create(milestone models.CampaignMilestone) (int, error)
+		Get(id int) (*models.CampaignMilestone, error)
+		GetAllForCampaign(campaignID int) (*[]models.CampaignMilestone, error)
+		Update(milestone models.CampaignMilestone) error
+		Delete(id int) error
+		Reorder(campaignID int, milestoneIDs []int) error
 	}
 	belongsTo interface {
 		Insert(c models.BelongsTo) error
 		GetAllCharacterCampaigns(characterID int) (*[]models.Campaign, error)
 		GetAllCampaignCharacters(campaignID int) (*[]models.Character, error)
+		Delete(characterID, campaignID int) error
+		IsPlayerInCampaign(username string, campaignID int) (bool, error)
+	}
+	campaignLog interface {
+		Insert(campaignID int, actor, kind, message string) (int, error)
+		GetAllForCampaign(campaignID, limit, offset int) (*[]models.CampaignLogEntry, error)
+		AwardXP(campaignID int, actor, message string, amounts map[int]int) (*[]models.XPAwardResult, error)
+	}
+	journal interface {
+		Insert(entry models.JournalEntry) (int, error)
+		Get(id int) (*models.JournalEntry, error)
+		GetAllForCampaign(campaignID int, username string, isDungeonMaster bool, query string, limit, offset int) (*[]models.JournalEntry, error)
+		Update(entry models.JournalEntry, editor string) error
+		Delete(id int) error
+		GetRevisions(id int) (*[]models.JournalEntryRevision, error)
+	}
+	npcs interface {
+		Insert(npc models.CampaignNPC) (int, error)
+		Get(id int) (*models.CampaignNPC, error)
+		GetAllForCampaign(campaignID int, includeHidden bool, kind models.NPCKindType, tag string) (*[]models.CampaignNPC, error)
+		Update(npc models.CampaignNPC) error
+		Delete(id int) error
+	}
+	encounters interface {
+		Insert(encounter models.Encounter) (int, error)
+		Get(id int) (*models.Encounter, error)
+		GetAllForCampaign(campaignID int) (*[]models.Encounter, error)
+		Update(encounter models.Encounter) error
+		Delete(id int) error
+	}
+	combats interface {
+		Start(combat models.Combat, combatants []models.Combatant) (int, error)
+		GetActive(campaignID int) (*models.Combat, error)
+		End(id int) error
+		AddCombatants(combatID int, combatants []models.Combatant) error
+		RemoveCombatant(combatID, combatantID int) error
+		SetInitiative(combatID int, initiatives map[int]int) error
+		NextTurn(combatID int) error
+		AdjustHP(combatID, combatantID, damage, healing int, tempHP *int) error
+		SetDefeated(combatID, combatantID int, defeated bool) error
+		AddCondition(combatID, combatantID int, condition models.AppliedCondition) error
+		RemoveCondition(combatID, combatantID int, condition models.ConditionType) error
+	}
+	diceRolls interface {
+		Insert(roll models.DiceRoll) (int, error)
+		Get(id int) (*models.DiceRoll, error)
+		GetAllForCampaign(campaignID int, characterID *int, limit, offset int) (*[]models.DiceRoll, error)
+	}
+	invitations interface {
+		Insert(campaignID int, invitedUsername, code string, expiresAt *time.Time) (int, error)
+		Get(id int) (*models.CampaignInvitation, error)
+		GetAllForCampaign(campaignID int) (*[]models.CampaignInvitation, error)
+		GetPendingForPlayer(username string) (*[]models.CampaignInvitation, error)
+		Respond(id int, status models.RequestStatusType) error
+		Accept(id, characterID int) error
+		Redeem(code string, characterID int) (int, error)
+	}
+	joinRequests interface {
+		Insert(campaignID, characterID int, message string) (int, error)
+		Get(id int) (*models.CampaignJoinRequest, error)
+		GetAllForCampaign(campaignID int, status models.RequestStatusType) (*[]models.CampaignJoinRequest, error)
+		Accept(id int) error
+		Decline(id int) error
 	}
 	stats interface {
 		GetAll() (*models.Stats, error)
+		GetAdmin() (*models.AdminStats, error)
+	}
+	auditLog interface {
+		Insert(actor, action, target, details string) error
+		GetAll(limit, offset int) (*[]models.AuditLogEntry, error)
 	}
 }
 
 func (app *application) withDB(db *sqlx.DB) *application {
 	app.players = &postgresql.PlayerModel{DB: db}
+	app.twoFactor = &postgresql.TwoFactorModel{DB: db}
+	app.personalTokens = &postgresql.PersonalTokenModel{DB: db}
+	app.identities = &postgresql.IdentityModel{DB: db}
 	app.characters = &postgresql.CharacterModel{DB: db}
+	app.characterClasses = &postgresql.CharacterClassModel{DB: db}
+	app.proficiencies = &postgresql.ProficiencyModel{DB: db}
+	app.conditions = &postgresql.ConditionModel{DB: db}
+	app.hitPoints = &postgresql.HitPointModel{DB: db}
+	app.levels = &postgresql.LevelModel{DB: db}
+	app.features = &postgresql.FeatureModel{DB: db}
+	app.abilityRolls = &postgresql.AbilityRollModel{DB: db}
+	app.registry = &postgresql.RegistryModel{DB: db}
 	app.spells = &postgresql.SpellModel{DB: db}
 	app.items = &postgresql.ItemModel{DB: db}
 	app.campaigns = &postgresql.CampaignModel{DB: db}
 	app.milestones = &postgresql.MilestoneModel{DB: db}
 	app.belongsTo = &postgresql.BelongsToModel{DB: db}
+	app.invitations = &postgresql.InvitationModel{DB: db}
+	app.campaignLog = &postgresql.CampaignLogModel{DB: db}
+	app.journal = &postgresql.JournalModel{DB: db}
+	app.npcs = &postgresql.NPCModel{DB: db}
+	app.encounters = &postgresql.EncounterModel{DB: db}
+	app.combats = &postgresql.CombatModel{DB: db}
+	app.diceRolls = &postgresql.DiceRollModel{DB: db}
+	app.joinRequests = &postgresql.JoinRequestModel{DB: db}
 	app.stats = &postgresql.StatsModel{DB: db}
+	app.auditLog = &postgresql.AuditLogModel{DB: db}
 	return app
 }
 
@@ -87,6 +269,25 @@ func (app *application) withJWTSigningKey(key string) *application {
 	return app
 }
 
+func (app *application) withOIDC(cfg *Config) *application {
+	if !cfg.OIDC.Enabled {
+		return app
+	}
+
+	if strings.TrimSpace(cfg.OIDC.Issuer) == "" || strings.TrimSpace(cfg.OIDC.ClientID) == "" ||
+		strings.TrimSpace(cfg.OIDC.RedirectURL) == "" {
+		app.echoInstance.Logger.Fatal("error: OIDC issuer, client ID and redirect URL must be set")
+	}
+
+	app.oidc = newOIDCProvider(cfg)
+	return app
+}
+
+func (app *application) withEventHub(hub *events.Hub) *application {
+	app.events = hub
+	return app
+}
+
 func (app *application) withEchoInstance(e *echo.Echo) *application {
 	app.echoInstance = e
 	return app
@@ -105,8 +306,10 @@ func startServer(configFile *string) {
 	var app application
 
 	app.withDB(db).
+		withEchoInstance(echo.New()).
 		withJWTSigningKey(cfg.JWTSigningKey).
-		withEchoInstance(echo.New())
+		withOIDC(cfg).
+		withEventHub(events.NewHub(eventHistorySize))
 
 	app.registerMiddleware()
 	app.registerRoutes()
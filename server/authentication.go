@@ -1,15 +1,45 @@
 package main
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"draco/models"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/labstack/gommon/log"
 )
 
+var (
+	errInvalidTOTPCode  = errors.New("authentication: invalid two-factor code")
+	errNotDungeonMaster = errors.New("authentication: requestor is not the campaign's dungeon master")
+	errNotParticipant   = errors.New("authentication: requestor is not participating in the campaign")
+	errNotOwner         = errors.New("authentication: requestor does not own the character")
+)
+
+// loginChallengeTTL is how long a player has to submit their TOTP code
+// after successfully entering their password.
+const loginChallengeTTL = 5 * time.Minute
+
+// personalTokenPrefix identifies bearer tokens which are personal
+// access tokens rather than JWTs.
+const personalTokenPrefix = "draco_pat_"
+
 func (app *application) getJWTConfig() middleware.JWTConfig {
 	return middleware.JWTConfig{
+		// Requests carrying a personal access token have already been
+		// authenticated by `personalTokenAuth` on routes which accept
+		// them. Everywhere else the token is rejected as a malformed JWT.
+		Skipper: func(c echo.Context) bool {
+			return c.Get("token") != nil
+		},
 		SigningKey:  []byte(app.jwtSigningKey),
 		ContextKey:  "token",
 		TokenLookup: "header:" + echo.HeaderAuthorization,
@@ -17,11 +47,89 @@ func (app *application) getJWTConfig() middleware.JWTConfig {
 	}
 }
 
-func (app *application) createJWT(username string) (string, error) {
+// personalTokenFromRequest extracts a personal access token from the
+// Authorization header, if one is present.
+func personalTokenFromRequest(c echo.Context) (string, bool) {
+	auth := c.Request().Header.Get(echo.HeaderAuthorization)
+	token := strings.TrimPrefix(auth, "Bearer ")
+	if token == auth || !strings.HasPrefix(token, personalTokenPrefix) {
+		return "", false
+	}
+	return token, true
+}
+
+// personalTokenAuth authenticates requests which carry a personal
+// access token. On success, the request context holds a token with the
+// same claims as a JWT so that handlers do not need to distinguish the
+// two. Requests without a personal access token are passed through to
+// the JWT middleware.
+func (app *application) personalTokenAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		rawToken, ok := personalTokenFromRequest(c)
+		if !ok {
+			return next(c)
+		}
+
+		username, scopes, err := app.personalTokens.Authenticate(hashToken(rawToken))
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusUnauthorized, "Personal access token", "Access denied", nil)
+		}
+
+		if !personalTokenPermits(scopes, c.Request().Method, c.Path()) {
+			return sendJSONResponse(c, http.StatusForbidden, "Personal access token", "Token scope does not permit this request", nil)
+		}
+
+		c.Set("token", &jwt.Token{
+			Claims: jwt.MapClaims{
+				"username": username,
+				"pat":      true,
+			},
+			Valid: true,
+		})
+		return next(c)
+	}
+}
+
+// personalTokenPermits reports whether a token with `scopes` may be used
+// for a request to the route `path` using `method`. Account management
+// routes can never be accessed using a personal access token.
+func personalTokenPermits(scopes []models.TokenScope, method, path string) bool {
+	path = strings.TrimPrefix(path, "/auth")
+	if strings.HasPrefix(path, "/player/me") {
+		return false
+	}
+
+	for _, scope := range scopes {
+		switch scope {
+		case models.ScopeReadOnly:
+			if method == http.MethodGet || method == http.MethodHead {
+				return true
+			}
+		case models.ScopeCharacters:
+			if strings.HasPrefix(path, "/character") {
+				return true
+			}
+		case models.ScopeCampaigns:
+			if strings.HasPrefix(path, "/campaign") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// createJWT issues a token for `player`. The token carries the ID of the
+// player along with their username, so that it stops working once the
+// username is changed or taken by someone else.
+func (app *application) createJWT(player *models.Player) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 
 	claims := token.Claims.(jwt.MapClaims)
-	claims["username"] = username
+	claims["username"] = player.Username
+	claims["player_id"] = player.ID
+	claims["role"] = player.Role
 	claims["exp"] = time.Now().Add(time.Hour * 72).Unix()
 
 	tokenString, err := token.SignedString([]byte(app.jwtSigningKey))
@@ -38,3 +146,259 @@ func getUsernameFromToken(c echo.Context) string {
 	tokenUsername := claims["username"].(string)
 	return tokenUsername
 }
+
+// getPlayerIDFromToken returns the ID of the player the request's token
+// was issued to, or false if it carries none.
+func getPlayerIDFromToken(c echo.Context) (int, bool) {
+	token := c.Get("token").(*jwt.Token)
+	claims := token.Claims.(jwt.MapClaims)
+	id, ok := claims["player_id"].(float64) // JSON numbers decode as float64
+	return int(id), ok
+}
+
+// isPersonalToken reports whether the request was authenticated with a
+// personal access token.
+func isPersonalToken(c echo.Context) bool {
+	token := c.Get("token").(*jwt.Token)
+	claims := token.Claims.(jwt.MapClaims)
+	pat, _ := claims["pat"].(bool)
+	return pat
+}
+
+func getRoleFromToken(c echo.Context) models.RoleType {
+	token := c.Get("token").(*jwt.Token)
+	claims := token.Claims.(jwt.MapClaims)
+	role, _ := claims["role"].(string)
+	return models.RoleType(role)
+}
+
+// requireActivePlayer rejects requests from players whose account has
+// been disabled by an administrator, and tokens issued before a change
+// of username. Players who have been asked to reset their password may
+// only change their password.
+func (app *application) requireActivePlayer(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// Stream tickets are only good for event streams, which accept
+		// nothing else
+		if _, isTicket := getStreamCampaignFromToken(c); isTicket != isStreamPath(c.Path()) {
+			return sendJSONResponse(c, http.StatusUnauthorized, "Authentication", "Access denied", nil)
+		}
+
+		player, err := app.players.Get(getUsernameFromToken(c))
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusUnauthorized, "Authentication", "Access denied", nil)
+		}
+
+		// Personal access tokens are looked up by their current owner,
+		// but other tokens name the player as they were when issued
+		if !isPersonalToken(c) {
+			if id, ok := getPlayerIDFromToken(c); !ok || id != player.ID {
+				return sendJSONResponse(c, http.StatusUnauthorized, "Authentication", "Access denied", nil)
+			}
+		}
+
+		if player.Disabled {
+			return sendJSONResponse(c, http.StatusForbidden, "Authentication", "Account disabled", nil)
+		}
+
+		isPasswordChange := c.Request().Method == http.MethodPut && c.Path() == "/auth/player/me/password"
+		if player.PasswordResetRequired && !isPasswordChange {
+			return sendJSONResponse(c, http.StatusForbidden, "Authentication", "Password reset required", nil)
+		}
+
+		return next(c)
+	}
+}
+
+// requireAdmin rejects requests from players who are not
+// administrators. The role claim in the token is checked first, but the
+// stored role is authoritative so that demoted administrators lose
+// access immediately.
+func (app *application) requireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if getRoleFromToken(c) != models.RoleAdmin {
+			return sendJSONResponse(c, http.StatusForbidden, "Administration", "Access denied", nil)
+		}
+
+		player, err := app.players.Get(getUsernameFromToken(c))
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusForbidden, "Administration", "Access denied", nil)
+		}
+		if player.Role != models.RoleAdmin || player.Disabled {
+			return sendJSONResponse(c, http.StatusForbidden, "Administration", "Access denied", nil)
+		}
+
+		return next(c)
+	}
+}
+
+// generateRandomToken returns a URL-safe string containing `size` bytes
+// of cryptographically secure random data.
+func generateRandomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex-encoded SHA-256 digest of `token`. Random
+// tokens have enough entropy that a fast hash is sufficient; passwords
+// must still be hashed using bcrypt.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// verifySecondFactor checks that either `code` is a valid TOTP code or
+// `recoveryCode` is an unused recovery code for the player identified
+// by `username`. TOTP codes are checked first when both are supplied.
+func (app *application) verifySecondFactor(username, code, recoveryCode string) error {
+	secret, enabled, err := app.twoFactor.GetSecret(username)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return models.ErrTwoFactorNotEnabled
+	}
+
+	if strings.TrimSpace(code) != "" {
+		counter, ok := validateTOTPCode(secret, code, time.Now())
+		if !ok {
+			return errInvalidTOTPCode
+		}
+		return app.twoFactor.ClaimCounter(username, counter)
+	}
+
+	recoveryCode = strings.ToLower(strings.TrimSpace(recoveryCode))
+	if recoveryCode == "" {
+		return errInvalidTOTPCode
+	}
+	return app.twoFactor.UseRecoveryCode(username, recoveryCode)
+}
+
+// authorizeDungeonMaster retrieves the campaign identified by
+// `campaignID` and verifies that the requestor is its dungeon master.
+func (app *application) authorizeDungeonMaster(c echo.Context, campaignID int) (*models.Campaign, error) {
+	campaign, err := app.campaigns.Get(campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isDungeonMaster(campaign, getUsernameFromToken(c)) {
+		return nil, errNotDungeonMaster
+	}
+
+	return campaign, nil
+}
+
+// authorizeParticipant retrieves the campaign identified by
+// `campaignID` and verifies that the requestor is either its dungeon
+// master or plays a character in it.
+func (app *application) authorizeParticipant(c echo.Context, campaignID int) (*models.Campaign, error) {
+	campaign, err := app.campaigns.Get(campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	username := getUsernameFromToken(c)
+	if isDungeonMaster(campaign, username) {
+		return campaign, nil
+	}
+
+	isPlayer, err := app.belongsTo.IsPlayerInCampaign(username, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if !isPlayer {
+		return nil, errNotParticipant
+	}
+
+	return campaign, nil
+}
+
+// authorizeCharacterOwner retrieves the character identified by
+// `characterID` and verifies that it belongs to the requestor.
+func (app *application) authorizeCharacterOwner(c echo.Context, characterID int) (*models.Character, error) {
+	character, err := app.characters.Get(characterID)
+	if err != nil {
+		return nil, err
+	}
+
+	if character.PlayerUsername != getUsernameFromToken(c) {
+		return nil, errNotOwner
+	}
+
+	return character, nil
+}
+
+// authorizeCharacterKeeper retrieves the character identified by
+// `characterID` and verifies that the requestor either owns it or is the
+// dungeon master of a campaign it plays in, who keeps track of what
+// happens to it during play.
+func (app *application) authorizeCharacterKeeper(c echo.Context, characterID int) (*models.Character, error) {
+	character, err := app.characters.Get(characterID)
+	if err != nil {
+		return nil, err
+	}
+
+	username := getUsernameFromToken(c)
+	if character.PlayerUsername == username {
+		return character, nil
+	}
+
+	campaigns, err := app.belongsTo.GetAllCharacterCampaigns(characterID)
+	if err != nil {
+		return nil, err
+	}
+	for _, campaign := range *campaigns {
+		if isDungeonMaster(&campaign, username) {
+			return character, nil
+		}
+	}
+
+	return nil, errNotOwner
+}
+
+func isDungeonMaster(campaign *models.Campaign, username string) bool {
+	return campaign.DungeonMaster != nil && *campaign.DungeonMaster == username
+}
+
+// ensureCampaignWritable returns models.ErrCampaignArchived if
+// `campaign` has been archived, since archived campaigns are read-only.
+func ensureCampaignWritable(campaign *models.Campaign) error {
+	if campaign.State == models.CampaignArchived {
+		return models.ErrCampaignArchived
+	}
+	return nil
+}
+
+// authorizeWritableCampaign is authorizeDungeonMaster for requests
+// which modify the campaign.
+func (app *application) authorizeWritableCampaign(c echo.Context, campaignID int) (*models.Campaign, error) {
+	campaign, err := app.authorizeDungeonMaster(c, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureCampaignWritable(campaign); err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// authorizationStatus maps an error returned by one of the authorize
+// helpers to an HTTP status code.
+func authorizationStatus(err error) int {
+	switch {
+	case errors.Is(err, models.ErrNoRecord):
+		return http.StatusNotFound
+	case errors.Is(err, errNotDungeonMaster), errors.Is(err, errNotParticipant), errors.Is(err, errNotOwner):
+		return http.StatusForbidden
+	case errors.Is(err, models.ErrCampaignArchived):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
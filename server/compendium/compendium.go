@@ -0,0 +1,90 @@
+// Package compendium bundles the stat blocks of common creatures from the
+// 5th edition System Reference Document, so that dungeon masters can add
+// them to a campaign without looking them up elsewhere.
+package compendium
+
+import (
+	"draco/models"
+	"sort"
+	"strings"
+)
+
+// Monster is a creature's stat block as it appears in the compendium.
+type Monster struct {
+	Slug            string             `json:"slug"`
+	Name            string             `json:"name"`
+	Kind            models.NPCKindType `json:"kind"`
+	Size            string             `json:"size"`
+	CreatureType    string             `json:"creature_type"`
+	Alignment       string             `json:"alignment"`
+	ArmorClass      int                `json:"armor_class"`
+	HPMax           int                `json:"hp_max"`
+	HitDice         string             `json:"hit_dice"`
+	Speed           int                `json:"speed"`
+	ChallengeRating string             `json:"challenge_rating"`
+	Strength        int                `json:"strength"`
+	Dexterity       int                `json:"dexterity"`
+	Constitution    int                `json:"constitution"`
+	Intelligence    int                `json:"intelligence"`
+	Wisdom          int                `json:"wisdom"`
+	Charisma        int                `json:"charisma"`
+	Actions         []models.NPCAction `json:"actions"`
+}
+
+// Search returns the monsters whose name contains `query`, ignoring
+// case, in alphabetical order. Every monster is returned if `query` is
+// empty.
+func Search(query string) []Monster {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	found := []Monster{}
+	for _, m := range monsters {
+		if strings.Contains(strings.ToLower(m.Name), query) {
+			found = append(found, m)
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool { return found[i].Name < found[j].Name })
+	return found
+}
+
+// Get returns the monster identified by `slug`.
+func Get(slug string) (Monster, bool) {
+	for _, m := range monsters {
+		if m.Slug == slug {
+			return m, true
+		}
+	}
+	return Monster{}, false
+}
+
+// NPC converts the monster into an NPC belonging to the campaign
+// identified by `campaignID`.
+func (m Monster) NPC(campaignID int) models.CampaignNPC {
+	slug := m.Slug
+	actions := make([]models.NPCAction, len(m.Actions))
+	copy(actions, m.Actions)
+
+	return models.CampaignNPC{
+		CampaignID:      campaignID,
+		Name:            m.Name,
+		Kind:            m.Kind,
+		Size:            m.Size,
+		CreatureType:    m.CreatureType,
+		Alignment:       m.Alignment,
+		ArmorClass:      m.ArmorClass,
+		HPMax:           m.HPMax,
+		HitDice:         m.HitDice,
+		Speed:           m.Speed,
+		ChallengeRating: m.ChallengeRating,
+		Strength:        m.Strength,
+		Dexterity:       m.Dexterity,
+		Constitution:    m.Constitution,
+		Intelligence:    m.Intelligence,
+		Wisdom:          m.Wisdom,
+		Charisma:        m.Charisma,
+		CompendiumSlug:  &slug,
+		Actions:         actions,
+		Tags:            []string{},
+	}
+}
@@ -0,0 +1,275 @@
+package compendium
+
+import "draco/models"
+
+// monsters holds the stat blocks of the compendium. Values are taken from
+// the System Reference Document 5.1.
+var monsters = []Monster{
+	{
+		Slug: "bandit", Name: "Bandit", Kind: models.NPCKindNPC,
+		Size: "Medium", CreatureType: "humanoid (any race)", Alignment: "any non-lawful alignment",
+		ArmorClass: 12, HPMax: 11, HitDice: "2d8+2", Speed: 30, ChallengeRating: "1/8",
+		Strength: 11, Dexterity: 12, Constitution: 12, Intelligence: 10, Wisdom: 10, Charisma: 10,
+		Actions: []models.NPCAction{
+			{Name: "Scimitar", Description: "Melee Weapon Attack: +3 to hit, reach 5 ft., one target. Hit: 4 (1d6 + 1) slashing damage."},
+			{Name: "Light Crossbow", Description: "Ranged Weapon Attack: +3 to hit, range 80/320 ft., one target. Hit: 5 (1d8 + 1) piercing damage."},
+		},
+	},
+	{
+		Slug: "basilisk", Name: "Basilisk", Kind: models.NPCKindMonster,
+		Size: "Medium", CreatureType: "monstrosity", Alignment: "unaligned",
+		ArmorClass: 15, HPMax: 52, HitDice: "8d8+16", Speed: 20, ChallengeRating: "3",
+		Strength: 16, Dexterity: 8, Constitution: 15, Intelligence: 2, Wisdom: 8, Charisma: 7,
+		Actions: []models.NPCAction{
+			{Name: "Petrifying Gaze", Description: "A creature that starts its turn within 30 feet of the basilisk and can see its eyes must succeed on a DC 12 Constitution saving throw or begin to turn to stone."},
+			{Name: "Bite", Description: "Melee Weapon Attack: +5 to hit, reach 5 ft., one target. Hit: 10 (2d6 + 3) piercing damage plus 7 (2d6) poison damage."},
+		},
+	},
+	{
+		Slug: "bugbear", Name: "Bugbear", Kind: models.NPCKindMonster,
+		Size: "Medium", CreatureType: "humanoid (goblinoid)", Alignment: "chaotic evil",
+		ArmorClass: 16, HPMax: 27, HitDice: "5d8+5", Speed: 30, ChallengeRating: "1",
+		Strength: 15, Dexterity: 14, Constitution: 13, Intelligence: 8, Wisdom: 11, Charisma: 9,
+		Actions: []models.NPCAction{
+			{Name: "Brute", Description: "A melee weapon deals one extra die of its damage when the bugbear hits with it (included in the attack)."},
+			{Name: "Surprise Attack", Description: "If the bugbear surprises a creature and hits it with an attack during the first round of combat, the target takes an extra 7 (2d6) damage from the attack."},
+			{Name: "Morningstar", Description: "Melee Weapon Attack: +4 to hit, reach 5 ft., one target. Hit: 11 (2d8 + 2) piercing damage."},
+			{Name: "Javelin", Description: "Melee or Ranged Weapon Attack: +4 to hit, reach 5 ft. or range 30/120 ft., one target. Hit: 9 (2d6 + 2) piercing damage in melee or 5 (1d6 + 2) piercing damage at range."},
+		},
+	},
+	{
+		Slug: "commoner", Name: "Commoner", Kind: models.NPCKindNPC,
+		Size: "Medium", CreatureType: "humanoid (any race)", Alignment: "any alignment",
+		ArmorClass: 10, HPMax: 4, HitDice: "1d8", Speed: 30, ChallengeRating: "0",
+		Strength: 10, Dexterity: 10, Constitution: 10, Intelligence: 10, Wisdom: 10, Charisma: 10,
+		Actions: []models.NPCAction{
+			{Name: "Club", Description: "Melee Weapon Attack: +2 to hit, reach 5 ft., one target. Hit: 2 (1d4) bludgeoning damage."},
+		},
+	},
+	{
+		Slug: "dire-wolf", Name: "Dire Wolf", Kind: models.NPCKindMonster,
+		Size: "Large", CreatureType: "beast", Alignment: "unaligned",
+		ArmorClass: 14, HPMax: 37, HitDice: "5d10+10", Speed: 50, ChallengeRating: "1",
+		Strength: 17, Dexterity: 15, Constitution: 15, Intelligence: 3, Wisdom: 12, Charisma: 7,
+		Actions: []models.NPCAction{
+			{Name: "Pack Tactics", Description: "The wolf has advantage on an attack roll against a creature if at least one of the wolf's allies is within 5 feet of the creature and the ally isn't incapacitated."},
+			{Name: "Bite", Description: "Melee Weapon Attack: +5 to hit, reach 5 ft., one target. Hit: 10 (2d6 + 3) piercing damage. If the target is a creature, it must succeed on a DC 13 Strength saving throw or be knocked prone."},
+		},
+	},
+	{
+		Slug: "ghoul", Name: "Ghoul", Kind: models.NPCKindMonster,
+		Size: "Medium", CreatureType: "undead", Alignment: "chaotic evil",
+		ArmorClass: 12, HPMax: 22, HitDice: "5d8", Speed: 30, ChallengeRating: "1",
+		Strength: 13, Dexterity: 15, Constitution: 10, Intelligence: 7, Wisdom: 10, Charisma: 6,
+		Actions: []models.NPCAction{
+			{Name: "Bite", Description: "Melee Weapon Attack: +2 to hit, reach 5 ft., one creature. Hit: 9 (2d6 + 2) piercing damage."},
+			{Name: "Claws", Description: "Melee Weapon Attack: +4 to hit, reach 5 ft., one target. Hit: 7 (2d4 + 2) slashing damage. If the target is a creature other than an elf or undead, it must succeed on a DC 10 Constitution saving throw or be paralyzed for 1 minute."},
+		},
+	},
+	{
+		Slug: "giant-spider", Name: "Giant Spider", Kind: models.NPCKindMonster,
+		Size: "Large", CreatureType: "beast", Alignment: "unaligned",
+		ArmorClass: 14, HPMax: 26, HitDice: "4d10+4", Speed: 30, ChallengeRating: "1",
+		Strength: 14, Dexterity: 16, Constitution: 12, Intelligence: 2, Wisdom: 11, Charisma: 4,
+		Actions: []models.NPCAction{
+			{Name: "Spider Climb", Description: "The spider can climb difficult surfaces, including upside down on ceilings, without needing to make an ability check."},
+			{Name: "Bite", Description: "Melee Weapon Attack: +5 to hit, reach 5 ft., one creature. Hit: 7 (1d8 + 3) piercing damage, and the target must make a DC 11 Constitution saving throw, taking 9 (2d8) poison damage on a failed save, or half as much damage on a successful one."},
+			{Name: "Web (Recharge 5-6)", Description: "Ranged Weapon Attack: +5 to hit, range 30/60 ft., one creature. Hit: The target is restrained by webbing (escape DC 12)."},
+		},
+	},
+	{
+		Slug: "gnoll", Name: "Gnoll", Kind: models.NPCKindMonster,
+		Size: "Medium", CreatureType: "humanoid (gnoll)", Alignment: "chaotic evil",
+		ArmorClass: 15, HPMax: 22, HitDice: "5d8", Speed: 30, ChallengeRating: "1/2",
+		Strength: 14, Dexterity: 12, Constitution: 11, Intelligence: 6, Wisdom: 10, Charisma: 7,
+		Actions: []models.NPCAction{
+			{Name: "Rampage", Description: "When the gnoll reduces a creature to 0 hit points with a melee attack on its turn, the gnoll can take a bonus action to move up to half its speed and make a bite attack."},
+			{Name: "Bite", Description: "Melee Weapon Attack: +4 to hit, reach 5 ft., one creature. Hit: 4 (1d4 + 2) piercing damage."},
+			{Name: "Spear", Description: "Melee or Ranged Weapon Attack: +4 to hit, reach 5 ft. or range 20/60 ft., one target. Hit: 5 (1d6 + 2) piercing damage."},
+			{Name: "Longbow", Description: "Ranged Weapon Attack: +3 to hit, range 150/600 ft., one target. Hit: 5 (1d8 + 1) piercing damage."},
+		},
+	},
+	{
+		Slug: "goblin", Name: "Goblin", Kind: models.NPCKindMonster,
+		Size: "Small", CreatureType: "humanoid (goblinoid)", Alignment: "neutral evil",
+		ArmorClass: 15, HPMax: 7, HitDice: "2d6", Speed: 30, ChallengeRating: "1/4",
+		Strength: 8, Dexterity: 14, Constitution: 10, Intelligence: 10, Wisdom: 8, Charisma: 8,
+		Actions: []models.NPCAction{
+			{Name: "Nimble Escape", Description: "The goblin can take the Disengage or Hide action as a bonus action on each of its turns."},
+			{Name: "Scimitar", Description: "Melee Weapon Attack: +4 to hit, reach 5 ft., one target. Hit: 5 (1d6 + 2) slashing damage."},
+			{Name: "Shortbow", Description: "Ranged Weapon Attack: +4 to hit, range 80/320 ft., one target. Hit: 5 (1d6 + 2) piercing damage."},
+		},
+	},
+	{
+		Slug: "guard", Name: "Guard", Kind: models.NPCKindNPC,
+		Size: "Medium", CreatureType: "humanoid (any race)", Alignment: "any alignment",
+		ArmorClass: 16, HPMax: 11, HitDice: "2d8+2", Speed: 30, ChallengeRating: "1/8",
+		Strength: 13, Dexterity: 12, Constitution: 12, Intelligence: 10, Wisdom: 11, Charisma: 10,
+		Actions: []models.NPCAction{
+			{Name: "Spear", Description: "Melee or Ranged Weapon Attack: +3 to hit, reach 5 ft. or range 20/60 ft., one target. Hit: 4 (1d6 + 1) piercing damage, or 5 (1d8 + 1) piercing damage if used with two hands to make a melee attack."},
+		},
+	},
+	{
+		Slug: "hobgoblin", Name: "Hobgoblin", Kind: models.NPCKindMonster,
+		Size: "Medium", CreatureType: "humanoid (goblinoid)", Alignment: "lawful evil",
+		ArmorClass: 18, HPMax: 11, HitDice: "2d8+2", Speed: 30, ChallengeRating: "1/2",
+		Strength: 13, Dexterity: 12, Constitution: 12, Intelligence: 10, Wisdom: 10, Charisma: 9,
+		Actions: []models.NPCAction{
+			{Name: "Martial Advantage", Description: "Once per turn, the hobgoblin can deal an extra 7 (2d6) damage to a creature it hits with a weapon attack if that creature is within 5 feet of an ally of the hobgoblin that isn't incapacitated."},
+			{Name: "Longsword", Description: "Melee Weapon Attack: +3 to hit, reach 5 ft., one target. Hit: 5 (1d8 + 1) slashing damage, or 6 (1d10 + 1) slashing damage if used with two hands."},
+			{Name: "Longbow", Description: "Ranged Weapon Attack: +3 to hit, range 150/600 ft., one target. Hit: 5 (1d8 + 1) piercing damage."},
+		},
+	},
+	{
+		Slug: "kobold", Name: "Kobold", Kind: models.NPCKindMonster,
+		Size: "Small", CreatureType: "humanoid (kobold)", Alignment: "lawful evil",
+		ArmorClass: 12, HPMax: 5, HitDice: "2d6-2", Speed: 30, ChallengeRating: "1/8",
+		Strength: 7, Dexterity: 15, Constitution: 9, Intelligence: 8, Wisdom: 7, Charisma: 8,
+		Actions: []models.NPCAction{
+			{Name: "Sunlight Sensitivity", Description: "While in sunlight, the kobold has disadvantage on attack rolls, as well as on Wisdom (Perception) checks that rely on sight."},
+			{Name: "Pack Tactics", Description: "The kobold has advantage on an attack roll against a creature if at least one of the kobold's allies is within 5 feet of the creature and the ally isn't incapacitated."},
+			{Name: "Dagger", Description: "Melee Weapon Attack: +4 to hit, reach 5 ft., one target. Hit: 4 (1d4 + 2) piercing damage."},
+			{Name: "Sling", Description: "Ranged Weapon Attack: +4 to hit, range 30/120 ft., one target. Hit: 4 (1d4 + 2) bludgeoning damage."},
+		},
+	},
+	{
+		Slug: "mage", Name: "Mage", Kind: models.NPCKindNPC,
+		Size: "Medium", CreatureType: "humanoid (any race)", Alignment: "any alignment",
+		ArmorClass: 12, HPMax: 40, HitDice: "9d8", Speed: 30, ChallengeRating: "6",
+		Strength: 9, Dexterity: 14, Constitution: 11, Intelligence: 17, Wisdom: 12, Charisma: 11,
+		Actions: []models.NPCAction{
+			{Name: "Spellcasting", Description: "The mage is a 9th-level spellcaster. Its spellcasting ability is Intelligence (spell save DC 14, +6 to hit with spell attacks)."},
+			{Name: "Dagger", Description: "Melee or Ranged Weapon Attack: +5 to hit, reach 5 ft. or range 20/60 ft., one target. Hit: 4 (1d4 + 2) piercing damage."},
+		},
+	},
+	{
+		Slug: "mimic", Name: "Mimic", Kind: models.NPCKindMonster,
+		Size: "Medium", CreatureType: "monstrosity (shapechanger)", Alignment: "neutral",
+		ArmorClass: 12, HPMax: 58, HitDice: "9d8+18", Speed: 15, ChallengeRating: "2",
+		Strength: 17, Dexterity: 12, Constitution: 15, Intelligence: 5, Wisdom: 13, Charisma: 8,
+		Actions: []models.NPCAction{
+			{Name: "Adhesive", Description: "The mimic adheres to anything that touches it. A Huge or smaller creature adhered to the mimic is also grappled by it (escape DC 13)."},
+			{Name: "Pseudopod", Description: "Melee Weapon Attack: +5 to hit, reach 5 ft., one target. Hit: 7 (1d8 + 3) bludgeoning damage. If the mimic is in object form, the target is subjected to its Adhesive trait."},
+			{Name: "Bite", Description: "Melee Weapon Attack: +5 to hit, reach 5 ft., one target. Hit: 7 (1d8 + 3) piercing damage plus 4 (1d8) acid damage."},
+		},
+	},
+	{
+		Slug: "noble", Name: "Noble", Kind: models.NPCKindNPC,
+		Size: "Medium", CreatureType: "humanoid (any race)", Alignment: "any alignment",
+		ArmorClass: 15, HPMax: 9, HitDice: "2d8", Speed: 30, ChallengeRating: "1/8",
+		Strength: 11, Dexterity: 12, Constitution: 11, Intelligence: 12, Wisdom: 14, Charisma: 16,
+		Actions: []models.NPCAction{
+			{Name: "Rapier", Description: "Melee Weapon Attack: +3 to hit, reach 5 ft., one target. Hit: 5 (1d8 + 1) piercing damage."},
+			{Name: "Parry", Description: "Reaction: The noble adds 2 to its AC against one melee attack that would hit it. To do so, the noble must see the attacker and be wielding a melee weapon."},
+		},
+	},
+	{
+		Slug: "ogre", Name: "Ogre", Kind: models.NPCKindMonster,
+		Size: "Large", CreatureType: "giant", Alignment: "chaotic evil",
+		ArmorClass: 11, HPMax: 59, HitDice: "7d10+21", Speed: 40, ChallengeRating: "2",
+		Strength: 19, Dexterity: 8, Constitution: 16, Intelligence: 5, Wisdom: 7, Charisma: 7,
+		Actions: []models.NPCAction{
+			{Name: "Greatclub", Description: "Melee Weapon Attack: +6 to hit, reach 5 ft., one target. Hit: 13 (2d8 + 4) bludgeoning damage."},
+			{Name: "Javelin", Description: "Melee or Ranged Weapon Attack: +6 to hit, reach 5 ft. or range 30/120 ft., one target. Hit: 11 (2d6 + 4) piercing damage."},
+		},
+	},
+	{
+		Slug: "orc", Name: "Orc", Kind: models.NPCKindMonster,
+		Size: "Medium", CreatureType: "humanoid (orc)", Alignment: "chaotic evil",
+		ArmorClass: 13, HPMax: 15, HitDice: "2d8+6", Speed: 30, ChallengeRating: "1/2",
+		Strength: 16, Dexterity: 12, Constitution: 16, Intelligence: 7, Wisdom: 11, Charisma: 10,
+		Actions: []models.NPCAction{
+			{Name: "Aggressive", Description: "As a bonus action, the orc can move up to its speed toward a hostile creature that it can see."},
+			{Name: "Greataxe", Description: "Melee Weapon Attack: +5 to hit, reach 5 ft., one target. Hit: 9 (1d12 + 3) slashing damage."},
+			{Name: "Javelin", Description: "Melee or Ranged Weapon Attack: +5 to hit, reach 5 ft. or range 30/120 ft., one target. Hit: 6 (1d6 + 3) piercing damage."},
+		},
+	},
+	{
+		Slug: "owlbear", Name: "Owlbear", Kind: models.NPCKindMonster,
+		Size: "Large", CreatureType: "monstrosity", Alignment: "unaligned",
+		ArmorClass: 13, HPMax: 59, HitDice: "7d10+21", Speed: 40, ChallengeRating: "3",
+		Strength: 20, Dexterity: 12, Constitution: 17, Intelligence: 3, Wisdom: 12, Charisma: 7,
+		Actions: []models.NPCAction{
+			{Name: "Multiattack", Description: "The owlbear makes two attacks: one with its beak and one with its claws."},
+			{Name: "Beak", Description: "Melee Weapon Attack: +7 to hit, reach 5 ft., one creature. Hit: 10 (1d10 + 5) piercing damage."},
+			{Name: "Claws", Description: "Melee Weapon Attack: +7 to hit, reach 5 ft., one target. Hit: 14 (2d8 + 5) slashing damage."},
+		},
+	},
+	{
+		Slug: "priest", Name: "Priest", Kind: models.NPCKindNPC,
+		Size: "Medium", CreatureType: "humanoid (any race)", Alignment: "any alignment",
+		ArmorClass: 13, HPMax: 27, HitDice: "5d8+5", Speed: 25, ChallengeRating: "2",
+		Strength: 10, Dexterity: 10, Constitution: 12, Intelligence: 13, Wisdom: 16, Charisma: 13,
+		Actions: []models.NPCAction{
+			{Name: "Divine Eminence", Description: "As a bonus action, the priest can expend a spell slot to cause its melee weapon attacks to magically deal an extra 10 (3d6) radiant damage to a target on a hit."},
+			{Name: "Spellcasting", Description: "The priest is a 5th-level spellcaster. Its spellcasting ability is Wisdom (spell save DC 13, +5 to hit with spell attacks)."},
+			{Name: "Mace", Description: "Melee Weapon Attack: +2 to hit, reach 5 ft., one target. Hit: 3 (1d6) bludgeoning damage."},
+		},
+	},
+	{
+		Slug: "skeleton", Name: "Skeleton", Kind: models.NPCKindMonster,
+		Size: "Medium", CreatureType: "undead", Alignment: "lawful evil",
+		ArmorClass: 13, HPMax: 13, HitDice: "2d8+4", Speed: 30, ChallengeRating: "1/4",
+		Strength: 10, Dexterity: 14, Constitution: 15, Intelligence: 6, Wisdom: 8, Charisma: 5,
+		Actions: []models.NPCAction{
+			{Name: "Shortsword", Description: "Melee Weapon Attack: +4 to hit, reach 5 ft., one target. Hit: 5 (1d6 + 2) piercing damage."},
+			{Name: "Shortbow", Description: "Ranged Weapon Attack: +4 to hit, range 80/320 ft., one target. Hit: 5 (1d6 + 2) piercing damage."},
+		},
+	},
+	{
+		Slug: "troll", Name: "Troll", Kind: models.NPCKindMonster,
+		Size: "Large", CreatureType: "giant", Alignment: "chaotic evil",
+		ArmorClass: 15, HPMax: 84, HitDice: "8d10+40", Speed: 30, ChallengeRating: "5",
+		Strength: 18, Dexterity: 13, Constitution: 20, Intelligence: 7, Wisdom: 9, Charisma: 7,
+		Actions: []models.NPCAction{
+			{Name: "Regeneration", Description: "The troll regains 10 hit points at the start of its turn. If the troll takes acid or fire damage, this trait doesn't function at the start of the troll's next turn."},
+			{Name: "Multiattack", Description: "The troll makes three attacks: one with its bite and two with its claws."},
+			{Name: "Bite", Description: "Melee Weapon Attack: +7 to hit, reach 5 ft., one target. Hit: 7 (1d6 + 4) piercing damage."},
+			{Name: "Claw", Description: "Melee Weapon Attack: +7 to hit, reach 5 ft., one target. Hit: 11 (2d6 + 4) slashing damage."},
+		},
+	},
+	{
+		Slug: "veteran", Name: "Veteran", Kind: models.NPCKindNPC,
+		Size: "Medium", CreatureType: "humanoid (any race)", Alignment: "any alignment",
+		ArmorClass: 17, HPMax: 58, HitDice: "9d8+18", Speed: 30, ChallengeRating: "3",
+		Strength: 16, Dexterity: 13, Constitution: 14, Intelligence: 10, Wisdom: 11, Charisma: 10,
+		Actions: []models.NPCAction{
+			{Name: "Multiattack", Description: "The veteran makes two longsword attacks. If it has a shortsword drawn, it can also make a shortsword attack."},
+			{Name: "Longsword", Description: "Melee Weapon Attack: +5 to hit, reach 5 ft., one target. Hit: 7 (1d8 + 3) slashing damage, or 8 (1d10 + 3) slashing damage if used with two hands."},
+			{Name: "Shortsword", Description: "Melee Weapon Attack: +5 to hit, reach 5 ft., one target. Hit: 6 (1d6 + 3) piercing damage."},
+			{Name: "Heavy Crossbow", Description: "Ranged Weapon Attack: +3 to hit, range 100/400 ft., one target. Hit: 6 (1d10 + 1) piercing damage."},
+		},
+	},
+	{
+		Slug: "wolf", Name: "Wolf", Kind: models.NPCKindMonster,
+		Size: "Medium", CreatureType: "beast", Alignment: "unaligned",
+		ArmorClass: 13, HPMax: 11, HitDice: "2d8+2", Speed: 40, ChallengeRating: "1/4",
+		Strength: 12, Dexterity: 15, Constitution: 12, Intelligence: 3, Wisdom: 12, Charisma: 6,
+		Actions: []models.NPCAction{
+			{Name: "Pack Tactics", Description: "The wolf has advantage on an attack roll against a creature if at least one of the wolf's allies is within 5 feet of the creature and the ally isn't incapacitated."},
+			{Name: "Bite", Description: "Melee Weapon Attack: +4 to hit, reach 5 ft., one target. Hit: 7 (2d4 + 2) piercing damage. If the target is a creature, it must succeed on a DC 11 Strength saving throw or be knocked prone."},
+		},
+	},
+	{
+		Slug: "young-red-dragon", Name: "Young Red Dragon", Kind: models.NPCKindMonster,
+		Size: "Large", CreatureType: "dragon", Alignment: "chaotic evil",
+		ArmorClass: 18, HPMax: 178, HitDice: "17d10+85", Speed: 40, ChallengeRating: "10",
+		Strength: 23, Dexterity: 10, Constitution: 21, Intelligence: 14, Wisdom: 11, Charisma: 19,
+		Actions: []models.NPCAction{
+			{Name: "Multiattack", Description: "The dragon makes three attacks: one with its bite and two with its claws."},
+			{Name: "Bite", Description: "Melee Weapon Attack: +10 to hit, reach 10 ft., one target. Hit: 17 (2d10 + 6) piercing damage plus 3 (1d6) fire damage."},
+			{Name: "Claw", Description: "Melee Weapon Attack: +10 to hit, reach 5 ft., one target. Hit: 13 (2d6 + 6) slashing damage."},
+			{Name: "Fire Breath (Recharge 5-6)", Description: "The dragon exhales fire in a 30-foot cone. Each creature in that area must make a DC 17 Dexterity saving throw, taking 56 (16d6) fire damage on a failed save, or half as much damage on a successful one."},
+		},
+	},
+	{
+		Slug: "zombie", Name: "Zombie", Kind: models.NPCKindMonster,
+		Size: "Medium", CreatureType: "undead", Alignment: "neutral evil",
+		ArmorClass: 8, HPMax: 22, HitDice: "3d8+9", Speed: 20, ChallengeRating: "1/4",
+		Strength: 13, Dexterity: 6, Constitution: 16, Intelligence: 3, Wisdom: 6, Charisma: 5,
+		Actions: []models.NPCAction{
+			{Name: "Undead Fortitude", Description: "If damage reduces the zombie to 0 hit points, it must make a Constitution saving throw with a DC of 5 + the damage taken, unless the damage is radiant or from a critical hit. On a success, the zombie drops to 1 hit point instead."},
+			{Name: "Slam", Description: "Melee Weapon Attack: +3 to hit, reach 5 ft., one target. Hit: 4 (1d6 + 1) bludgeoning damage."},
+		},
+	},
+}
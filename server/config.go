@@ -25,6 +25,15 @@ type Config struct {
 		Port int `yaml:"port"`
 	} `yaml:"http_server"`
 	JWTSigningKey string `yaml:"jwt_signing_key"`
+	OIDC          struct {
+		Enabled       bool     `yaml:"enabled"`
+		Issuer        string   `yaml:"issuer"`
+		ClientID      string   `yaml:"client_id"`
+		ClientSecret  string   `yaml:"client_secret"`
+		RedirectURL   string   `yaml:"redirect_url"`
+		Scopes        []string `yaml:"scopes"`
+		AutoProvision bool     `yaml:"auto_provision"`
+	} `yaml:"oidc"`
 }
 
 // CreatePostgreSQLDBConnString returns a formatted string used the
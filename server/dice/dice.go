@@ -0,0 +1,31 @@
+// Package dice rolls dice using a cryptographically secure source of
+// randomness, so that rolls made by the server cannot be predicted.
+package dice
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+// ErrInvalidSides is returned when rolling a die with fewer than one
+// side.
+var ErrInvalidSides = errors.New("dice: a die must have at least one side")
+
+// Roll rolls a single die with `sides` sides.
+func Roll(sides int) (int, error) {
+	if sides < 1 {
+		return 0, ErrInvalidSides
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(sides)))
+	if err != nil {
+		return 0, err
+	}
+	return int(n.Int64()) + 1, nil
+}
+
+// D20 rolls a twenty-sided die.
+func D20() (int, error) {
+	return Roll(20)
+}
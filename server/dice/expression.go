@@ -0,0 +1,397 @@
+package dice
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits on dice expressions, so that a single roll stays cheap.
+const (
+	MaxTerms        = 20
+	MaxDice         = 100
+	MaxSides        = 1000
+	MaxConstant     = 10000
+	maxExplosions   = 100
+	maxNumberLength = 5
+)
+
+// ErrNoD20 is returned when rolling with advantage or disadvantage an
+// expression which has no single d20 to roll twice.
+var ErrNoD20 = errors.New("dice: advantage and disadvantage need a single d20 to roll")
+
+// ExpressionError describes why an expression could not be parsed.
+type ExpressionError struct {
+	Expression string
+	Offset     int // Counted with spaces removed
+	Reason     string
+}
+
+func (e *ExpressionError) Error() string {
+	return fmt.Sprintf("dice: %s at offset %d of %q", e.Reason, e.Offset, e.Expression)
+}
+
+// KeepRule selects which dice of a term count towards its value.
+type KeepRule string
+
+const (
+	KeepAll     KeepRule = ""
+	KeepHighest          = "kh"
+	KeepLowest           = "kl"
+	DropHighest          = "dh"
+	DropLowest           = "dl"
+)
+
+// Term is a group of identical dice, or a constant when Count is zero.
+type Term struct {
+	Negative  bool
+	Count     int
+	Sides     int
+	Exploding bool // Dice showing their highest face are rolled again
+	Keep      KeepRule
+	KeepCount int
+	Constant  int
+}
+
+func (t Term) String() string {
+	if t.Count == 0 {
+		return strconv.Itoa(t.Constant)
+	}
+
+	var b strings.Builder
+	b.WriteString(strconv.Itoa(t.Count))
+	b.WriteString("d")
+	if t.Sides == 100 {
+		b.WriteString("%")
+	} else {
+		b.WriteString(strconv.Itoa(t.Sides))
+	}
+	if t.Exploding {
+		b.WriteString("!")
+	}
+	if t.Keep != KeepAll {
+		b.WriteString(string(t.Keep))
+		b.WriteString(strconv.Itoa(t.KeepCount))
+	}
+	return b.String()
+}
+
+// Expression is a sum of dice and constants, such as `4d6kh3+2`.
+type Expression struct {
+	Terms []Term
+}
+
+// String returns the expression in its canonical form.
+func (e *Expression) String() string {
+	var b strings.Builder
+	for i, t := range e.Terms {
+		if t.Negative {
+			b.WriteString("-")
+		} else if i > 0 {
+			b.WriteString("+")
+		}
+		b.WriteString(t.String())
+	}
+	return b.String()
+}
+
+// Parse reads an expression made of dice terms and constants joined by
+// `+` and `-`. A dice term is written `NdS`, where the count N defaults
+// to one and `d%` stands for `d100`, optionally followed by `!` to
+// explode dice and by one of `khN`, `klN`, `dhN` or `dlN` to keep or
+// drop the N highest or lowest dice. Letters are case-insensitive and
+// spaces are ignored.
+func Parse(s string) (*Expression, error) {
+	p := parser{input: s, s: strings.ToLower(strings.Join(strings.Fields(s), ""))}
+	if p.s == "" {
+		return nil, p.fail("expression is empty")
+	}
+
+	expr := &Expression{}
+	for p.pos < len(p.s) {
+		negative := false
+		switch p.s[p.pos] {
+		case '+':
+			p.pos++
+		case '-':
+			negative = true
+			p.pos++
+		default:
+			if len(expr.Terms) > 0 {
+				return nil, p.fail("expected + or -")
+			}
+		}
+
+		t, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		t.Negative = negative
+
+		expr.Terms = append(expr.Terms, t)
+		if len(expr.Terms) > MaxTerms {
+			return nil, p.fail(fmt.Sprintf("an expression can have at most %d terms", MaxTerms))
+		}
+	}
+
+	dice := 0
+	for _, t := range expr.Terms {
+		dice += t.Count
+	}
+	if dice > MaxDice {
+		return nil, &ExpressionError{s, 0, fmt.Sprintf("at most %d dice can be rolled at once", MaxDice)}
+	}
+
+	return expr, nil
+}
+
+type parser struct {
+	input string // As given, for error messages
+	s     string // Lowercased without spaces
+	pos   int
+}
+
+func (p *parser) fail(reason string) error {
+	return &ExpressionError{p.input, p.pos, reason}
+}
+
+func (p *parser) peek(prefix string) bool {
+	return strings.HasPrefix(p.s[p.pos:], prefix)
+}
+
+// number reads an unsigned number, returning false if there is none.
+func (p *parser) number() (int, bool, error) {
+	start := p.pos
+	for p.pos < len(p.s) && p.s[p.pos] >= '0' && p.s[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos == start {
+		return 0, false, nil
+	}
+	if p.pos-start > maxNumberLength {
+		p.pos = start
+		return 0, false, p.fail("number is too large")
+	}
+
+	n, _ := strconv.Atoi(p.s[start:p.pos])
+	return n, true, nil
+}
+
+func (p *parser) term() (Term, error) {
+	start := p.pos
+	count, hasCount, err := p.number()
+	if err != nil {
+		return Term{}, err
+	}
+
+	if !p.peek("d") {
+		if !hasCount {
+			return Term{}, p.fail("expected a number or dice")
+		}
+		if count > MaxConstant {
+			p.pos = start
+			return Term{}, p.fail(fmt.Sprintf("constants can be at most %d", MaxConstant))
+		}
+		return Term{Constant: count}, nil
+	}
+	p.pos++
+
+	if !hasCount {
+		count = 1
+	}
+	if count < 1 || count > MaxDice {
+		p.pos = start
+		return Term{}, p.fail(fmt.Sprintf("between 1 and %d dice can be rolled", MaxDice))
+	}
+	t := Term{Count: count}
+
+	if p.peek("%") {
+		p.pos++
+		t.Sides = 100
+	} else {
+		sides, ok, err := p.number()
+		if err != nil {
+			return Term{}, err
+		}
+		if !ok {
+			return Term{}, p.fail("expected the number of sides")
+		}
+		if sides < 1 || sides > MaxSides {
+			return Term{}, p.fail(fmt.Sprintf("dice must have between 1 and %d sides", MaxSides))
+		}
+		t.Sides = sides
+	}
+
+	if p.peek("!") {
+		if t.Sides == 1 {
+			return Term{}, p.fail("a one-sided die cannot explode")
+		}
+		p.pos++
+		t.Exploding = true
+	}
+
+	for _, rule := range []KeepRule{KeepHighest, KeepLowest, DropHighest, DropLowest} {
+		if !p.peek(string(rule)) {
+			continue
+		}
+		p.pos += len(rule)
+
+		n, ok, err := p.number()
+		if err != nil {
+			return Term{}, err
+		}
+		if !ok {
+			n = 1
+		}
+		if n < 1 || n > t.Count {
+			return Term{}, p.fail(fmt.Sprintf("can only keep or drop between 1 and %d dice", t.Count))
+		}
+		t.Keep = rule
+		t.KeepCount = n
+		break
+	}
+
+	return t, nil
+}
+
+// Add appends the constant `n` to the expression, such as an ability
+// modifier.
+func (e *Expression) Add(n int) {
+	if n == 0 {
+		return
+	}
+	if n < 0 {
+		e.Terms = append(e.Terms, Term{Negative: true, Constant: -n})
+	} else {
+		e.Terms = append(e.Terms, Term{Constant: n})
+	}
+}
+
+// WithAdvantage turns the first plain `1d20` of the expression into
+// `2d20kh1`, or into `2d20kl1` when `disadvantage` is set.
+func (e *Expression) WithAdvantage(disadvantage bool) error {
+	for i, t := range e.Terms {
+		if t.Count != 1 || t.Sides != 20 || t.Exploding || t.Keep != KeepAll {
+			continue
+		}
+
+		e.Terms[i].Count = 2
+		e.Terms[i].Keep = KeepHighest
+		if disadvantage {
+			e.Terms[i].Keep = KeepLowest
+		}
+		e.Terms[i].KeepCount = 1
+		return nil
+	}
+	return ErrNoD20
+}
+
+// Die is a single die rolled for a term.
+type Die struct {
+	Value    int  `json:"value"`
+	Kept     bool `json:"kept"`
+	Exploded bool `json:"exploded,omitempty"` // Showed its highest face and was rolled again
+}
+
+// TermResult is the outcome of a single term.
+type TermResult struct {
+	Term  string `json:"term"`
+	Dice  []Die  `json:"dice,omitempty"`
+	Value int    `json:"value"` // Negative for subtracted terms
+}
+
+// Result is the outcome of rolling an expression.
+type Result struct {
+	Expression string       `json:"expression"`
+	Terms      []TermResult `json:"terms"`
+	Total      int          `json:"total"`
+}
+
+// Roll rolls every die of the expression.
+func (e *Expression) Roll() (Result, error) {
+	result := Result{Expression: e.String(), Terms: []TermResult{}}
+
+	for _, t := range e.Terms {
+		r := TermResult{Term: t.String(), Value: t.Constant}
+		if t.Count > 0 {
+			var err error
+			if r.Dice, err = t.roll(); err != nil {
+				return Result{}, err
+			}
+			r.Value = 0
+			for _, d := range r.Dice {
+				if d.Kept {
+					r.Value += d.Value
+				}
+			}
+		}
+		if t.Negative {
+			r.Value = -r.Value
+		}
+
+		result.Terms = append(result.Terms, r)
+		result.Total += r.Value
+	}
+
+	return result, nil
+}
+
+func (t Term) roll() ([]Die, error) {
+	rolled := make([]Die, 0, t.Count)
+	explosions := 0
+	for i := 0; i < t.Count; i++ {
+		value, err := Roll(t.Sides)
+		if err != nil {
+			return nil, err
+		}
+		d := Die{Value: value, Kept: true}
+
+		if t.Exploding && value == t.Sides && explosions < maxExplosions {
+			d.Exploded = true
+			explosions++
+			i-- // Roll one more
+		}
+		rolled = append(rolled, d)
+	}
+
+	if t.Keep == KeepAll {
+		return rolled, nil
+	}
+
+	// Order the dice from lowest to highest, then mark the ones which
+	// do not count
+	order := make([]int, len(rolled))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return rolled[order[a]].Value < rolled[order[b]].Value
+	})
+
+	n := t.KeepCount
+	var dropped []int
+	switch t.Keep {
+	case KeepHighest:
+		dropped = order[:len(order)-min(n, len(order))]
+	case KeepLowest:
+		dropped = order[min(n, len(order)):]
+	case DropHighest:
+		dropped = order[len(order)-min(n, len(order)):]
+	case DropLowest:
+		dropped = order[:min(n, len(order))]
+	}
+	for _, i := range dropped {
+		rolled[i].Kept = false
+	}
+
+	return rolled, nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
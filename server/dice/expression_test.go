@@ -0,0 +1,197 @@
+package dice
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"d20", "1d20"},
+		{"1d20+5", "1d20+5"},
+		{" 2 D6 - 1 ", "2d6-1"},
+		{"-1d4", "-1d4"},
+		{"d%", "1d%"},
+		{"1d100", "1d%"},
+		{"4d6kh3", "4d6kh3"},
+		{"4d6dl", "4d6dl1"},
+		{"2d20KL1", "2d20kl1"},
+		{"3d6dh1+1d8!", "3d6dh1+1d8!"},
+		{"1d6!kh1", "1d6!kh1"},
+		{"10", "10"},
+		{"1d8+1d6-2+3", "1d8+1d6-2+3"},
+		{"00003d06", "3d6"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			expr, err := Parse(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := expr.String(); got != tt.want {
+				t.Errorf("Parse(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src        string
+		wantOffset int
+		wantReason string
+	}{
+		{"", 0, "expression is empty"},
+		{"   ", 0, "expression is empty"},
+		{"d", 1, "expected the number of sides"},
+		{"1d20d6", 4, "expected + or -"},
+		{"1d20+", 5, "expected a number or dice"},
+		{"1d20+x", 5, "expected a number or dice"},
+		{"0d6", 0, "between 1 and 100 dice can be rolled"},
+		{"101d6", 0, "between 1 and 100 dice can be rolled"},
+		{"1d0", 3, "dice must have between 1 and 1000 sides"},
+		{"1d1001", 6, "dice must have between 1 and 1000 sides"},
+		{"123456", 0, "number is too large"},
+		{"20000", 0, "constants can be at most 10000"},
+		{"1d1!", 3, "a one-sided die cannot explode"},
+		{"4d6kh5", 6, "can only keep or drop between 1 and 4 dice"},
+		{"4d6kh0", 6, "can only keep or drop between 1 and 4 dice"},
+		{"60d6+41d6", 0, "at most 100 dice can be rolled at once"},
+		{strings.Repeat("1+", 20) + "1", 41, "an expression can have at most 20 terms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := Parse(tt.src)
+			var exprErr *ExpressionError
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("Parse(%q) error = %v, want an ExpressionError", tt.src, err)
+			}
+			if exprErr.Offset != tt.wantOffset || exprErr.Reason != tt.wantReason {
+				t.Errorf("Parse(%q) failed with %q at %d, want %q at %d",
+					tt.src, exprErr.Reason, exprErr.Offset, tt.wantReason, tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestWithAdvantage(t *testing.T) {
+	tests := []struct {
+		src          string
+		disadvantage bool
+		want         string
+		wantErr      error
+	}{
+		{"1d20+3", false, "2d20kh1+3", nil},
+		{"1d20+3", true, "2d20kl1+3", nil},
+		{"1d4+1d20", false, "1d4+2d20kh1", nil},
+		{"2d20", false, "", ErrNoD20},
+		{"1d20!", false, "", ErrNoD20},
+		{"1d12", true, "", ErrNoD20},
+	}
+
+	for _, tt := range tests {
+		expr, err := Parse(tt.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = expr.WithAdvantage(tt.disadvantage)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("WithAdvantage(%q) error = %v, want %v", tt.src, err, tt.wantErr)
+		}
+		if err == nil && expr.String() != tt.want {
+			t.Errorf("WithAdvantage(%q) = %q, want %q", tt.src, expr.String(), tt.want)
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	for _, tt := range []struct {
+		n    int
+		want string
+	}{{0, "1d20"}, {3, "1d20+3"}, {-2, "1d20-2"}} {
+		expr, _ := Parse("1d20")
+		expr.Add(tt.n)
+		if got := expr.String(); got != tt.want {
+			t.Errorf("Add(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestRoll(t *testing.T) {
+	tests := []struct {
+		src      string
+		min, max int
+		kept     int // Dice counted in the first term, ignoring explosions
+	}{
+		{"3d6", 3, 18, 3},
+		{"4d6kh3", 3, 18, 3},
+		{"4d6dl1", 3, 18, 3},
+		{"2d20kl1-1", 0, 19, 1},
+		{"-1d4+10", 6, 9, 1},
+		{"1d1+1d1", 2, 2, 1},
+	}
+
+	for _, tt := range tests {
+		expr, err := Parse(tt.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i := 0; i < 200; i++ {
+			r, err := expr.Roll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Total < tt.min || r.Total > tt.max {
+				t.Fatalf("%s rolled %d, want between %d and %d", tt.src, r.Total, tt.min, tt.max)
+			}
+
+			kept := 0
+			for _, d := range r.Terms[0].Dice {
+				if d.Value < 1 || d.Value > expr.Terms[0].Sides {
+					t.Fatalf("%s rolled a die showing %d", tt.src, d.Value)
+				}
+				if d.Kept {
+					kept++
+				}
+			}
+			if kept != tt.kept {
+				t.Fatalf("%s kept %d dice, want %d", tt.src, kept, tt.kept)
+			}
+		}
+	}
+}
+
+func TestRollExploding(t *testing.T) {
+	expr, err := Parse("1d2!")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 200; i++ {
+		r, err := expr.Roll()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dice := r.Terms[0].Dice
+		last := dice[len(dice)-1]
+		if last.Exploded {
+			t.Fatalf("the last die exploded: %+v", dice)
+		}
+		for _, d := range dice[:len(dice)-1] {
+			if !d.Exploded || d.Value != 2 {
+				t.Fatalf("only dice showing their highest face explode: %+v", dice)
+			}
+		}
+		if r.Total < 1 || r.Total > 2*(maxExplosions+1) {
+			t.Fatalf("1d2! rolled %d", r.Total)
+		}
+	}
+}
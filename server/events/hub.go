@@ -0,0 +1,169 @@
+// Package events delivers domain events, such as a milestone being added
+// or a combat turn ending, to clients following a campaign in real time.
+//
+// Events only identify what changed. Clients fetch the new state through
+// the regular endpoints, which apply the usual access checks, so an event
+// never reveals more than its subscriber could already read.
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Types of events published to campaign subscribers.
+const (
+	CampaignUpdated     = "campaign.updated"
+	MilestoneAdded      = "milestone.added"
+	MilestoneUpdated    = "milestone.updated"
+	MilestoneDeleted    = "milestone.deleted"
+	MilestonesReordered = "milestone.reordered"
+	CharacterUpdated    = "character.updated"
+	XPAwarded           = "xp.awarded"
+	CombatStarted       = "combat.started"
+	CombatUpdated       = "combat.updated"
+	CombatTurnAdvanced  = "combat.turn"
+	CombatEnded         = "combat.ended"
+	DiceRolled          = "dice.rolled"
+	NPCAdded            = "npc.added"
+	NPCUpdated          = "npc.updated"
+	NPCDeleted          = "npc.deleted"
+
+	// Resync tells a subscriber that events were missed and cannot be
+	// replayed, so it must fetch the campaign's state again.
+	Resync = "resync"
+)
+
+// subscriberBuffer is how many events may be waiting for a subscriber.
+// Subscribers which fall further behind are disconnected and resume from
+// the history when they reconnect.
+const subscriberBuffer = 64
+
+// Event is a change to a campaign.
+type Event struct {
+	ID         uint64      `json:"id"`
+	CampaignID int         `json:"campaign_id"`
+	Type       string      `json:"type"`
+	Data       interface{} `json:"data,omitempty"`
+	Time       time.Time   `json:"time"`
+
+	// DungeonMasterOnly events are not delivered to players, such as
+	// changes to NPCs hidden from them.
+	DungeonMasterOnly bool `json:"-"`
+}
+
+// Subscription receives the events of a single campaign.
+type Subscription struct {
+	CampaignID    int
+	DungeonMaster bool
+
+	events chan Event
+}
+
+// Events returns the channel on which events are delivered. It is closed
+// when the subscription ends.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) wants(e Event) bool {
+	return e.CampaignID == s.CampaignID && (s.DungeonMaster || !e.DungeonMasterOnly)
+}
+
+// Hub fans published events out to subscribers and keeps a history of
+// recent events so that subscribers can resume after reconnecting.
+type Hub struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event
+	historySize int
+	subscribers map[*Subscription]struct{}
+}
+
+// NewHub returns a hub which remembers the last `historySize` events.
+func NewHub(historySize int) *Hub {
+	return &Hub{
+		historySize: historySize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns the next ID to `e` and delivers it to every
+// subscriber of its campaign.
+func (h *Hub) Publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	e.ID = h.lastID
+	e.Time = time.Now()
+
+	h.history = append(h.history, e)
+	if len(h.history) > h.historySize {
+		h.history = h.history[len(h.history)-h.historySize:]
+	}
+
+	for s := range h.subscribers {
+		if !s.wants(e) {
+			continue
+		}
+		select {
+		case s.events <- e:
+		default:
+			h.remove(s)
+		}
+	}
+}
+
+// Subscribe follows the campaign identified by `campaignID`. When
+// `lastEventID` is not zero, the events published after it are returned
+// so that they can be replayed first. The returned flag is false if
+// those events are no longer known, in which case the subscriber should
+// resynchronize.
+func (h *Hub) Subscribe(campaignID int, isDungeonMaster bool, lastEventID uint64) (*Subscription, []Event, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := &Subscription{
+		CampaignID:    campaignID,
+		DungeonMaster: isDungeonMaster,
+		events:        make(chan Event, subscriberBuffer),
+	}
+	h.subscribers[s] = struct{}{}
+
+	missed := []Event{}
+	if lastEventID == 0 {
+		return s, missed, true
+	}
+
+	// IDs restart with the server, so an ID from the future is as unknown
+	// as one which has left the history.
+	resumable := lastEventID <= h.lastID &&
+		(len(h.history) == 0 || lastEventID+1 >= h.history[0].ID)
+	if !resumable {
+		return s, missed, false
+	}
+
+	for _, e := range h.history {
+		if e.ID > lastEventID && s.wants(e) {
+			missed = append(missed, e)
+		}
+	}
+	return s, missed, true
+}
+
+// Unsubscribe ends `s` and closes its channel. Ending a subscription
+// more than once has no effect.
+func (h *Hub) Unsubscribe(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(s)
+}
+
+func (h *Hub) remove(s *Subscription) {
+	if _, ok := h.subscribers[s]; ok {
+		delete(h.subscribers, s)
+		close(s.events)
+	}
+}
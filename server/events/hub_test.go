@@ -0,0 +1,33 @@
+package events
+
+import "testing"
+
+func TestDungeonMasterOnlyEvents(t *testing.T) {
+	h := NewHub(10)
+	player, _, _ := h.Subscribe(1, false, 0)
+	dm, _, _ := h.Subscribe(1, true, 0)
+
+	h.Publish(Event{CampaignID: 1, Type: CampaignUpdated})
+	<-player.Events()
+	<-dm.Events()
+
+	h.Publish(Event{CampaignID: 1, Type: NPCAdded, DungeonMasterOnly: true})
+	h.Publish(Event{CampaignID: 1, Type: NPCUpdated})
+
+	if e := <-dm.Events(); e.Type != NPCAdded {
+		t.Errorf("dungeon master received %q first, want %q", e.Type, NPCAdded)
+	}
+	if e := <-player.Events(); e.Type != NPCUpdated {
+		t.Errorf("player received %q first, want %q", e.Type, NPCUpdated)
+	}
+
+	// Replayed history is filtered the same way.
+	_, missed, ok := h.Subscribe(1, false, 1)
+	if !ok || len(missed) != 1 || missed[0].Type != NPCUpdated {
+		t.Errorf("player replay = %v, %v", missed, ok)
+	}
+	_, missed, ok = h.Subscribe(1, true, 1)
+	if !ok || len(missed) != 2 {
+		t.Errorf("dungeon master replay = %v, %v", missed, ok)
+	}
+}
@@ -9,5 +9,6 @@ require (
 	github.com/labstack/gommon v0.3.0
 	github.com/lib/pq v1.9.0
 	golang.org/x/crypto v0.0.0-20200820211705-5c72a883971a
+	golang.org/x/net v0.0.0-20200822124328-c89045814202
 	gopkg.in/yaml.v2 v2.4.0
 )
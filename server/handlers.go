@@ -1,15 +1,25 @@
 package main
 
 import (
+	"draco/compendium"
+	"draco/dice"
+	"draco/events"
+	"draco/markdown"
 	"draco/models"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/gommon/log"
+	"golang.org/x/net/websocket"
 )
 
 type playerCreationRequest struct {
@@ -40,6 +50,55 @@ type loginRequest struct {
 	Password string `json:"password"`
 }
 
+// loginResponse is returned by every endpoint which can complete a
+// login. When the player has two-factor authentication enabled, a
+// password login returns a challenge instead of a token.
+type loginResponse struct {
+	Username              string          `json:"username"`
+	Role                  models.RoleType `json:"role,omitempty"`
+	Token                 string          `json:"token,omitempty"`
+	PasswordResetRequired bool            `json:"password_reset_required"`
+	TwoFactorRequired     bool            `json:"two_factor_required"`
+	Challenge             string          `json:"challenge,omitempty"`
+	ChallengeExpiry       *time.Time      `json:"challenge_expires_at,omitempty"`
+}
+
+// newLoginResponse issues an authentication token for `player`.
+func (app *application) newLoginResponse(player *models.Player) (*loginResponse, error) {
+	token, err := app.createJWT(player)
+	if err != nil {
+		return nil, err
+	}
+
+	return &loginResponse{
+		Username:              player.Username,
+		Role:                  player.Role,
+		Token:                 token,
+		PasswordResetRequired: player.PasswordResetRequired,
+	}, nil
+}
+
+// newLoginChallenge issues a login challenge which `username` must
+// complete with their second factor at POST /login/2fa.
+func (app *application) newLoginChallenge(username string) (*loginResponse, error) {
+	challenge, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(loginChallengeTTL)
+	if err := app.twoFactor.CreateChallenge(username, hashToken(challenge), expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &loginResponse{
+		Username:          username,
+		TwoFactorRequired: true,
+		Challenge:         challenge,
+		ChallengeExpiry:   &expiresAt,
+	}, nil
+}
+
 func (app *application) loginPlayer(c echo.Context) error {
 	var req loginRequest
 	if err := c.Bind(&req); err != nil {
@@ -53,21 +112,341 @@ func (app *application) loginPlayer(c echo.Context) error {
 		return sendJSONResponse(c, http.StatusUnauthorized, "Player login", "Login failed", nil)
 	}
 
-	token, err := app.createJWT(username)
+	player, err := app.players.Get(username)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Player login", "Login failed", nil)
+	}
+
+	if player.TOTPEnabled {
+		resp, err := app.newLoginChallenge(username)
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Player login", "Login failed", nil)
+		}
+
+		return sendJSONResponse(c, http.StatusOK, "Player login", "Two-factor authentication required", resp)
+	}
+
+	resp, err := app.newLoginResponse(player)
 	if err != nil {
 		log.Error(err)
 		return sendJSONResponse(c, http.StatusUnauthorized, "Player login", "Login failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Player login", "Login successful",
+	return sendJSONResponse(c, http.StatusOK, "Player login", "Login successful", resp)
+}
+
+// completeTwoFactorLogin exchanges a login challenge and a TOTP code
+// (or recovery code) for an authentication token.
+func (app *application) completeTwoFactorLogin(c echo.Context) error {
+	req := struct {
+		Challenge    string `json:"challenge"`
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Two-factor login", "Could not process request", nil)
+	}
+
+	challengeHash := hashToken(strings.TrimSpace(req.Challenge))
+	username, err := app.twoFactor.GetChallenge(challengeHash)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnauthorized, "Two-factor login", "Login failed", nil)
+	}
+
+	if err := app.verifySecondFactor(username, req.Code, req.RecoveryCode); err != nil {
+		log.Error(err)
+		if err := app.twoFactor.RecordFailedAttempt(challengeHash); err != nil {
+			log.Error(err)
+		}
+		return sendJSONResponse(c, http.StatusUnauthorized, "Two-factor login", "Login failed", nil)
+	}
+
+	if err := app.twoFactor.DeleteChallenge(challengeHash); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Two-factor login", "Login failed", nil)
+	}
+
+	player, err := app.players.Get(username)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnauthorized, "Two-factor login", "Login failed", nil)
+	}
+	if player.Disabled {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Two-factor login", "Login failed", nil)
+	}
+
+	resp, err := app.newLoginResponse(player)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnauthorized, "Two-factor login", "Login failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Two-factor login", "Login successful", resp)
+}
+
+// oidcFlowResponse is sent to the client which starts a single sign-on
+// request.
+type oidcFlowResponse struct {
+	AuthorizationURL string `json:"authorization_url"`
+	// SessionKey must be sent back with the callback. It is only known
+	// to the client which started the request, so a callback URL
+	// forwarded to somebody else cannot be completed by them.
+	SessionKey string `json:"session_key"`
+}
+
+// beginOIDCFlow stores a new single sign-on request bound to a fresh
+// session key. `linkUsername` is set when an existing player is linking
+// their account.
+func (app *application) beginOIDCFlow(linkUsername string) (*oidcFlowResponse, error) {
+	state, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	sessionKey, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	codeVerifier, err := generateRandomToken(48)
+	if err != nil {
+		return nil, err
+	}
+
+	authURL, err := app.oidc.authorizationURL(state, nonce, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(oidcStateTTL)
+	if err := app.identities.CreateLoginState(hashToken(state), hashToken(sessionKey), nonce, codeVerifier, linkUsername, expiresAt); err != nil {
+		return nil, err
+	}
+
+	return &oidcFlowResponse{AuthorizationURL: authURL, SessionKey: sessionKey}, nil
+}
+
+// Starts a single sign-on login. The client should keep the returned
+// session key and send the player to the returned URL.
+func (app *application) startOIDCLogin(c echo.Context) error {
+	if app.oidc == nil {
+		return sendJSONResponse(c, http.StatusNotFound, "Single sign-on login", "Single sign-on is not configured", nil)
+	}
+
+	resp, err := app.beginOIDCFlow("")
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusBadGateway, "Single sign-on login", "Login failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Single sign-on login", "Redirect to identity provider", resp)
+}
+
+// Completes a single sign-on login or account link once the identity
+// provider has redirected the player back with an authorization code.
+// Players with two-factor authentication enabled receive a login
+// challenge instead of a token.
+func (app *application) completeOIDCLogin(c echo.Context) error {
+	if app.oidc == nil {
+		return sendJSONResponse(c, http.StatusNotFound, "Single sign-on login", "Single sign-on is not configured", nil)
+	}
+
+	req := struct {
+		Code       string `json:"code" query:"code"`
+		State      string `json:"state" query:"state"`
+		SessionKey string `json:"session_key" query:"session_key"`
+		Error      string `json:"error" query:"error"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Single sign-on login", "Could not process request", nil)
+	}
+
+	if req.State == "" || req.SessionKey == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Single sign-on login", "Login failed", nil)
+	}
+
+	nonce, codeVerifier, linkUsername, err := app.identities.ConsumeLoginState(hashToken(req.State), hashToken(req.SessionKey))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnauthorized, "Single sign-on login", "Login failed", nil)
+	}
+
+	if req.Error != "" || req.Code == "" {
+		log.Error("oidc: provider returned error: ", req.Error)
+		return sendJSONResponse(c, http.StatusUnauthorized, "Single sign-on login", "Login failed", nil)
+	}
+
+	claims, err := app.oidc.exchangeCode(req.Code, codeVerifier, nonce)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnauthorized, "Single sign-on login", "Login failed", nil)
+	}
+
+	var username string
+	if linkUsername != "" {
+		if err := app.identities.Link(claims.Issuer, claims.Subject, linkUsername); err != nil {
+			log.Error(err)
+			if errors.Is(err, models.ErrDuplicateIdentity) {
+				return sendJSONResponse(c, http.StatusConflict, "Single sign-on login", "This account is already linked to a player", nil)
+			}
+			return sendJSONResponse(c, http.StatusInternalServerError, "Single sign-on login", "Linking failed", nil)
+		}
+		username = linkUsername
+	} else {
+		username, err = app.identities.GetPlayer(claims.Issuer, claims.Subject)
+		if errors.Is(err, models.ErrNoRecord) && app.oidc.autoProvision {
+			username, err = app.provisionOIDCPlayer(claims)
+		}
+		if err != nil {
+			log.Error(err)
+			if errors.Is(err, models.ErrNoRecord) {
+				return sendJSONResponse(c, http.StatusUnauthorized, "Single sign-on login", "No player is linked to this account", nil)
+			}
+			return sendJSONResponse(c, http.StatusInternalServerError, "Single sign-on login", "Login failed", nil)
+		}
+	}
+
+	player, err := app.players.Get(username)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Single sign-on login", "Login failed", nil)
+	}
+	if player.Disabled {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Single sign-on login", "Login failed", nil)
+	}
+
+	if player.TOTPEnabled {
+		resp, err := app.newLoginChallenge(username)
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Single sign-on login", "Login failed", nil)
+		}
+
+		return sendJSONResponse(c, http.StatusOK, "Single sign-on login", "Two-factor authentication required", resp)
+	}
+
+	resp, err := app.newLoginResponse(player)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Single sign-on login", "Login failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Single sign-on login", "Login successful", resp)
+}
+
+// provisionOIDCPlayer creates a player for an identity which has not
+// been seen before. The player is given a random password, so they can
+// only log in through the identity provider until they set one.
+func (app *application) provisionOIDCPlayer(claims *oidcClaims) (string, error) {
+	password, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	base := provisionUsername(claims)
+	name := strings.TrimSpace(claims.Name)
+	if name == "" {
+		name = base
+	}
+	if utf8.RuneCountInString(name) > maxPlayerNameLength {
+		name = string([]rune(name)[:maxPlayerNameLength])
+	}
+
+	for i := 0; i < 100; i++ {
+		username := base
+		if i > 0 {
+			username = base + strconv.Itoa(i)
+		}
+
+		err := app.identities.Provision(claims.Issuer, claims.Subject, username, password, name)
+		if errors.Is(err, models.ErrDuplicateUsername) {
+			continue
+		}
+		if errors.Is(err, models.ErrDuplicateIdentity) {
+			// Another login provisioned a player for the identity first
+			return app.identities.GetPlayer(claims.Issuer, claims.Subject)
+		}
+		if err != nil {
+			return "", err
+		}
+		return username, nil
+	}
+
+	return "", models.ErrDuplicateUsername
+}
+
+// Starts linking the requestor's account to their account at the
+// identity provider.
+func (app *application) startOIDCLink(c echo.Context) error {
+	playerUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(playerUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Link single sign-on account", "Access denied", nil)
+	}
+
+	if app.oidc == nil {
+		return sendJSONResponse(c, http.StatusNotFound, "Link single sign-on account", "Single sign-on is not configured", nil)
+	}
+
+	resp, err := app.beginOIDCFlow(playerUsername)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusBadGateway, "Link single sign-on account", "Linking failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Link single sign-on account", "Redirect to identity provider", resp)
+}
+
+// Retrieves the external accounts linked to the requestor.
+func (app *application) retrieveOIDCIdentities(c echo.Context) error {
+	playerUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(playerUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Retrieve single sign-on accounts", "Access denied", nil)
+	}
+
+	identities, err := app.identities.GetAllForPlayer(playerUsername)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Retrieve single sign-on accounts", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Retrieve single sign-on accounts", "Retrieval successful",
 		struct {
-			Username string `json:"username"`
-			Token    string `json:"token"`
+			Identities []models.PlayerIdentity `json:"identities"`
 		}{
-			username,
-			token,
-		},
-	)
+			*identities,
+		})
+}
+
+// Removes the link between the requestor and their account at the
+// identity provider.
+func (app *application) unlinkOIDCIdentity(c echo.Context) error {
+	playerUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(playerUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Unlink single sign-on account", "Access denied", nil)
+	}
+
+	if app.oidc == nil {
+		return sendJSONResponse(c, http.StatusNotFound, "Unlink single sign-on account", "Single sign-on is not configured", nil)
+	}
+
+	if err := app.identities.Unlink(playerUsername, app.oidc.issuer); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Unlink single sign-on account", "No linked account", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Unlink single sign-on account", "Unlinking failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Unlink single sign-on account", "Unlinking successful", nil)
 }
 
 func (app *application) retrievePlayer(c echo.Context) error {
@@ -150,604 +529,6336 @@ func (app *application) deletePlayerSelf(c echo.Context) error {
 	return nil
 }
 
-func (app *application) createCharacter(c echo.Context) error {
-	var req models.Character
+// Maximum lengths of profile fields, matching the database schema.
+const (
+	maxPlayerNameLength = 50
+	maxAvatarURLLength  = 2048
+	maxBioLength        = 2000
+)
+
+// Updates the requestor's display name, avatar and bio. Fields which
+// are omitted from the request are left unchanged.
+func (app *application) updatePlayerProfile(c echo.Context) error {
+	playerUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(playerUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Update player profile", "Access denied", nil)
+	}
+
+	req := struct {
+		Name      *string `json:"name"`
+		AvatarURL *string `json:"avatar_url"`
+		Bio       *string `json:"bio"`
+	}{}
+
 	if err := c.Bind(&req); err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character creation", "Could not process request", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Update player profile", "Could not process request", nil)
 	}
 
-	creatorUsername := getUsernameFromToken(c)
-	if strings.TrimSpace(creatorUsername) == "" {
-		return sendJSONResponse(c, http.StatusUnauthorized, "Character creation", "Creation failed", nil)
+	if req.Name != nil {
+		*req.Name = strings.TrimSpace(*req.Name)
+		if *req.Name == "" || utf8.RuneCountInString(*req.Name) > maxPlayerNameLength {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Update player profile", "Name must be between 1 and 50 characters", nil)
+		}
 	}
 
-	req.PlayerUsername = creatorUsername
-
-	id, err := app.characters.Insert(req)
-	if err != nil {
-		log.Error(err)
-		return sendJSONResponse(c, http.StatusInternalServerError, "Character creation", "Creation failed", nil)
+	if req.AvatarURL != nil {
+		*req.AvatarURL = strings.TrimSpace(*req.AvatarURL)
+		if len(*req.AvatarURL) > maxAvatarURLLength {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Update player profile", "Avatar URL is too long", nil)
+		}
+		if *req.AvatarURL != "" {
+			u, err := url.Parse(*req.AvatarURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return sendJSONResponse(c, http.StatusUnprocessableEntity, "Update player profile", "Avatar URL must be an http(s) URL", nil)
+			}
+		}
 	}
 
-	return sendJSONResponse(c, http.StatusCreated, "Character creation", "Creation successful",
-		struct {
-			ResourceURI string `json:"resource_uri"`
-		}{
-			"/characters/" + strconv.Itoa(id),
-		},
-	)
-}
+	if req.Bio != nil && utf8.RuneCountInString(*req.Bio) > maxBioLength {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Update player profile", "Bio must be at most 2000 characters", nil)
+	}
 
-func (app *application) retrieveCharacter(c echo.Context) error {
-	requestCharID := c.Param("id")
-	charID, err := strconv.Atoi(requestCharID)
-	if err != nil {
+	if err := app.players.UpdateProfile(playerUsername, req.Name, req.AvatarURL, req.Bio); err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character retrieval", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Update player profile", "Update failed", nil)
 	}
 
-	character, err := app.characters.Get(charID)
+	player, err := app.players.Get(playerUsername)
 	if err != nil {
 		log.Error(err)
-		if errors.Is(err, models.ErrNoRecord) {
-			return sendJSONResponse(c, http.StatusNotFound, "Character retrieval", "Retrieval failed", nil)
-		}
-		return sendJSONResponse(c, http.StatusInternalServerError, "Character retrieval", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Update player profile", "Update failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Character retrieval", "Retrieval successful", character)
+	return sendJSONResponse(c, http.StatusOK, "Update player profile", "Update successful", player)
 }
 
-// Retrieve all characters belonging to the requesting user.
-func (app *application) retrieveUserCharacters(c echo.Context) error {
-	username := getUsernameFromToken(c)
-	if strings.TrimSpace(username) == "" {
-		return sendJSONResponse(c, http.StatusUnauthorized, "Retrieve all user characters", "Retrieval failed", nil)
+// Changes the requestor's username. The current password is required,
+// and a new token is issued since the old one carries the old username.
+func (app *application) changePlayerUsername(c echo.Context) error {
+	playerUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(playerUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Change player username", "Access denied", nil)
 	}
 
-	characters, err := app.characters.GetAllUserCharacters(username)
-	if err != nil {
+	req := struct {
+		NewUsername string `json:"new_username"`
+		Password    string `json:"password"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
 		log.Error(err)
-		if errors.Is(err, models.ErrNoRecord) {
-			return sendJSONResponse(c, http.StatusNotFound, "Retrieve all user characters", "Retrieval failed", nil)
-		}
-		return sendJSONResponse(c, http.StatusInternalServerError, "Retrieve all user characters", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Change player username", "Could not process request", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Retrieve all user characters", "Retrieval successful",
-		struct {
-			Characters *[]models.Character `json:"characters"`
-		}{
-			characters,
-		})
-}
+	req.NewUsername = strings.TrimSpace(req.NewUsername)
+	if !models.IsValidUsername(req.NewUsername) {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Change player username", models.ErrInvalidUsername.Error(), nil)
+	}
 
-// updateCharacter updates a character with a given ID
-func (app *application) updateCharacter(c echo.Context) error {
-	var req models.Character
-	requestCharIDStr := c.Param("id")
+	if _, err := app.players.Authenticate(playerUsername, req.Password); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnauthorized, "Change player username", "Incorrect password", nil)
+	}
 
-	err := c.Bind(&req)
-	if err != nil {
+	if err := app.players.ChangeUsername(playerUsername, req.NewUsername); err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character update", "Could not process request", nil)
+		if errors.Is(err, models.ErrDuplicateUsername) {
+			return sendJSONResponse(c, http.StatusConflict, "Change player username", "Username is already taken", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Change player username", "Update failed", nil)
 	}
 
-	numericCharID, err := strconv.Atoi(requestCharIDStr)
+	player, err := app.players.Get(req.NewUsername)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character update", "Could not process request", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Change player username", "Update failed", nil)
 	}
 
-	req.ID = numericCharID
-	err = app.characters.Update(req)
+	resp, err := app.newLoginResponse(player)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusInternalServerError, "Character update", "update failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Change player username", "Update failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Character update", "Update successful", nil)
+	return sendJSONResponse(c, http.StatusOK, "Change player username", "Update successful", resp)
 }
 
-// deleteCharacter attempts to a delete a character given its ID.
-func (app *application) deleteCharacter(c echo.Context) error {
-	requestCharIDStr := c.Param("id")
-	numericCharID, err := strconv.Atoi(requestCharIDStr)
+// Starts two-factor enrolment for the requestor by generating a new
+// TOTP secret. The secret is not required at login until it has been
+// confirmed with a valid code.
+func (app *application) enrolTwoFactor(c echo.Context) error {
+	playerUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(playerUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Two-factor enrolment", "Access denied", nil)
+	}
+
+	secret, err := generateTOTPSecret()
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character deletion", "Could not process request", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Two-factor enrolment", "Enrolment failed", nil)
 	}
 
-	err = app.characters.Delete(numericCharID)
-	if err != nil {
+	if err := app.twoFactor.SetPendingSecret(playerUsername, secret); err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusInternalServerError, "Character deletion", "Deletion failed", nil)
+		if errors.Is(err, models.ErrTwoFactorAlreadyEnabled) {
+			return sendJSONResponse(c, http.StatusConflict, "Two-factor enrolment", "Two-factor authentication is already enabled", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Two-factor enrolment", "Enrolment failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Character deletion", "Deletion successful", nil)
+	return sendJSONResponse(c, http.StatusOK, "Two-factor enrolment", "Enrolment started",
+		struct {
+			Secret          string `json:"secret"`
+			ProvisioningURI string `json:"otpauth_uri"`
+		}{
+			secret,
+			totpProvisioningURI(playerUsername, secret),
+		})
 }
 
-// Create a spell which belongs to a character.
-func (app *application) createSpell(c echo.Context) error {
-	charIDString := c.Param("id")
-	charID, err := strconv.Atoi(charIDString)
-	if err != nil {
-		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Spell creation", "Could not process request", nil)
+// Completes two-factor enrolment for the requestor. The response
+// contains the player's recovery codes, which are never shown again.
+func (app *application) confirmTwoFactor(c echo.Context) error {
+	playerUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(playerUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Two-factor confirmation", "Access denied", nil)
 	}
 
-	var req models.Spell
+	req := struct {
+		Code string `json:"code"`
+	}{}
+
 	if err := c.Bind(&req); err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Spell creation", "Could not process request", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Two-factor confirmation", "Could not process request", nil)
 	}
 
-	req.CharacterID = charID
-	// TODO: Check if the character actually belongs to the user.
-	err = app.spells.Insert(req)
+	secret, enabled, err := app.twoFactor.GetSecret(playerUsername)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusInternalServerError, "Spell creation", "Creation failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Two-factor confirmation", "Confirmation failed", nil)
+	}
+	if enabled {
+		return sendJSONResponse(c, http.StatusConflict, "Two-factor confirmation", "Two-factor authentication is already enabled", nil)
+	}
+	if secret == "" {
+		return sendJSONResponse(c, http.StatusBadRequest, "Two-factor confirmation", "Two-factor enrolment has not been started", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusCreated, "Spell creation", "Creation successful", nil)
-}
+	counter, ok := validateTOTPCode(secret, req.Code, time.Now())
+	if !ok {
+		return sendJSONResponse(c, http.StatusBadRequest, "Two-factor confirmation", "Invalid code", nil)
+	}
 
-// Retrieve a spell belonging to a character.
-func (app *application) retrieveSpell(c echo.Context) error {
-	charIDString := c.Param("id")
-	charID, err := strconv.Atoi(charIDString)
-	if err != nil {
+	if err := app.twoFactor.ClaimCounter(playerUsername, counter); err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Spell retrieval", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusBadRequest, "Two-factor confirmation", "Invalid code", nil)
 	}
 
-	rawSpellName := c.Param("name")
-	decodedSpellName, err := url.QueryUnescape(rawSpellName)
+	recoveryCodes, err := generateRecoveryCodes(numRecoveryCodes)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Spell retrieval", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Two-factor confirmation", "Confirmation failed", nil)
 	}
 
-	spell, err := app.spells.Get(charID, decodedSpellName)
-	if err != nil {
+	if err := app.twoFactor.Enable(playerUsername, recoveryCodes); err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusNotFound, "Spell retrieval", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Two-factor confirmation", "Confirmation failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Spell retrieval", "Retrieval successful", spell)
+	return sendJSONResponse(c, http.StatusOK, "Two-factor confirmation", "Two-factor authentication enabled",
+		struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		}{
+			recoveryCodes,
+		})
 }
 
-// Delete a spell belonging to a character.
-func (app *application) deleteSpell(c echo.Context) error {
-	charIDString := c.Param("id")
-	charID, err := strconv.Atoi(charIDString)
-	if err != nil {
+// Replaces the requestor's recovery codes. A valid TOTP code is
+// required so that a stolen session cannot be used to take over the
+// second factor.
+func (app *application) regenerateRecoveryCodes(c echo.Context) error {
+	playerUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(playerUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Regenerate recovery codes", "Access denied", nil)
+	}
+
+	req := struct {
+		Code string `json:"code"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Spell deletion", "Deletion failed", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Regenerate recovery codes", "Could not process request", nil)
 	}
 
-	rawSpellName := c.Param("name")
-	decodedSpellName, err := url.QueryUnescape(rawSpellName)
-	if err != nil {
+	if err := app.verifySecondFactor(playerUsername, req.Code, ""); err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Spell deletion", "Deletion failed", nil)
+		if errors.Is(err, models.ErrTwoFactorNotEnabled) {
+			return sendJSONResponse(c, http.StatusBadRequest, "Regenerate recovery codes", "Two-factor authentication is not enabled", nil)
+		}
+		return sendJSONResponse(c, http.StatusUnauthorized, "Regenerate recovery codes", "Invalid code", nil)
 	}
 
-	err = app.spells.Delete(charID, decodedSpellName)
+	recoveryCodes, err := generateRecoveryCodes(numRecoveryCodes)
 	if err != nil {
 		log.Error(err)
-		if errors.Is(err, models.ErrNoRecord) {
-			return sendJSONResponse(c, http.StatusNotFound, "Spell deletion", "Deletion failed", nil)
-		}
-		return sendJSONResponse(c, http.StatusInternalServerError, "Spell deletion", "Deletion failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Regenerate recovery codes", "Regeneration failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Spell deletion", "Deletion successful", nil)
+	if err := app.twoFactor.Enable(playerUsername, recoveryCodes); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Regenerate recovery codes", "Regeneration failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Regenerate recovery codes", "Regeneration successful",
+		struct {
+			RecoveryCodes []string `json:"recovery_codes"`
+		}{
+			recoveryCodes,
+		})
 }
 
-// Get all spells belonging to a character.
-func (app *application) retrieveAllCharacterSpells(c echo.Context) error {
-	charIDString := c.Param("id")
-	charID, err := strconv.Atoi(charIDString)
+// Retrieves the requestor's two-factor authentication status.
+func (app *application) retrieveTwoFactorStatus(c echo.Context) error {
+	playerUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(playerUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Two-factor status", "Access denied", nil)
+	}
+
+	_, enabled, err := app.twoFactor.GetSecret(playerUsername)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Retrieve all character spells", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Two-factor status", "Retrieval failed", nil)
 	}
 
-	spells, err := app.spells.GetAllCharacterSpells(charID)
+	remaining, err := app.twoFactor.CountUnusedRecoveryCodes(playerUsername)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusNotFound, "Retrieve all character spells", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Two-factor status", "Retrieval failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Retrieve all character spells", "Retrieval successful", struct {
-		Spells *[]models.Spell `json:"spells"`
-	}{
-		spells,
-	})
+	return sendJSONResponse(c, http.StatusOK, "Two-factor status", "Retrieval successful",
+		struct {
+			Enabled                bool `json:"enabled"`
+			RemainingRecoveryCodes int  `json:"remaining_recovery_codes"`
+		}{
+			enabled,
+			remaining,
+		})
 }
 
-// Create an item which belongs to a character.
-func (app *application) createItem(c echo.Context) error {
-	charIDString := c.Param("id")
-	charID, err := strconv.Atoi(charIDString)
-	if err != nil {
-		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Item creation", "Could not process request", nil)
+// Turns off two-factor authentication for the requestor. Either a
+// valid TOTP code or an unused recovery code must be supplied.
+func (app *application) disableTwoFactor(c echo.Context) error {
+	playerUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(playerUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Disable two-factor authentication", "Access denied", nil)
 	}
 
-	var req models.Item
+	req := struct {
+		Code         string `json:"code"`
+		RecoveryCode string `json:"recovery_code"`
+	}{}
+
 	if err := c.Bind(&req); err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Item creation", "Could not process request", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Disable two-factor authentication", "Could not process request", nil)
 	}
 
-	req.CharacterID = charID
-	// TODO: Check if the character actually belongs to the user.
-	err = app.items.Insert(req)
-	if err != nil {
+	if err := app.verifySecondFactor(playerUsername, req.Code, req.RecoveryCode); err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusInternalServerError, "Item creation", "Creation failed", nil)
+		if errors.Is(err, models.ErrTwoFactorNotEnabled) {
+			return sendJSONResponse(c, http.StatusBadRequest, "Disable two-factor authentication", "Two-factor authentication is not enabled", nil)
+		}
+		return sendJSONResponse(c, http.StatusUnauthorized, "Disable two-factor authentication", "Invalid code", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusCreated, "Item creation", "Creation successful", nil)
+	if err := app.twoFactor.Disable(playerUsername); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Disable two-factor authentication", "Disabling failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Disable two-factor authentication", "Two-factor authentication disabled", nil)
 }
 
-// Retrieve an item belonging to a character.
-func (app *application) retrieveItem(c echo.Context) error {
-	charIDString := c.Param("id")
-	charID, err := strconv.Atoi(charIDString)
-	if err != nil {
+// Creates a named, scoped personal access token for the requestor. The
+// token is only returned once and cannot be retrieved later.
+func (app *application) createPersonalToken(c echo.Context) error {
+	playerUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(playerUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Personal access token creation", "Access denied", nil)
+	}
+
+	req := struct {
+		Name   string              `json:"name"`
+		Scopes []models.TokenScope `json:"scopes"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Item retrieval", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Personal access token creation", "Could not process request", nil)
 	}
 
-	rawItemName := c.Param("name")
-	decodedItemName, err := url.QueryUnescape(rawItemName)
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Scopes) == 0 {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Personal access token creation", "A name and at least one scope must be specified", nil)
+	}
+
+	rawToken, err := generateRandomToken(32)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Item retrieval", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Personal access token creation", "Creation failed", nil)
 	}
+	rawToken = personalTokenPrefix + rawToken
 
-	item, err := app.items.Get(charID, decodedItemName)
+	id, err := app.personalTokens.Insert(playerUsername, req.Name, hashToken(rawToken), req.Scopes)
 	if err != nil {
 		log.Error(err)
-		if errors.Is(err, models.ErrNoRecord) {
-			return sendJSONResponse(c, http.StatusNotFound, "Item retrieval", "Retrieval failed", nil)
+		if errors.Is(err, models.ErrDuplicateTokenName) {
+			return sendJSONResponse(c, http.StatusConflict, "Personal access token creation", "A token with this name already exists", nil)
 		}
-		return sendJSONResponse(c, http.StatusInternalServerError, "Item retrieval", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Personal access token creation", "Creation failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Item retrieval", "Retrieval successful", item)
+	return sendJSONResponse(c, http.StatusCreated, "Personal access token creation", "Creation successful",
+		struct {
+			ID     int                 `json:"id"`
+			Name   string              `json:"name"`
+			Scopes []models.TokenScope `json:"scopes"`
+			Token  string              `json:"token"`
+		}{
+			id,
+			req.Name,
+			req.Scopes,
+			rawToken,
+		})
 }
 
-// Get all items belonging to a character.
-func (app *application) retrieveAllCharacterItems(c echo.Context) error {
-	charIDString := c.Param("id")
-	charID, err := strconv.Atoi(charIDString)
-	if err != nil {
-		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Retrieve all character items", "Retrieval failed", nil)
+// Retrieves all personal access tokens belonging to the requestor.
+func (app *application) retrievePersonalTokens(c echo.Context) error {
+	playerUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(playerUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Retrieve personal access tokens", "Access denied", nil)
 	}
 
-	items, err := app.items.GetAllCharacterItems(charID)
+	tokens, err := app.personalTokens.GetAllForPlayer(playerUsername)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusNotFound, "Retrieve all character items", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Retrieve personal access tokens", "Retrieval failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Retrieve all character items", "Retrieval successful", struct {
-		Items *[]models.Item `json:"items"`
-	}{
-		items,
-	})
+	return sendJSONResponse(c, http.StatusOK, "Retrieve personal access tokens", "Retrieval successful",
+		struct {
+			Tokens []models.PersonalAccessToken `json:"tokens"`
+		}{
+			*tokens,
+		})
 }
 
-// Delete an item belonging to a character.
-func (app *application) deleteItem(c echo.Context) error {
-	charIDString := c.Param("id")
-	charID, err := strconv.Atoi(charIDString)
-	if err != nil {
-		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Item deletion", "Deletion failed", nil)
+// Revokes one of the requestor's personal access tokens.
+func (app *application) revokePersonalToken(c echo.Context) error {
+	playerUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(playerUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Revoke personal access token", "Access denied", nil)
 	}
 
-	rawItemName := c.Param("name")
-	decodedItemName, err := url.QueryUnescape(rawItemName)
+	tokenID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Item deletion", "Deletion failed", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Revoke personal access token", "Revocation failed", nil)
 	}
 
-	err = app.items.Delete(charID, decodedItemName)
-	if err != nil {
+	if err := app.personalTokens.Revoke(playerUsername, tokenID); err != nil {
 		log.Error(err)
 		if errors.Is(err, models.ErrNoRecord) {
-			return sendJSONResponse(c, http.StatusNotFound, "Item deletion", "Deletion failed", nil)
+			return sendJSONResponse(c, http.StatusNotFound, "Revoke personal access token", "Revocation failed", nil)
 		}
-		return sendJSONResponse(c, http.StatusInternalServerError, "Item deletion", "Deletion failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Revoke personal access token", "Revocation failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Item deletion", "Deletion successful", nil)
-}
-
-type CreateCampaignRequest struct {
-	CampaignInfo models.Campaign `json:"campaign"`
-	CharacterId  []int           `json:"character_id"`
+	return sendJSONResponse(c, http.StatusOK, "Revoke personal access token", "Revocation successful", nil)
 }
 
-// Create a new campaign, insert its info into the "Campaign" table, and insert
-// campaign/character relationship info into the "BelongsTo" table if successful
-func (app *application) createCampaign(c echo.Context) error {
-	var req CreateCampaignRequest
-
-	if err := c.Bind(&req); err != nil {
+func (app *application) createCharacter(c echo.Context) error {
+	var creation characterCreationRequest
+	if err := c.Bind(&creation); err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign creation", "Could not process request", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character creation", "Could not process request", nil)
 	}
 
 	creatorUsername := getUsernameFromToken(c)
 	if strings.TrimSpace(creatorUsername) == "" {
-		return sendJSONResponse(c, http.StatusUnauthorized, "Campaign creation", "Creation failed", nil)
+		return sendJSONResponse(c, http.StatusUnauthorized, "Character creation", "Creation failed", nil)
 	}
 
-	req.CampaignInfo.DungeonMaster = creatorUsername
+	req := creation.Character
+	req.PlayerUsername = creatorUsername
 
-	campaignId, err := app.campaigns.Insert(req.CampaignInfo, req.CharacterId)
+	msg, err := app.resolveCharacterRegistry(&req, creatorUsername)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign creation", "Creation failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character creation", "Creation failed", nil)
+	}
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character creation", msg, nil)
 	}
 
-	return sendJSONResponse(c, http.StatusCreated, "Campaign creation", "Creation successful",
+	// Scores entered by hand already include any racial bonus
+	if req.AbilityMethod == "" {
+		req.AbilityMethod = models.AbilityMethodManual
+	}
+	if req.AbilityMethod != models.AbilityMethodRolled {
+		req.AbilityRollID = nil
+	}
+	if req.AbilityMethod != models.AbilityMethodManual {
+		var roll *models.AbilityRoll
+		if req.AbilityMethod == models.AbilityMethodRolled {
+			if req.AbilityRollID == nil {
+				return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character creation", "Rolled scores need an ability roll", nil)
+			}
+			roll, err = app.abilityRolls.Get(*req.AbilityRollID)
+			if err != nil && !errors.Is(err, models.ErrNoRecord) {
+				log.Error(err)
+				return sendJSONResponse(c, http.StatusInternalServerError, "Character creation", "Creation failed", nil)
+			}
+			if roll == nil || roll.PlayerUsername != creatorUsername {
+				return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character creation", "Ability roll is not known", nil)
+			}
+			if roll.CharacterID != nil {
+				return sendJSONResponse(c, http.StatusConflict, "Character creation", "Ability roll has already been used", nil)
+			}
+		}
+
+		if msg := validateAbilityMethod(&req, roll); msg != "" {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character creation", msg, nil)
+		}
+
+		msg, err := app.applyRacialBonuses(&req, creatorUsername, creation.ChosenAbilityBonuses)
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Character creation", "Creation failed", nil)
+		}
+		if msg != "" {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character creation", msg, nil)
+		}
+	}
+
+	id, err := app.characters.Insert(req)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrAbilityRollUsed) {
+			return sendJSONResponse(c, http.StatusConflict, "Character creation", "Ability roll has already been used", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character creation", "Creation failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusCreated, "Character creation", "Creation successful",
 		struct {
 			ResourceURI string `json:"resource_uri"`
 		}{
-			"/campaign/" + strconv.Itoa(campaignId),
+			"/characters/" + strconv.Itoa(id),
 		},
 	)
 }
 
-type CampaignUpdateRequest struct {
-	State    string `json:"state"`
-	Location string `json:"location"`
-}
-
-func (app *application) updateCampaign(c echo.Context) error {
-	var req CampaignUpdateRequest
-
-	if err := c.Bind(&req); err != nil {
+func (app *application) retrieveCharacter(c echo.Context) error {
+	requestCharID := c.Param("id")
+	charID, err := strconv.Atoi(requestCharID)
+	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign modification", "Could not process request", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character retrieval", "Retrieval failed", nil)
 	}
 
-	campaignIDString := c.Param("id")
-	campaignID, err := strconv.Atoi(campaignIDString)
+	character, err := app.characters.Get(charID)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign modification", "Modification failed", nil)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Character retrieval", "Retrieval failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character retrieval", "Retrieval failed", nil)
 	}
 
-	err = app.campaigns.Update(campaignID, req.State, req.Location)
+	proficiencies, err := app.proficiencies.GetAll(charID)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign modification", "Modification failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character retrieval", "Retrieval failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Campaign modification", "Modification successful", nil)
-}
-
-// Deletes a single campaign given its unique `id.`
-func (app *application) deleteCampaign(c echo.Context) error {
-	// TODO: Check if the requestor actually is the player who created
-	// the campaign. Right now anyone can delete another player's
-	// campaign as long as they are authenticated.
-
-	campaignIDString := c.Param("id")
-	campaignID, err := strconv.Atoi(campaignIDString)
+	conditions, err := app.conditions.GetAll(charID)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign deletion", "Deletion failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character retrieval", "Retrieval failed", nil)
 	}
 
-	err = app.campaigns.Delete(campaignID)
+	features, err := app.characterFeatures(character)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign deletion", "Deletion failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character retrieval", "Retrieval failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Campaign deletion", "Deletion successful", nil)
+	return sendJSONResponse(c, http.StatusOK, "Character retrieval", "Retrieval successful",
+		struct {
+			*models.Character
+			LifeState models.LifeStateType `json:"life_state"`
+			characterProficienciesResponse
+			characterConditionsResponse
+			characterFeaturesResponse
+		}{
+			character,
+			character.LifeState(),
+			newCharacterProficienciesResponse(character, *proficiencies),
+			newCharacterConditionsResponse(character, *conditions),
+			features,
+		})
 }
 
-// Fetches all campaigns started by the requestor.
-func (app *application) getsPlayersCreatedCampaigns(c echo.Context) error {
-	dungeonMaster := getUsernameFromToken(c)
-	if strings.TrimSpace(dungeonMaster) == "" {
-		return sendJSONResponse(c, http.StatusUnauthorized, "Retrieve all player's started campaigns", "Retrieval failed", nil)
+// Retrieve all characters belonging to the requesting user.
+func (app *application) retrieveUserCharacters(c echo.Context) error {
+	username := getUsernameFromToken(c)
+	if strings.TrimSpace(username) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Retrieve all user characters", "Retrieval failed", nil)
 	}
 
-	campaigns, err := app.campaigns.GetPlayersCreatedCampaigns(dungeonMaster)
+	characters, err := app.characters.GetAllUserCharacters(username)
 	if err != nil {
-		return sendJSONResponse(c, http.StatusInternalServerError, "Retrieve all player's started campaigns", "Retrieval failed", nil)
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Retrieve all user characters", "Retrieval failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Retrieve all user characters", "Retrieval failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Retrieve all player's started campaigns", "Retrieval successful",
+	return sendJSONResponse(c, http.StatusOK, "Retrieve all user characters", "Retrieval successful",
 		struct {
-			Campaigns []models.Campaign `json:"campaigns"`
+			Characters *[]models.Character `json:"characters"`
 		}{
-			*campaigns,
+			characters,
 		})
 }
 
-// Fetches all campaigns which a given character is participating in (as
-// a player)
-func (app *application) getAllCharacterCampaigns(c echo.Context) error {
-	charIDString := c.Param("id")
-	charID, err := strconv.Atoi(charIDString)
+// updateCharacter updates a character with a given ID
+func (app *application) updateCharacter(c echo.Context) error {
+	var req models.Character
+	requestCharIDStr := c.Param("id")
+
+	err := c.Bind(&req)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Retrieve all character campaigns", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character update", "Could not process request", nil)
 	}
 
-	campaigns, err := app.campaigns.GetAllCharacterCampaigns(charID)
+	numericCharID, err := strconv.Atoi(requestCharIDStr)
 	if err != nil {
-		return sendJSONResponse(c, http.StatusInternalServerError, "Retrieve all character campaigns", "Retrieval failed", nil)
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character update", "Could not process request", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Retrieve all character campaigns", "Retrieval successful",
-		struct {
-			Campaigns []models.Campaign `json:"campaigns"`
-		}{
-			*campaigns,
-		})
+	req.ID = numericCharID
+
+	// Homebrew is checked against what the character's owner may choose
+	character, err := app.characters.Get(req.ID)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Character update", "update failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character update", "update failed", nil)
+	}
+	msg, err := app.resolveCharacterRegistry(&req, character.PlayerUsername)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character update", "update failed", nil)
+	}
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character update", msg, nil)
+	}
+
+	err = app.characters.Update(req)
+	if err != nil {
+		log.Error(err)
+		switch {
+		case errors.Is(err, models.ErrDuplicateCharacterClass):
+			return sendJSONResponse(c, http.StatusConflict, "Character update", "Character already has levels in this class", nil)
+		case errors.Is(err, models.ErrNotEnoughLevels):
+			return sendJSONResponse(c, http.StatusConflict, "Character update", "Character would not have enough levels for its classes", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character update", "update failed", nil)
+	}
+	app.publishCharacterUpdated(req.ID)
+
+	return sendJSONResponse(c, http.StatusOK, "Character update", "Update successful", nil)
 }
 
-// Fetches a list of player usernames that have attended each campaign
-// that was created by the requestor.
-func (app *application) getPlayersAttendedAll(c echo.Context) error {
-	dungeonMaster := getUsernameFromToken(c)
-	if strings.TrimSpace(dungeonMaster) == "" {
-		return sendJSONResponse(c, http.StatusUnauthorized,
-			"Campaign stats - Players with perfect attendance in requestor's created campaigns", "Access denied", nil)
+// deleteCharacter attempts to a delete a character given its ID.
+func (app *application) deleteCharacter(c echo.Context) error {
+	requestCharIDStr := c.Param("id")
+	numericCharID, err := strconv.Atoi(requestCharIDStr)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character deletion", "Could not process request", nil)
 	}
 
-	usernames, err := app.campaigns.GetPlayersAttendedAll(dungeonMaster)
+	err = app.characters.Delete(numericCharID)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnauthorized,
-			"Campaign stats -  Players with perfect attendance in requestor's created campaigns", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character deletion", "Deletion failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK,
-		"Campaign stats - Players with perfect attendance in requestor's created campaigns", "Retrieval successful",
-		struct {
-			Usernames []string `json:"usernames"`
-		}{
-			*usernames,
-		},
-	)
+	return sendJSONResponse(c, http.StatusOK, "Character deletion", "Deletion successful", nil)
 }
 
-// Create a new milestone for a given campaign.
-func (app *application) createMilestone(c echo.Context) error {
-	var req models.CampaignMilestone
+// Create a spell which belongs to a character.
+func (app *application) createSpell(c echo.Context) error {
+	charIDString := c.Param("id")
+	charID, err := strconv.Atoi(charIDString)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Spell creation", "Could not process request", nil)
+	}
 
+	var req models.Spell
 	if err := c.Bind(&req); err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Milestone creation", "Could not process request", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Spell creation", "Could not process request", nil)
 	}
 
-	err := app.milestones.Insert(req.CampaignID, req.Milestone)
+	req.CharacterID = charID
+	// TODO: Check if the character actually belongs to the user.
+	err = app.spells.Insert(req)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusInternalServerError, "Milestone creation", "Creation failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Spell creation", "Creation failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Milestone creation", "Creation successful", nil)
+	return sendJSONResponse(c, http.StatusCreated, "Spell creation", "Creation successful", nil)
 }
 
-// Retrieve all milestones belonging to a campaign given the campaign ID.
-func (app *application) getAllMilestonesForCampaign(c echo.Context) error {
-	campaignIDString := c.Param("id")
-	campaignID, err := strconv.Atoi(campaignIDString)
+// Retrieve a spell belonging to a character.
+func (app *application) retrieveSpell(c echo.Context) error {
+	charIDString := c.Param("id")
+	charID, err := strconv.Atoi(charIDString)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Milestone retrieval", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Spell retrieval", "Retrieval failed", nil)
 	}
 
-	milestones, err := app.milestones.GetAllForCampaign(campaignID)
+	rawSpellName := c.Param("name")
+	decodedSpellName, err := url.QueryUnescape(rawSpellName)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusInternalServerError, "Milestone retrieval", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Spell retrieval", "Retrieval failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Milestone retrieval", "Retrieval successful",
-		struct {
-			Milestones []string `json:"milestones"`
-		}{
-			*milestones,
-		})
+	spell, err := app.spells.Get(charID, decodedSpellName)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusNotFound, "Spell retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Spell retrieval", "Retrieval successful", spell)
 }
 
-// Retrieve some data related to players and characters who are
-// participaring in a campaign.
-func (app *application) getCampaignParticipants(c echo.Context) error {
-	campaignIDString := c.Param("id")
-	campaignID, err := strconv.Atoi(campaignIDString)
+// Delete a spell belonging to a character.
+func (app *application) deleteSpell(c echo.Context) error {
+	charIDString := c.Param("id")
+	charID, err := strconv.Atoi(charIDString)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign participant retrieval", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Spell deletion", "Deletion failed", nil)
 	}
 
-	participants, err := app.campaigns.GetCampaignParticpants(campaignID)
+	rawSpellName := c.Param("name")
+	decodedSpellName, err := url.QueryUnescape(rawSpellName)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign participant retrieval", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Spell deletion", "Deletion failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Milestone retrieval", "Retrieval successful",
-		struct {
-			Participants []models.CampaignParticipants `json:"participants"`
-		}{
-			*participants,
-		})
+	err = app.spells.Delete(charID, decodedSpellName)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Spell deletion", "Deletion failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Spell deletion", "Deletion failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Spell deletion", "Deletion successful", nil)
 }
 
-// Get all global stats.
-func (app *application) retrieveAllStats(c echo.Context) error {
-	stats, err := app.stats.GetAll()
+// Get all spells belonging to a character.
+func (app *application) retrieveAllCharacterSpells(c echo.Context) error {
+	charIDString := c.Param("id")
+	charID, err := strconv.Atoi(charIDString)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusInternalServerError, "Retrieve all stats", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Retrieve all character spells", "Retrieval failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Retrieve all stats", "Retrieval successful", stats)
+	spells, err := app.spells.GetAllCharacterSpells(charID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusNotFound, "Retrieve all character spells", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Retrieve all character spells", "Retrieval successful", struct {
+		Spells *[]models.Spell `json:"spells"`
+	}{
+		spells,
+	})
 }
 
-// Get several interesting items for a given character
-func (app *application) getItemStats(c echo.Context) error {
+// Create an item which belongs to a character.
+func (app *application) createItem(c echo.Context) error {
 	charIDString := c.Param("id")
 	charID, err := strconv.Atoi(charIDString)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Retrieve character item stats", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Item creation", "Could not process request", nil)
 	}
 
-	stats, err := app.items.GetItemStats(charID)
+	var req models.Item
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Item creation", "Could not process request", nil)
+	}
+
+	req.CharacterID = charID
+	// TODO: Check if the character actually belongs to the user.
+	err = app.items.Insert(req)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusInternalServerError, "Retrieve character item stats", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Item creation", "Creation failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Retrieve character item stats", "Retrieval successful",
-		struct {
-			Stats models.ItemStats `json:"stats"`
-		}{
-			*stats,
-		})
+	return sendJSONResponse(c, http.StatusCreated, "Item creation", "Creation successful", nil)
 }
 
-// Get number of spells belonging to a character per class.
-func (app *application) getCountSpellsPerSchool(c echo.Context) error {
+// Retrieve an item belonging to a character.
+func (app *application) retrieveItem(c echo.Context) error {
 	charIDString := c.Param("id")
 	charID, err := strconv.Atoi(charIDString)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Retrieve count character spells per school", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Item retrieval", "Retrieval failed", nil)
 	}
 
-	spellsCount, err := app.spells.GetCountSpellsPerSchool(charID)
+	rawItemName := c.Param("name")
+	decodedItemName, err := url.QueryUnescape(rawItemName)
 	if err != nil {
 		log.Error(err)
-		return sendJSONResponse(c, http.StatusInternalServerError, "Retrieve count character spells per school test", "Retrieval failed", nil)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Item retrieval", "Retrieval failed", nil)
 	}
 
-	return sendJSONResponse(c, http.StatusOK, "Retrieve count character spells per school", "Retrieval successful",
-		struct {
-			SpellsCount *[]models.SpellSchoolCountType `json:"spells_count"`
+	item, err := app.items.Get(charID, decodedItemName)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Item retrieval", "Retrieval failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Item retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Item retrieval", "Retrieval successful", item)
+}
+
+// Get all items belonging to a character.
+func (app *application) retrieveAllCharacterItems(c echo.Context) error {
+	charIDString := c.Param("id")
+	charID, err := strconv.Atoi(charIDString)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Retrieve all character items", "Retrieval failed", nil)
+	}
+
+	items, err := app.items.GetAllCharacterItems(charID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusNotFound, "Retrieve all character items", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Retrieve all character items", "Retrieval successful", struct {
+		Items *[]models.Item `json:"items"`
+	}{
+		items,
+	})
+}
+
+// Delete an item belonging to a character.
+func (app *application) deleteItem(c echo.Context) error {
+	charIDString := c.Param("id")
+	charID, err := strconv.Atoi(charIDString)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Item deletion", "Deletion failed", nil)
+	}
+
+	rawItemName := c.Param("name")
+	decodedItemName, err := url.QueryUnescape(rawItemName)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Item deletion", "Deletion failed", nil)
+	}
+
+	err = app.items.Delete(charID, decodedItemName)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Item deletion", "Deletion failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Item deletion", "Deletion failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Item deletion", "Deletion successful", nil)
+}
+
+type CreateCampaignRequest struct {
+	CampaignInfo models.Campaign `json:"campaign"`
+	CharacterId  []int           `json:"character_id"`
+}
+
+// Create a new campaign, insert its info into the "Campaign" table, and insert
+// campaign/character relationship info into the "BelongsTo" table if successful.
+// Characters belonging to other players are not added directly; their
+// players are sent an invitation instead.
+func (app *application) createCampaign(c echo.Context) error {
+	var req CreateCampaignRequest
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign creation", "Could not process request", nil)
+	}
+
+	creatorUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(creatorUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Campaign creation", "Creation failed", nil)
+	}
+
+	req.CampaignInfo.DungeonMaster = &creatorUsername
+
+	switch req.CampaignInfo.State {
+	case "":
+		req.CampaignInfo.State = models.CampaignPlanning
+	case models.CampaignPlanning, models.CampaignRecruiting:
+	default:
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign creation", "New campaigns must be planning or recruiting", nil)
+	}
+
+	if utf8.RuneCountInString(req.CampaignInfo.StatusNote) > maxStatusNoteLength {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign creation", "Status note must be at most 1024 characters", nil)
+	}
+
+	var ownCharacterIDs []int
+	var invitees []string
+	invited := make(map[string]bool)
+	for _, id := range req.CharacterId {
+		character, err := app.characters.Get(id)
+		if err != nil {
+			log.Error(err)
+			if errors.Is(err, models.ErrNoRecord) {
+				return sendJSONResponse(c, http.StatusNotFound, "Campaign creation", "Character not found", nil)
+			}
+			return sendJSONResponse(c, http.StatusInternalServerError, "Campaign creation", "Creation failed", nil)
+		}
+
+		if character.PlayerUsername == creatorUsername {
+			ownCharacterIDs = append(ownCharacterIDs, id)
+		} else if !invited[character.PlayerUsername] {
+			invited[character.PlayerUsername] = true
+			invitees = append(invitees, character.PlayerUsername)
+		}
+	}
+
+	expiresAt := time.Now().Add(defaultInvitationTTL)
+	campaignId, err := app.campaigns.Insert(req.CampaignInfo, ownCharacterIDs, invitees, &expiresAt)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign creation", "Creation failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusCreated, "Campaign creation", "Creation successful",
+		struct {
+			ResourceURI string   `json:"resource_uri"`
+			Invited     []string `json:"invited_players"`
+		}{
+			"/campaign/" + strconv.Itoa(campaignId),
+			invitees,
+		},
+	)
+}
+
+type CampaignUpdateRequest struct {
+	StatusNote string `json:"status_note"`
+	Location   string `json:"location"`
+}
+
+const maxStatusNoteLength = 1024
+
+func (app *application) updateCampaign(c echo.Context) error {
+	var req CampaignUpdateRequest
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign modification", "Could not process request", nil)
+	}
+
+	campaignIDString := c.Param("id")
+	campaignID, err := strconv.Atoi(campaignIDString)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign modification", "Modification failed", nil)
+	}
+
+	if utf8.RuneCountInString(req.StatusNote) > maxStatusNoteLength {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign modification", "Status note must be at most 1024 characters", nil)
+	}
+
+	if _, err := app.authorizeWritableCampaign(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Campaign modification", "Modification failed", nil)
+	}
+
+	err = app.campaigns.Update(campaignID, req.StatusNote, req.Location)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign modification", "Modification failed", nil)
+	}
+	app.publish(campaignID, events.CampaignUpdated, nil)
+
+	return sendJSONResponse(c, http.StatusOK, "Campaign modification", "Modification successful", nil)
+}
+
+// Deletes a single campaign given its unique `id.`
+func (app *application) deleteCampaign(c echo.Context) error {
+	// TODO: Check if the requestor actually is the player who created
+	// the campaign. Right now anyone can delete another player's
+	// campaign as long as they are authenticated.
+
+	campaignIDString := c.Param("id")
+	campaignID, err := strconv.Atoi(campaignIDString)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign deletion", "Deletion failed", nil)
+	}
+
+	err = app.campaigns.Delete(campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign deletion", "Deletion failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Campaign deletion", "Deletion successful", nil)
+}
+
+// Fetches all campaigns started by the requestor.
+func (app *application) getsPlayersCreatedCampaigns(c echo.Context) error {
+	dungeonMaster := getUsernameFromToken(c)
+	if strings.TrimSpace(dungeonMaster) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Retrieve all player's started campaigns", "Retrieval failed", nil)
+	}
+
+	campaigns, err := app.campaigns.GetPlayersCreatedCampaigns(dungeonMaster)
+	if err != nil {
+		return sendJSONResponse(c, http.StatusInternalServerError, "Retrieve all player's started campaigns", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Retrieve all player's started campaigns", "Retrieval successful",
+		struct {
+			Campaigns []models.Campaign `json:"campaigns"`
+		}{
+			*campaigns,
+		})
+}
+
+// Fetches all campaigns which a given character is participating in (as
+// a player)
+func (app *application) getAllCharacterCampaigns(c echo.Context) error {
+	charIDString := c.Param("id")
+	charID, err := strconv.Atoi(charIDString)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Retrieve all character campaigns", "Retrieval failed", nil)
+	}
+
+	campaigns, err := app.campaigns.GetAllCharacterCampaigns(charID)
+	if err != nil {
+		return sendJSONResponse(c, http.StatusInternalServerError, "Retrieve all character campaigns", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Retrieve all character campaigns", "Retrieval successful",
+		struct {
+			Campaigns []models.Campaign `json:"campaigns"`
+		}{
+			*campaigns,
+		})
+}
+
+// Fetches a list of player usernames that have attended each campaign
+// that was created by the requestor.
+func (app *application) getPlayersAttendedAll(c echo.Context) error {
+	dungeonMaster := getUsernameFromToken(c)
+	if strings.TrimSpace(dungeonMaster) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized,
+			"Campaign stats - Players with perfect attendance in requestor's created campaigns", "Access denied", nil)
+	}
+
+	usernames, err := app.campaigns.GetPlayersAttendedAll(dungeonMaster)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnauthorized,
+			"Campaign stats -  Players with perfect attendance in requestor's created campaigns", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK,
+		"Campaign stats - Players with perfect attendance in requestor's created campaigns", "Retrieval successful",
+		struct {
+			Usernames []string `json:"usernames"`
+		}{
+			*usernames,
+		},
+	)
+}
+
+// Limits on the free-text fields of a milestone.
+const (
+	maxMilestoneLength            = 100
+	maxMilestoneDescriptionLength = 2000
+	maxInGameDateLength           = 100
+)
+
+// validateMilestone trims the text fields of `milestone` and returns a
+// message describing the first invalid field, or an empty string if the
+// milestone is valid.
+func validateMilestone(milestone *models.CampaignMilestone) string {
+	milestone.Milestone = strings.TrimSpace(milestone.Milestone)
+	milestone.InGameDate = strings.TrimSpace(milestone.InGameDate)
+
+	switch {
+	case milestone.Milestone == "":
+		return "Milestone is required"
+	case utf8.RuneCountInString(milestone.Milestone) > maxMilestoneLength:
+		return "Milestone must be at most 100 characters"
+	case utf8.RuneCountInString(milestone.Description) > maxMilestoneDescriptionLength:
+		return "Description must be at most 2000 characters"
+	case utf8.RuneCountInString(milestone.InGameDate) > maxInGameDateLength:
+		return "In-game date must be at most 100 characters"
+	case milestone.XPReward < 0 || milestone.XPReward > models.MaxXP:
+		return "XP reward must be between 0 and 355000"
+	}
+
+	if milestone.OccurredOn != nil {
+		if *milestone.OccurredOn == "" {
+			milestone.OccurredOn = nil
+		} else if _, err := time.Parse("2006-01-02", *milestone.OccurredOn); err != nil {
+			return "Date must be formatted as YYYY-MM-DD"
+		}
+	}
+
+	return ""
+}
+
+// insertMilestone appends `milestone` to its campaign on behalf of the
+// campaign's dungeon master.
+func (app *application) insertMilestone(c echo.Context, milestone models.CampaignMilestone) error {
+	if _, err := app.authorizeWritableCampaign(c, milestone.CampaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Milestone creation", "Creation failed", nil)
+	}
+
+	if msg := validateMilestone(&milestone); msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Milestone creation", msg, nil)
+	}
+
+	author := getUsernameFromToken(c)
+	milestone.AuthorUsername = &author
+
+	id, err := app.milestones.Create(milestone)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Milestone creation", "Creation failed", nil)
+	}
+
+	created, err := app.milestones.Get(id)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Milestone creation", "Creation failed", nil)
+	}
+	app.publish(created.CampaignID, events.MilestoneAdded, map[string]int{"milestone_id": id})
+
+	return sendJSONResponse(c, http.StatusOK, "Milestone creation", "Creation successful", created)
+}
+
+// Create a new milestone for a given campaign.
+func (app *application) createMilestone(c echo.Context) error {
+	var req models.CampaignMilestone
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Milestone creation", "Could not process request", nil)
+	}
+
+	return app.insertMilestone(c, req)
+}
+
+// Create a new milestone for the campaign identified in the route.
+func (app *application) createCampaignMilestone(c echo.Context) error {
+	var req models.CampaignMilestone
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Milestone creation", "Could not process request", nil)
+	}
+
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Milestone creation", "Could not process request", nil)
+	}
+	req.CampaignID = campaignID
+
+	return app.insertMilestone(c, req)
+}
+
+// Retrieve all milestones belonging to a campaign given the campaign ID.
+func (app *application) getAllMilestonesForCampaign(c echo.Context) error {
+	campaignIDString := c.Param("id")
+	campaignID, err := strconv.Atoi(campaignIDString)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Milestone retrieval", "Retrieval failed", nil)
+	}
+
+	milestones, err := app.milestones.GetAllForCampaign(campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Milestone retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Milestone retrieval", "Retrieval successful",
+		struct {
+			Milestones []models.CampaignMilestone `json:"milestones"`
+		}{
+			*milestones,
+		})
+}
+
+// getCampaignMilestoneParams parses the campaign and milestone IDs of
+// a milestone route and retrieves the milestone, provided that it
+// belongs to the campaign.
+func (app *application) getCampaignMilestoneParams(c echo.Context) (int, *models.CampaignMilestone, int) {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return 0, nil, http.StatusUnprocessableEntity
+	}
+
+	milestoneID, err := strconv.Atoi(c.Param("milestoneID"))
+	if err != nil {
+		log.Error(err)
+		return 0, nil, http.StatusUnprocessableEntity
+	}
+
+	milestone, err := app.milestones.Get(milestoneID)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return 0, nil, http.StatusNotFound
+		}
+		return 0, nil, http.StatusInternalServerError
+	}
+	if milestone.CampaignID != campaignID {
+		return 0, nil, http.StatusNotFound
+	}
+
+	return campaignID, milestone, http.StatusOK
+}
+
+// Retrieve a single milestone of a campaign.
+func (app *application) getCampaignMilestone(c echo.Context) error {
+	_, milestone, status := app.getCampaignMilestoneParams(c)
+	if milestone == nil {
+		return sendJSONResponse(c, status, "Milestone retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Milestone retrieval", "Retrieval successful", milestone)
+}
+
+// Replace the title, description, dates and XP reward of a milestone.
+func (app *application) updateCampaignMilestone(c echo.Context) error {
+	var req models.CampaignMilestone
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Milestone modification", "Could not process request", nil)
+	}
+
+	campaignID, milestone, status := app.getCampaignMilestoneParams(c)
+	if milestone == nil {
+		return sendJSONResponse(c, status, "Milestone modification", "Modification failed", nil)
+	}
+
+	if _, err := app.authorizeWritableCampaign(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Milestone modification", "Modification failed", nil)
+	}
+
+	if msg := validateMilestone(&req); msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Milestone modification", msg, nil)
+	}
+
+	req.ID = milestone.ID
+	if err := app.milestones.Update(req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Milestone modification", "Modification failed", nil)
+	}
+
+	app.publish(campaignID, events.MilestoneUpdated, map[string]int{"milestone_id": milestone.ID})
+
+	updated, err := app.milestones.Get(milestone.ID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Milestone modification", "Modification failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Milestone modification", "Modification successful", updated)
+}
+
+// Delete a milestone. Later milestones move up to fill its place.
+func (app *application) deleteCampaignMilestone(c echo.Context) error {
+	campaignID, milestone, status := app.getCampaignMilestoneParams(c)
+	if milestone == nil {
+		return sendJSONResponse(c, status, "Milestone deletion", "Deletion failed", nil)
+	}
+
+	if _, err := app.authorizeWritableCampaign(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Milestone deletion", "Deletion failed", nil)
+	}
+
+	if err := app.milestones.Delete(milestone.ID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Milestone deletion", "Deletion failed", nil)
+	}
+
+	app.publish(campaignID, events.MilestoneDeleted, map[string]int{"milestone_id": milestone.ID})
+
+	return sendJSONResponse(c, http.StatusOK, "Milestone deletion", "Deletion successful", nil)
+}
+
+// Reorder the milestones of a campaign. The request lists the IDs of
+// every milestone of the campaign in their new order.
+func (app *application) reorderCampaignMilestones(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Milestone reordering", "Could not process request", nil)
+	}
+
+	req := struct {
+		MilestoneIDs []int `json:"milestone_ids"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Milestone reordering", "Could not process request", nil)
+	}
+
+	if _, err := app.authorizeWritableCampaign(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Milestone reordering", "Reordering failed", nil)
+	}
+
+	if err := app.milestones.Reorder(campaignID, req.MilestoneIDs); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrInvalidMilestoneOrder) {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Milestone reordering", "Every milestone of the campaign must be listed exactly once", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Milestone reordering", "Reordering failed", nil)
+	}
+	app.publish(campaignID, events.MilestonesReordered, nil)
+
+	milestones, err := app.milestones.GetAllForCampaign(campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Milestone reordering", "Reordering failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Milestone reordering", "Reordering successful",
+		struct {
+			Milestones []models.CampaignMilestone `json:"milestones"`
+		}{
+			*milestones,
+		})
+}
+
+// Retrieve some data related to players and characters who are
+// participaring in a campaign.
+func (app *application) getCampaignParticipants(c echo.Context) error {
+	campaignIDString := c.Param("id")
+	campaignID, err := strconv.Atoi(campaignIDString)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign participant retrieval", "Retrieval failed", nil)
+	}
+
+	participants, err := app.campaigns.GetCampaignParticpants(campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign participant retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Milestone retrieval", "Retrieval successful",
+		struct {
+			Participants []models.CampaignParticipants `json:"participants"`
+		}{
+			*participants,
+		})
+}
+
+// Get all global stats.
+func (app *application) retrieveAllStats(c echo.Context) error {
+	stats, err := app.stats.GetAll()
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Retrieve all stats", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Retrieve all stats", "Retrieval successful", stats)
+}
+
+// Get several interesting items for a given character
+func (app *application) getItemStats(c echo.Context) error {
+	charIDString := c.Param("id")
+	charID, err := strconv.Atoi(charIDString)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Retrieve character item stats", "Retrieval failed", nil)
+	}
+
+	stats, err := app.items.GetItemStats(charID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Retrieve character item stats", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Retrieve character item stats", "Retrieval successful",
+		struct {
+			Stats models.ItemStats `json:"stats"`
+		}{
+			*stats,
+		})
+}
+
+// Get number of spells belonging to a character per class.
+func (app *application) getCountSpellsPerSchool(c echo.Context) error {
+	charIDString := c.Param("id")
+	charID, err := strconv.Atoi(charIDString)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Retrieve count character spells per school", "Retrieval failed", nil)
+	}
+
+	spellsCount, err := app.spells.GetCountSpellsPerSchool(charID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Retrieve count character spells per school test", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Retrieve count character spells per school", "Retrieval successful",
+		struct {
+			SpellsCount *[]models.SpellSchoolCountType `json:"spells_count"`
+		}{
+			spellsCount,
+		})
+}
+
+// recordAdminAction adds an entry to the audit log on behalf of the
+// requesting administrator. A failure to write the audit log is logged
+// but does not fail the request.
+func (app *application) recordAdminAction(c echo.Context, action, target, details string) {
+	if err := app.auditLog.Insert(getUsernameFromToken(c), action, target, details); err != nil {
+		log.Error(err)
+	}
+}
+
+// Lists players, optionally filtered by a search term matching their
+// username or name.
+func (app *application) adminSearchPlayers(c echo.Context) error {
+	query := strings.TrimSpace(c.QueryParam("q"))
+	limit, offset := parsePagination(c)
+
+	players, err := app.players.Search(query, limit, offset)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Admin - Search players", "Retrieval failed", nil)
+	}
+
+	app.recordAdminAction(c, "player.search", "", "q="+query)
+
+	return sendJSONResponse(c, http.StatusOK, "Admin - Search players", "Retrieval successful",
+		struct {
+			Players []models.Player `json:"players"`
+		}{
+			*players,
+		})
+}
+
+// Retrieves any player's account details.
+func (app *application) adminRetrievePlayer(c echo.Context) error {
+	username := c.Param("username")
+
+	player, err := app.players.Get(username)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Admin - Player retrieval", "Retrieval failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Admin - Player retrieval", "Retrieval failed", nil)
+	}
+
+	app.recordAdminAction(c, "player.view", username, "")
+
+	return sendJSONResponse(c, http.StatusOK, "Admin - Player retrieval", "Retrieval successful", player)
+}
+
+// Disables or re-enables a player's account. Administrators cannot
+// disable their own account.
+func (app *application) adminSetPlayerDisabled(c echo.Context) error {
+	username := c.Param("username")
+
+	req := struct {
+		Disabled *bool `json:"disabled"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Admin - Disable player", "Could not process request", nil)
+	}
+	if req.Disabled == nil {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Admin - Disable player", "Disabled must be true or false", nil)
+	}
+
+	if username == getUsernameFromToken(c) {
+		return sendJSONResponse(c, http.StatusBadRequest, "Admin - Disable player", "Administrators cannot disable their own account", nil)
+	}
+
+	if err := app.players.SetDisabled(username, *req.Disabled); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Admin - Disable player", "Update failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Admin - Disable player", "Update failed", nil)
+	}
+
+	action := "player.enable"
+	if *req.Disabled {
+		action = "player.disable"
+	}
+	app.recordAdminAction(c, action, username, "")
+
+	return sendJSONResponse(c, http.StatusOK, "Admin - Disable player", "Update successful", nil)
+}
+
+// Changes a player's role. Administrators cannot demote themselves.
+func (app *application) adminSetPlayerRole(c echo.Context) error {
+	username := c.Param("username")
+
+	req := struct {
+		Role models.RoleType `json:"role"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Admin - Change player role", "Role must be player or admin", nil)
+	}
+	if req.Role == "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Admin - Change player role", "Role must be player or admin", nil)
+	}
+
+	if username == getUsernameFromToken(c) && req.Role != models.RoleAdmin {
+		return sendJSONResponse(c, http.StatusBadRequest, "Admin - Change player role", "Administrators cannot demote themselves", nil)
+	}
+
+	if err := app.players.SetRole(username, req.Role); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Admin - Change player role", "Update failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Admin - Change player role", "Update failed", nil)
+	}
+
+	app.recordAdminAction(c, "player.role", username, "role="+string(req.Role))
+
+	return sendJSONResponse(c, http.StatusOK, "Admin - Change player role", "Update successful", nil)
+}
+
+// Forces a player to choose a new password the next time they use the
+// API.
+func (app *application) adminForcePasswordReset(c echo.Context) error {
+	username := c.Param("username")
+
+	if err := app.players.RequirePasswordReset(username); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Admin - Force password reset", "Update failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Admin - Force password reset", "Update failed", nil)
+	}
+
+	app.recordAdminAction(c, "player.password_reset", username, "")
+
+	return sendJSONResponse(c, http.StatusOK, "Admin - Force password reset", "Update successful", nil)
+}
+
+// Lists campaigns whose dungeon master's account has been deleted.
+func (app *application) adminRetrieveOrphanedCampaigns(c echo.Context) error {
+	campaigns, err := app.campaigns.GetOrphaned()
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Admin - Orphaned campaigns", "Retrieval failed", nil)
+	}
+
+	app.recordAdminAction(c, "campaign.list_orphaned", "", "")
+
+	return sendJSONResponse(c, http.StatusOK, "Admin - Orphaned campaigns", "Retrieval successful",
+		struct {
+			Campaigns []models.Campaign `json:"campaigns"`
+		}{
+			*campaigns,
+		})
+}
+
+// Assigns a campaign to a new dungeon master.
+func (app *application) adminReassignCampaign(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Admin - Reassign campaign", "Could not process request", nil)
+	}
+
+	req := struct {
+		Username string `json:"username"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Admin - Reassign campaign", "Could not process request", nil)
+	}
+
+	if err := app.campaigns.SetDungeonMaster(campaignID, req.Username); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Admin - Reassign campaign", "Campaign or player not found", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Admin - Reassign campaign", "Update failed", nil)
+	}
+
+	app.recordAdminAction(c, "campaign.reassign", strconv.Itoa(campaignID), "dungeon_master="+req.Username)
+
+	return sendJSONResponse(c, http.StatusOK, "Admin - Reassign campaign", "Update successful", nil)
+}
+
+// Get all global stats, including those only visible to administrators.
+func (app *application) adminRetrieveStats(c echo.Context) error {
+	stats, err := app.stats.GetAdmin()
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Admin - Retrieve all stats", "Retrieval failed", nil)
+	}
+
+	app.recordAdminAction(c, "stats.view", "", "")
+
+	return sendJSONResponse(c, http.StatusOK, "Admin - Retrieve all stats", "Retrieval successful", stats)
+}
+
+// Retrieves the audit trail of administrator actions.
+func (app *application) adminRetrieveAuditLog(c echo.Context) error {
+	limit, offset := parsePagination(c)
+
+	entries, err := app.auditLog.GetAll(limit, offset)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Admin - Audit log", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Admin - Audit log", "Retrieval successful",
+		struct {
+			Entries []models.AuditLogEntry `json:"entries"`
+		}{
+			*entries,
+		})
+}
+
+// Invitations which do not specify an expiry are valid for this long.
+const (
+	defaultInvitationTTL = 14 * 24 * time.Hour
+	defaultInviteCodeTTL = 7 * 24 * time.Hour
+)
+
+// Invites a player to the campaign, or creates a shareable invite code
+// when no username is given. Only the dungeon master may invite.
+func (app *application) createCampaignInvitation(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign invitation creation", "Could not process request", nil)
+	}
+
+	req := struct {
+		Username       string `json:"username"`
+		ExpiresInHours int    `json:"expires_in_hours"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign invitation creation", "Could not process request", nil)
+	}
+
+	if _, err := app.authorizeWritableCampaign(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Campaign invitation creation", "Creation failed", nil)
+	}
+
+	if req.ExpiresInHours < 0 {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign invitation creation", "Expiry must not be negative", nil)
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	var code string
+	expiresAt := time.Now().Add(defaultInvitationTTL)
+	if req.Username == "" {
+		code, err = generateRandomToken(9)
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Campaign invitation creation", "Creation failed", nil)
+		}
+		expiresAt = time.Now().Add(defaultInviteCodeTTL)
+	} else if req.Username == getUsernameFromToken(c) {
+		return sendJSONResponse(c, http.StatusBadRequest, "Campaign invitation creation", "The dungeon master cannot invite themselves", nil)
+	}
+
+	if req.ExpiresInHours > 0 {
+		expiresAt = time.Now().Add(time.Duration(req.ExpiresInHours) * time.Hour)
+	}
+
+	id, err := app.invitations.Insert(campaignID, req.Username, code, &expiresAt)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Campaign invitation creation", "Player not found", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign invitation creation", "Creation failed", nil)
+	}
+
+	invitation, err := app.invitations.Get(id)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign invitation creation", "Creation failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusCreated, "Campaign invitation creation", "Creation successful", invitation)
+}
+
+// Lists every invitation to a campaign. Only the dungeon master may see
+// them, since they include shareable invite codes.
+func (app *application) getCampaignInvitations(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign invitation retrieval", "Retrieval failed", nil)
+	}
+
+	if _, err := app.authorizeDungeonMaster(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Campaign invitation retrieval", "Retrieval failed", nil)
+	}
+
+	invitations, err := app.invitations.GetAllForCampaign(campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign invitation retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Campaign invitation retrieval", "Retrieval successful",
+		struct {
+			Invitations []models.CampaignInvitation `json:"invitations"`
+		}{
+			*invitations,
+		})
+}
+
+// Revokes a pending invitation or invite code.
+func (app *application) revokeCampaignInvitation(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign invitation revocation", "Revocation failed", nil)
+	}
+
+	invitationID, err := strconv.Atoi(c.Param("invitationID"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign invitation revocation", "Revocation failed", nil)
+	}
+
+	if _, err := app.authorizeDungeonMaster(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Campaign invitation revocation", "Revocation failed", nil)
+	}
+
+	invitation, err := app.invitations.Get(invitationID)
+	if err != nil || invitation.CampaignID != campaignID {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusNotFound, "Campaign invitation revocation", "Revocation failed", nil)
+	}
+
+	if err := app.invitations.Respond(invitationID, models.RequestRevoked); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrInvitationExpired) {
+			return sendJSONResponse(c, http.StatusConflict, "Campaign invitation revocation", "Invitation is no longer pending", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign invitation revocation", "Revocation failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Campaign invitation revocation", "Revocation successful", nil)
+}
+
+// Lists the pending invitations addressed to the requestor.
+func (app *application) getPlayerInvitations(c echo.Context) error {
+	playerUsername := getUsernameFromToken(c)
+	if strings.TrimSpace(playerUsername) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Player invitation retrieval", "Access denied", nil)
+	}
+
+	invitations, err := app.invitations.GetPendingForPlayer(playerUsername)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Player invitation retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Player invitation retrieval", "Retrieval successful",
+		struct {
+			Invitations []models.CampaignInvitation `json:"invitations"`
+		}{
+			*invitations,
+		})
+}
+
+// getOwnInvitation retrieves the invitation identified by the `id`
+// route parameter, provided that it is addressed to the requestor.
+func (app *application) getOwnInvitation(c echo.Context) (*models.CampaignInvitation, int) {
+	invitationID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return nil, http.StatusUnprocessableEntity
+	}
+
+	invitation, err := app.invitations.Get(invitationID)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return nil, http.StatusNotFound
+		}
+		return nil, http.StatusInternalServerError
+	}
+
+	if invitation.InvitedUsername == nil || *invitation.InvitedUsername != getUsernameFromToken(c) {
+		return nil, http.StatusNotFound
+	}
+
+	return invitation, http.StatusOK
+}
+
+// Accepts an invitation addressed to the requestor, adding one of their
+// characters to the campaign.
+func (app *application) acceptInvitation(c echo.Context) error {
+	req := struct {
+		CharacterID int `json:"character_id"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Accept campaign invitation", "Could not process request", nil)
+	}
+
+	invitation, status := app.getOwnInvitation(c)
+	if invitation == nil {
+		return sendJSONResponse(c, status, "Accept campaign invitation", "Acceptance failed", nil)
+	}
+
+	if _, err := app.authorizeCharacterOwner(c, req.CharacterID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Accept campaign invitation", "Character not found", nil)
+	}
+
+	if err := app.invitations.Accept(invitation.ID, req.CharacterID); err != nil {
+		log.Error(err)
+		switch {
+		case errors.Is(err, models.ErrInvitationExpired):
+			return sendJSONResponse(c, http.StatusConflict, "Accept campaign invitation", "Invitation has expired or was already answered", nil)
+		case errors.Is(err, models.ErrDuplicateBelongsTo):
+			return sendJSONResponse(c, http.StatusConflict, "Accept campaign invitation", "Character is already part of the campaign", nil)
+		case errors.Is(err, models.ErrCampaignArchived):
+			return sendJSONResponse(c, http.StatusConflict, "Accept campaign invitation", "Campaign has been archived", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Accept campaign invitation", "Acceptance failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Accept campaign invitation", "Acceptance successful", nil)
+}
+
+// Declines an invitation addressed to the requestor.
+func (app *application) declineInvitation(c echo.Context) error {
+	invitation, status := app.getOwnInvitation(c)
+	if invitation == nil {
+		return sendJSONResponse(c, status, "Decline campaign invitation", "Decline failed", nil)
+	}
+
+	if err := app.invitations.Respond(invitation.ID, models.RequestDeclined); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrInvitationExpired) {
+			return sendJSONResponse(c, http.StatusConflict, "Decline campaign invitation", "Invitation was already answered", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Decline campaign invitation", "Decline failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Decline campaign invitation", "Decline successful", nil)
+}
+
+// Adds one of the requestor's characters to a campaign using a
+// shareable invite code.
+func (app *application) joinCampaignWithCode(c echo.Context) error {
+	req := struct {
+		Code        string `json:"code"`
+		CharacterID int    `json:"character_id"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Join campaign with code", "Could not process request", nil)
+	}
+
+	if _, err := app.authorizeCharacterOwner(c, req.CharacterID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Join campaign with code", "Character not found", nil)
+	}
+
+	campaignID, err := app.invitations.Redeem(strings.TrimSpace(req.Code), req.CharacterID)
+	if err != nil {
+		log.Error(err)
+		switch {
+		case errors.Is(err, models.ErrInvitationExpired):
+			return sendJSONResponse(c, http.StatusNotFound, "Join campaign with code", "Invite code is invalid or has expired", nil)
+		case errors.Is(err, models.ErrDuplicateBelongsTo):
+			return sendJSONResponse(c, http.StatusConflict, "Join campaign with code", "Character is already part of the campaign", nil)
+		case errors.Is(err, models.ErrCampaignArchived):
+			return sendJSONResponse(c, http.StatusConflict, "Join campaign with code", "Campaign has been archived", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Join campaign with code", "Join failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Join campaign with code", "Join successful",
+		struct {
+			ResourceURI string `json:"resource_uri"`
+		}{
+			"/campaign/" + strconv.Itoa(campaignID),
+		})
+}
+
+// Asks the dungeon master of a campaign to let one of the requestor's
+// characters join.
+func (app *application) createJoinRequest(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign join request", "Could not process request", nil)
+	}
+
+	req := struct {
+		CharacterID int    `json:"character_id"`
+		Message     string `json:"message"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign join request", "Could not process request", nil)
+	}
+
+	if utf8.RuneCountInString(req.Message) > 500 {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign join request", "Message must be at most 500 characters", nil)
+	}
+
+	if _, err := app.authorizeCharacterOwner(c, req.CharacterID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Campaign join request", "Character not found", nil)
+	}
+
+	campaign, err := app.campaigns.Get(campaignID)
+	if err == nil {
+		err = ensureCampaignWritable(campaign)
+	}
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Campaign join request", "Request failed", nil)
+	}
+	if isDungeonMaster(campaign, getUsernameFromToken(c)) {
+		return sendJSONResponse(c, http.StatusBadRequest, "Campaign join request", "The dungeon master cannot request to join their own campaign", nil)
+	}
+
+	id, err := app.joinRequests.Insert(campaignID, req.CharacterID, strings.TrimSpace(req.Message))
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrDuplicateJoinRequest) {
+			return sendJSONResponse(c, http.StatusConflict, "Campaign join request", "A request for this character is already pending", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign join request", "Request failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusCreated, "Campaign join request", "Request successful",
+		struct {
+			ID int `json:"id"`
+		}{
+			id,
+		})
+}
+
+// Lists the requests to join a campaign. An optional `status` query
+// parameter filters the results.
+func (app *application) getCampaignJoinRequests(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign join request retrieval", "Retrieval failed", nil)
+	}
+
+	if _, err := app.authorizeDungeonMaster(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Campaign join request retrieval", "Retrieval failed", nil)
+	}
+
+	status := models.RequestStatusType(c.QueryParam("status"))
+	requests, err := app.joinRequests.GetAllForCampaign(campaignID, status)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign join request retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Campaign join request retrieval", "Retrieval successful",
+		struct {
+			Requests []models.CampaignJoinRequest `json:"requests"`
+		}{
+			*requests,
+		})
+}
+
+// respondToJoinRequest accepts or declines a join request on behalf of
+// the campaign's dungeon master.
+func (app *application) respondToJoinRequest(c echo.Context, accept bool) error {
+	event := "Decline campaign join request"
+	if accept {
+		event = "Accept campaign join request"
+	}
+
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, event, "Could not process request", nil)
+	}
+
+	requestID, err := strconv.Atoi(c.Param("requestID"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, event, "Could not process request", nil)
+	}
+
+	if _, err := app.authorizeDungeonMaster(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), event, "Response failed", nil)
+	}
+
+	joinRequest, err := app.joinRequests.Get(requestID)
+	if err != nil || joinRequest.CampaignID != campaignID {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusNotFound, event, "Response failed", nil)
+	}
+
+	if accept {
+		err = app.joinRequests.Accept(requestID)
+	} else {
+		err = app.joinRequests.Decline(requestID)
+	}
+	if err != nil {
+		log.Error(err)
+		switch {
+		case errors.Is(err, models.ErrInvitationExpired):
+			return sendJSONResponse(c, http.StatusConflict, event, "Request was already answered", nil)
+		case errors.Is(err, models.ErrDuplicateBelongsTo):
+			return sendJSONResponse(c, http.StatusConflict, event, "Character is already part of the campaign", nil)
+		case errors.Is(err, models.ErrCampaignArchived):
+			return sendJSONResponse(c, http.StatusConflict, event, "Campaign has been archived", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, event, "Response failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, event, "Response successful", nil)
+}
+
+func (app *application) acceptJoinRequest(c echo.Context) error {
+	return app.respondToJoinRequest(c, true)
+}
+
+func (app *application) declineJoinRequest(c echo.Context) error {
+	return app.respondToJoinRequest(c, false)
+}
+
+// Removes a character from a campaign. The dungeon master may remove
+// any character, and players may withdraw their own characters.
+func (app *application) removeCampaignCharacter(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Remove campaign character", "Removal failed", nil)
+	}
+
+	characterID, err := strconv.Atoi(c.Param("characterID"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Remove campaign character", "Removal failed", nil)
+	}
+
+	campaign, err := app.campaigns.Get(campaignID)
+	if err == nil {
+		err = ensureCampaignWritable(campaign)
+	}
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Remove campaign character", "Removal failed", nil)
+	}
+
+	if !isDungeonMaster(campaign, getUsernameFromToken(c)) {
+		if _, err := app.authorizeCharacterOwner(c, characterID); err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, authorizationStatus(err), "Remove campaign character", "Removal failed", nil)
+		}
+	}
+
+	if err := app.belongsTo.Delete(characterID, campaignID); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Remove campaign character", "Character is not part of the campaign", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Remove campaign character", "Removal failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Remove campaign character", "Removal successful", nil)
+}
+
+// Moves a campaign to a new lifecycle state. Only the dungeon master may
+// change the state, and only along the transitions listed in
+// models.CampaignStateTransitions.
+func (app *application) changeCampaignState(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign state change", "Could not process request", nil)
+	}
+
+	req := struct {
+		State models.CampaignStateType `json:"state"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign state change", "Could not process request", nil)
+	}
+	if req.State == "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign state change", "Could not process request", nil)
+	}
+
+	campaign, err := app.authorizeDungeonMaster(c, campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Campaign state change", "State change failed", nil)
+	}
+
+	if err := app.campaigns.Transition(campaignID, req.State, getUsernameFromToken(c)); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrInvalidStateTransition) {
+			return sendJSONResponse(c, http.StatusConflict, "Campaign state change",
+				"Campaign cannot move from "+string(campaign.State)+" to "+string(req.State),
+				struct {
+					Allowed []models.CampaignStateType `json:"allowed_states"`
+				}{
+					models.CampaignStateTransitions[campaign.State],
+				})
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign state change", "State change failed", nil)
+	}
+	app.publish(campaignID, events.CampaignUpdated, nil)
+
+	campaign, err = app.campaigns.Get(campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign state change", "State change failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Campaign state change", "State change successful", campaign)
+}
+
+// Retrieves the state transitions of a campaign, oldest first.
+func (app *application) getCampaignStateHistory(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign state history retrieval", "Retrieval failed", nil)
+	}
+
+	campaign, err := app.authorizeParticipant(c, campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Campaign state history retrieval", "Retrieval failed", nil)
+	}
+
+	transitions, err := app.campaigns.GetStateHistory(campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign state history retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Campaign state history retrieval", "Retrieval successful",
+		struct {
+			State       models.CampaignStateType         `json:"state"`
+			Allowed     []models.CampaignStateType       `json:"allowed_states"`
+			Transitions []models.CampaignStateTransition `json:"transitions"`
+		}{
+			campaign.State,
+			models.CampaignStateTransitions[campaign.State],
+			*transitions,
+		})
+}
+
+// Experience award modes. With `each` every character receives the full
+// amount, while with `split` the amount is divided evenly between them
+// and what is left over is handed out one point at a time.
+const (
+	xpAwardEach  = "each"
+	xpAwardSplit = "split"
+)
+
+// Awards experience to all or some of the characters in a campaign in
+// one go, and reports which of them reached a new level. The amount may
+// be taken from a milestone's XP reward.
+func (app *application) awardCampaignXP(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign XP award", "Could not process request", nil)
+	}
+
+	req := struct {
+		Amount       int    `json:"amount"`
+		Mode         string `json:"mode"`
+		CharacterIDs []int  `json:"character_ids"`
+		Reason       string `json:"reason"`
+		MilestoneID  *int   `json:"milestone_id"`
+	}{}
+
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign XP award", "Could not process request", nil)
+	}
+
+	if _, err := app.authorizeWritableCampaign(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Campaign XP award", "Award failed", nil)
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.MilestoneID != nil {
+		milestone, err := app.milestones.Get(*req.MilestoneID)
+		if err != nil || milestone.CampaignID != campaignID {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusNotFound, "Campaign XP award", "Milestone not found", nil)
+		}
+		if req.Amount == 0 {
+			req.Amount = milestone.XPReward
+		}
+		if req.Reason == "" {
+			req.Reason = milestone.Milestone
+		}
+	}
+
+	if req.Amount <= 0 || req.Amount > models.MaxXP {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign XP award", "Amount must be between 1 and 355000", nil)
+	}
+	if utf8.RuneCountInString(req.Reason) > 500 {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign XP award", "Reason must be at most 500 characters", nil)
+	}
+	if req.Mode == "" {
+		req.Mode = xpAwardEach
+	}
+	if req.Mode != xpAwardEach && req.Mode != xpAwardSplit {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign XP award", "Mode must be each or split", nil)
+	}
+
+	if len(req.CharacterIDs) == 0 {
+		characters, err := app.belongsTo.GetAllCampaignCharacters(campaignID)
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Campaign XP award", "Award failed", nil)
+		}
+		for _, character := range *characters {
+			req.CharacterIDs = append(req.CharacterIDs, character.ID)
+		}
+	}
+
+	amounts := make(map[int]int)
+	for _, id := range req.CharacterIDs {
+		amounts[id] = req.Amount
+	}
+	if len(amounts) == 0 {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign XP award", "The campaign has no characters", nil)
+	}
+
+	share := req.Amount
+	message := fmt.Sprintf("Awarded %d XP to each of %d characters", req.Amount, len(amounts))
+	if req.Mode == xpAwardSplit {
+		share = req.Amount / len(amounts)
+		if share == 0 {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign XP award", "Amount is too small to split", nil)
+		}
+
+		// The lowest character IDs receive the remainder, so that the
+		// same award always splits the same way.
+		ids := make([]int, 0, len(amounts))
+		for id := range amounts {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		remainder := req.Amount % len(ids)
+		for i, id := range ids {
+			amounts[id] = share
+			if i < remainder {
+				amounts[id]++
+			}
+		}
+
+		message = fmt.Sprintf("Split %d XP between %d characters (%d each)", req.Amount, len(amounts), share)
+		if remainder > 0 {
+			message = fmt.Sprintf("Split %d XP between %d characters (%d each, %d of them receiving 1 more)",
+				req.Amount, len(amounts), share, remainder)
+		}
+	}
+	if req.Reason != "" {
+		message += ": " + req.Reason
+	}
+
+	results, err := app.campaignLog.AwardXP(campaignID, getUsernameFromToken(c), message, amounts)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrCharacterNotInCampaign) {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign XP award", "Every character must belong to the campaign", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign XP award", "Award failed", nil)
+	}
+	app.publish(campaignID, events.XPAwarded, nil)
+
+	leveledUp := []models.XPAwardResult{}
+	for _, r := range *results {
+		if r.LeveledUp {
+			leveledUp = append(leveledUp, r)
+		}
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Campaign XP award", message,
+		struct {
+			PerCharacter int                    `json:"per_character"`
+			Awards       []models.XPAwardResult `json:"awards"`
+			LeveledUp    []models.XPAwardResult `json:"leveled_up"`
+		}{
+			share,
+			*results,
+			leveledUp,
+		})
+}
+
+// Retrieves the log of a campaign, most recent first.
+func (app *application) getCampaignLog(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Campaign log retrieval", "Retrieval failed", nil)
+	}
+
+	if _, err := app.authorizeParticipant(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Campaign log retrieval", "Retrieval failed", nil)
+	}
+
+	limit, offset := parsePagination(c)
+	entries, err := app.campaignLog.GetAllForCampaign(campaignID, limit, offset)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Campaign log retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Campaign log retrieval", "Retrieval successful",
+		struct {
+			Entries []models.CampaignLogEntry `json:"entries"`
+		}{
+			*entries,
+		})
+}
+
+// Limits on the size of journal entries.
+const (
+	maxJournalTitleLength = 200
+	maxJournalBodyLength  = 50000
+	maxJournalViewers     = 50
+)
+
+type journalEntryRequest struct {
+	Title      string                       `json:"title"`
+	Body       string                       `json:"body"`
+	Visibility models.JournalVisibilityType `json:"visibility"`
+	Viewers    []string                     `json:"viewers"`
+}
+
+// validateJournalEntry checks `req` and converts it into a journal entry
+// for `campaign`. Entries may only be shared with the campaign's
+// participants. A message describing the first problem is returned if
+// the request is invalid.
+func (app *application) validateJournalEntry(req journalEntryRequest, campaign *models.Campaign) (*models.JournalEntry, string, error) {
+	entry := &models.JournalEntry{
+		CampaignID: campaign.ID,
+		Title:      strings.TrimSpace(req.Title),
+		Body:       req.Body,
+		Visibility: req.Visibility,
+	}
+
+	switch {
+	case entry.Title == "":
+		return nil, "Title is required", nil
+	case utf8.RuneCountInString(entry.Title) > maxJournalTitleLength:
+		return nil, "Title must be at most 200 characters", nil
+	case utf8.RuneCountInString(entry.Body) > maxJournalBodyLength:
+		return nil, "Body must be at most 50000 characters", nil
+	}
+
+	if entry.Visibility == "" {
+		entry.Visibility = models.JournalEveryone
+	}
+	if entry.Visibility != models.JournalPlayers {
+		return entry, "", nil
+	}
+
+	seen := make(map[string]bool)
+	for _, username := range req.Viewers {
+		username = strings.TrimSpace(username)
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+
+		if !isDungeonMaster(campaign, username) {
+			isPlayer, err := app.belongsTo.IsPlayerInCampaign(username, campaign.ID)
+			if err != nil {
+				return nil, "", err
+			}
+			if !isPlayer {
+				return nil, "Entries can only be shared with players in the campaign", nil
+			}
+		}
+		entry.Viewers = append(entry.Viewers, username)
+	}
+
+	if len(entry.Viewers) == 0 {
+		return nil, "Entries shared with specific players must list at least one player", nil
+	}
+	if len(entry.Viewers) > maxJournalViewers {
+		return nil, "Entries can be shared with at most 50 players", nil
+	}
+
+	return entry, "", nil
+}
+
+// canReadJournalEntry reports whether `username` may read `entry` in
+// `campaign`.
+func canReadJournalEntry(entry *models.JournalEntry, campaign *models.Campaign, username string) bool {
+	if isDungeonMaster(campaign, username) || entry.Visibility == models.JournalEveryone {
+		return true
+	}
+	if entry.AuthorUsername != nil && *entry.AuthorUsername == username {
+		return true
+	}
+	if entry.Visibility == models.JournalPlayers {
+		for _, viewer := range entry.Viewers {
+			if viewer == username {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// canEditJournalEntry reports whether `username` may edit or delete
+// `entry`, which only its author and the dungeon master may do.
+func canEditJournalEntry(entry *models.JournalEntry, campaign *models.Campaign, username string) bool {
+	return isDungeonMaster(campaign, username) ||
+		(entry.AuthorUsername != nil && *entry.AuthorUsername == username)
+}
+
+// getCampaignJournalEntry parses the campaign and entry IDs of a journal
+// route and retrieves the entry, provided the requestor may read it.
+func (app *application) getCampaignJournalEntry(c echo.Context) (*models.Campaign, *models.JournalEntry, int) {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return nil, nil, http.StatusUnprocessableEntity
+	}
+
+	entryID, err := strconv.Atoi(c.Param("entryID"))
+	if err != nil {
+		log.Error(err)
+		return nil, nil, http.StatusUnprocessableEntity
+	}
+
+	campaign, err := app.authorizeParticipant(c, campaignID)
+	if err != nil {
+		log.Error(err)
+		return nil, nil, authorizationStatus(err)
+	}
+
+	entry, err := app.journal.Get(entryID)
+	if err != nil {
+		log.Error(err)
+		return nil, nil, authorizationStatus(err)
+	}
+
+	if entry.CampaignID != campaignID || !canReadJournalEntry(entry, campaign, getUsernameFromToken(c)) {
+		return nil, nil, http.StatusNotFound
+	}
+
+	return campaign, entry, http.StatusOK
+}
+
+// Creates a journal entry in a campaign. Any participant of the
+// campaign may write entries.
+func (app *application) createJournalEntry(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Journal entry creation", "Could not process request", nil)
+	}
+
+	var req journalEntryRequest
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Journal entry creation", "Could not process request", nil)
+	}
+
+	campaign, err := app.authorizeParticipant(c, campaignID)
+	if err == nil {
+		err = ensureCampaignWritable(campaign)
+	}
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Journal entry creation", "Creation failed", nil)
+	}
+
+	entry, msg, err := app.validateJournalEntry(req, campaign)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Journal entry creation", "Creation failed", nil)
+	}
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Journal entry creation", msg, nil)
+	}
+
+	author := getUsernameFromToken(c)
+	entry.AuthorUsername = &author
+
+	id, err := app.journal.Insert(*entry)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Journal entry creation", "Creation failed", nil)
+	}
+
+	created, err := app.journal.Get(id)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Journal entry creation", "Creation failed", nil)
+	}
+	created.BodyHTML = markdown.Render(created.Body)
+
+	return sendJSONResponse(c, http.StatusCreated, "Journal entry creation", "Creation successful", created)
+}
+
+// Lists the journal entries of a campaign which the requestor may read.
+// The optional `q` query parameter searches the entries' text.
+func (app *application) getJournalEntries(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Journal retrieval", "Retrieval failed", nil)
+	}
+
+	campaign, err := app.authorizeParticipant(c, campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Journal retrieval", "Retrieval failed", nil)
+	}
+
+	username := getUsernameFromToken(c)
+	query := strings.TrimSpace(c.QueryParam("q"))
+	limit, offset := parsePagination(c)
+
+	entries, err := app.journal.GetAllForCampaign(campaignID, username, isDungeonMaster(campaign, username), query, limit, offset)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Journal retrieval", "Retrieval failed", nil)
+	}
+
+	for i := range *entries {
+		(*entries)[i].BodyHTML = markdown.Render((*entries)[i].Body)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Journal retrieval", "Retrieval successful",
+		struct {
+			Entries []models.JournalEntry `json:"entries"`
+		}{
+			*entries,
+		})
+}
+
+// Retrieves a single journal entry.
+func (app *application) getJournalEntry(c echo.Context) error {
+	_, entry, status := app.getCampaignJournalEntry(c)
+	if entry == nil {
+		return sendJSONResponse(c, status, "Journal entry retrieval", "Retrieval failed", nil)
+	}
+
+	entry.BodyHTML = markdown.Render(entry.Body)
+
+	return sendJSONResponse(c, http.StatusOK, "Journal entry retrieval", "Retrieval successful", entry)
+}
+
+// Replaces the contents of a journal entry, keeping the previous version
+// in its revision history.
+func (app *application) updateJournalEntry(c echo.Context) error {
+	var req journalEntryRequest
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Journal entry modification", "Could not process request", nil)
+	}
+
+	campaign, entry, status := app.getCampaignJournalEntry(c)
+	if entry == nil {
+		return sendJSONResponse(c, status, "Journal entry modification", "Modification failed", nil)
+	}
+
+	username := getUsernameFromToken(c)
+	if !canEditJournalEntry(entry, campaign, username) {
+		return sendJSONResponse(c, http.StatusForbidden, "Journal entry modification", "Only the author or the dungeon master may edit this entry", nil)
+	}
+	if err := ensureCampaignWritable(campaign); err != nil {
+		return sendJSONResponse(c, authorizationStatus(err), "Journal entry modification", "Campaign has been archived", nil)
+	}
+
+	updated, msg, err := app.validateJournalEntry(req, campaign)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Journal entry modification", "Modification failed", nil)
+	}
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Journal entry modification", msg, nil)
+	}
+
+	updated.ID = entry.ID
+	if err := app.journal.Update(*updated, username); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Journal entry modification", "Modification failed", nil)
+	}
+
+	entry, err = app.journal.Get(entry.ID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Journal entry modification", "Modification failed", nil)
+	}
+	entry.BodyHTML = markdown.Render(entry.Body)
+
+	return sendJSONResponse(c, http.StatusOK, "Journal entry modification", "Modification successful", entry)
+}
+
+// Deletes a journal entry along with its revision history.
+func (app *application) deleteJournalEntry(c echo.Context) error {
+	campaign, entry, status := app.getCampaignJournalEntry(c)
+	if entry == nil {
+		return sendJSONResponse(c, status, "Journal entry deletion", "Deletion failed", nil)
+	}
+
+	if !canEditJournalEntry(entry, campaign, getUsernameFromToken(c)) {
+		return sendJSONResponse(c, http.StatusForbidden, "Journal entry deletion", "Only the author or the dungeon master may delete this entry", nil)
+	}
+	if err := ensureCampaignWritable(campaign); err != nil {
+		return sendJSONResponse(c, authorizationStatus(err), "Journal entry deletion", "Campaign has been archived", nil)
+	}
+
+	if err := app.journal.Delete(entry.ID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Journal entry deletion", "Deletion failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Journal entry deletion", "Deletion successful", nil)
+}
+
+// Retrieves the revision history of a journal entry. Earlier revisions
+// may have been visible to fewer players, so only the author and the
+// dungeon master may see them.
+func (app *application) getJournalEntryRevisions(c echo.Context) error {
+	campaign, entry, status := app.getCampaignJournalEntry(c)
+	if entry == nil {
+		return sendJSONResponse(c, status, "Journal revision retrieval", "Retrieval failed", nil)
+	}
+
+	if !canEditJournalEntry(entry, campaign, getUsernameFromToken(c)) {
+		return sendJSONResponse(c, http.StatusForbidden, "Journal revision retrieval", "Only the author or the dungeon master may view revisions", nil)
+	}
+
+	revisions, err := app.journal.GetRevisions(entry.ID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Journal revision retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Journal revision retrieval", "Retrieval successful",
+		struct {
+			Revisions []models.JournalEntryRevision `json:"revisions"`
+		}{
+			*revisions,
+		})
+}
+
+// Limits on the contents of an NPC's stat block.
+const (
+	maxNPCNameLength        = 100
+	maxNPCDescriptionLength = 5000
+	maxNPCActions           = 30
+	maxNPCTags              = 20
+	maxNPCTagLength         = 30
+)
+
+type npcRequest struct {
+	Name            string             `json:"name"`
+	Kind            models.NPCKindType `json:"kind"`
+	Size            string             `json:"size"`
+	CreatureType    string             `json:"creature_type"`
+	Alignment       string             `json:"alignment"`
+	Description     string             `json:"description"`
+	ArmorClass      int                `json:"armor_class"`
+	HPMax           int                `json:"hp_max"`
+	HitDice         string             `json:"hit_dice"`
+	Speed           int                `json:"speed"`
+	ChallengeRating string             `json:"challenge_rating"`
+	Strength        int                `json:"strength"`
+	Dexterity       int                `json:"dexterity"`
+	Constitution    int                `json:"constitution"`
+	Intelligence    int                `json:"intelligence"`
+	Wisdom          int                `json:"wisdom"`
+	Charisma        int                `json:"charisma"`
+	DMNotes         string             `json:"dm_notes"`
+	Hidden          bool               `json:"hidden"`
+	Actions         []models.NPCAction `json:"actions"`
+	Tags            []string           `json:"tags"`
+}
+
+// validateNPC checks `req` and converts it into an NPC of the campaign
+// identified by `campaignID`. A message describing the first problem is
+// returned if the request is invalid.
+func validateNPC(req npcRequest, campaignID int) (*models.CampaignNPC, string) {
+	npc := &models.CampaignNPC{
+		CampaignID:      campaignID,
+		Name:            strings.TrimSpace(req.Name),
+		Kind:            req.Kind,
+		Size:            strings.TrimSpace(req.Size),
+		CreatureType:    strings.TrimSpace(req.CreatureType),
+		Alignment:       strings.TrimSpace(req.Alignment),
+		Description:     req.Description,
+		ArmorClass:      req.ArmorClass,
+		HPMax:           req.HPMax,
+		HitDice:         strings.TrimSpace(req.HitDice),
+		Speed:           req.Speed,
+		ChallengeRating: strings.TrimSpace(req.ChallengeRating),
+		Strength:        req.Strength,
+		Dexterity:       req.Dexterity,
+		Constitution:    req.Constitution,
+		Intelligence:    req.Intelligence,
+		Wisdom:          req.Wisdom,
+		Charisma:        req.Charisma,
+		DMNotes:         req.DMNotes,
+		Hidden:          req.Hidden,
+		Actions:         []models.NPCAction{},
+		Tags:            []string{},
+	}
+
+	if npc.Kind == "" {
+		npc.Kind = models.NPCKindNPC
+	}
+	if npc.Size == "" {
+		npc.Size = "Medium"
+	}
+	if npc.ChallengeRating == "" {
+		npc.ChallengeRating = "0"
+	}
+
+	switch {
+	case npc.Name == "":
+		return nil, "Name is required"
+	case utf8.RuneCountInString(npc.Name) > maxNPCNameLength:
+		return nil, "Name must be at most 100 characters"
+	case utf8.RuneCountInString(npc.Size) > 20:
+		return nil, "Size must be at most 20 characters"
+	case utf8.RuneCountInString(npc.CreatureType) > 50:
+		return nil, "Creature type must be at most 50 characters"
+	case utf8.RuneCountInString(npc.Alignment) > 50:
+		return nil, "Alignment must be at most 50 characters"
+	case utf8.RuneCountInString(npc.Description) > maxNPCDescriptionLength:
+		return nil, "Description must be at most 5000 characters"
+	case utf8.RuneCountInString(npc.HitDice) > 20:
+		return nil, "Hit dice must be at most 20 characters"
+	case npc.ArmorClass < 1 || npc.ArmorClass > 30:
+		return nil, "Armor class must be between 1 and 30"
+	case npc.HPMax < 1 || npc.HPMax > 1000:
+		return nil, "Maximum hit points must be between 1 and 1000"
+	case npc.Speed < 0 || npc.Speed > 1280:
+		return nil, "Speed must be between 0 and 1280 feet"
+	}
+
+	if _, ok := models.ChallengeRatingXP[npc.ChallengeRating]; !ok {
+		return nil, "Challenge rating must be 0, 1/8, 1/4, 1/2 or a whole number up to 30"
+	}
+
+	for _, score := range []int{npc.Strength, npc.Dexterity, npc.Constitution, npc.Intelligence, npc.Wisdom, npc.Charisma} {
+		if score < 1 || score > 30 {
+			return nil, "Ability scores must be between 1 and 30"
+		}
+	}
+
+	if len(req.Actions) > maxNPCActions {
+		return nil, "An NPC can have at most 30 actions"
+	}
+	for _, action := range req.Actions {
+		action.Name = strings.TrimSpace(action.Name)
+		if action.Name == "" || utf8.RuneCountInString(action.Name) > maxNPCNameLength {
+			return nil, "Action names must be between 1 and 100 characters"
+		}
+		if utf8.RuneCountInString(action.Description) > maxNPCDescriptionLength {
+			return nil, "Action descriptions must be at most 5000 characters"
+		}
+		npc.Actions = append(npc.Actions, action)
+	}
+
+	seen := make(map[string]bool)
+	for _, tag := range req.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxNPCTagLength {
+			return nil, "Tags must be at most 30 characters"
+		}
+		seen[tag] = true
+		npc.Tags = append(npc.Tags, tag)
+	}
+	if len(npc.Tags) > maxNPCTags {
+		return nil, "An NPC can have at most 20 tags"
+	}
+
+	return npc, ""
+}
+
+// redactNPC removes the fields of `npc` which only the dungeon master of
+// `campaign` may see when `username` is someone else.
+func redactNPC(npc *models.CampaignNPC, campaign *models.Campaign, username string) {
+	if !isDungeonMaster(campaign, username) {
+		npc.DMNotes = ""
+	}
+}
+
+// getCampaignNPCParams parses the campaign and NPC IDs of an NPC route
+// and retrieves the NPC, provided the requestor may see it. Hidden NPCs
+// are only visible to the dungeon master.
+func (app *application) getCampaignNPCParams(c echo.Context) (*models.Campaign, *models.CampaignNPC, int) {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return nil, nil, http.StatusUnprocessableEntity
+	}
+
+	npcID, err := strconv.Atoi(c.Param("npcID"))
+	if err != nil {
+		log.Error(err)
+		return nil, nil, http.StatusUnprocessableEntity
+	}
+
+	campaign, err := app.authorizeParticipant(c, campaignID)
+	if err != nil {
+		log.Error(err)
+		return nil, nil, authorizationStatus(err)
+	}
+
+	npc, err := app.npcs.Get(npcID)
+	if err != nil {
+		log.Error(err)
+		return nil, nil, authorizationStatus(err)
+	}
+
+	username := getUsernameFromToken(c)
+	if npc.CampaignID != campaignID || (npc.Hidden && !isDungeonMaster(campaign, username)) {
+		return nil, nil, http.StatusNotFound
+	}
+	redactNPC(npc, campaign, username)
+
+	return campaign, npc, http.StatusOK
+}
+
+// insertNPC stores `npc` and retrieves it again as it was saved.
+func (app *application) insertNPC(npc models.CampaignNPC) (*models.CampaignNPC, error) {
+	id, err := app.npcs.Insert(npc)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.npcs.Get(id)
+}
+
+// Adds an NPC or monster to a campaign's roster. Only the dungeon master
+// may do so.
+func (app *application) createCampaignNPC(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "NPC creation", "Could not process request", nil)
+	}
+
+	var req npcRequest
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "NPC creation", "Could not process request", nil)
+	}
+
+	if _, err := app.authorizeWritableCampaign(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "NPC creation", "Creation failed", nil)
+	}
+
+	npc, msg := validateNPC(req, campaignID)
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "NPC creation", msg, nil)
+	}
+
+	created, err := app.insertNPC(*npc)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "NPC creation", "Creation failed", nil)
+	}
+	app.publishNPCEvent(campaignID, events.NPCAdded, created.ID, created.Hidden)
+
+	return sendJSONResponse(c, http.StatusCreated, "NPC creation", "Creation successful", created)
+}
+
+// Lists a campaign's NPCs. Players do not see hidden NPCs or the dungeon
+// master's notes. The optional `kind` and `tag` query parameters filter
+// the roster.
+func (app *application) getCampaignNPCs(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "NPC retrieval", "Retrieval failed", nil)
+	}
+
+	kind := models.NPCKindType(c.QueryParam("kind"))
+	switch kind {
+	case "", models.NPCKindNPC, models.NPCKindMonster:
+	default:
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "NPC retrieval", "Kind must be npc or monster", nil)
+	}
+	tag := strings.ToLower(strings.TrimSpace(c.QueryParam("tag")))
+
+	campaign, err := app.authorizeParticipant(c, campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "NPC retrieval", "Retrieval failed", nil)
+	}
+
+	username := getUsernameFromToken(c)
+	npcs, err := app.npcs.GetAllForCampaign(campaignID, isDungeonMaster(campaign, username), kind, tag)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "NPC retrieval", "Retrieval failed", nil)
+	}
+
+	for i := range *npcs {
+		redactNPC(&(*npcs)[i], campaign, username)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "NPC retrieval", "Retrieval successful",
+		struct {
+			NPCs []models.CampaignNPC `json:"npcs"`
+		}{
+			*npcs,
+		})
+}
+
+// Retrieves a single NPC of a campaign.
+func (app *application) getCampaignNPC(c echo.Context) error {
+	_, npc, status := app.getCampaignNPCParams(c)
+	if npc == nil {
+		return sendJSONResponse(c, status, "NPC retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "NPC retrieval", "Retrieval successful", npc)
+}
+
+// Replaces the stat block, notes and tags of an NPC. Only the dungeon
+// master may do so.
+func (app *application) updateCampaignNPC(c echo.Context) error {
+	var req npcRequest
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "NPC modification", "Could not process request", nil)
+	}
+
+	campaign, npc, status := app.getCampaignNPCParams(c)
+	if npc == nil {
+		return sendJSONResponse(c, status, "NPC modification", "Modification failed", nil)
+	}
+
+	if _, err := app.authorizeWritableCampaign(c, campaign.ID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "NPC modification", "Modification failed", nil)
+	}
+
+	updated, msg := validateNPC(req, campaign.ID)
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "NPC modification", msg, nil)
+	}
+
+	updated.ID = npc.ID
+	if err := app.npcs.Update(*updated); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "NPC modification", "Modification failed", nil)
+	}
+	// Players are told when an NPC is revealed or hidden from them.
+	app.publishNPCEvent(campaign.ID, events.NPCUpdated, npc.ID, npc.Hidden && updated.Hidden)
+
+	npc, err := app.npcs.Get(npc.ID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "NPC modification", "Modification failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "NPC modification", "Modification successful", npc)
+}
+
+// Removes an NPC from a campaign's roster. Only the dungeon master may do
+// so.
+func (app *application) deleteCampaignNPC(c echo.Context) error {
+	campaign, npc, status := app.getCampaignNPCParams(c)
+	if npc == nil {
+		return sendJSONResponse(c, status, "NPC deletion", "Deletion failed", nil)
+	}
+
+	if _, err := app.authorizeWritableCampaign(c, campaign.ID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "NPC deletion", "Deletion failed", nil)
+	}
+
+	if err := app.npcs.Delete(npc.ID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "NPC deletion", "Deletion failed", nil)
+	}
+	app.publishNPCEvent(campaign.ID, events.NPCDeleted, npc.ID, npc.Hidden)
+
+	return sendJSONResponse(c, http.StatusOK, "NPC deletion", "Deletion successful", nil)
+}
+
+// Copies an NPC within its campaign, for instance to field several of the
+// same monster. The copy may be given a new name.
+func (app *application) duplicateCampaignNPC(c echo.Context) error {
+	req := struct {
+		Name string `json:"name"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "NPC duplication", "Could not process request", nil)
+	}
+
+	campaign, npc, status := app.getCampaignNPCParams(c)
+	if npc == nil {
+		return sendJSONResponse(c, status, "NPC duplication", "Duplication failed", nil)
+	}
+
+	if _, err := app.authorizeWritableCampaign(c, campaign.ID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "NPC duplication", "Duplication failed", nil)
+	}
+
+	if name := strings.TrimSpace(req.Name); name != "" {
+		if utf8.RuneCountInString(name) > maxNPCNameLength {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "NPC duplication", "Name must be at most 100 characters", nil)
+		}
+		npc.Name = name
+	}
+
+	created, err := app.insertNPC(*npc)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "NPC duplication", "Duplication failed", nil)
+	}
+	app.publishNPCEvent(campaign.ID, events.NPCAdded, created.ID, created.Hidden)
+
+	return sendJSONResponse(c, http.StatusCreated, "NPC duplication", "Duplication successful", created)
+}
+
+// Adds a copy of a compendium monster to a campaign's roster. The copy
+// may be renamed, hidden from players and edited afterwards like any
+// other NPC.
+func (app *application) createNPCFromCompendium(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "NPC creation", "Could not process request", nil)
+	}
+
+	req := struct {
+		Slug   string `json:"slug"`
+		Name   string `json:"name"`
+		Hidden bool   `json:"hidden"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "NPC creation", "Could not process request", nil)
+	}
+
+	if _, err := app.authorizeWritableCampaign(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "NPC creation", "Creation failed", nil)
+	}
+
+	monster, ok := compendium.Get(strings.TrimSpace(req.Slug))
+	if !ok {
+		return sendJSONResponse(c, http.StatusNotFound, "NPC creation", "No such monster in the compendium", nil)
+	}
+
+	npc := monster.NPC(campaignID)
+	npc.Hidden = req.Hidden
+	if name := strings.TrimSpace(req.Name); name != "" {
+		if utf8.RuneCountInString(name) > maxNPCNameLength {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "NPC creation", "Name must be at most 100 characters", nil)
+		}
+		npc.Name = name
+	}
+
+	created, err := app.insertNPC(npc)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "NPC creation", "Creation failed", nil)
+	}
+	app.publishNPCEvent(campaignID, events.NPCAdded, created.ID, created.Hidden)
+
+	return sendJSONResponse(c, http.StatusCreated, "NPC creation", "Creation successful", created)
+}
+
+// Lists the monsters of the bundled compendium. The optional `q` query
+// parameter searches their names.
+func (app *application) getCompendiumMonsters(c echo.Context) error {
+	return sendJSONResponse(c, http.StatusOK, "Compendium retrieval", "Retrieval successful",
+		struct {
+			Monsters []compendium.Monster `json:"monsters"`
+		}{
+			compendium.Search(c.QueryParam("q")),
+		})
+}
+
+// Retrieves a single monster of the bundled compendium.
+func (app *application) getCompendiumMonster(c echo.Context) error {
+	monster, ok := compendium.Get(c.Param("slug"))
+	if !ok {
+		return sendJSONResponse(c, http.StatusNotFound, "Compendium retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Compendium retrieval", "Retrieval successful", monster)
+}
+
+// Limits on the size of encounters.
+const (
+	maxEncounterNameLength  = 100
+	maxEncounterNotesLength = 5000
+	maxEncounterMonsters    = 50
+	maxEncounterQuantity    = 100
+)
+
+type encounterMonsterRequest struct {
+	NPCID           *int   `json:"npc_id"`
+	CompendiumSlug  string `json:"compendium_slug"`
+	Name            string `json:"name"`
+	ChallengeRating string `json:"challenge_rating"`
+	Quantity        int    `json:"quantity"`
+}
+
+type encounterRequest struct {
+	Name     string                    `json:"name"`
+	Notes    string                    `json:"notes"`
+	Monsters []encounterMonsterRequest `json:"monsters"`
+}
+
+// resolveEncounterMonsters converts the monsters of an encounter request
+// for the campaign identified by `campaignID`. Each monster is either an
+// NPC of the campaign, a compendium monster or a name with a challenge
+// rating. A message describing the first problem is returned if the
+// request is invalid.
+func (app *application) resolveEncounterMonsters(campaignID int, reqs []encounterMonsterRequest) ([]models.EncounterMonster, string, error) {
+	if len(reqs) > maxEncounterMonsters {
+		return nil, "An encounter can have at most 50 kinds of monsters", nil
+	}
+
+	monsters := []models.EncounterMonster{}
+	for _, req := range reqs {
+		monster := models.EncounterMonster{
+			Name:            strings.TrimSpace(req.Name),
+			ChallengeRating: strings.TrimSpace(req.ChallengeRating),
+			Quantity:        req.Quantity,
+		}
+		if monster.Quantity == 0 {
+			monster.Quantity = 1
+		}
+		if monster.Quantity < 1 || monster.Quantity > maxEncounterQuantity {
+			return nil, "Quantity must be between 1 and 100", nil
+		}
+
+		switch slug := strings.TrimSpace(req.CompendiumSlug); {
+		case req.NPCID != nil:
+			npc, err := app.npcs.Get(*req.NPCID)
+			if errors.Is(err, models.ErrNoRecord) || (err == nil && npc.CampaignID != campaignID) {
+				return nil, "Every NPC must belong to the campaign", nil
+			}
+			if err != nil {
+				return nil, "", err
+			}
+			monster.NPCID = &npc.ID
+			monster.Name = npc.Name
+			monster.ChallengeRating = npc.ChallengeRating
+
+		case slug != "":
+			entry, ok := compendium.Get(slug)
+			if !ok {
+				return nil, "No such monster in the compendium: " + slug, nil
+			}
+			monster.CompendiumSlug = &entry.Slug
+			if monster.Name == "" {
+				monster.Name = entry.Name
+			}
+			monster.ChallengeRating = entry.ChallengeRating
+
+		default:
+			if _, ok := models.ChallengeRatingXP[monster.ChallengeRating]; !ok {
+				return nil, "Challenge rating must be 0, 1/8, 1/4, 1/2 or a whole number up to 30", nil
+			}
+			if monster.Name == "" {
+				monster.Name = "CR " + monster.ChallengeRating + " monster"
+			}
+		}
+
+		if utf8.RuneCountInString(monster.Name) > maxNPCNameLength {
+			return nil, "Monster names must be at most 100 characters", nil
+		}
+		monsters = append(monsters, monster)
+	}
+
+	return monsters, "", nil
+}
+
+// getPartyLevels returns the level of every character in the campaign
+// identified by `campaignID`.
+func (app *application) getPartyLevels(campaignID int) ([]int, error) {
+	characters, err := app.belongsTo.GetAllCampaignCharacters(campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := []int{}
+	for _, character := range *characters {
+		levels = append(levels, character.Level)
+	}
+	return levels, nil
+}
+
+// rateEncounters rates each of `encounters` against the current party of
+// the campaign identified by `campaignID`. Encounters are left unrated if
+// the campaign has no characters.
+func (app *application) rateEncounters(campaignID int, encounters []models.Encounter) error {
+	levels, err := app.getPartyLevels(campaignID)
+	if err != nil || len(levels) == 0 {
+		return err
+	}
+
+	for i := range encounters {
+		rating := models.RateEncounter(levels, encounters[i].Monsters)
+		encounters[i].Rating = &rating
+	}
+	return nil
+}
+
+// validateEncounter checks `req` and converts it into an encounter of the
+// campaign identified by `campaignID`. A message describing the first
+// problem is returned if the request is invalid.
+func (app *application) validateEncounter(req encounterRequest, campaignID int) (*models.Encounter, string, error) {
+	encounter := &models.Encounter{
+		CampaignID: campaignID,
+		Name:       strings.TrimSpace(req.Name),
+		Notes:      req.Notes,
+	}
+
+	switch {
+	case encounter.Name == "":
+		return nil, "Name is required", nil
+	case utf8.RuneCountInString(encounter.Name) > maxEncounterNameLength:
+		return nil, "Name must be at most 100 characters", nil
+	case utf8.RuneCountInString(encounter.Notes) > maxEncounterNotesLength:
+		return nil, "Notes must be at most 5000 characters", nil
+	}
+
+	monsters, msg, err := app.resolveEncounterMonsters(campaignID, req.Monsters)
+	if err != nil || msg != "" {
+		return nil, msg, err
+	}
+	encounter.Monsters = monsters
+
+	return encounter, "", nil
+}
+
+// getCampaignEncounterParams parses the campaign and encounter IDs of an
+// encounter route and retrieves the encounter, provided the requestor is
+// the campaign's dungeon master.
+func (app *application) getCampaignEncounterParams(c echo.Context) (*models.Campaign, *models.Encounter, int) {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return nil, nil, http.StatusUnprocessableEntity
+	}
+
+	encounterID, err := strconv.Atoi(c.Param("encounterID"))
+	if err != nil {
+		log.Error(err)
+		return nil, nil, http.StatusUnprocessableEntity
+	}
+
+	campaign, err := app.authorizeDungeonMaster(c, campaignID)
+	if err != nil {
+		log.Error(err)
+		return nil, nil, authorizationStatus(err)
+	}
+
+	encounter, err := app.encounters.Get(encounterID)
+	if err != nil {
+		log.Error(err)
+		return nil, nil, authorizationStatus(err)
+	}
+	if encounter.CampaignID != campaignID {
+		return nil, nil, http.StatusNotFound
+	}
+
+	return campaign, encounter, http.StatusOK
+}
+
+// Rates a group of monsters against the campaign's party without saving
+// it. Only the dungeon master may build encounters.
+func (app *application) evaluateEncounter(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Encounter evaluation", "Could not process request", nil)
+	}
+
+	req := struct {
+		Monsters []encounterMonsterRequest `json:"monsters"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Encounter evaluation", "Could not process request", nil)
+	}
+
+	if _, err := app.authorizeDungeonMaster(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Encounter evaluation", "Evaluation failed", nil)
+	}
+
+	monsters, msg, err := app.resolveEncounterMonsters(campaignID, req.Monsters)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Encounter evaluation", "Evaluation failed", nil)
+	}
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Encounter evaluation", msg, nil)
+	}
+
+	levels, err := app.getPartyLevels(campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Encounter evaluation", "Evaluation failed", nil)
+	}
+	if len(levels) == 0 {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Encounter evaluation", "The campaign has no characters", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Encounter evaluation", "Evaluation successful",
+		struct {
+			Monsters []models.EncounterMonster `json:"monsters"`
+			Rating   models.EncounterRating    `json:"rating"`
+		}{
+			monsters,
+			models.RateEncounter(levels, monsters),
+		})
+}
+
+// Saves an encounter on a campaign for later use.
+func (app *application) createEncounter(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Encounter creation", "Could not process request", nil)
+	}
+
+	var req encounterRequest
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Encounter creation", "Could not process request", nil)
+	}
+
+	if _, err := app.authorizeWritableCampaign(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Encounter creation", "Creation failed", nil)
+	}
+
+	encounter, msg, err := app.validateEncounter(req, campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Encounter creation", "Creation failed", nil)
+	}
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Encounter creation", msg, nil)
+	}
+
+	creator := getUsernameFromToken(c)
+	encounter.CreatedBy = &creator
+
+	id, err := app.encounters.Insert(*encounter)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Encounter creation", "Creation failed", nil)
+	}
+
+	created, err := app.encounters.Get(id)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Encounter creation", "Creation failed", nil)
+	}
+
+	encounters := []models.Encounter{*created}
+	if err := app.rateEncounters(campaignID, encounters); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Encounter creation", "Creation failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusCreated, "Encounter creation", "Creation successful", encounters[0])
+}
+
+// Lists the encounters saved on a campaign, each rated against the
+// current party.
+func (app *application) getCampaignEncounters(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Encounter retrieval", "Retrieval failed", nil)
+	}
+
+	if _, err := app.authorizeDungeonMaster(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Encounter retrieval", "Retrieval failed", nil)
+	}
+
+	encounters, err := app.encounters.GetAllForCampaign(campaignID)
+	if err == nil {
+		err = app.rateEncounters(campaignID, *encounters)
+	}
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Encounter retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Encounter retrieval", "Retrieval successful",
+		struct {
+			Encounters []models.Encounter `json:"encounters"`
+		}{
+			*encounters,
+		})
+}
+
+// Retrieves a single encounter, rated against the current party.
+func (app *application) getCampaignEncounter(c echo.Context) error {
+	campaign, encounter, status := app.getCampaignEncounterParams(c)
+	if encounter == nil {
+		return sendJSONResponse(c, status, "Encounter retrieval", "Retrieval failed", nil)
+	}
+
+	encounters := []models.Encounter{*encounter}
+	if err := app.rateEncounters(campaign.ID, encounters); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Encounter retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Encounter retrieval", "Retrieval successful", encounters[0])
+}
+
+// Replaces the name, notes and monsters of a saved encounter.
+func (app *application) updateCampaignEncounter(c echo.Context) error {
+	var req encounterRequest
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Encounter modification", "Could not process request", nil)
+	}
+
+	campaign, encounter, status := app.getCampaignEncounterParams(c)
+	if encounter == nil {
+		return sendJSONResponse(c, status, "Encounter modification", "Modification failed", nil)
+	}
+	if err := ensureCampaignWritable(campaign); err != nil {
+		return sendJSONResponse(c, authorizationStatus(err), "Encounter modification", "Campaign has been archived", nil)
+	}
+
+	updated, msg, err := app.validateEncounter(req, campaign.ID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Encounter modification", "Modification failed", nil)
+	}
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Encounter modification", msg, nil)
+	}
+
+	updated.ID = encounter.ID
+	if err := app.encounters.Update(*updated); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Encounter modification", "Modification failed", nil)
+	}
+
+	encounter, err = app.encounters.Get(encounter.ID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Encounter modification", "Modification failed", nil)
+	}
+
+	encounters := []models.Encounter{*encounter}
+	if err := app.rateEncounters(campaign.ID, encounters); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Encounter modification", "Modification failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Encounter modification", "Modification successful", encounters[0])
+}
+
+// Deletes a saved encounter.
+func (app *application) deleteCampaignEncounter(c echo.Context) error {
+	campaign, encounter, status := app.getCampaignEncounterParams(c)
+	if encounter == nil {
+		return sendJSONResponse(c, status, "Encounter deletion", "Deletion failed", nil)
+	}
+	if err := ensureCampaignWritable(campaign); err != nil {
+		return sendJSONResponse(c, authorizationStatus(err), "Encounter deletion", "Campaign has been archived", nil)
+	}
+
+	if err := app.encounters.Delete(encounter.ID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Encounter deletion", "Deletion failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Encounter deletion", "Deletion successful", nil)
+}
+
+// Ways of rolling initiative when a combat starts.
+const (
+	rollInitiativeNone = "none"
+	rollInitiativeNPCs = "npcs"
+	rollInitiativeAll  = "all"
+)
+
+// maxCombatants limits the size of a single combat.
+const maxCombatants = 100
+
+type combatantRequest struct {
+	CharacterID    *int   `json:"character_id"`
+	NPCID          *int   `json:"npc_id"`
+	CompendiumSlug string `json:"compendium_slug"`
+	Quantity       int    `json:"quantity"`
+	Initiative     *int   `json:"initiative"`
+}
+
+// rollInitiative rolls a d20 and adds `bonus`.
+func rollInitiative(bonus int) (int, error) {
+	roll, err := dice.D20()
+	if err != nil {
+		return 0, err
+	}
+	return roll + bonus, nil
+}
+
+// buildCombatants converts combatant requests for the campaign identified
+// by `campaignID` into combatants. Characters must belong to the
+// campaign, and each NPC or compendium monster is added `Quantity`
+// times. A message describing the first problem is returned if a request
+// is invalid.
+func (app *application) buildCombatants(campaignID int, reqs []combatantRequest) ([]models.Combatant, string, error) {
+	characters, err := app.belongsTo.GetAllCampaignCharacters(campaignID)
+	if err != nil {
+		return nil, "", err
+	}
+	partyByID := make(map[int]models.Character)
+	for _, character := range *characters {
+		partyByID[character.ID] = character
+	}
+
+	combatants := []models.Combatant{}
+	for _, req := range reqs {
+		quantity := req.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+		if quantity < 1 || quantity > maxEncounterQuantity {
+			return nil, "Quantity must be between 1 and 100", nil
+		}
+
+		var combatant models.Combatant
+		switch slug := strings.TrimSpace(req.CompendiumSlug); {
+		case req.CharacterID != nil:
+			character, ok := partyByID[*req.CharacterID]
+			if !ok {
+				return nil, "Every character must belong to the campaign", nil
+			}
+			quantity = 1
+			combatant = models.Combatant{
+				CharacterID:     &character.ID,
+				Name:            character.Name,
+				InitiativeBonus: models.AbilityModifier(character.Dexterity),
+				// Characters have no armor class of their own, so unarmored
+				// armor class is used until the dungeon master corrects it.
+				ArmorClass: 10 + models.AbilityModifier(character.Dexterity),
+				HPMax:      character.HPMax,
+				HPCurrent:  character.HPCurrent,
+			}
+
+		case req.NPCID != nil:
+			npc, err := app.npcs.Get(*req.NPCID)
+			if errors.Is(err, models.ErrNoRecord) || (err == nil && npc.CampaignID != campaignID) {
+				return nil, "Every NPC must belong to the campaign", nil
+			}
+			if err != nil {
+				return nil, "", err
+			}
+			combatant = models.Combatant{
+				NPCID:           &npc.ID,
+				Name:            npc.Name,
+				InitiativeBonus: models.AbilityModifier(npc.Dexterity),
+				ArmorClass:      npc.ArmorClass,
+				HPMax:           npc.HPMax,
+				HPCurrent:       npc.HPMax,
+			}
+
+		case slug != "":
+			monster, ok := compendium.Get(slug)
+			if !ok {
+				return nil, "No such monster in the compendium: " + slug, nil
+			}
+			combatant = models.Combatant{
+				Name:            monster.Name,
+				InitiativeBonus: models.AbilityModifier(monster.Dexterity),
+				ArmorClass:      monster.ArmorClass,
+				HPMax:           monster.HPMax,
+				HPCurrent:       monster.HPMax,
+			}
+
+		default:
+			return nil, "Every combatant must be a character, an NPC or a compendium monster", nil
+		}
+
+		combatant.Initiative = req.Initiative
+		for i := 1; i <= quantity; i++ {
+			clone := combatant
+			if quantity > 1 {
+				clone.Name = fmt.Sprintf("%s %d", combatant.Name, i)
+			}
+			combatants = append(combatants, clone)
+		}
+	}
+
+	if len(combatants) > maxCombatants {
+		return nil, "A combat can have at most 100 combatants", nil
+	}
+
+	return combatants, "", nil
+}
+
+// canControlCombatant reports whether `username` may act for `combatant`
+// in `campaign`: the dungeon master controls everyone, and players
+// control their own characters.
+func canControlCombatant(combatant *models.Combatant, campaign *models.Campaign, username string) bool {
+	return isDungeonMaster(campaign, username) ||
+		(combatant.PlayerUsername != nil && *combatant.PlayerUsername == username)
+}
+
+// findCombatant returns the combatant of `combat` identified by `id`, or
+// nil if there is none.
+func findCombatant(combat *models.Combat, id int) *models.Combatant {
+	for i := range combat.Combatants {
+		if combat.Combatants[i].ID == id {
+			return &combat.Combatants[i]
+		}
+	}
+	return nil
+}
+
+// prepareCombat fills in the derived fields of `combat` and, unless
+// `username` is the dungeon master of `campaign`, hides the statistics
+// of every combatant which is not a player's character.
+func prepareCombat(combat *models.Combat, campaign *models.Campaign, username string) {
+	isDM := isDungeonMaster(campaign, username)
+
+	for i := range combat.Combatants {
+		combatant := &combat.Combatants[i]
+		combatant.Bloodied = combatant.HPCurrent*2 <= combatant.HPMax
+		combatant.Effects = models.CalculateConditionEffects(combatant.Conditions)
+
+		if !isDM && combatant.CharacterID == nil {
+			combatant.InitiativeBonus = 0
+			combatant.ArmorClass = 0
+			combatant.HPMax = 0
+			combatant.HPCurrent = 0
+			combatant.TempHP = 0
+			combatant.StatsHidden = true
+		}
+	}
+}
+
+// getCampaignCombat parses the campaign ID of a combat route and
+// retrieves the campaign's combat in progress, provided the requestor
+// takes part in the campaign. The campaign must not be archived if
+// `writable` is true.
+func (app *application) getCampaignCombat(c echo.Context, writable bool) (*models.Campaign, *models.Combat, int) {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return nil, nil, http.StatusUnprocessableEntity
+	}
+
+	campaign, err := app.authorizeParticipant(c, campaignID)
+	if err == nil && writable {
+		err = ensureCampaignWritable(campaign)
+	}
+	if err != nil {
+		log.Error(err)
+		return nil, nil, authorizationStatus(err)
+	}
+
+	combat, err := app.combats.GetActive(campaignID)
+	if err != nil {
+		log.Error(err)
+		return nil, nil, authorizationStatus(err)
+	}
+
+	return campaign, combat, http.StatusOK
+}
+
+// getCombatantParams retrieves the campaign's combat in progress and the
+// combatant named by the `combatantID` route parameter, provided the
+// requestor controls it.
+func (app *application) getCombatantParams(c echo.Context) (*models.Campaign, *models.Combat, *models.Combatant, int) {
+	combatantID, err := strconv.Atoi(c.Param("combatantID"))
+	if err != nil {
+		log.Error(err)
+		return nil, nil, nil, http.StatusUnprocessableEntity
+	}
+
+	campaign, combat, status := app.getCampaignCombat(c, true)
+	if combat == nil {
+		return nil, nil, nil, status
+	}
+
+	combatant := findCombatant(combat, combatantID)
+	if combatant == nil {
+		return nil, nil, nil, http.StatusNotFound
+	}
+	if !canControlCombatant(combatant, campaign, getUsernameFromToken(c)) {
+		return nil, nil, nil, http.StatusForbidden
+	}
+
+	return campaign, combat, combatant, http.StatusOK
+}
+
+// sendCombat publishes a change of type `eventType` to the campaign's
+// combat in progress and responds with the combat as seen by the
+// requestor.
+func (app *application) sendCombat(c echo.Context, status int, event, msg string, campaign *models.Campaign, eventType string) error {
+	combat, err := app.combats.GetActive(campaign.ID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, event, "Retrieval failed", nil)
+	}
+
+	app.publishCombat(eventType, combat)
+
+	prepareCombat(combat, campaign, getUsernameFromToken(c))
+	return sendJSONResponse(c, status, event, msg, combat)
+}
+
+// Starts a combat in a campaign with the given characters and monsters,
+// or with every character and the monsters of a saved encounter. Only
+// the dungeon master may start a combat, and a campaign can only have
+// one combat in progress.
+func (app *application) startCombat(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combat start", "Could not process request", nil)
+	}
+
+	req := struct {
+		Name           string             `json:"name"`
+		EncounterID    *int               `json:"encounter_id"`
+		CharacterIDs   []int              `json:"character_ids"`
+		Monsters       []combatantRequest `json:"monsters"`
+		RollInitiative string             `json:"roll_initiative"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combat start", "Could not process request", nil)
+	}
+
+	campaign, err := app.authorizeWritableCampaign(c, campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Combat start", "Start failed", nil)
+	}
+
+	if req.RollInitiative == "" {
+		req.RollInitiative = rollInitiativeNPCs
+	}
+	switch req.RollInitiative {
+	case rollInitiativeNone, rollInitiativeNPCs, rollInitiativeAll:
+	default:
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combat start", "Initiative must be rolled for none, npcs or all", nil)
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if utf8.RuneCountInString(req.Name) > maxEncounterNameLength {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combat start", "Name must be at most 100 characters", nil)
+	}
+
+	if req.EncounterID != nil {
+		encounter, err := app.encounters.Get(*req.EncounterID)
+		if err != nil || encounter.CampaignID != campaignID {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusNotFound, "Combat start", "Encounter not found", nil)
+		}
+		if req.Name == "" {
+			req.Name = encounter.Name
+		}
+		for _, monster := range encounter.Monsters {
+			if monster.NPCID == nil && monster.CompendiumSlug == nil {
+				return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combat start",
+					"Monsters of the encounter need an NPC or compendium stat block", nil)
+			}
+			r := combatantRequest{NPCID: monster.NPCID, Quantity: monster.Quantity}
+			if monster.CompendiumSlug != nil {
+				r.CompendiumSlug = *monster.CompendiumSlug
+			}
+			req.Monsters = append(req.Monsters, r)
+		}
+	}
+
+	if req.CharacterIDs == nil {
+		characters, err := app.belongsTo.GetAllCampaignCharacters(campaignID)
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Combat start", "Start failed", nil)
+		}
+		for _, character := range *characters {
+			req.CharacterIDs = append(req.CharacterIDs, character.ID)
+		}
+	}
+
+	reqs := []combatantRequest{}
+	for i := range req.CharacterIDs {
+		reqs = append(reqs, combatantRequest{CharacterID: &req.CharacterIDs[i]})
+	}
+	reqs = append(reqs, req.Monsters...)
+
+	combatants, msg, err := app.buildCombatants(campaignID, reqs)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Combat start", "Start failed", nil)
+	}
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combat start", msg, nil)
+	}
+
+	for i := range combatants {
+		combatant := &combatants[i]
+		if combatant.Initiative != nil || req.RollInitiative == rollInitiativeNone ||
+			(req.RollInitiative == rollInitiativeNPCs && combatant.CharacterID != nil) {
+			continue
+		}
+
+		initiative, err := rollInitiative(combatant.InitiativeBonus)
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Combat start", "Start failed", nil)
+		}
+		combatant.Initiative = &initiative
+	}
+
+	username := getUsernameFromToken(c)
+	combat := models.Combat{
+		CampaignID:  campaignID,
+		EncounterID: req.EncounterID,
+		Name:        req.Name,
+		StartedBy:   &username,
+	}
+	if _, err := app.combats.Start(combat, combatants); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrCombatInProgress) {
+			return sendJSONResponse(c, http.StatusConflict, "Combat start", "A combat is already in progress", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Combat start", "Start failed", nil)
+	}
+
+	return app.sendCombat(c, http.StatusCreated, "Combat start", "Start successful", campaign, events.CombatStarted)
+}
+
+// Retrieves the campaign's combat in progress. Clients polling the combat
+// can send the previous ETag in If-None-Match and receive 304 Not
+// Modified until something changes.
+func (app *application) getCombat(c echo.Context) error {
+	campaign, combat, status := app.getCampaignCombat(c, false)
+	if combat == nil {
+		return sendJSONResponse(c, status, "Combat retrieval", "Retrieval failed", nil)
+	}
+
+	etag := fmt.Sprintf(`"combat-%d-%d"`, combat.ID, combat.Version)
+	c.Response().Header().Set("ETag", etag)
+	if etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	prepareCombat(combat, campaign, getUsernameFromToken(c))
+	return sendJSONResponse(c, http.StatusOK, "Combat retrieval", "Retrieval successful", combat)
+}
+
+// Ends the campaign's combat in progress. Only the dungeon master may end
+// a combat.
+func (app *application) endCombat(c echo.Context) error {
+	campaign, combat, status := app.getCampaignCombat(c, true)
+	if combat == nil {
+		return sendJSONResponse(c, status, "Combat end", "End failed", nil)
+	}
+	if !isDungeonMaster(campaign, getUsernameFromToken(c)) {
+		return sendJSONResponse(c, http.StatusForbidden, "Combat end", "Only the dungeon master may end the combat", nil)
+	}
+
+	if err := app.combats.End(combat.ID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Combat end", "End failed", nil)
+	}
+	app.publish(campaign.ID, events.CombatEnded, map[string]int{"combat_id": combat.ID})
+
+	return sendJSONResponse(c, http.StatusOK, "Combat end", "End successful", nil)
+}
+
+// Adds characters or monsters to the campaign's combat in progress.
+// Initiative is rolled for monsters which are not given one.
+func (app *application) addCombatants(c echo.Context) error {
+	req := struct {
+		Combatants []combatantRequest `json:"combatants"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combatant addition", "Could not process request", nil)
+	}
+
+	campaign, combat, status := app.getCampaignCombat(c, true)
+	if combat == nil {
+		return sendJSONResponse(c, status, "Combatant addition", "Addition failed", nil)
+	}
+	if !isDungeonMaster(campaign, getUsernameFromToken(c)) {
+		return sendJSONResponse(c, http.StatusForbidden, "Combatant addition", "Only the dungeon master may add combatants", nil)
+	}
+
+	combatants, msg, err := app.buildCombatants(campaign.ID, req.Combatants)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Combatant addition", "Addition failed", nil)
+	}
+	if msg == "" && len(combatants) == 0 {
+		msg = "At least one combatant is required"
+	}
+	if msg == "" && len(combat.Combatants)+len(combatants) > maxCombatants {
+		msg = "A combat can have at most 100 combatants"
+	}
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combatant addition", msg, nil)
+	}
+
+	for i := range combatants {
+		combatant := &combatants[i]
+		if combatant.Initiative != nil || combatant.CharacterID != nil {
+			continue
+		}
+
+		initiative, err := rollInitiative(combatant.InitiativeBonus)
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Combatant addition", "Addition failed", nil)
+		}
+		combatant.Initiative = &initiative
+	}
+
+	if err := app.combats.AddCombatants(combat.ID, combatants); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Combatant addition", "Addition failed", nil)
+	}
+
+	return app.sendCombat(c, http.StatusOK, "Combatant addition", "Addition successful", campaign, events.CombatUpdated)
+}
+
+// Removes a combatant from the campaign's combat in progress. Only the
+// dungeon master may remove combatants.
+func (app *application) removeCombatant(c echo.Context) error {
+	combatantID, err := strconv.Atoi(c.Param("combatantID"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combatant removal", "Could not process request", nil)
+	}
+
+	campaign, combat, status := app.getCampaignCombat(c, true)
+	if combat == nil {
+		return sendJSONResponse(c, status, "Combatant removal", "Removal failed", nil)
+	}
+	if !isDungeonMaster(campaign, getUsernameFromToken(c)) {
+		return sendJSONResponse(c, http.StatusForbidden, "Combatant removal", "Only the dungeon master may remove combatants", nil)
+	}
+
+	if err := app.combats.RemoveCombatant(combat.ID, combatantID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Combatant removal", "Removal failed", nil)
+	}
+
+	return app.sendCombat(c, http.StatusOK, "Combatant removal", "Removal successful", campaign, events.CombatUpdated)
+}
+
+// Enters initiative for combatants of the campaign's combat in progress.
+// Players may only enter initiative for their own characters.
+func (app *application) setCombatInitiative(c echo.Context) error {
+	req := struct {
+		Initiatives []struct {
+			CombatantID int `json:"combatant_id"`
+			Initiative  int `json:"initiative"`
+		} `json:"initiatives"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Initiative entry", "Could not process request", nil)
+	}
+
+	campaign, combat, status := app.getCampaignCombat(c, true)
+	if combat == nil {
+		return sendJSONResponse(c, status, "Initiative entry", "Entry failed", nil)
+	}
+
+	username := getUsernameFromToken(c)
+	initiatives := make(map[int]int)
+	for _, entry := range req.Initiatives {
+		combatant := findCombatant(combat, entry.CombatantID)
+		if combatant == nil {
+			return sendJSONResponse(c, http.StatusNotFound, "Initiative entry", "Combatant not found", nil)
+		}
+		if !canControlCombatant(combatant, campaign, username) {
+			return sendJSONResponse(c, http.StatusForbidden, "Initiative entry", "Players may only enter initiative for their own characters", nil)
+		}
+		if entry.Initiative < -10 || entry.Initiative > 50 {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Initiative entry", "Initiative must be between -10 and 50", nil)
+		}
+		initiatives[entry.CombatantID] = entry.Initiative
+	}
+	if len(initiatives) == 0 {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Initiative entry", "At least one initiative is required", nil)
+	}
+
+	if err := app.combats.SetInitiative(combat.ID, initiatives); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Initiative entry", "Entry failed", nil)
+	}
+
+	return app.sendCombat(c, http.StatusOK, "Initiative entry", "Entry successful", campaign, events.CombatUpdated)
+}
+
+// Rolls initiative for combatants of the campaign's combat in progress,
+// adding each combatant's dexterity modifier. Without a list of
+// combatants, initiative is rolled for every combatant the requestor
+// controls which has none yet.
+func (app *application) rollCombatInitiative(c echo.Context) error {
+	req := struct {
+		CombatantIDs []int `json:"combatant_ids"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Initiative roll", "Could not process request", nil)
+	}
+
+	campaign, combat, status := app.getCampaignCombat(c, true)
+	if combat == nil {
+		return sendJSONResponse(c, status, "Initiative roll", "Roll failed", nil)
+	}
+
+	username := getUsernameFromToken(c)
+	if len(req.CombatantIDs) == 0 {
+		for _, combatant := range combat.Combatants {
+			if combatant.Initiative == nil && canControlCombatant(&combatant, campaign, username) {
+				req.CombatantIDs = append(req.CombatantIDs, combatant.ID)
+			}
+		}
+	}
+
+	initiatives := make(map[int]int)
+	for _, id := range req.CombatantIDs {
+		combatant := findCombatant(combat, id)
+		if combatant == nil {
+			return sendJSONResponse(c, http.StatusNotFound, "Initiative roll", "Combatant not found", nil)
+		}
+		if !canControlCombatant(combatant, campaign, username) {
+			return sendJSONResponse(c, http.StatusForbidden, "Initiative roll", "Players may only roll initiative for their own characters", nil)
+		}
+
+		initiative, err := rollInitiative(combatant.InitiativeBonus)
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Initiative roll", "Roll failed", nil)
+		}
+		initiatives[id] = initiative
+	}
+	if len(initiatives) == 0 {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Initiative roll", "No combatants need initiative", nil)
+	}
+
+	if err := app.combats.SetInitiative(combat.ID, initiatives); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Initiative roll", "Roll failed", nil)
+	}
+
+	return app.sendCombat(c, http.StatusOK, "Initiative roll", "Roll successful", campaign, events.CombatUpdated)
+}
+
+// Passes the turn to the next combatant, starting the first round if the
+// combat has not begun. The dungeon master may always advance the turn,
+// and players may end their own characters' turns.
+func (app *application) nextCombatTurn(c echo.Context) error {
+	campaign, combat, status := app.getCampaignCombat(c, true)
+	if combat == nil {
+		return sendJSONResponse(c, status, "Combat turn", "Turn failed", nil)
+	}
+
+	username := getUsernameFromToken(c)
+	if !isDungeonMaster(campaign, username) {
+		var current *models.Combatant
+		if combat.CurrentCombatantID != nil {
+			current = findCombatant(combat, *combat.CurrentCombatantID)
+		}
+		if current == nil || !canControlCombatant(current, campaign, username) {
+			return sendJSONResponse(c, http.StatusForbidden, "Combat turn", "Players may only end their own turn", nil)
+		}
+	}
+
+	if err := app.combats.NextTurn(combat.ID); err != nil {
+		log.Error(err)
+		switch {
+		case errors.Is(err, models.ErrInitiativeMissing):
+			return sendJSONResponse(c, http.StatusConflict, "Combat turn", "Every combatant needs an initiative before the first turn", nil)
+		case errors.Is(err, models.ErrNoActiveCombatants):
+			return sendJSONResponse(c, http.StatusConflict, "Combat turn", "No combatants are left to take a turn", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Combat turn", "Turn failed", nil)
+	}
+
+	return app.sendCombat(c, http.StatusOK, "Combat turn", "Turn successful", campaign, events.CombatTurnAdvanced)
+}
+
+// Applies damage or healing to a combatant, sets its temporary hit points
+// or marks it as defeated. Players may only change their own characters'
+// hit points, and only the dungeon master may mark combatants as
+// defeated.
+func (app *application) updateCombatantHP(c echo.Context) error {
+	req := struct {
+		Damage   int   `json:"damage"`
+		Healing  int   `json:"healing"`
+		TempHP   *int  `json:"temp_hp"`
+		Defeated *bool `json:"defeated"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combatant hit points", "Could not process request", nil)
+	}
+
+	campaign, combat, combatant, status := app.getCombatantParams(c)
+	if combatant == nil {
+		return sendJSONResponse(c, status, "Combatant hit points", "Update failed", nil)
+	}
+
+	switch {
+	case req.Damage < 0 || req.Healing < 0:
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combatant hit points", "Damage and healing cannot be negative", nil)
+	case req.Damage > 10000 || req.Healing > 10000:
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combatant hit points", "Damage and healing must be at most 10000", nil)
+	case req.TempHP != nil && (*req.TempHP < 0 || *req.TempHP > 1000):
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combatant hit points", "Temporary hit points must be between 0 and 1000", nil)
+	case req.Defeated != nil && !isDungeonMaster(campaign, getUsernameFromToken(c)):
+		return sendJSONResponse(c, http.StatusForbidden, "Combatant hit points", "Only the dungeon master may mark combatants as defeated", nil)
+	}
+
+	if req.Damage > 0 || req.Healing > 0 || req.TempHP != nil {
+		if err := app.combats.AdjustHP(combat.ID, combatant.ID, req.Damage, req.Healing, req.TempHP); err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, authorizationStatus(err), "Combatant hit points", "Update failed", nil)
+		}
+	}
+	if req.Defeated != nil {
+		if err := app.combats.SetDefeated(combat.ID, combatant.ID, *req.Defeated); err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, authorizationStatus(err), "Combatant hit points", "Update failed", nil)
+		}
+	}
+
+	return app.sendCombat(c, http.StatusOK, "Combatant hit points", "Update successful", campaign, events.CombatUpdated)
+}
+
+// Applies a condition to a combatant, or replaces it if the combatant
+// already has it. Conditions applied to a character's combatant stay
+// with the character once the combat ends.
+func (app *application) addCombatantCondition(c echo.Context) error {
+	var req conditionRequest
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combatant condition", "Could not process request", nil)
+	}
+
+	condition, msg := validateCondition(req)
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combatant condition", msg, nil)
+	}
+
+	campaign, combat, combatant, status := app.getCombatantParams(c)
+	if combatant == nil {
+		return sendJSONResponse(c, status, "Combatant condition", "Update failed", nil)
+	}
+
+	if err := app.combats.AddCondition(combat.ID, combatant.ID, condition); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Combatant condition", "Update failed", nil)
+	}
+
+	return app.sendCombat(c, http.StatusOK, "Combatant condition", "Update successful", campaign, events.CombatUpdated)
+}
+
+// Ends a condition on a combatant.
+func (app *application) removeCombatantCondition(c echo.Context) error {
+	var condition models.ConditionType
+	if err := condition.UnmarshalJSON([]byte(strconv.Quote(c.Param("condition")))); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Combatant condition", "Could not process request", nil)
+	}
+
+	campaign, combat, combatant, status := app.getCombatantParams(c)
+	if combatant == nil {
+		return sendJSONResponse(c, status, "Combatant condition", "Update failed", nil)
+	}
+
+	if err := app.combats.RemoveCondition(combat.ID, combatant.ID, condition); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Combatant condition", "Update failed", nil)
+	}
+
+	return app.sendCombat(c, http.StatusOK, "Combatant condition", "Update successful", campaign, events.CombatUpdated)
+}
+
+// Issues a ticket for following a campaign's events in real time. The
+// ticket is passed as the `ticket` query parameter when opening the
+// event stream and expires after a minute, so clients request a new one
+// whenever they reconnect.
+func (app *application) createCampaignEventTicket(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Event stream ticket", "Could not process request", nil)
+	}
+
+	if _, err := app.authorizeParticipant(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Event stream ticket", "Ticket creation failed", nil)
+	}
+
+	player, err := app.players.Get(getUsernameFromToken(c))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Event stream ticket", "Ticket creation failed", nil)
+	}
+
+	ticket, expiresAt, err := app.createStreamTicket(player, campaignID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Event stream ticket", "Ticket creation failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusCreated, "Event stream ticket", "Ticket creation successful",
+		struct {
+			Ticket    string    `json:"ticket"`
+			ExpiresAt time.Time `json:"expires_at"`
+		}{
+			ticket,
+			expiresAt,
+		})
+}
+
+// getStreamCampaign parses the campaign ID of an event stream route and
+// retrieves the campaign, provided the stream ticket was issued for it
+// and the requestor still takes part in it.
+func (app *application) getStreamCampaign(c echo.Context) (*models.Campaign, int) {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return nil, http.StatusUnprocessableEntity
+	}
+
+	if ticketCampaignID, _ := getStreamCampaignFromToken(c); ticketCampaignID != campaignID {
+		return nil, http.StatusForbidden
+	}
+
+	campaign, err := app.authorizeParticipant(c, campaignID)
+	if err != nil {
+		log.Error(err)
+		return nil, authorizationStatus(err)
+	}
+
+	return campaign, http.StatusOK
+}
+
+// Streams a campaign's events using Server-Sent Events. Reconnecting
+// clients resume from the Last-Event-ID header or the `last_event_id`
+// query parameter.
+func (app *application) streamCampaignEvents(c echo.Context) error {
+	campaign, status := app.getStreamCampaign(c)
+	if campaign == nil {
+		return sendJSONResponse(c, status, "Campaign event stream", "Access denied", nil)
+	}
+
+	w := c.Response()
+	w.Header().Set(echo.HeaderContentType, "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	w.Flush()
+
+	send := func(e events.Event) error {
+		return writeServerSentEvent(c, e)
+	}
+	ping := func() error {
+		if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+			return err
+		}
+		w.Flush()
+		return nil
+	}
+
+	if err := app.followCampaign(c, campaign, send, ping, c.Request().Context().Done()); err != nil {
+		log.Error(err)
+	}
+	return nil
+}
+
+// Streams a campaign's events over a WebSocket as JSON messages.
+// Reconnecting clients resume from the `last_event_id` query parameter.
+// Messages from the client are ignored.
+func (app *application) streamCampaignEventsWebSocket(c echo.Context) error {
+	campaign, status := app.getStreamCampaign(c)
+	if campaign == nil {
+		return sendJSONResponse(c, status, "Campaign event stream", "Access denied", nil)
+	}
+
+	websocket.Handler(func(ws *websocket.Conn) {
+		defer ws.Close()
+
+		done := make(chan struct{})
+		go func() {
+			var msg string
+			for websocket.Message.Receive(ws, &msg) == nil {
+			}
+			close(done)
+		}()
+
+		send := func(e events.Event) error {
+			return websocket.JSON.Send(ws, e)
+		}
+		ping := func() error {
+			return websocket.Message.Send(ws, `{"type":"ping"}`)
+		}
+
+		if err := app.followCampaign(c, campaign, send, ping, done); err != nil {
+			log.Error(err)
+		}
+	}).ServeHTTP(c.Response(), c.Request())
+
+	return nil
+}
+
+// Limits on dice rolls.
+const (
+	maxRollExpressionLength = 100
+	maxRollLabelLength      = 100
+)
+
+type diceRollRequest struct {
+	Expression  string              `json:"expression"`
+	Mode        models.RollModeType `json:"mode"`
+	Label       string              `json:"label"`
+	CharacterID *int                `json:"character_id"`
+	Ability     *models.AbilityType `json:"ability"`
+}
+
+// findCampaignCharacter returns the character of the campaign identified
+// by `campaignID` which is identified by `characterID`, or nil if the
+// character does not belong to the campaign.
+func (app *application) findCampaignCharacter(campaignID, characterID int) (*models.Character, error) {
+	characters, err := app.belongsTo.GetAllCampaignCharacters(campaignID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, character := range *characters {
+		if character.ID == characterID {
+			return &character, nil
+		}
+	}
+	return nil, nil
+}
+
+// Rolls dice for everyone in a campaign to see and records the result in
+// the campaign's roll log. When an ability is given, the roll is an
+// ability check for the given character: the expression defaults to
+// `1d20` and the character's ability modifier is added to it. Players
+// may only roll for their own characters.
+func (app *application) createCampaignDiceRoll(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Dice roll", "Could not process request", nil)
+	}
+
+	var req diceRollRequest
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Dice roll", "Could not process request", nil)
+	}
+
+	campaign, err := app.authorizeParticipant(c, campaignID)
+	if err == nil {
+		err = ensureCampaignWritable(campaign)
+	}
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Dice roll", "Roll failed", nil)
+	}
+	username := getUsernameFromToken(c)
+
+	roll := models.DiceRoll{
+		CampaignID:  campaignID,
+		RolledBy:    &username,
+		CharacterID: req.CharacterID,
+		Label:       strings.TrimSpace(req.Label),
+		Expression:  strings.TrimSpace(req.Expression),
+		Mode:        req.Mode,
+		Ability:     req.Ability,
+	}
+	if roll.Mode == "" {
+		roll.Mode = models.RollNormal
+	}
+	if len(roll.Label) > maxRollLabelLength {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Dice roll", "Label must be at most 100 characters", nil)
+	}
+	if roll.Expression == "" && roll.Ability != nil {
+		roll.Expression = "1d20"
+	}
+	if roll.Expression == "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Dice roll", "Expression is required", nil)
+	}
+	if len(roll.Expression) > maxRollExpressionLength {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Dice roll", "Expression must be at most 100 characters", nil)
+	}
+
+	expr, err := dice.Parse(roll.Expression)
+	if err != nil {
+		var exprErr *dice.ExpressionError
+		if errors.As(err, &exprErr) {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Dice roll", "Expression is invalid: "+exprErr.Reason, nil)
+		}
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Dice roll", "Roll failed", nil)
+	}
+
+	if roll.CharacterID != nil {
+		character, err := app.findCampaignCharacter(campaignID, *roll.CharacterID)
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Dice roll", "Roll failed", nil)
+		}
+		if character == nil {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Dice roll", "Character must belong to the campaign", nil)
+		}
+		if character.PlayerUsername != username && !isDungeonMaster(campaign, username) {
+			return sendJSONResponse(c, http.StatusForbidden, "Dice roll", "Players may only roll for their own characters", nil)
+		}
+
+		if roll.Ability != nil {
+			roll.Modifier = models.AbilityModifier(character.AbilityScore(*roll.Ability))
+			expr.Add(roll.Modifier)
+		}
+	} else if roll.Ability != nil {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Dice roll", "Ability checks need a character", nil)
+	}
+
+	if roll.Mode != models.RollNormal {
+		if err := expr.WithAdvantage(roll.Mode == models.RollDisadvantage); err != nil {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Dice roll", "Advantage and disadvantage need a single d20 to roll", nil)
+		}
+	}
+
+	result, err := expr.Roll()
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Dice roll", "Roll failed", nil)
+	}
+	roll.Expression = result.Expression
+	roll.Total = result.Total
+	if roll.Terms, err = json.Marshal(result.Terms); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Dice roll", "Roll failed", nil)
+	}
+
+	id, err := app.diceRolls.Insert(roll)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Dice roll", "Roll failed", nil)
+	}
+	app.publish(campaignID, events.DiceRolled, map[string]int{"roll_id": id})
+
+	created, err := app.diceRolls.Get(id)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Dice roll", "Roll failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusCreated, "Dice roll", fmt.Sprintf("Rolled %d", created.Total), created)
+}
+
+// Retrieves the dice rolls of a campaign, most recent first, optionally
+// only those made for the character given by the `character_id` query
+// parameter.
+func (app *application) getCampaignDiceRolls(c echo.Context) error {
+	campaignID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Dice roll retrieval", "Retrieval failed", nil)
+	}
+
+	var characterID *int
+	if raw := c.QueryParam("character_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Dice roll retrieval", "Retrieval failed", nil)
+		}
+		characterID = &id
+	}
+
+	if _, err := app.authorizeParticipant(c, campaignID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Dice roll retrieval", "Retrieval failed", nil)
+	}
+
+	limit, offset := parsePagination(c)
+	rolls, err := app.diceRolls.GetAllForCampaign(campaignID, characterID, limit, offset)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Dice roll retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Dice roll retrieval", "Retrieval successful",
+		struct {
+			Rolls []models.DiceRoll `json:"rolls"`
+		}{
+			*rolls,
+		})
+}
+
+// Limits on homebrew races, classes and subclasses.
+const (
+	maxRegistryNameLength        = 50
+	maxRegistryDescriptionLength = 5000
+)
+
+type registryEntryRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CampaignID  *int   `json:"campaign_id"` // Shares the entry with a campaign
+	Class       string `json:"class"`       // Subclasses only
+	HitDie      *int   `json:"hit_die"`     // Classes only
+
+	AbilityBonuses      map[models.AbilityType]int `json:"ability_bonuses"`       // Races only
+	AbilityBonusChoices *int                       `json:"ability_bonus_choices"` // Races only
+}
+
+// parseRegistryKind reads the kind of registry entry from the `kind`
+// route parameter.
+func parseRegistryKind(c echo.Context) (models.RegistryKindType, bool) {
+	switch kind := models.RegistryKindType(c.Param("kind")); kind {
+	case models.RegistryRace, models.RegistryClass, models.RegistrySubclass:
+		return kind, true
+	}
+	return "", false
+}
+
+// validateRegistryEntry checks the name, description, hit die and
+// ability bonuses of `req` and copies them into `entry`. A message describing the first
+// problem is returned if the request is invalid.
+func validateRegistryEntry(req registryEntryRequest, entry *models.RegistryEntry) string {
+	entry.Name = strings.TrimSpace(req.Name)
+	entry.Description = strings.TrimSpace(req.Description)
+
+	if entry.Name == "" || utf8.RuneCountInString(entry.Name) > maxRegistryNameLength {
+		return "Name must be between 1 and 50 characters"
+	}
+	if len(entry.Description) > maxRegistryDescriptionLength {
+		return "Description must be at most 5000 characters"
+	}
+
+	if entry.Kind == models.RegistryClass {
+		if req.HitDie != nil {
+			entry.HitDie = req.HitDie
+		}
+		if entry.HitDie == nil {
+			hitDie := 8
+			entry.HitDie = &hitDie
+		}
+		switch *entry.HitDie {
+		case 6, 8, 10, 12:
+		default:
+			return "Hit die must be 6, 8, 10 or 12"
+		}
+	}
+
+	if entry.AbilityBonuses == nil {
+		entry.AbilityBonuses = json.RawMessage("{}")
+	}
+	if entry.Kind == models.RegistryRace {
+		if req.AbilityBonuses != nil {
+			for ability, bonus := range req.AbilityBonuses {
+				if !isAbility(ability) {
+					return "Ability bonuses must be for abilities"
+				}
+				if bonus < -2 || bonus > 2 || bonus == 0 {
+					return "Ability bonuses must be between -2 and 2, and not 0"
+				}
+			}
+			bonuses, err := json.Marshal(req.AbilityBonuses)
+			if err != nil {
+				return "Ability bonuses are invalid"
+			}
+			entry.AbilityBonuses = bonuses
+		}
+
+		if req.AbilityBonusChoices != nil {
+			entry.AbilityBonusChoices = req.AbilityBonusChoices
+		}
+		if entry.AbilityBonusChoices == nil {
+			choices := 0
+			entry.AbilityBonusChoices = &choices
+		}
+		if *entry.AbilityBonusChoices < 0 || *entry.AbilityBonusChoices > 3 {
+			return "Ability bonus choices must be between 0 and 3"
+		}
+	}
+
+	return ""
+}
+
+// isAbility reports whether `ability` is one of the six abilities. JSON
+// object keys are not checked when they are unmarshalled.
+func isAbility(ability models.AbilityType) bool {
+	for _, a := range models.Abilities {
+		if a == ability {
+			return true
+		}
+	}
+	return false
+}
+
+// authorizeRegistryEntry retrieves the homebrew entry of kind `kind`
+// identified by the `entryID` route parameter and verifies that the
+// requestor may change it: players manage their own homebrew, and
+// dungeon masters that of their campaign.
+func (app *application) authorizeRegistryEntry(c echo.Context, kind models.RegistryKindType) (*models.RegistryEntry, int) {
+	entryID, err := strconv.Atoi(c.Param("entryID"))
+	if err != nil {
+		log.Error(err)
+		return nil, http.StatusUnprocessableEntity
+	}
+
+	entry, err := app.registry.Get(entryID)
+	if err != nil {
+		log.Error(err)
+		return nil, authorizationStatus(err)
+	}
+	if entry.Kind != kind {
+		return nil, http.StatusNotFound
+	}
+
+	if entry.IsOfficial() {
+		return nil, http.StatusForbidden
+	}
+	if entry.CampaignID != nil {
+		if _, err := app.authorizeWritableCampaign(c, *entry.CampaignID); err != nil {
+			log.Error(err)
+			return nil, authorizationStatus(err)
+		}
+	} else if *entry.OwnerUsername != getUsernameFromToken(c) {
+		return nil, http.StatusForbidden
+	}
+
+	return entry, http.StatusOK
+}
+
+// resolveCharacterRegistry checks that the race, class and subclass of
+// `character` are registry entries which `username` may choose and that
+// the subclass belongs to the class, then replaces them with their names
+// as registered. A message describing the first problem is returned
+// otherwise.
+func (app *application) resolveCharacterRegistry(character *models.Character, username string) (string, error) {
+	race, err := app.registry.FindVisible(username, models.RegistryRace, strings.TrimSpace(string(character.Race)), nil)
+	if errors.Is(err, models.ErrNoRecord) {
+		return "Race " + strconv.Quote(string(character.Race)) + " is not known", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	class, err := app.registry.FindVisible(username, models.RegistryClass, strings.TrimSpace(string(character.Class)), nil)
+	if errors.Is(err, models.ErrNoRecord) {
+		return "Class " + strconv.Quote(string(character.Class)) + " is not known", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	subclass, err := app.registry.FindVisible(username, models.RegistrySubclass, strings.TrimSpace(string(character.ClassAttribute)), &class.ID)
+	if errors.Is(err, models.ErrNoRecord) {
+		return "Subclass " + strconv.Quote(string(character.ClassAttribute)) + " is not a subclass of " + class.Name, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	character.Race = models.RaceType(race.Name)
+	character.Class = models.ClassType(class.Name)
+	character.ClassAttribute = models.ClassAttributeType(subclass.Name)
+	return "", nil
+}
+
+// Retrieves the races, classes or subclasses which the requestor may
+// choose for a character: the official ones, their own homebrew and
+// that of the campaigns they take part in. Subclasses can be limited to
+// those of the class named by the `class` query parameter.
+func (app *application) getRegistryEntries(c echo.Context) error {
+	kind, ok := parseRegistryKind(c)
+	if !ok {
+		return sendJSONResponse(c, http.StatusNotFound, "Registry retrieval", "Retrieval failed", nil)
+	}
+
+	entries, err := app.registry.GetAllVisible(getUsernameFromToken(c), kind)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Registry retrieval", "Retrieval failed", nil)
+	}
+
+	if class := strings.TrimSpace(c.QueryParam("class")); class != "" && kind == models.RegistrySubclass {
+		filtered := []models.RegistryEntry{}
+		for _, entry := range *entries {
+			if strings.EqualFold(*entry.ClassName, class) {
+				filtered = append(filtered, entry)
+			}
+		}
+		entries = &filtered
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Registry retrieval", "Retrieval successful",
+		struct {
+			Entries []models.RegistryEntry `json:"entries"`
+		}{
+			*entries,
+		})
+}
+
+// Creates a homebrew race, class or subclass. Homebrew belongs to the
+// requestor unless a campaign is given, in which case it is shared with
+// everyone in the campaign and only its dungeon master may create it. A
+// subclass must extend an official class or one with the same owner or
+// campaign.
+func (app *application) createRegistryEntry(c echo.Context) error {
+	kind, ok := parseRegistryKind(c)
+	if !ok {
+		return sendJSONResponse(c, http.StatusNotFound, "Homebrew creation", "Creation failed", nil)
+	}
+
+	var req registryEntryRequest
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Homebrew creation", "Could not process request", nil)
+	}
+
+	username := getUsernameFromToken(c)
+	entry := models.RegistryEntry{Kind: kind}
+	if req.CampaignID != nil {
+		if _, err := app.authorizeWritableCampaign(c, *req.CampaignID); err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, authorizationStatus(err), "Homebrew creation", "Creation failed", nil)
+		}
+		entry.CampaignID = req.CampaignID
+	} else {
+		entry.OwnerUsername = &username
+	}
+
+	if msg := validateRegistryEntry(req, &entry); msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Homebrew creation", msg, nil)
+	}
+
+	if kind == models.RegistrySubclass {
+		class, err := app.registry.FindVisible(username, models.RegistryClass, strings.TrimSpace(req.Class), nil)
+		if err != nil && !errors.Is(err, models.ErrNoRecord) {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Homebrew creation", "Creation failed", nil)
+		}
+		sameScope := class != nil && (class.IsOfficial() ||
+			(class.CampaignID != nil && entry.CampaignID != nil && *class.CampaignID == *entry.CampaignID) ||
+			(class.OwnerUsername != nil && entry.OwnerUsername != nil && *class.OwnerUsername == *entry.OwnerUsername))
+		if !sameScope {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Homebrew creation",
+				"Class must be an official class or homebrew of the same player or campaign", nil)
+		}
+		entry.ClassID = &class.ID
+	}
+
+	id, err := app.registry.Insert(entry)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrDuplicateRegistryEntry) {
+			return sendJSONResponse(c, http.StatusConflict, "Homebrew creation", "An entry with this name already exists", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Homebrew creation", "Creation failed", nil)
+	}
+
+	created, err := app.registry.Get(id)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Homebrew creation", "Creation failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusCreated, "Homebrew creation", "Creation successful", created)
+}
+
+// Changes the name, description or hit die of a homebrew race, class or
+// subclass. Entries cannot be renamed while a character uses them.
+func (app *application) updateRegistryEntry(c echo.Context) error {
+	kind, ok := parseRegistryKind(c)
+	if !ok {
+		return sendJSONResponse(c, http.StatusNotFound, "Homebrew update", "Update failed", nil)
+	}
+
+	var req registryEntryRequest
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Homebrew update", "Could not process request", nil)
+	}
+
+	entry, status := app.authorizeRegistryEntry(c, kind)
+	if entry == nil {
+		return sendJSONResponse(c, status, "Homebrew update", "Update failed", nil)
+	}
+
+	if msg := validateRegistryEntry(req, entry); msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Homebrew update", msg, nil)
+	}
+
+	if err := app.registry.Update(*entry); err != nil {
+		log.Error(err)
+		switch {
+		case errors.Is(err, models.ErrDuplicateRegistryEntry):
+			return sendJSONResponse(c, http.StatusConflict, "Homebrew update", "An entry with this name already exists", nil)
+		case errors.Is(err, models.ErrRegistryEntryInUse):
+			return sendJSONResponse(c, http.StatusConflict, "Homebrew update", "Entries cannot be renamed while a character uses them", nil)
+		case errors.Is(err, models.ErrNoRecord):
+			return sendJSONResponse(c, http.StatusNotFound, "Homebrew update", "Update failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Homebrew update", "Update failed", nil)
+	}
+
+	updated, err := app.registry.Get(entry.ID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Homebrew update", "Update failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Homebrew update", "Update successful", updated)
+}
+
+// Deletes a homebrew race, class or subclass, along with the subclasses
+// of a class, unless a character uses it.
+func (app *application) deleteRegistryEntry(c echo.Context) error {
+	kind, ok := parseRegistryKind(c)
+	if !ok {
+		return sendJSONResponse(c, http.StatusNotFound, "Homebrew deletion", "Deletion failed", nil)
+	}
+
+	entry, status := app.authorizeRegistryEntry(c, kind)
+	if entry == nil {
+		return sendJSONResponse(c, status, "Homebrew deletion", "Deletion failed", nil)
+	}
+
+	if err := app.registry.Delete(entry.ID); err != nil {
+		log.Error(err)
+		switch {
+		case errors.Is(err, models.ErrRegistryEntryInUse):
+			return sendJSONResponse(c, http.StatusConflict, "Homebrew deletion", "Entries cannot be deleted while a character uses them", nil)
+		case errors.Is(err, models.ErrNoRecord):
+			return sendJSONResponse(c, http.StatusNotFound, "Homebrew deletion", "Deletion failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Homebrew deletion", "Deletion failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Homebrew deletion", "Deletion successful", nil)
+}
+
+// Lists the official subclasses of each official class. Homebrew can be
+// listed per class with the `class` query parameter of the subclass
+// registry.
+func (app *application) getClassSubclasses(c echo.Context) error {
+	type classSubclasses struct {
+		Class      models.ClassType            `json:"class"`
+		Subclasses []models.ClassAttributeType `json:"subclasses"`
+	}
+
+	classes := []classSubclasses{}
+	for class, subclasses := range models.ClassSubclasses {
+		classes = append(classes, classSubclasses{class, subclasses})
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].Class < classes[j].Class
+	})
+
+	return sendJSONResponse(c, http.StatusOK, "Subclass retrieval", "Retrieval successful",
+		struct {
+			Classes []classSubclasses `json:"classes"`
+		}{
+			classes,
+		})
+}
+
+// Retrieves every enumeration of the server with display labels, along
+// with the rule tables such as the experience needed for each level.
+// Clients may cache the response and revalidate it with If-None-Match.
+func (app *application) getMetadata(c echo.Context) error {
+	meta, etag := getMetadata()
+
+	c.Response().Header().Set("ETag", etag)
+	c.Response().Header().Set("Cache-Control", "public, no-cache")
+	if etagMatches(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Metadata retrieval", "Retrieval successful", meta)
+}
+
+// characterClassesResponse lists the classes of a character along with
+// the spell slots they give.
+type characterClassesResponse struct {
+	Classes    []models.CharacterClass `json:"classes"`
+	Level      int                     `json:"level"`
+	SpellSlots models.SpellSlots       `json:"spell_slots"`
+}
+
+// Retrieves the classes of a character, with its total level and the
+// spell slots of all its spellcasting classes combined.
+func (app *application) getCharacterClasses(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character class retrieval", "Could not process request", nil)
+	}
+
+	if _, err := app.characters.Get(characterID); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Character class retrieval", "Retrieval failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character class retrieval", "Retrieval failed", nil)
+	}
+
+	classes, err := app.characterClasses.GetAll(characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character class retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Character class retrieval", "Retrieval successful", newCharacterClassesResponse(*classes))
+}
+
+func newCharacterClassesResponse(classes []models.CharacterClass) characterClassesResponse {
+	res := characterClassesResponse{Classes: classes, SpellSlots: models.CalculateSpellSlots(classes)}
+	for _, class := range classes {
+		res.Level += class.Levels
+	}
+	return res
+}
+
+// multiclassPrerequisiteMessage describes the ability scores needed to
+// multiclass with `class`, such as "Monk needs 13 dexterity and 13
+// wisdom".
+func multiclassPrerequisiteMessage(class models.ClassType) string {
+	groups := []string{}
+	for _, group := range models.MulticlassPrerequisites[class] {
+		abilities := []string{}
+		for _, ability := range group {
+			abilities = append(abilities, strconv.Itoa(models.MulticlassMinimumScore)+" "+string(ability))
+		}
+		groups = append(groups, strings.Join(abilities, " or "))
+	}
+	return string(class) + " needs " + strings.Join(groups, " and ")
+}
+
+// Replaces the classes of a character owned by the requestor. The first
+// class becomes the character's own class and must have a subclass; the
+// others may leave it out until one is chosen. The levels of all classes
+// must add up to the level of the character, and a character with
+// several classes must meet the ability prerequisites of each of them.
+func (app *application) updateCharacterClasses(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character class update", "Could not process request", nil)
+	}
+
+	var req struct {
+		Classes []struct {
+			Class    string  `json:"class"`
+			Subclass *string `json:"subclass"`
+			Levels   int     `json:"levels"`
+		} `json:"classes"`
+	}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character class update", "Could not process request", nil)
+	}
+
+	character, err := app.authorizeCharacterOwner(c, characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Character class update", "Character not found", nil)
+	}
+
+	if len(req.Classes) == 0 {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character class update", "A character needs at least one class", nil)
+	}
+
+	classes := []models.CharacterClass{}
+	level := 0
+	for i, r := range req.Classes {
+		class, err := app.registry.FindVisible(character.PlayerUsername, models.RegistryClass, strings.TrimSpace(r.Class), nil)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character class update", "Class "+strconv.Quote(r.Class)+" is not known", nil)
+		}
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Character class update", "Update failed", nil)
+		}
+
+		for _, other := range classes {
+			if other.Class == models.ClassType(class.Name) {
+				return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character class update", "Class "+class.Name+" is listed twice", nil)
+			}
+		}
+
+		if r.Levels < 1 {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character class update", "Every class needs at least one level", nil)
+		}
+		level += r.Levels
+
+		characterClass := models.CharacterClass{
+			CharacterID: characterID,
+			Position:    i,
+			Class:       models.ClassType(class.Name),
+			Levels:      r.Levels,
+		}
+
+		if r.Subclass == nil && i == 0 {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character class update", "The first class needs a subclass", nil)
+		}
+		if r.Subclass != nil {
+			subclass, err := app.registry.FindVisible(character.PlayerUsername, models.RegistrySubclass, strings.TrimSpace(*r.Subclass), &class.ID)
+			if errors.Is(err, models.ErrNoRecord) {
+				return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character class update",
+					"Subclass "+strconv.Quote(*r.Subclass)+" is not a subclass of "+class.Name, nil)
+			}
+			if err != nil {
+				log.Error(err)
+				return sendJSONResponse(c, http.StatusInternalServerError, "Character class update", "Update failed", nil)
+			}
+			name := models.ClassAttributeType(subclass.Name)
+			characterClass.Subclass = &name
+		}
+
+		classes = append(classes, characterClass)
+	}
+
+	if level != character.Level {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character class update",
+			"Levels must add up to "+strconv.Itoa(character.Level)+", the level of the character", nil)
+	}
+
+	if len(classes) > 1 {
+		for _, class := range classes {
+			if !models.MeetsMulticlassPrerequisites(character, class.Class) {
+				return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character class update", multiclassPrerequisiteMessage(class.Class), nil)
+			}
+		}
+	}
+
+	if err := app.characterClasses.Replace(characterID, classes); err != nil {
+		log.Error(err)
+		switch {
+		case errors.Is(err, models.ErrNoRecord):
+			return sendJSONResponse(c, http.StatusNotFound, "Character class update", "Character not found", nil)
+		case errors.Is(err, models.ErrDuplicateCharacterClass):
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character class update", "A class is listed twice", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character class update", "Update failed", nil)
+	}
+	app.publishCharacterUpdated(characterID)
+
+	return sendJSONResponse(c, http.StatusOK, "Character class update", "Update successful", newCharacterClassesResponse(classes))
+}
+
+// maxProficiencyNameLength is the longest name of a tool or language.
+const maxProficiencyNameLength = 50
+
+// characterProficienciesResponse lists the proficiencies of a character
+// along with the bonuses they give.
+type characterProficienciesResponse struct {
+	ProficiencyBonus int                           `json:"proficiency_bonus"`
+	SavingThrows     []models.SavingThrowBonus     `json:"saving_throws"`
+	Skills           []models.SkillBonus           `json:"skills"`
+	Proficiencies    []models.CharacterProficiency `json:"proficiencies"`
+}
+
+func newCharacterProficienciesResponse(character *models.Character, proficiencies []models.CharacterProficiency) characterProficienciesResponse {
+	savingThrows, skills := models.CalculateBonuses(character, proficiencies)
+	return characterProficienciesResponse{
+		ProficiencyBonus: models.ProficiencyBonus(character.Level),
+		SavingThrows:     savingThrows,
+		Skills:           skills,
+		Proficiencies:    proficiencies,
+	}
+}
+
+// parseProficiency reads the kind and name of a proficiency from the
+// path. Saving throws and skills are normalized to their identifiers, so
+// that "Sleight of Hand" names the sleight_of_hand skill. A message
+// describing the problem is returned if they are invalid.
+func parseProficiency(c echo.Context) (models.ProficiencyKindType, string, string) {
+	kind := models.ProficiencyKindType(c.Param("kind"))
+	name, err := url.QueryUnescape(c.Param("name"))
+	if err != nil {
+		return "", "", "Could not process request"
+	}
+	name = strings.TrimSpace(name)
+
+	switch kind {
+	case models.ProficiencySavingThrow:
+		ability := models.AbilityType(strings.ToLower(name))
+		for _, a := range models.Abilities {
+			if a == ability {
+				return kind, string(ability), ""
+			}
+		}
+		return "", "", strconv.Quote(name) + " is not an ability"
+	case models.ProficiencySkill:
+		skill := models.SkillType(strings.ReplaceAll(strings.ToLower(name), " ", "_"))
+		if _, ok := models.SkillAbilities[skill]; ok {
+			return kind, string(skill), ""
+		}
+		return "", "", strconv.Quote(name) + " is not a skill"
+	case models.ProficiencyTool, models.ProficiencyLanguage:
+		if name == "" || utf8.RuneCountInString(name) > maxProficiencyNameLength {
+			return "", "", "Name must be between 1 and " + strconv.Itoa(maxProficiencyNameLength) + " characters"
+		}
+		return kind, name, ""
+	}
+	return "", "", "Proficiency kind must be saving_throw, skill, tool or language"
+}
+
+// Retrieves the proficiencies of a character, with its saving throw and
+// skill bonuses.
+func (app *application) getCharacterProficiencies(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Proficiency retrieval", "Could not process request", nil)
+	}
+
+	character, err := app.characters.Get(characterID)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Proficiency retrieval", "Retrieval failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Proficiency retrieval", "Retrieval failed", nil)
+	}
+
+	proficiencies, err := app.proficiencies.GetAll(characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Proficiency retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Proficiency retrieval", "Retrieval successful",
+		newCharacterProficienciesResponse(character, *proficiencies))
+}
+
+// Makes a character owned by the requestor proficient in a saving
+// throw, skill, tool or language. Skills and tools may also be given
+// expertise, which a later request without it takes away again.
+func (app *application) setCharacterProficiency(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Proficiency update", "Could not process request", nil)
+	}
+
+	var req struct {
+		Expertise bool `json:"expertise"`
+	}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Proficiency update", "Could not process request", nil)
+	}
+
+	kind, name, msg := parseProficiency(c)
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Proficiency update", msg, nil)
+	}
+	if req.Expertise && kind != models.ProficiencySkill && kind != models.ProficiencyTool {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Proficiency update", "Only skills and tools can have expertise", nil)
+	}
+
+	character, err := app.authorizeCharacterOwner(c, characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Proficiency update", "Character not found", nil)
+	}
+
+	err = app.proficiencies.Set(models.CharacterProficiency{
+		CharacterID: characterID,
+		Kind:        kind,
+		Name:        name,
+		Expertise:   req.Expertise,
+	})
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Proficiency update", "Update failed", nil)
+	}
+
+	return app.sendCharacterProficiencies(c, character, "Proficiency update", "Update successful")
+}
+
+// Removes a proficiency from a character owned by the requestor.
+func (app *application) deleteCharacterProficiency(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Proficiency deletion", "Could not process request", nil)
+	}
+
+	kind, name, msg := parseProficiency(c)
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Proficiency deletion", msg, nil)
+	}
+
+	character, err := app.authorizeCharacterOwner(c, characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Proficiency deletion", "Character not found", nil)
+	}
+
+	if err := app.proficiencies.Delete(characterID, kind, name); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Proficiency deletion", "Character is not proficient in "+name, nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Proficiency deletion", "Deletion failed", nil)
+	}
+
+	return app.sendCharacterProficiencies(c, character, "Proficiency deletion", "Deletion successful")
+}
+
+// sendCharacterProficiencies responds with the proficiencies of
+// `character` once they have changed, and lets its campaigns know.
+func (app *application) sendCharacterProficiencies(c echo.Context, character *models.Character, event, msg string) error {
+	app.publishCharacterUpdated(character.ID)
+
+	proficiencies, err := app.proficiencies.GetAll(character.ID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, event, "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, event, msg, newCharacterProficienciesResponse(character, *proficiencies))
+}
+
+// Limits on the conditions applied to characters and combatants.
+const (
+	maxConditionSourceLength = 100
+	maxConditionRounds       = 1000
+)
+
+// conditionRequest is the body of a request applying a condition. A
+// condition lasts either a number of rounds, until a rest, or until it
+// is removed.
+type conditionRequest struct {
+	Condition models.ConditionType `json:"condition"`
+	Level     *int                 `json:"level"` // Exhaustion only, 1 if left out
+	Source    string               `json:"source"`
+	Rounds    *int                 `json:"rounds"`
+	UntilRest *models.RestType     `json:"until_rest"`
+}
+
+// validateCondition checks `req` and returns the condition it applies.
+// A message describing the first problem is returned if the request is
+// invalid.
+func validateCondition(req conditionRequest) (models.AppliedCondition, string) {
+	condition := models.AppliedCondition{
+		Condition:       req.Condition,
+		Level:           req.Level,
+		Source:          strings.TrimSpace(req.Source),
+		RoundsRemaining: req.Rounds,
+		UntilRest:       req.UntilRest,
+	}
+
+	switch {
+	case req.Condition == "":
+		return condition, "A condition is required"
+	case req.Condition != models.ConditionExhaustion && req.Level != nil:
+		return condition, "Only exhaustion has levels"
+	case req.Level != nil && (*req.Level < 1 || *req.Level > models.MaxExhaustion):
+		return condition, "Exhaustion level must be between 1 and " + strconv.Itoa(models.MaxExhaustion)
+	case utf8.RuneCountInString(condition.Source) > maxConditionSourceLength:
+		return condition, "Source must be at most " + strconv.Itoa(maxConditionSourceLength) + " characters"
+	case req.Rounds != nil && req.UntilRest != nil:
+		return condition, "A condition lasts either a number of rounds or until a rest"
+	case req.Rounds != nil && (*req.Rounds < 1 || *req.Rounds > maxConditionRounds):
+		return condition, "Rounds must be between 1 and " + strconv.Itoa(maxConditionRounds)
+	}
+
+	if condition.Condition == models.ConditionExhaustion && condition.Level == nil {
+		level := 1
+		condition.Level = &level
+	}
+	return condition, ""
+}
+
+// characterConditionsResponse lists the conditions of a character along
+// with their effects on its statistics.
+type characterConditionsResponse struct {
+	Conditions     []models.AppliedCondition `json:"conditions"`
+	Effects        models.ConditionEffects   `json:"effects"`
+	EffectiveSpeed int                       `json:"effective_speed"`
+	EffectiveHPMax int                       `json:"effective_hp_max"`
+}
+
+func newCharacterConditionsResponse(character *models.Character, conditions []models.AppliedCondition) characterConditionsResponse {
+	effects := models.CalculateConditionEffects(conditions)
+	return characterConditionsResponse{
+		Conditions:     conditions,
+		Effects:        effects,
+		EffectiveSpeed: effects.EffectiveSpeed(character.Speed),
+		EffectiveHPMax: effects.EffectiveHPMax(character.HPMax),
+	}
+}
+
+// Retrieves the conditions of a character and their effects.
+func (app *application) getCharacterConditions(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Condition retrieval", "Could not process request", nil)
+	}
+
+	character, err := app.characters.Get(characterID)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Condition retrieval", "Retrieval failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Condition retrieval", "Retrieval failed", nil)
+	}
+
+	conditions, err := app.conditions.GetAll(characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Condition retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Condition retrieval", "Retrieval successful",
+		newCharacterConditionsResponse(character, *conditions))
+}
+
+// Applies a condition to a character. The character's owner and the
+// dungeon masters of its campaigns may change its conditions.
+func (app *application) addCharacterCondition(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character condition", "Could not process request", nil)
+	}
+
+	var req conditionRequest
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character condition", "Could not process request", nil)
+	}
+
+	condition, msg := validateCondition(req)
+	if msg != "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character condition", msg, nil)
+	}
+
+	character, err := app.authorizeCharacterKeeper(c, characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Character condition", "Character not found", nil)
+	}
+
+	if err := app.conditions.Set(characterID, condition); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character condition", "Update failed", nil)
+	}
+
+	return app.sendCharacterConditions(c, character, "Character condition", "Update successful")
+}
+
+// Ends a condition on a character.
+func (app *application) removeCharacterCondition(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character condition", "Could not process request", nil)
+	}
+
+	var condition models.ConditionType
+	if err := condition.UnmarshalJSON([]byte(strconv.Quote(c.Param("condition")))); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character condition", "Could not process request", nil)
+	}
+
+	character, err := app.authorizeCharacterKeeper(c, characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Character condition", "Character not found", nil)
+	}
+
+	if err := app.conditions.Delete(characterID, condition); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Character condition", "Character is not "+string(condition), nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character condition", "Update failed", nil)
+	}
+
+	return app.sendCharacterConditions(c, character, "Character condition", "Update successful")
+}
+
+// Takes a short or long rest, ending the conditions which last until
+// then and recharging the features which recharge with it. A long rest
+// also removes a level of exhaustion and restores every hit point of a
+// character who is still alive.
+func (app *application) restCharacter(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character rest", "Could not process request", nil)
+	}
+
+	var req struct {
+		Rest models.RestType `json:"rest"`
+	}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character rest", "Rest must be short or long", nil)
+	}
+	if req.Rest == "" {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character rest", "Rest must be short or long", nil)
+	}
+
+	character, err := app.authorizeCharacterKeeper(c, characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Character rest", "Character not found", nil)
+	}
+
+	if err := app.conditions.Rest(characterID, req.Rest); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Character rest", "Character not found", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character rest", "Rest failed", nil)
+	}
+
+	return app.sendCharacterConditions(c, character, "Character rest", "Rest successful")
+}
+
+// sendCharacterConditions responds with the conditions of `character`
+// once they have changed, and lets its campaigns and the combats it is
+// in know.
+func (app *application) sendCharacterConditions(c echo.Context, character *models.Character, event, msg string) error {
+	app.publishCharacterUpdated(character.ID)
+	app.publishCharacterCombatsUpdated(character.ID)
+
+	conditions, err := app.conditions.GetAll(character.ID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, event, "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, event, msg, newCharacterConditionsResponse(character, *conditions))
+}
+
+// maxHPChange is the most damage or healing applied at once.
+const maxHPChange = 10000
+
+// characterVitalsResponse is the hit point and death save state of a
+// character.
+type characterVitalsResponse struct {
+	HPCurrent          int                  `json:"hp_current"`
+	HPMax              int                  `json:"hp_max"`
+	DeathSaveSuccesses int                  `json:"death_save_successes"`
+	DeathSaveFailures  int                  `json:"death_save_failures"`
+	LifeState          models.LifeStateType `json:"life_state"`
+	Roll               *int                 `json:"roll,omitempty"` // The d20 of a death save
+}
+
+func newCharacterVitalsResponse(character *models.Character, roll *int) characterVitalsResponse {
+	return characterVitalsResponse{
+		HPCurrent:          character.HPCurrent,
+		HPMax:              character.HPMax,
+		DeathSaveSuccesses: character.DeathSaveSuccesses,
+		DeathSaveFailures:  character.DeathSaveFailures,
+		LifeState:          character.LifeState(),
+		Roll:               roll,
+	}
+}
+
+// Applies damage and healing to a character outside of combat. A
+// character dropping to 0 hit points starts dying, and healing a dying
+// character brings it back and resets its death saves.
+func (app *application) adjustCharacterHP(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character hit points", "Could not process request", nil)
+	}
+
+	req := struct {
+		Damage  int `json:"damage"`
+		Healing int `json:"healing"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character hit points", "Could not process request", nil)
+	}
+
+	switch {
+	case req.Damage < 0 || req.Healing < 0:
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character hit points", "Damage and healing cannot be negative", nil)
+	case req.Damage > maxHPChange || req.Healing > maxHPChange:
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character hit points",
+			"Damage and healing must be at most "+strconv.Itoa(maxHPChange), nil)
+	}
+
+	if _, err := app.authorizeCharacterKeeper(c, characterID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Character hit points", "Character not found", nil)
+	}
+
+	character, err := app.hitPoints.AdjustHP(characterID, req.Damage, req.Healing)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrCharacterDead) {
+			return sendJSONResponse(c, http.StatusConflict, "Character hit points", "Character is dead", nil)
+		}
+		return sendJSONResponse(c, authorizationStatus(err), "Character hit points", "Update failed", nil)
+	}
+	app.publishCharacterUpdated(characterID)
+
+	return sendJSONResponse(c, http.StatusOK, "Character hit points", "Update successful", newCharacterVitalsResponse(character, nil))
+}
+
+// Records a death save for a dying character. The d20 is rolled by the
+// server unless a roll made at the table is given.
+func (app *application) rollDeathSave(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Death save", "Could not process request", nil)
+	}
+
+	req := struct {
+		Roll *int `json:"roll"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Death save", "Could not process request", nil)
+	}
+	if req.Roll != nil && (*req.Roll < 1 || *req.Roll > 20) {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Death save", "Roll must be between 1 and 20", nil)
+	}
+
+	if _, err := app.authorizeCharacterKeeper(c, characterID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Death save", "Character not found", nil)
+	}
+
+	if req.Roll == nil {
+		roll, err := dice.Roll(20)
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Death save", "Roll failed", nil)
+		}
+		req.Roll = &roll
+	}
+
+	character, err := app.hitPoints.DeathSave(characterID, *req.Roll)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNotDying) {
+			return sendJSONResponse(c, http.StatusConflict, "Death save", "Only dying characters roll death saves", nil)
+		}
+		return sendJSONResponse(c, authorizationStatus(err), "Death save", "Roll failed", nil)
+	}
+	app.publishCharacterUpdated(characterID)
+
+	return sendJSONResponse(c, http.StatusOK, "Death save", "Roll successful", newCharacterVitalsResponse(character, req.Roll))
+}
+
+// Stabilizes a dying character, such as with a Medicine check or a
+// healer's kit, so that it stops rolling death saves.
+func (app *application) stabilizeCharacter(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character stabilization", "Could not process request", nil)
+	}
+
+	if _, err := app.authorizeCharacterKeeper(c, characterID); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Character stabilization", "Character not found", nil)
+	}
+
+	character, err := app.hitPoints.Stabilize(characterID)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNotDying) {
+			return sendJSONResponse(c, http.StatusConflict, "Character stabilization", "Only dying characters can be stabilized", nil)
+		}
+		return sendJSONResponse(c, authorizationStatus(err), "Character stabilization", "Stabilization failed", nil)
+	}
+	app.publishCharacterUpdated(characterID)
+
+	return sendJSONResponse(c, http.StatusOK, "Character stabilization", "Stabilization successful", newCharacterVitalsResponse(character, nil))
+}
+
+// characterCreationRequest is a character along with the abilities
+// chosen for the free increases of its race.
+type characterCreationRequest struct {
+	models.Character
+	ChosenAbilityBonuses []models.AbilityType `json:"chosen_ability_bonuses"`
+}
+
+// validateAbilityMethod checks the ability scores of `character` against
+// its generation method. Rolled scores must be assigned from `roll`. A
+// message describing the first problem is returned if they do not match.
+func validateAbilityMethod(character *models.Character, roll *models.AbilityRoll) string {
+	scores := character.AbilityScores()
+
+	switch character.AbilityMethod {
+	case models.AbilityMethodPointBuy:
+		spent := 0
+		for _, score := range scores {
+			cost, ok := models.PointBuyCosts[score]
+			if !ok {
+				return fmt.Sprintf("Point-buy scores must be between %d and %d", models.PointBuyMinScore, models.PointBuyMaxScore)
+			}
+			spent += cost
+		}
+		if spent > models.PointBuyBudget {
+			return fmt.Sprintf("Point-buy scores cost %d points, but only %d may be spent", spent, models.PointBuyBudget)
+		}
+	case models.AbilityMethodStandardArray:
+		if !isPermutation(scores, models.StandardArray) {
+			return "Standard array scores must be 15, 14, 13, 12, 10 and 8, each assigned once"
+		}
+	case models.AbilityMethodRolled:
+		if !isPermutation(scores, roll.Scores) {
+			return "Rolled scores must be those of the roll, each assigned once"
+		}
+	}
+
+	return ""
+}
+
+// isPermutation reports whether `a` holds the same numbers as `b`, in
+// any order.
+func isPermutation(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	counts := map[int]int{}
+	for _, n := range b {
+		counts[n]++
+	}
+	for _, n := range a {
+		if counts[n] == 0 {
+			return false
+		}
+		counts[n]--
+	}
+	return true
+}
+
+// applyRacialBonuses adds the ability score increases of the race of
+// `character`, along with a +1 to each ability of `chosen` for the races
+// which let players pick some. A message describing the first problem is
+// returned if the choices do not fit the race.
+func (app *application) applyRacialBonuses(character *models.Character, username string, chosen []models.AbilityType) (string, error) {
+	race, err := app.registry.FindVisible(username, models.RegistryRace, string(character.Race), nil)
+	if err != nil {
+		return "", err
+	}
+
+	bonuses, err := race.RacialAbilityBonuses()
+	if err != nil {
+		return "", err
+	}
+
+	choices := 0
+	if race.AbilityBonusChoices != nil {
+		choices = *race.AbilityBonusChoices
+	}
+	if len(chosen) != choices {
+		return fmt.Sprintf("%s must choose %d abilities to increase", race.Name, choices), nil
+	}
+	for _, ability := range chosen {
+		if _, ok := bonuses[ability]; ok {
+			return "Each ability can only be increased once by the race", nil
+		}
+		bonuses[ability] = 1
+	}
+
+	for ability, bonus := range bonuses {
+		character.SetAbilityScore(ability, character.AbilityScore(ability)+bonus)
+	}
+	for _, score := range character.AbilityScores() {
+		if score < 1 || score > 30 {
+			return "Ability scores must be between 1 and 30 after racial bonuses", nil
+		}
+	}
+
+	return "", nil
+}
+
+// Rolls six ability scores for the requestor, each the sum of the three
+// highest of four d6. Every die is kept so that characters created from
+// the roll can be audited.
+func (app *application) createAbilityRoll(c echo.Context) error {
+	username := getUsernameFromToken(c)
+	if strings.TrimSpace(username) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Ability roll", "Roll failed", nil)
+	}
+
+	expr, err := dice.Parse(models.AbilityRollExpression)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Ability roll", "Roll failed", nil)
+	}
+
+	scores := make([]int, len(models.Abilities))
+	results := make([]dice.Result, len(models.Abilities))
+	for i := range scores {
+		if results[i], err = expr.Roll(); err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Ability roll", "Roll failed", nil)
+		}
+		scores[i] = results[i].Total
+	}
+
+	rolls, err := json.Marshal(results)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Ability roll", "Roll failed", nil)
+	}
+
+	id, err := app.abilityRolls.Insert(username, scores, rolls)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Ability roll", "Roll failed", nil)
+	}
+
+	created, err := app.abilityRolls.Get(id)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Ability roll", "Roll failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusCreated, "Ability roll", "Roll successful", created)
+}
+
+// Retrieves the ability rolls of the requestor, most recent first.
+func (app *application) getAbilityRolls(c echo.Context) error {
+	username := getUsernameFromToken(c)
+	if strings.TrimSpace(username) == "" {
+		return sendJSONResponse(c, http.StatusUnauthorized, "Ability roll retrieval", "Retrieval failed", nil)
+	}
+
+	limit, offset := parsePagination(c)
+	rolls, err := app.abilityRolls.GetAllForPlayer(username, limit, offset)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Ability roll retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Ability roll retrieval", "Retrieval successful",
+		struct {
+			Rolls []models.AbilityRoll `json:"rolls"`
 		}{
-			spellsCount,
+			*rolls,
+		})
+}
+
+// characterLevelResponse describes the level of a character, whether its
+// experience allows it to gain another, and the levels it has gained.
+type characterLevelResponse struct {
+	Level            int              `json:"level"`
+	XPLevel          int              `json:"xp_level"`      // Level which the experience of the character allows
+	NextLevelXP      *int             `json:"next_level_xp"` // Not set at level 20
+	LevelUpAvailable bool             `json:"level_up_available"`
+	History          []models.LevelUp `json:"history"`
+}
+
+func newCharacterLevelResponse(character *models.Character, history []models.LevelUp) characterLevelResponse {
+	res := characterLevelResponse{
+		Level:   character.Level,
+		XPLevel: models.LevelForXP(character.XPPoints),
+		History: history,
+	}
+	if character.Level < len(models.XPThresholds) {
+		res.NextLevelXP = &models.XPThresholds[character.Level]
+	}
+	res.LevelUpAvailable = res.XPLevel > character.Level && !character.Dead
+	return res
+}
+
+// Retrieves the level of a character along with the levels it has
+// gained and the choices made for each.
+func (app *application) getCharacterLevel(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Character level retrieval", "Could not process request", nil)
+	}
+
+	character, err := app.characters.Get(characterID)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Character level retrieval", "Retrieval failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character level retrieval", "Retrieval failed", nil)
+	}
+
+	history, err := app.levels.GetHistory(characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Character level retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Character level retrieval", "Retrieval successful",
+		newCharacterLevelResponse(character, *history))
+}
+
+type levelUpRequest struct {
+	Class     string                     `json:"class"` // Defaults to the first class
+	HitPoints models.HitPointsMethodType `json:"hit_points"`
+	Subclass  string                     `json:"subclass"`
+
+	// At the levels which give one, either an Ability Score Improvement
+	// or a feat
+	AbilityScoreImprovement map[models.AbilityType]int `json:"ability_score_improvement"`
+	Feat                    string                     `json:"feat"`
+}
+
+// validateAbilityScoreImprovement checks that `increases` add up to an
+// Ability Score Improvement, either +2 to one ability or +1 to two, none
+// of which goes above models.MaxImprovedAbilityScore. A message
+// describing the first problem is returned otherwise.
+func validateAbilityScoreImprovement(character *models.Character, increases map[models.AbilityType]int) string {
+	total := 0
+	for ability, increase := range increases {
+		if !isAbility(ability) {
+			return "Ability Score Improvements must be for abilities"
+		}
+		if increase < 1 || increase > models.AbilityScoreImprovementPoints {
+			return "Ability Score Improvements give +2 to one ability or +1 to two"
+		}
+		if character.AbilityScore(ability)+increase > models.MaxImprovedAbilityScore {
+			return fmt.Sprintf("An Ability Score Improvement cannot raise %s above %d", ability, models.MaxImprovedAbilityScore)
+		}
+		total += increase
+	}
+	if total != models.AbilityScoreImprovementPoints {
+		return "Ability Score Improvements give +2 to one ability or +1 to two"
+	}
+	return ""
+}
+
+// Gains a level for a character owned by the requestor once its
+// experience allows it. The level goes to the first class unless another
+// is given, which the character must meet the multiclassing prerequisites
+// of if it is new. Hit points are rolled on the class hit die or taken
+// as its average, plus the Constitution modifier. At the right class
+// levels, the requestor chooses either an Ability Score Improvement or a
+// feat from the catalog, and the subclass of a class which has none yet.
+// The features of the new level are granted along with it.
+func (app *application) levelUpCharacter(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Level-up", "Could not process request", nil)
+	}
+
+	var req levelUpRequest
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Level-up", "Could not process request", nil)
+	}
+
+	character, err := app.authorizeCharacterOwner(c, characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Level-up", "Character not found", nil)
+	}
+
+	if character.Dead {
+		return sendJSONResponse(c, http.StatusConflict, "Level-up", "Dead characters cannot gain levels", nil)
+	}
+	if models.LevelForXP(character.XPPoints) <= character.Level {
+		if character.Level >= len(models.XPThresholds) {
+			return sendJSONResponse(c, http.StatusConflict, "Level-up", "Character is already at the highest level", nil)
+		}
+		return sendJSONResponse(c, http.StatusConflict, "Level-up",
+			fmt.Sprintf("Character needs %d XP to gain a level", models.XPThresholds[character.Level]), nil)
+	}
+
+	classes, err := app.characterClasses.GetAll(characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Level-up", "Level-up failed", nil)
+	}
+
+	className := strings.TrimSpace(req.Class)
+	if className == "" {
+		className = string(character.Class)
+	}
+	class, err := app.registry.FindVisible(character.PlayerUsername, models.RegistryClass, className, nil)
+	if errors.Is(err, models.ErrNoRecord) {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Level-up", "Class "+strconv.Quote(className)+" is not known", nil)
+	}
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Level-up", "Level-up failed", nil)
+	}
+
+	levelUp := models.LevelUp{
+		CharacterID:     characterID,
+		Level:           character.Level + 1,
+		Class:           models.ClassType(class.Name),
+		ClassLevel:      1,
+		HitPointsMethod: req.HitPoints,
+	}
+
+	var current *models.CharacterClass
+	for i := range *classes {
+		if (*classes)[i].Class == levelUp.Class {
+			current = &(*classes)[i]
+		}
+	}
+	if current != nil {
+		levelUp.ClassLevel = current.Levels + 1
+	} else {
+		// Multiclassing needs the prerequisites of the new class and of
+		// every class the character already has
+		for _, other := range append(*classes, models.CharacterClass{Class: levelUp.Class}) {
+			if !models.MeetsMulticlassPrerequisites(character, other.Class) {
+				return sendJSONResponse(c, http.StatusUnprocessableEntity, "Level-up", multiclassPrerequisiteMessage(other.Class), nil)
+			}
+		}
+	}
+
+	subclassLevel := models.SubclassLevel(levelUp.Class)
+	needsSubclass := (current == nil || current.Subclass == nil) && levelUp.ClassLevel >= subclassLevel
+	switch subclassName := strings.TrimSpace(req.Subclass); {
+	case needsSubclass && subclassName == "":
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Level-up",
+			fmt.Sprintf("%s chooses a subclass at level %d", class.Name, subclassLevel), nil)
+	case !needsSubclass && subclassName != "" && current != nil && current.Subclass != nil:
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Level-up", class.Name+" already has a subclass", nil)
+	case !needsSubclass && subclassName != "":
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Level-up",
+			fmt.Sprintf("%s cannot choose a subclass before level %d", class.Name, subclassLevel), nil)
+	case needsSubclass:
+		subclass, err := app.registry.FindVisible(character.PlayerUsername, models.RegistrySubclass, subclassName, &class.ID)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Level-up",
+				"Subclass "+strconv.Quote(subclassName)+" is not a subclass of "+class.Name, nil)
+		}
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Level-up", "Level-up failed", nil)
+		}
+		name := models.ClassAttributeType(subclass.Name)
+		levelUp.Subclass = &name
+	}
+
+	feat := strings.TrimSpace(req.Feat)
+	if !models.GrantsAbilityScoreImprovement(levelUp.Class, levelUp.ClassLevel) {
+		if len(req.AbilityScoreImprovement) > 0 || feat != "" {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Level-up",
+				fmt.Sprintf("Level %d of %s gives no Ability Score Improvement or feat", levelUp.ClassLevel, class.Name), nil)
+		}
+	} else {
+		switch {
+		case (len(req.AbilityScoreImprovement) > 0) == (feat != ""):
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Level-up",
+				fmt.Sprintf("Level %d of %s gives either an Ability Score Improvement or a feat", levelUp.ClassLevel, class.Name), nil)
+		case feat != "":
+			// Feats come from the catalog, and can only be taken once
+			stored, err := app.features.FindFeat(feat)
+			if errors.Is(err, models.ErrNoRecord) {
+				return sendJSONResponse(c, http.StatusUnprocessableEntity, "Level-up", "Feat "+strconv.Quote(feat)+" is not known", nil)
+			}
+			if err != nil {
+				log.Error(err)
+				return sendJSONResponse(c, http.StatusInternalServerError, "Level-up", "Level-up failed", nil)
+			}
+			levelUp.Feat = &stored.Name
+		default:
+			if msg := validateAbilityScoreImprovement(character, req.AbilityScoreImprovement); msg != "" {
+				return sendJSONResponse(c, http.StatusUnprocessableEntity, "Level-up", msg, nil)
+			}
+		}
+	}
+
+	levelUp.AbilityIncreases = json.RawMessage("{}")
+	if len(req.AbilityScoreImprovement) > 0 {
+		if levelUp.AbilityIncreases, err = json.Marshal(req.AbilityScoreImprovement); err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Level-up", "Level-up failed", nil)
+		}
+	}
+
+	if levelUp.HitPointsMethod == "" {
+		levelUp.HitPointsMethod = models.HitPointsAverage
+	}
+	if levelUp.HitPointsMethod == models.HitPointsRoll {
+		roll, err := dice.Roll(*class.HitDie)
+		if err != nil {
+			log.Error(err)
+			return sendJSONResponse(c, http.StatusInternalServerError, "Level-up", "Level-up failed", nil)
+		}
+		levelUp.HitDieRoll = &roll
+		levelUp.HPGained = roll
+	} else {
+		levelUp.HPGained = models.AverageHitDie(*class.HitDie)
+	}
+	levelUp.HPGained += models.AbilityModifier(character.Constitution)
+	if levelUp.HPGained < 1 {
+		levelUp.HPGained = 1
+	}
+
+	updated, gained, err := app.levels.LevelUp(levelUp)
+	if err != nil {
+		log.Error(err)
+		switch {
+		case errors.Is(err, models.ErrCharacterDead):
+			return sendJSONResponse(c, http.StatusConflict, "Level-up", "Dead characters cannot gain levels", nil)
+		case errors.Is(err, models.ErrNoLevelAvailable), errors.Is(err, models.ErrLevelChanged):
+			return sendJSONResponse(c, http.StatusConflict, "Level-up", "Character has changed, please try again", nil)
+		case errors.Is(err, models.ErrDuplicateCharacterFeature):
+			return sendJSONResponse(c, http.StatusConflict, "Level-up", "Character already has "+*levelUp.Feat, nil)
+		}
+		return sendJSONResponse(c, authorizationStatus(err), "Level-up", "Level-up failed", nil)
+	}
+	app.publishCharacterUpdated(characterID)
+
+	classes, err = app.characterClasses.GetAll(characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Level-up", "Level-up failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Level-up", fmt.Sprintf("Reached level %d", updated.Level),
+		struct {
+			LevelUp   *models.LevelUp   `json:"level_up"`
+			Character *models.Character `json:"character"`
+			characterClassesResponse
+		}{
+			gained,
+			updated,
+			newCharacterClassesResponse(*classes),
+		})
+}
+
+// characterFeaturesResponse lists the features of a character, with the
+// uses remaining of the limited ones.
+type characterFeaturesResponse struct {
+	Features []models.CharacterFeature `json:"features"`
+}
+
+// characterFeatures retrieves the features of `character` and works out
+// the uses of the limited ones from its levels.
+func (app *application) characterFeatures(character *models.Character) (characterFeaturesResponse, error) {
+	features, err := app.features.GetAllForCharacter(character.ID)
+	if err != nil {
+		return characterFeaturesResponse{}, err
+	}
+
+	classes, err := app.characterClasses.GetAll(character.ID)
+	if err != nil {
+		return characterFeaturesResponse{}, err
+	}
+
+	for i := range *features {
+		if err := (*features)[i].SetUses(character, *classes); err != nil {
+			return characterFeaturesResponse{}, err
+		}
+	}
+
+	return characterFeaturesResponse{*features}, nil
+}
+
+// Retrieves the catalog of racial traits, class and subclass features
+// and feats. The `source`, `race` and `class` query parameters narrow it
+// down.
+func (app *application) getFeatures(c echo.Context) error {
+	var source *models.FeatureSourceType
+	if s := strings.TrimSpace(c.QueryParam("source")); s != "" {
+		switch parsed := models.FeatureSourceType(s); parsed {
+		case models.FeatureSourceRace, models.FeatureSourceClass, models.FeatureSourceSubclass, models.FeatureSourceFeat:
+			source = &parsed
+		default:
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Feature retrieval",
+				"Source must be race, class, subclass or feat", nil)
+		}
+	}
+
+	features, err := app.features.GetAll(source, strings.TrimSpace(c.QueryParam("race")), strings.TrimSpace(c.QueryParam("class")))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Feature retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Feature retrieval", "Retrieval successful",
+		struct {
+			Features []models.Feature `json:"features"`
+		}{
+			*features,
 		})
 }
+
+// Retrieves the features of a character, with the uses remaining of the
+// limited ones.
+func (app *application) getCharacterFeatures(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Feature retrieval", "Could not process request", nil)
+	}
+
+	character, err := app.characters.Get(characterID)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Feature retrieval", "Retrieval failed", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Feature retrieval", "Retrieval failed", nil)
+	}
+
+	features, err := app.characterFeatures(character)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Feature retrieval", "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, "Feature retrieval", "Retrieval successful", features)
+}
+
+// Gives a character owned by the requestor a feat from the catalog.
+// Racial traits and class features are granted on their own as the
+// character gains levels.
+func (app *application) addCharacterFeat(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Feat addition", "Could not process request", nil)
+	}
+
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Feat addition", "Could not process request", nil)
+	}
+
+	character, err := app.authorizeCharacterOwner(c, characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Feat addition", "Character not found", nil)
+	}
+
+	feat, err := app.features.FindFeat(strings.TrimSpace(req.Name))
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusUnprocessableEntity, "Feat addition", "Feat "+strconv.Quote(req.Name)+" is not known", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Feat addition", "Addition failed", nil)
+	}
+
+	if err := app.features.AddFeat(characterID, feat.Name); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrDuplicateCharacterFeature) {
+			return sendJSONResponse(c, http.StatusConflict, "Feat addition", "Character already has "+feat.Name, nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Feat addition", "Addition failed", nil)
+	}
+
+	return app.sendCharacterFeatures(c, character, "Feat addition", "Addition successful")
+}
+
+// Takes a feat away from a character owned by the requestor.
+func (app *application) removeCharacterFeat(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Feat removal", "Could not process request", nil)
+	}
+
+	featureID, err := strconv.Atoi(c.Param("featureID"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Feat removal", "Could not process request", nil)
+	}
+
+	character, err := app.authorizeCharacterOwner(c, characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Feat removal", "Character not found", nil)
+	}
+
+	if err := app.features.RemoveFeat(characterID, featureID); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Feat removal", "Character does not have this feat", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Feat removal", "Removal failed", nil)
+	}
+
+	return app.sendCharacterFeatures(c, character, "Feat removal", "Removal successful")
+}
+
+// Spends uses of a limited feature of a character, one unless `uses` is
+// given. Negative uses are regained, such as when a use was recorded by
+// mistake. Uses come back with the rest the feature recharges with.
+func (app *application) useCharacterFeature(c echo.Context) error {
+	characterID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Feature use", "Could not process request", nil)
+	}
+
+	featureID, err := strconv.Atoi(c.Param("featureID"))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Feature use", "Could not process request", nil)
+	}
+
+	var req struct {
+		Uses *int `json:"uses"`
+	}
+	if err := c.Bind(&req); err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Feature use", "Could not process request", nil)
+	}
+	uses := 1
+	if req.Uses != nil {
+		uses = *req.Uses
+	}
+	if uses == 0 {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Feature use", "Uses cannot be 0", nil)
+	}
+
+	character, err := app.authorizeCharacterKeeper(c, characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, authorizationStatus(err), "Feature use", "Character not found", nil)
+	}
+
+	feature, err := app.features.GetForCharacter(characterID, featureID)
+	if err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoRecord) {
+			return sendJSONResponse(c, http.StatusNotFound, "Feature use", "Character does not have this feature", nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Feature use", "Use failed", nil)
+	}
+	if !feature.IsLimited() {
+		return sendJSONResponse(c, http.StatusUnprocessableEntity, "Feature use", feature.Name+" can be used without limit", nil)
+	}
+
+	classes, err := app.characterClasses.GetAll(characterID)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Feature use", "Use failed", nil)
+	}
+	maxUses, err := feature.Feature.MaxUses(character, feature.SourceLevel(character, *classes))
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, "Feature use", "Use failed", nil)
+	}
+
+	if _, err := app.features.Use(characterID, featureID, uses, maxUses); err != nil {
+		log.Error(err)
+		if errors.Is(err, models.ErrNoUsesRemaining) {
+			return sendJSONResponse(c, http.StatusConflict, "Feature use",
+				fmt.Sprintf("%s does not have %d uses remaining", feature.Name, uses), nil)
+		}
+		return sendJSONResponse(c, http.StatusInternalServerError, "Feature use", "Use failed", nil)
+	}
+
+	return app.sendCharacterFeatures(c, character, "Feature use", "Use successful")
+}
+
+// sendCharacterFeatures responds with the features of `character` once
+// they have changed, and lets its campaigns know.
+func (app *application) sendCharacterFeatures(c echo.Context, character *models.Character, event, msg string) error {
+	app.publishCharacterUpdated(character.ID)
+
+	features, err := app.characterFeatures(character)
+	if err != nil {
+		log.Error(err)
+		return sendJSONResponse(c, http.StatusInternalServerError, event, "Retrieval failed", nil)
+	}
+
+	return sendJSONResponse(c, http.StatusOK, event, msg, features)
+}
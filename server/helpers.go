@@ -1,9 +1,18 @@
 package main
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/labstack/echo/v4"
 )
 
+// Pagination defaults used by endpoints which return long lists.
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
 type standardResponse struct {
 	Event      string      `json:"event"`
 	URI        string      `json:"request_uri"`
@@ -28,3 +37,56 @@ func sendJSONResponse(c echo.Context, statusCode int, event, message string, dat
 
 	return c.JSON(statusCode, resp)
 }
+
+// parsePagination reads the `limit` and `offset` query parameters,
+// falling back to sensible defaults when they are missing or invalid.
+func parsePagination(c echo.Context) (int, int) {
+	limit, err := strconv.Atoi(c.QueryParam("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	offset, err := strconv.Atoi(c.QueryParam("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	return limit, offset
+}
+
+// etagMatches reports whether the If-None-Match header `header` matches
+// `etag`, following RFC 7232: the header is a comma-separated list of
+// entity tags compared weakly, so a W/ prefix is ignored, or "*", which
+// matches any current representation. A malformed list matches nothing.
+func etagMatches(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for header != "" {
+		header = strings.TrimLeft(header, " \t,")
+		if header == "" {
+			break
+		}
+
+		candidate := strings.TrimPrefix(header, "W/")
+		if !strings.HasPrefix(candidate, `"`) {
+			return false
+		}
+		end := strings.IndexByte(candidate[1:], '"')
+		if end < 0 {
+			return false
+		}
+		if candidate[:end+2] == etag {
+			return true
+		}
+		header = candidate[end+2:]
+	}
+
+	return false
+}
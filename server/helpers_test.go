@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestETagMatches(t *testing.T) {
+	const etag = `"meta-0123abcd"`
+
+	tests := []struct {
+		name   string
+		header string
+		want   bool
+	}{
+		{"missing", "", false},
+		{"exact", `"meta-0123abcd"`, true},
+		{"weak", `W/"meta-0123abcd"`, true},
+		{"other", `"meta-ffffffff"`, false},
+		{"unquoted", `meta-0123abcd`, false},
+		{"any", "*", true},
+		{"any with spaces", " * ", true},
+		{"list", `"a", "meta-0123abcd"`, true},
+		{"list without spaces", `"a","b",W/"meta-0123abcd"`, true},
+		{"list without match", `"a", W/"b"`, false},
+		{"comma in tag", `"a,b", "meta-0123abcd"`, true},
+		{"prefix of tag", `"meta-0123"`, false},
+		{"malformed list", `"a", meta-0123abcd`, false},
+		{"unterminated", `"meta-0123abcd`, false},
+		{"trailing comma", `"meta-0123abcd",`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagMatches(tt.header, etag); got != tt.want {
+				t.Errorf("etagMatches(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+// Package markdown renders a small subset of Markdown to HTML which is
+// safe to display in the browser. Raw HTML in the input is always
+// escaped and links may only point to http, https and mailto URLs or to
+// paths on the same site.
+//
+// Supported syntax: ATX headings, paragraphs, emphasis, strong text,
+// inline code, fenced code blocks, links, block quotes, flat ordered and
+// unordered lists and horizontal rules.
+package markdown
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	headingPattern     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	unorderedPattern   = regexp.MustCompile(`^\s{0,3}[-*+]\s+(.*)$`)
+	orderedPattern     = regexp.MustCompile(`^\s{0,3}\d{1,9}[.)]\s+(.*)$`)
+	ruleCharacters     = "-*_"
+	allowedLinkSchemes = map[string]bool{"http": true, "https": true, "mailto": true}
+)
+
+// Render converts `src` to HTML.
+func Render(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	lines := strings.Split(src, "\n")
+
+	var b strings.Builder
+	renderBlocks(&b, lines)
+	return b.String()
+}
+
+func renderBlocks(b *strings.Builder, lines []string) {
+	var paragraph []string
+
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>")
+			b.WriteString(renderInline(strings.Join(paragraph, "\n")))
+			b.WriteString("</p>\n")
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			flushParagraph()
+
+		case strings.HasPrefix(trimmed, "```"):
+			flushParagraph()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code>")
+			b.WriteString(html.EscapeString(strings.Join(code, "\n")))
+			b.WriteString("</code></pre>\n")
+
+		case headingPattern.MatchString(trimmed):
+			flushParagraph()
+			m := headingPattern.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(m[1])))
+			b.WriteString("<h" + level + ">")
+			b.WriteString(renderInline(m[2]))
+			b.WriteString("</h" + level + ">\n")
+
+		case isRule(trimmed):
+			flushParagraph()
+			b.WriteString("<hr>\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flushParagraph()
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				q := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(q, " "))
+			}
+			i--
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quoted)
+			b.WriteString("</blockquote>\n")
+
+		case unorderedPattern.MatchString(line) || orderedPattern.MatchString(line):
+			flushParagraph()
+			pattern, tag := unorderedPattern, "ul"
+			if !unorderedPattern.MatchString(line) {
+				pattern, tag = orderedPattern, "ol"
+			}
+			b.WriteString("<" + tag + ">\n")
+			for ; i < len(lines) && pattern.MatchString(lines[i]); i++ {
+				b.WriteString("<li>")
+				b.WriteString(renderInline(pattern.FindStringSubmatch(lines[i])[1]))
+				b.WriteString("</li>\n")
+			}
+			i--
+			b.WriteString("</" + tag + ">\n")
+
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+
+	flushParagraph()
+}
+
+// isRule reports whether `line` is a horizontal rule: three or more of
+// the same rule character, optionally separated by spaces.
+func isRule(line string) bool {
+	compact := strings.ReplaceAll(line, " ", "")
+	if len(compact) < 3 || !strings.ContainsAny(compact[:1], ruleCharacters) {
+		return false
+	}
+	return strings.Count(compact, compact[:1]) == len(compact)
+}
+
+// renderInline formats the inline elements of `s`, escaping everything
+// else.
+func renderInline(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_[]()#+-.!>", s[i+1]) >= 0:
+			writeEscapedByte(&b, s[i+1])
+			i += 2
+			continue
+
+		case c == '\n':
+			b.WriteString("<br>\n")
+			i++
+			continue
+
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end >= 0 {
+				b.WriteString("<code>")
+				b.WriteString(html.EscapeString(s[i+1 : i+1+end]))
+				b.WriteString("</code>")
+				i += end + 2
+				continue
+			}
+
+		case (c == '*' || c == '_') && i+1 < len(s) && s[i+1] == c:
+			delim := s[i : i+2]
+			if end := strings.Index(s[i+2:], delim); end > 0 && canOpen(s, i) {
+				b.WriteString("<strong>")
+				b.WriteString(renderInline(s[i+2 : i+2+end]))
+				b.WriteString("</strong>")
+				i += end + 4
+				continue
+			}
+
+		case c == '*' || c == '_':
+			if end := strings.IndexByte(s[i+1:], c); end > 0 && canOpen(s, i) {
+				b.WriteString("<em>")
+				b.WriteString(renderInline(s[i+1 : i+1+end]))
+				b.WriteString("</em>")
+				i += end + 2
+				continue
+			}
+
+		case c == '[':
+			if text, target, n, ok := parseLink(s[i:]); ok {
+				if href, safe := safeURL(target); safe {
+					b.WriteString(`<a href="` + html.EscapeString(href) + `" rel="nofollow noopener noreferrer">`)
+					b.WriteString(renderInline(text))
+					b.WriteString("</a>")
+				} else {
+					b.WriteString(renderInline(text))
+				}
+				i += n
+				continue
+			}
+		}
+
+		writeEscapedByte(&b, c)
+		i++
+	}
+
+	return b.String()
+}
+
+// canOpen reports whether the emphasis delimiter at `s[i]` may open
+// emphasis. Underscores inside words, as in snake_case, do not.
+func canOpen(s string, i int) bool {
+	if s[i] != '_' || i == 0 {
+		return true
+	}
+	prev := s[i-1]
+	isWordByte := prev >= 'a' && prev <= 'z' || prev >= 'A' && prev <= 'Z' || prev >= '0' && prev <= '9'
+	return !isWordByte
+}
+
+// parseLink parses a `[text](target)` link at the start of `s` and
+// returns its parts along with the number of bytes consumed. Neither the
+// text nor the target may contain an opening bracket or a line break, so
+// the scan never reaches past the next link and rendering stays linear
+// in the length of the input.
+func parseLink(s string) (string, string, int, bool) {
+	closeText := -1
+	for j := 1; j+1 < len(s) && s[j] != '[' && s[j] != '\n'; j++ {
+		if s[j] == ']' && s[j+1] == '(' {
+			closeText = j
+			break
+		}
+	}
+	if closeText < 0 {
+		return "", "", 0, false
+	}
+
+	// The target ends at the first unbalanced closing parenthesis.
+	closeTarget, depth := -1, 0
+	for j, r := range s[closeText+2:] {
+		if r == '\n' || r == '[' {
+			break
+		} else if r == '(' {
+			depth++
+		} else if r == ')' {
+			if depth == 0 {
+				closeTarget = j
+				break
+			}
+			depth--
+		}
+	}
+	if closeTarget < 0 {
+		return "", "", 0, false
+	}
+
+	text := s[1:closeText]
+	target := strings.TrimSpace(s[closeText+2 : closeText+2+closeTarget])
+	return text, target, closeText + 3 + closeTarget, true
+}
+
+// safeURL reports whether `target` may be used as a link. Absolute URLs
+// must use an allowed scheme, and relative URLs must be paths or
+// fragments on the same site.
+func safeURL(target string) (string, bool) {
+	u, err := url.Parse(target)
+	if err != nil || target == "" {
+		return "", false
+	}
+
+	if u.Scheme != "" {
+		return u.String(), allowedLinkSchemes[strings.ToLower(u.Scheme)]
+	}
+
+	sameSite := (strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//")) ||
+		strings.HasPrefix(target, "#")
+	return u.String(), sameSite
+}
+
+func writeEscapedByte(b *strings.Builder, c byte) {
+	switch c {
+	case '<':
+		b.WriteString("&lt;")
+	case '>':
+		b.WriteString("&gt;")
+	case '&':
+		b.WriteString("&amp;")
+	case '"':
+		b.WriteString("&#34;")
+	case '\'':
+		b.WriteString("&#39;")
+	default:
+		b.WriteByte(c)
+	}
+}
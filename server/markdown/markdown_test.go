@@ -0,0 +1,103 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+const linkAttributes = `" rel="nofollow noopener noreferrer">`
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"paragraph", "Hello\nworld", "<p>Hello<br>\nworld</p>\n"},
+		{"heading", "## The *Sunless* Citadel ##", "<h2>The <em>Sunless</em> Citadel</h2>\n"},
+		{"rule", "- - -", "<hr>\n"},
+		{"unordered list", "- one\n* two", "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n"},
+		{"ordered list", "1. one\n2) two", "<ol>\n<li>one</li>\n<li>two</li>\n</ol>\n"},
+		{"block quote", "> quoted\n> # heading", "<blockquote>\n<p>quoted</p>\n<h1>heading</h1>\n</blockquote>\n"},
+		{"code block", "```\nx := <-ch\n```", "<pre><code>x := &lt;-ch</code></pre>\n"},
+		{"inline code", "`a * b`", "<p><code>a * b</code></p>\n"},
+		{"snake case", "snake_case_name", "<p>snake_case_name</p>\n"},
+		{"backslash escape", `\*not emphasis\*`, "<p>*not emphasis*</p>\n"},
+		{"link", "[the map](https://example.com/map)", `<p><a href="https://example.com/map` + linkAttributes + "the map</a></p>\n"},
+		{"relative link", "[notes](/campaign/1#notes)", `<p><a href="/campaign/1#notes` + linkAttributes + "notes</a></p>\n"},
+		{"mailto link", "[DM](mailto:dm@example.com)", `<p><a href="mailto:dm@example.com` + linkAttributes + "DM</a></p>\n"},
+		{"parenthesis in target", "[a](/b(c))", `<p><a href="/b(c)` + linkAttributes + "a</a></p>\n"},
+		{"bracket before link", "[a] and [b](/c)", `<p>[a] and <a href="/c` + linkAttributes + "b</a></p>\n"},
+		{"unclosed link", "[a](/b", "<p>[a](/b</p>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.src); got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderSanitizes(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"script tag", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"event handler", `<img src=x onerror="alert(1)">`, "<p>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</p>\n"},
+		{"entities", `&amp; 'a'`, "<p>&amp;amp; &#39;a&#39;</p>\n"},
+		{"script in code block", "```\n</code><script>\n```", "<pre><code>&lt;/code&gt;&lt;script&gt;</code></pre>\n"},
+		{"script in inline code", "`</code><script>`", "<p><code>&lt;/code&gt;&lt;script&gt;</code></p>\n"},
+
+		{"javascript URL", "[x](javascript:alert(1))", "<p>x</p>\n"},
+		{"mixed case javascript URL", "[x](JaVaScRiPt:alert(1))", "<p>x</p>\n"},
+		{"padded javascript URL", "[x](  javascript:alert(1)  )", "<p>x</p>\n"},
+		{"javascript URL with a tab", "[x](java\tscript:alert(1))", "<p>x</p>\n"},
+		{"vbscript URL", "[x](vbscript:msgbox(1))", "<p>x</p>\n"},
+		{"data URL", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>\n"},
+		{"protocol-relative URL", "[x](//evil.example)", "<p>x</p>\n"},
+		{"relative path", "[x](evil.html)", "<p>x</p>\n"},
+
+		{"quote in relative target", `[x](/a" onmouseover="alert(1))`,
+			`<p><a href="/a%22%20onmouseover=%22alert%281%29` + linkAttributes + "x</a></p>\n"},
+		{"quote in absolute target", `[x](https://example.com/?q="><script>)`,
+			`<p><a href="https://example.com/?q=&#34;&gt;&lt;script&gt;` + linkAttributes + "x</a></p>\n"},
+		{"markup in link text", "[<img src=x onerror=alert(1)>](/ok)",
+			`<p><a href="/ok` + linkAttributes + "&lt;img src=x onerror=alert(1)&gt;</a></p>\n"},
+
+		{"markup in strong text", "**bold *em* <b>**", "<p><strong>bold <em>em</em> &lt;b&gt;</strong></p>\n"},
+		{"formatted link text", "[**x** `<y>`](/z)",
+			`<p><a href="/z` + linkAttributes + "<strong>x</strong> <code>&lt;y&gt;</code></a></p>\n"},
+		{"link in emphasis", "*see [x](javascript:alert(1))*", "<p><em>see x</em></p>\n"},
+		{"link in quoted list", "> - [x](javascript:alert(1))\n> - <i>",
+			"<blockquote>\n<ul>\n<li>x</li>\n<li>&lt;i&gt;</li>\n</ul>\n</blockquote>\n"},
+		{"markup in heading", "# <h1>title</h1>", "<h1>&lt;h1&gt;title&lt;/h1&gt;</h1>\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Render(tt.src); got != tt.want {
+				t.Errorf("Render(%q) = %q, want %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRenderUnclosedLinks guards against rendering time growing with the
+// square of the input when brackets are never closed. With a quadratic
+// scan some of these inputs take minutes to render.
+func TestRenderUnclosedLinks(t *testing.T) {
+	for _, src := range []string{
+		strings.Repeat("[", 200000),
+		strings.Repeat("[a](", 200000),
+		strings.Repeat("[a", 200000) + "](",
+		strings.Repeat("[a]", 200000) + "(",
+	} {
+		if got := Render(src); !strings.HasPrefix(got, "<p>[") {
+			t.Errorf("Render(%q...) = %q...", src[:8], got[:8])
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"crypto/sha256"
+	"draco/dice"
+	"draco/models"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// metaOption is a value of an enumeration along with its display label.
+type metaOption struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// metaClass is an official class along with its official subclasses.
+type metaClass struct {
+	metaOption
+	Subclasses []metaOption `json:"subclasses"`
+}
+
+type metaLevel struct {
+	Level int `json:"level"`
+	XP    int `json:"xp"`
+}
+
+type metaChallengeRating struct {
+	ChallengeRating string `json:"challenge_rating"`
+	XP              int    `json:"xp"`
+}
+
+type metaEncounterThresholds struct {
+	Level  int `json:"level"`
+	Easy   int `json:"easy"`
+	Medium int `json:"medium"`
+	Hard   int `json:"hard"`
+	Deadly int `json:"deadly"`
+}
+
+type metaAbilityModifier struct {
+	Score    int `json:"score"`
+	Modifier int `json:"modifier"`
+}
+
+// metadata lists the enumerations and rule tables of the server, so that
+// clients do not need to keep their own copies.
+type metadata struct {
+	Enums struct {
+		Races                 []metaOption `json:"races"`
+		Classes               []metaClass  `json:"classes"`
+		FightingStyles        []metaOption `json:"fighting_styles"`
+		Alignments            []metaOption `json:"alignments"`
+		Sexes                 []metaOption `json:"sexes"`
+		Abilities             []metaOption `json:"abilities"`
+		Skills                []metaOption `json:"skills"`
+		ProficiencyKinds      []metaOption `json:"proficiency_kinds"`
+		HitPointsMethods      []metaOption `json:"hit_points_methods"`
+		AbilityMethods        []metaOption `json:"ability_methods"`
+		ItemTypes             []metaOption `json:"item_types"`
+		Rarities              []metaOption `json:"rarities"`
+		MagicSchools          []metaOption `json:"magic_schools"`
+		Conditions            []metaOption `json:"conditions"`
+		Rests                 []metaOption `json:"rests"`
+		FeatureSources        []metaOption `json:"feature_sources"`
+		LifeStates            []metaOption `json:"life_states"`
+		RollModes             []metaOption `json:"roll_modes"`
+		CampaignStates        []metaOption `json:"campaign_states"`
+		RequestStatuses       []metaOption `json:"request_statuses"`
+		JournalVisibilities   []metaOption `json:"journal_visibilities"`
+		NPCKinds              []metaOption `json:"npc_kinds"`
+		EncounterDifficulties []metaOption `json:"encounter_difficulties"`
+		RegistryKinds         []metaOption `json:"registry_kinds"`
+		TokenScopes           []metaOption `json:"token_scopes"`
+		Roles                 []metaOption `json:"roles"`
+	} `json:"enums"`
+
+	Rules struct {
+		MaxXP                    int                                                     `json:"max_xp"`
+		Levels                   []metaLevel                                             `json:"levels"`
+		AbilityModifiers         []metaAbilityModifier                                   `json:"ability_modifiers"`
+		ChallengeRatings         []metaChallengeRating                                   `json:"challenge_ratings"`
+		EncounterThresholds      []metaEncounterThresholds                               `json:"encounter_thresholds"`
+		CampaignStateTransitions map[models.CampaignStateType][]models.CampaignStateType `json:"campaign_state_transitions"`
+		SkillAbilities           map[models.SkillType]models.AbilityType                 `json:"skill_abilities"`
+		ClassSavingThrows        map[models.ClassType][]models.AbilityType               `json:"class_saving_throws"`
+		PointBuyBudget           int                                                     `json:"point_buy_budget"`
+		PointBuyCosts            map[int]int                                             `json:"point_buy_costs"`
+		StandardArray            []int                                                   `json:"standard_array"`
+		AbilityRollExpression    string                                                  `json:"ability_roll_expression"`
+		MaxImprovedAbilityScore  int                                                     `json:"max_improved_ability_score"`
+		ImprovementLevels        map[models.ClassType][]int                              `json:"ability_score_improvement_levels"`
+		DefaultImprovementLevels []int                                                   `json:"default_ability_score_improvement_levels"`
+		SubclassLevels           map[models.ClassType]int                                `json:"subclass_levels"`
+		DefaultSubclassLevel     int                                                     `json:"default_subclass_level"`
+		DeathSavesNeeded         int                                                     `json:"death_saves_needed"`
+		MaxExhaustion            int                                                     `json:"max_exhaustion"`
+		MulticlassMinimumScore   int                                                     `json:"multiclass_minimum_score"`
+		MulticlassPrerequisites  map[models.ClassType][][]models.AbilityType             `json:"multiclass_prerequisites"`
+		SpellSlots               [20][9]int                                              `json:"spell_slots"`
+		PactSlots                [20][2]int                                              `json:"pact_slots"`
+		Dice                     struct {
+			MaxTerms    int `json:"max_terms"`
+			MaxDice     int `json:"max_dice"`
+			MaxSides    int `json:"max_sides"`
+			MaxConstant int `json:"max_constant"`
+		} `json:"dice"`
+	} `json:"rules"`
+}
+
+var (
+	metaOnce sync.Once
+	metaData metadata
+	metaETag string
+)
+
+// getMetadata returns the server's metadata along with an ETag which
+// changes whenever the metadata does.
+func getMetadata() (*metadata, string) {
+	metaOnce.Do(func() {
+		metaData = buildMetadata()
+
+		body, err := json.Marshal(metaData)
+		if err != nil {
+			panic(err)
+		}
+		sum := sha256.Sum256(body)
+		metaETag = `"meta-` + hex.EncodeToString(sum[:8]) + `"`
+	})
+
+	return &metaData, metaETag
+}
+
+// labelled returns options whose labels are their values.
+func labelled(values ...string) []metaOption {
+	options := make([]metaOption, len(values))
+	for i, v := range values {
+		options[i] = metaOption{v, v}
+	}
+	return options
+}
+
+// capitalized returns options labelled with their values, capitalized.
+func capitalized(values ...string) []metaOption {
+	options := make([]metaOption, len(values))
+	for i, v := range values {
+		options[i] = metaOption{v, strings.ToUpper(v[:1]) + v[1:]}
+	}
+	return options
+}
+
+// challengeRatingValue returns the numeric value of a challenge rating
+// such as "1/4".
+func challengeRatingValue(cr string) float64 {
+	if parts := strings.SplitN(cr, "/", 2); len(parts) == 2 {
+		n, _ := strconv.ParseFloat(parts[0], 64)
+		d, _ := strconv.ParseFloat(parts[1], 64)
+		return n / d
+	}
+	n, _ := strconv.ParseFloat(cr, 64)
+	return n
+}
+
+func buildMetadata() metadata {
+	var m metadata
+
+	m.Enums.Races = labelled(
+		string(models.Dragonborn), models.Dwarf, models.Elf, models.Gnome, models.HalfElf,
+		models.Halfling, models.HalfOrc, models.Human, models.Tiefling)
+
+	classes := make([]string, 0, len(models.ClassSubclasses))
+	for class := range models.ClassSubclasses {
+		classes = append(classes, string(class))
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		subclasses := []string{}
+		for _, subclass := range models.ClassSubclasses[models.ClassType(class)] {
+			subclasses = append(subclasses, string(subclass))
+		}
+		m.Enums.Classes = append(m.Enums.Classes, metaClass{metaOption{class, class}, labelled(subclasses...)})
+	}
+
+	m.Enums.FightingStyles = labelled(
+		string(models.FightingStyleArchery), string(models.FightingStyleBlindFighting),
+		string(models.FightingStyleDefense), string(models.FightingStyleDruidicWarrior),
+		string(models.FightingStyleDueling), string(models.FightingStyleGreatWeaponFighting),
+		string(models.FightingStyleInterception), string(models.FightingStyleProtection),
+		string(models.FightingStyleSuperiorTechnique), string(models.FightingStyleThrownWeaponFighting),
+		string(models.FightingStyleTwoWeaponFighting), string(models.FightingStyleUnarmedFighting))
+	m.Enums.Alignments = labelled(
+		string(models.LawfulGood), models.NeutralGood, models.ChaoticGood,
+		models.LawfulNeutral, models.TrueNeutral, models.ChaoticNeutral,
+		models.LawfulEvil, models.NeutralEvil, models.ChaoticEvil)
+	m.Enums.Sexes = labelled(string(models.Male), models.Female, models.Other)
+	m.Enums.Abilities = capitalized(
+		string(models.AbilityStrength), models.AbilityDexterity, models.AbilityConstitution,
+		models.AbilityIntelligence, models.AbilityWisdom, models.AbilityCharisma)
+	for _, skill := range models.Skills {
+		label := strings.Title(strings.ReplaceAll(string(skill), "_", " "))
+		m.Enums.Skills = append(m.Enums.Skills, metaOption{string(skill), strings.ReplaceAll(label, " Of ", " of ")})
+	}
+	m.Enums.ProficiencyKinds = []metaOption{
+		{string(models.ProficiencySavingThrow), "Saving throw"},
+		{models.ProficiencySkill, "Skill"},
+		{models.ProficiencyTool, "Tool"},
+		{models.ProficiencyLanguage, "Language"},
+	}
+	m.Enums.ItemTypes = labelled(
+		string(models.Armor), models.Potion, models.Ring, models.Rod, models.Scroll,
+		models.Staff, models.Wand, models.Weapon, models.WondrousItem)
+	m.Enums.Rarities = labelled(
+		string(models.Common), models.Uncommon, models.Rare, models.VeryRare, models.Legendary, models.Artifact)
+	m.Enums.MagicSchools = labelled(
+		string(models.Abjuration), models.Conjuration, models.Divination, models.Enchantment,
+		models.Evocation, models.Illusion, models.Necromancy, models.Transmuation)
+	m.Enums.Conditions = capitalized(
+		string(models.ConditionBlinded), models.ConditionCharmed, models.ConditionDeafened,
+		models.ConditionExhaustion, models.ConditionFrightened, models.ConditionGrappled,
+		models.ConditionIncapacitated, models.ConditionInvisible, models.ConditionParalyzed,
+		models.ConditionPetrified, models.ConditionPoisoned, models.ConditionProne,
+		models.ConditionRestrained, models.ConditionStunned, models.ConditionUnconscious)
+	m.Enums.HitPointsMethods = capitalized(string(models.HitPointsAverage), models.HitPointsRoll)
+	m.Enums.AbilityMethods = []metaOption{
+		{string(models.AbilityMethodManual), "Manual"},
+		{models.AbilityMethodPointBuy, "Point-buy"},
+		{models.AbilityMethodStandardArray, "Standard array"},
+		{models.AbilityMethodRolled, "Rolled"},
+	}
+	m.Enums.Rests = capitalized(string(models.RestShort), models.RestLong)
+	m.Enums.FeatureSources = capitalized(
+		string(models.FeatureSourceRace), models.FeatureSourceClass, models.FeatureSourceSubclass, models.FeatureSourceFeat)
+	m.Enums.LifeStates = capitalized(string(models.LifeConscious), models.LifeDying, models.LifeStable, models.LifeDead)
+	m.Enums.RollModes = capitalized(string(models.RollNormal), models.RollAdvantage, models.RollDisadvantage)
+	m.Enums.CampaignStates = capitalized(
+		string(models.CampaignPlanning), models.CampaignRecruiting, models.CampaignActive,
+		models.CampaignOnHiatus, models.CampaignCompleted, models.CampaignArchived)
+	m.Enums.RequestStatuses = capitalized(
+		string(models.RequestPending), string(models.RequestAccepted),
+		string(models.RequestDeclined), string(models.RequestRevoked))
+	m.Enums.JournalVisibilities = []metaOption{
+		{string(models.JournalEveryone), "Everyone"},
+		{models.JournalDMOnly, "Dungeon master only"},
+		{models.JournalPlayers, "Selected players"},
+	}
+	m.Enums.NPCKinds = []metaOption{
+		{string(models.NPCKindNPC), "NPC"},
+		{models.NPCKindMonster, "Monster"},
+	}
+	m.Enums.EncounterDifficulties = capitalized(
+		string(models.EncounterTrivial), models.EncounterEasy, models.EncounterMedium,
+		models.EncounterHard, models.EncounterDeadly)
+	m.Enums.RegistryKinds = capitalized(string(models.RegistryRace), models.RegistryClass, models.RegistrySubclass)
+	m.Enums.TokenScopes = []metaOption{
+		{string(models.ScopeReadOnly), "Read only"},
+		{string(models.ScopeCharacters), "Characters"},
+		{string(models.ScopeCampaigns), "Campaigns"},
+	}
+	m.Enums.Roles = capitalized(string(models.RolePlayer), string(models.RoleAdmin))
+
+	m.Rules.MaxXP = models.MaxXP
+	for i, xp := range models.XPThresholds {
+		m.Rules.Levels = append(m.Rules.Levels, metaLevel{i + 1, xp})
+	}
+	for score := 1; score <= 30; score++ {
+		m.Rules.AbilityModifiers = append(m.Rules.AbilityModifiers, metaAbilityModifier{score, models.AbilityModifier(score)})
+	}
+	for cr, xp := range models.ChallengeRatingXP {
+		m.Rules.ChallengeRatings = append(m.Rules.ChallengeRatings, metaChallengeRating{cr, xp})
+	}
+	sort.Slice(m.Rules.ChallengeRatings, func(i, j int) bool {
+		return challengeRatingValue(m.Rules.ChallengeRatings[i].ChallengeRating) <
+			challengeRatingValue(m.Rules.ChallengeRatings[j].ChallengeRating)
+	})
+	for i, t := range models.EncounterXPThresholds {
+		m.Rules.EncounterThresholds = append(m.Rules.EncounterThresholds,
+			metaEncounterThresholds{i + 1, t[0], t[1], t[2], t[3]})
+	}
+	m.Rules.CampaignStateTransitions = models.CampaignStateTransitions
+	m.Rules.SkillAbilities = models.SkillAbilities
+	m.Rules.ClassSavingThrows = models.ClassSavingThrows
+	m.Rules.PointBuyBudget = models.PointBuyBudget
+	m.Rules.PointBuyCosts = models.PointBuyCosts
+	m.Rules.StandardArray = models.StandardArray
+	m.Rules.AbilityRollExpression = models.AbilityRollExpression
+	m.Rules.MaxImprovedAbilityScore = models.MaxImprovedAbilityScore
+	m.Rules.ImprovementLevels = models.AbilityScoreImprovementLevels
+	m.Rules.DefaultImprovementLevels = models.DefaultAbilityScoreImprovementLevels
+	m.Rules.SubclassLevels = models.SubclassLevels
+	m.Rules.DefaultSubclassLevel = models.DefaultSubclassLevel
+	m.Rules.MaxExhaustion = models.MaxExhaustion
+	m.Rules.DeathSavesNeeded = models.DeathSavesNeeded
+	m.Rules.MulticlassMinimumScore = models.MulticlassMinimumScore
+	m.Rules.MulticlassPrerequisites = models.MulticlassPrerequisites
+	m.Rules.SpellSlots = models.SpellSlotsByCasterLevel
+	m.Rules.PactSlots = models.PactSlotsByLevel
+	m.Rules.Dice.MaxTerms = dice.MaxTerms
+	m.Rules.Dice.MaxDice = dice.MaxDice
+	m.Rules.Dice.MaxSides = dice.MaxSides
+	m.Rules.Dice.MaxConstant = dice.MaxConstant
+
+	return m
+}
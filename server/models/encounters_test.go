@@ -0,0 +1,99 @@
+package models
+
+import "testing"
+
+func TestEncounterMultiplier(t *testing.T) {
+	tests := []struct {
+		monsters, party int
+		want            float64
+	}{
+		{1, 4, 1},
+		{2, 4, 1.5},
+		{3, 4, 2},
+		{6, 4, 2},
+		{7, 4, 2.5},
+		{10, 4, 2.5},
+		{11, 4, 3},
+		{14, 4, 3},
+		{15, 4, 4},
+		{1, 2, 1.5},
+		{15, 2, 5},
+		{1, 6, 0.5},
+		{2, 6, 1},
+		{15, 6, 3},
+	}
+
+	for _, tt := range tests {
+		if got := EncounterMultiplier(tt.monsters, tt.party); got != tt.want {
+			t.Errorf("EncounterMultiplier(%d, %d) = %v, want %v", tt.monsters, tt.party, got, tt.want)
+		}
+	}
+}
+
+func TestRateEncounter(t *testing.T) {
+	monster := func(cr string, quantity int) EncounterMonster {
+		return EncounterMonster{ChallengeRating: cr, Quantity: quantity}
+	}
+
+	tests := []struct {
+		name           string
+		party          []int
+		monsters       []EncounterMonster
+		wantThresholds EncounterThresholds
+		wantAdjusted   int
+		want           EncounterDifficultyType
+	}{
+		{
+			// The bugbear and hobgoblins example of the Dungeon Master's Guide
+			"mixed group", []int{3, 3, 3, 3}, []EncounterMonster{monster("1", 1), monster("1/2", 3)},
+			EncounterThresholds{300, 600, 900, 1600}, 1000, EncounterHard,
+		},
+		{
+			"single weak monster", []int{1, 1, 1, 1}, []EncounterMonster{monster("1/4", 1)},
+			EncounterThresholds{100, 200, 300, 400}, 50, EncounterTrivial,
+		},
+		{
+			"easy", []int{2, 2, 2, 2}, []EncounterMonster{monster("1/2", 2)},
+			EncounterThresholds{200, 400, 600, 800}, 300, EncounterEasy,
+		},
+		{
+			"medium", []int{4, 4, 4, 4}, []EncounterMonster{monster("2", 1), monster("1/2", 2)},
+			EncounterThresholds{500, 1000, 1500, 2000}, 1300, EncounterMedium,
+		},
+		{
+			"lone character", []int{5}, []EncounterMonster{monster("3", 1)},
+			EncounterThresholds{250, 500, 750, 1100}, 1050, EncounterHard,
+		},
+		{
+			"large party against a horde", []int{1, 1, 1, 1, 1, 1}, []EncounterMonster{monster("0", 20)},
+			EncounterThresholds{150, 300, 450, 600}, 600, EncounterDeadly,
+		},
+		{
+			"invalid levels are ignored", []int{0, 2, 21}, []EncounterMonster{monster("1/4", 1)},
+			EncounterThresholds{50, 100, 150, 200}, 50, EncounterEasy,
+		},
+		{
+			"unknown challenge rating", []int{1, 1, 1, 1}, []EncounterMonster{monster("31", 1)},
+			EncounterThresholds{100, 200, 300, 400}, 0, EncounterTrivial,
+		},
+		{
+			"no monsters", []int{3, 3, 3}, nil,
+			EncounterThresholds{225, 450, 675, 1200}, 0, EncounterTrivial,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := RateEncounter(tt.party, tt.monsters)
+			if r.Thresholds != tt.wantThresholds {
+				t.Errorf("thresholds = %+v, want %+v", r.Thresholds, tt.wantThresholds)
+			}
+			if r.AdjustedXP != tt.wantAdjusted {
+				t.Errorf("adjusted XP = %d, want %d", r.AdjustedXP, tt.wantAdjusted)
+			}
+			if r.Difficulty != tt.want {
+				t.Errorf("difficulty = %q, want %q", r.Difficulty, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFeatureMaxUses(t *testing.T) {
+	charisma, wisdom := AbilityType(AbilityCharisma), AbilityType(AbilityWisdom)
+	character := Character{Charisma: 16, Wisdom: 8}
+
+	tests := []struct {
+		name        string
+		usesByLevel string
+		usesAbility *AbilityType
+		level       int
+		want        int
+	}{
+		{"no uses listed", "", nil, 5, 1},
+		{"first level listed", `{"1": 2, "3": 3}`, nil, 1, 2},
+		{"between levels listed", `{"1": 2, "3": 3}`, nil, 2, 2},
+		{"highest level reached", `{"1": 2, "3": 3, "10": 5}`, nil, 9, 3},
+		{"past the last level listed", `{"1": 2, "3": 3}`, nil, 20, 3},
+		{"below the first level listed", `{"2": 4}`, nil, 1, 1},
+		{"ability modifier", "", &charisma, 1, 3},
+		{"uses and ability modifier", `{"1": 1, "5": 2}`, &charisma, 5, 5},
+		{"at least one", `{"1": 1}`, &wisdom, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Feature{UsesByLevel: json.RawMessage(tt.usesByLevel), UsesAbility: tt.usesAbility}
+			got, err := f.MaxUses(&character, tt.level)
+			if err != nil {
+				t.Fatalf("MaxUses() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("MaxUses(%s, %d) = %d, want %d", tt.usesByLevel, tt.level, got, tt.want)
+			}
+		})
+	}
+
+	f := Feature{UsesByLevel: json.RawMessage(`{"first": 2}`)}
+	if _, err := f.MaxUses(&character, 1); err == nil {
+		t.Error("MaxUses() with malformed uses returned no error")
+	}
+}
+
+func TestFeatureSourceLevel(t *testing.T) {
+	fighter, wizard := ClassType(Fighter), ClassType(Wizard)
+	character := Character{Level: 7}
+	classes := []CharacterClass{{Class: Fighter, Levels: 5}, {Class: Rogue, Levels: 2}}
+
+	tests := []struct {
+		name    string
+		feature Feature
+		want    int
+	}{
+		{"racial trait", Feature{Source: FeatureSourceRace}, 7},
+		{"feat", Feature{Source: FeatureSourceFeat}, 7},
+		{"class feature", Feature{Source: FeatureSourceClass, Class: &fighter}, 5},
+		{"subclass feature", Feature{Source: FeatureSourceSubclass, Class: &fighter}, 5},
+		{"class not taken", Feature{Source: FeatureSourceClass, Class: &wizard}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.feature.SourceLevel(&character, classes); got != tt.want {
+				t.Errorf("SourceLevel() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCharacterFeatureSetUses(t *testing.T) {
+	fighter, shortRest := ClassType(Fighter), RestType(RestShort)
+	character := Character{Level: 5}
+	classes := []CharacterClass{{Class: Fighter, Levels: 5}}
+	limited := Feature{Class: &fighter, UsesByLevel: json.RawMessage(`{"2": 1, "17": 2}`), Recharge: &shortRest}
+
+	unlimited := CharacterFeature{Feature: Feature{Class: &fighter}}
+	if err := unlimited.SetUses(&character, classes); err != nil || unlimited.MaxUses != nil || unlimited.UsesRemaining != nil {
+		t.Errorf("unlimited feature uses = %v/%v, %v", unlimited.UsesRemaining, unlimited.MaxUses, err)
+	}
+
+	tests := []struct {
+		spent, wantMax, wantRemaining int
+	}{
+		{0, 1, 1},
+		{1, 1, 0},
+		{3, 1, 0},
+	}
+
+	for _, tt := range tests {
+		f := CharacterFeature{Feature: limited, UsesSpent: tt.spent}
+		if err := f.SetUses(&character, classes); err != nil {
+			t.Fatalf("SetUses() error: %v", err)
+		}
+		if f.MaxUses == nil || f.UsesRemaining == nil {
+			t.Fatalf("SetUses() with %d spent left the uses unset", tt.spent)
+		}
+		if *f.MaxUses != tt.wantMax || *f.UsesRemaining != tt.wantRemaining {
+			t.Errorf("SetUses() with %d spent = %d/%d, want %d/%d",
+				tt.spent, *f.UsesRemaining, *f.MaxUses, tt.wantRemaining, tt.wantMax)
+		}
+	}
+}
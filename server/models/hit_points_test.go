@@ -0,0 +1,103 @@
+package models
+
+import "testing"
+
+func TestTakeDamage(t *testing.T) {
+	tests := []struct {
+		name   string
+		before Character
+		damage int
+		after  Character
+	}{
+		{"no damage", Character{HPMax: 20, HPCurrent: 15}, 0, Character{HPMax: 20, HPCurrent: 15}},
+		{"some hit points", Character{HPMax: 20, HPCurrent: 15}, 5, Character{HPMax: 20, HPCurrent: 10}},
+		{"down to 0", Character{HPMax: 20, HPCurrent: 10}, 10, Character{HPMax: 20}},
+		{"damage left over", Character{HPMax: 20, HPCurrent: 10}, 29, Character{HPMax: 20}},
+		{"massive damage", Character{HPMax: 20, HPCurrent: 10}, 30,
+			Character{HPMax: 20, DeathSaveFailures: 3, Dead: true}},
+		{"while dying", Character{HPMax: 20, DeathSaveSuccesses: 2}, 5,
+			Character{HPMax: 20, DeathSaveSuccesses: 2, DeathSaveFailures: 1}},
+		{"while stable", Character{HPMax: 20, Stable: true}, 5, Character{HPMax: 20, DeathSaveFailures: 1}},
+		{"third failure", Character{HPMax: 20, DeathSaveFailures: 2}, 5,
+			Character{HPMax: 20, DeathSaveFailures: 3, Dead: true}},
+		{"massive damage while dying", Character{HPMax: 20}, 20,
+			Character{HPMax: 20, DeathSaveFailures: 3, Dead: true}},
+		{"dead", Character{HPMax: 20, DeathSaveFailures: 3, Dead: true}, 5,
+			Character{HPMax: 20, DeathSaveFailures: 3, Dead: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.before
+			c.TakeDamage(tt.damage)
+			if c != tt.after {
+				t.Errorf("TakeDamage(%d) = %+v, want %+v", tt.damage, c, tt.after)
+			}
+		})
+	}
+}
+
+func TestHeal(t *testing.T) {
+	tests := []struct {
+		name    string
+		before  Character
+		healing int
+		after   Character
+	}{
+		{"some hit points", Character{HPMax: 20, HPCurrent: 5}, 10, Character{HPMax: 20, HPCurrent: 15}},
+		{"up to the maximum", Character{HPMax: 20, HPCurrent: 15}, 10, Character{HPMax: 20, HPCurrent: 20}},
+		{"while dying", Character{HPMax: 20, DeathSaveSuccesses: 2, DeathSaveFailures: 2}, 1,
+			Character{HPMax: 20, HPCurrent: 1}},
+		{"while stable", Character{HPMax: 20, Stable: true}, 3, Character{HPMax: 20, HPCurrent: 3}},
+		{"dead", Character{HPMax: 20, DeathSaveFailures: 3, Dead: true}, 10,
+			Character{HPMax: 20, DeathSaveFailures: 3, Dead: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.before
+			c.Heal(tt.healing)
+			if c != tt.after {
+				t.Errorf("Heal(%d) = %+v, want %+v", tt.healing, c, tt.after)
+			}
+		})
+	}
+}
+
+func TestRollDeathSave(t *testing.T) {
+	tests := []struct {
+		name   string
+		before Character
+		roll   int
+		after  Character
+		err    error
+	}{
+		{"success", Character{HPMax: 20}, 10, Character{HPMax: 20, DeathSaveSuccesses: 1}, nil},
+		{"failure", Character{HPMax: 20}, 9, Character{HPMax: 20, DeathSaveFailures: 1}, nil},
+		{"third success", Character{HPMax: 20, DeathSaveSuccesses: 2, DeathSaveFailures: 1}, 15,
+			Character{HPMax: 20, Stable: true}, nil},
+		{"third failure", Character{HPMax: 20, DeathSaveFailures: 2}, 2,
+			Character{HPMax: 20, DeathSaveFailures: 3, Dead: true}, nil},
+		{"natural 1", Character{HPMax: 20}, 1, Character{HPMax: 20, DeathSaveFailures: 2}, nil},
+		{"natural 1 after a failure", Character{HPMax: 20, DeathSaveFailures: 1}, 1,
+			Character{HPMax: 20, DeathSaveFailures: 3, Dead: true}, nil},
+		{"natural 20", Character{HPMax: 20, DeathSaveSuccesses: 1, DeathSaveFailures: 2}, 20,
+			Character{HPMax: 20, HPCurrent: 1}, nil},
+		{"conscious", Character{HPMax: 20, HPCurrent: 5}, 10, Character{HPMax: 20, HPCurrent: 5}, ErrNotDying},
+		{"stable", Character{HPMax: 20, Stable: true}, 10, Character{HPMax: 20, Stable: true}, ErrNotDying},
+		{"dead", Character{HPMax: 20, DeathSaveFailures: 3, Dead: true}, 20,
+			Character{HPMax: 20, DeathSaveFailures: 3, Dead: true}, ErrNotDying},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.before
+			if err := c.RollDeathSave(tt.roll); err != tt.err {
+				t.Errorf("RollDeathSave(%d) error = %v, want %v", tt.roll, err, tt.err)
+			}
+			if c != tt.after {
+				t.Errorf("RollDeathSave(%d) = %+v, want %+v", tt.roll, c, tt.after)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGrantsAbilityScoreImprovement(t *testing.T) {
+	tests := []struct {
+		class      ClassType
+		classLevel int
+		want       bool
+	}{
+		{Wizard, 4, true},
+		{Wizard, 6, false},
+		{Wizard, 19, true},
+		{Wizard, 20, false},
+		{Fighter, 6, true},
+		{Fighter, 10, false},
+		{Rogue, 10, true},
+		{Rogue, 14, false},
+		{ClassType("Blood Hunter"), 8, true},
+		{ClassType("Blood Hunter"), 6, false},
+	}
+
+	for _, tt := range tests {
+		if got := GrantsAbilityScoreImprovement(tt.class, tt.classLevel); got != tt.want {
+			t.Errorf("GrantsAbilityScoreImprovement(%q, %d) = %v, want %v", tt.class, tt.classLevel, got, tt.want)
+		}
+	}
+}
+
+func TestSubclassLevel(t *testing.T) {
+	tests := []struct {
+		class ClassType
+		want  int
+	}{
+		{Cleric, 1},
+		{Warlock, 1},
+		{Druid, 2},
+		{Wizard, 2},
+		{Fighter, 3},
+		{ClassType("Blood Hunter"), DefaultSubclassLevel},
+	}
+
+	for _, tt := range tests {
+		if got := SubclassLevel(tt.class); got != tt.want {
+			t.Errorf("SubclassLevel(%q) = %d, want %d", tt.class, got, tt.want)
+		}
+	}
+}
+
+func TestAbilityScoreIncreases(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		want     map[AbilityType]int
+		hasError bool
+	}{
+		{"none", "", map[AbilityType]int{}, false},
+		{"empty", "{}", map[AbilityType]int{}, false},
+		{"one ability", `{"strength": 2}`, map[AbilityType]int{AbilityStrength: 2}, false},
+		{"two abilities", `{"dexterity": 1, "wisdom": 1}`,
+			map[AbilityType]int{AbilityDexterity: 1, AbilityWisdom: 1}, false},
+		{"not an object", `[2]`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := LevelUp{AbilityIncreases: json.RawMessage(tt.raw)}
+			got, err := l.AbilityScoreIncreases()
+			if tt.hasError {
+				if err == nil {
+					t.Errorf("AbilityScoreIncreases() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AbilityScoreIncreases() error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("AbilityScoreIncreases() = %v, want %v", got, tt.want)
+			}
+			for ability, increase := range tt.want {
+				if got[ability] != increase {
+					t.Errorf("AbilityScoreIncreases() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyLevelUp(t *testing.T) {
+	tests := []struct {
+		name       string
+		character  Character
+		level      int
+		hpGained   int
+		increases  map[AbilityType]int
+		wantHPMax  int
+		wantHP     int
+		wantGained int
+	}{
+		{"hit points", Character{Level: 1, Constitution: 14, HPMax: 12, HPCurrent: 12},
+			2, 7, nil, 19, 19, 7},
+		{"heals the gain", Character{Level: 2, Constitution: 14, HPMax: 19, HPCurrent: 5},
+			3, 7, nil, 26, 12, 7},
+		{"stays at 0 hit points", Character{Level: 2, Constitution: 14, HPMax: 19},
+			3, 7, nil, 26, 0, 7},
+		{"capped at the maximum", Character{Level: 2, Constitution: 14, HPMax: 19, HPCurrent: 25},
+			3, 7, nil, 26, 26, 7},
+		{"other ability", Character{Level: 3, Strength: 16, Constitution: 14, HPMax: 26, HPCurrent: 26},
+			4, 7, map[AbilityType]int{AbilityStrength: 2}, 33, 33, 7},
+		{"same constitution modifier", Character{Level: 3, Constitution: 14, HPMax: 26, HPCurrent: 26},
+			4, 7, map[AbilityType]int{AbilityConstitution: 1}, 33, 33, 7},
+		{"higher constitution modifier", Character{Level: 3, Constitution: 15, HPMax: 26, HPCurrent: 26},
+			4, 7, map[AbilityType]int{AbilityConstitution: 1}, 37, 37, 11},
+		{"negative constitution modifier", Character{Level: 7, Constitution: 8, HPMax: 35, HPCurrent: 35},
+			8, 4, map[AbilityType]int{AbilityConstitution: 2, AbilityWisdom: 0}, 47, 47, 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.character
+			l := LevelUp{Level: tt.level, HPGained: tt.hpGained}
+			c.ApplyLevelUp(&l, tt.increases)
+
+			if c.Level != tt.level {
+				t.Errorf("level = %d, want %d", c.Level, tt.level)
+			}
+			if c.HPMax != tt.wantHPMax || c.HPCurrent != tt.wantHP {
+				t.Errorf("hit points = %d/%d, want %d/%d", c.HPCurrent, c.HPMax, tt.wantHP, tt.wantHPMax)
+			}
+			if l.HPGained != tt.wantGained {
+				t.Errorf("HPGained = %d, want %d", l.HPGained, tt.wantGained)
+			}
+			for ability, increase := range tt.increases {
+				if got, want := c.AbilityScore(ability), tt.character.AbilityScore(ability)+increase; got != want {
+					t.Errorf("%s = %d, want %d", ability, got, want)
+				}
+			}
+		})
+	}
+}
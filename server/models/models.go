@@ -3,25 +3,57 @@ package models
 import (
 	"encoding/json"
 	"errors"
+	"time"
 )
 
 var (
-	ErrNoRecord           = errors.New("models: no record was found")
-	ErrUpdateSingleRecord = errors.New("models: number of records updated was not one")
-	ErrDeleteSingleRecord = errors.New("models: number of records deleted was not one")
-	ErrDuplicateUsername  = errors.New("models: duplicate player username")
-	ErrDuplicateCharacter = errors.New("models: player cannot have two characters with the same name")
-	ErrDuplicateSpell     = errors.New("models: spell names must be unique for a given character")
-	ErrDuplicateItem      = errors.New("models: item names must be unique for a given character")
-	ErrDuplicateBelongsTo = errors.New("models: relation of character to campaign must be unique")
+	ErrNoRecord             = errors.New("models: no record was found")
+	ErrUpdateSingleRecord   = errors.New("models: number of records updated was not one")
+	ErrDeleteSingleRecord   = errors.New("models: number of records deleted was not one")
+	ErrDuplicateUsername    = errors.New("models: duplicate player username")
+	ErrDuplicateCharacter   = errors.New("models: player cannot have two characters with the same name")
+	ErrDuplicateSpell       = errors.New("models: spell names must be unique for a given character")
+	ErrDuplicateItem        = errors.New("models: item names must be unique for a given character")
+	ErrDuplicateBelongsTo   = errors.New("models: relation of character to campaign must be unique")
+	ErrDuplicateJoinRequest = errors.New("models: character already has a pending request to join the campaign")
+	ErrInvitationExpired    = errors.New("models: invitation is invalid, expired or has already been answered")
+	ErrAccountDisabled      = errors.New("models: player account has been disabled")
+	ErrInvalidUsername      = errors.New("models: usernames must be 1-25 letters, digits, '.', '-' or '_'")
 )
 
 // JSON unmarshal errors for custom character data types.
 var (
-	ErrInvalidClassType     = errors.New("models: invalid character class type")
 	ErrInvalidAlignmentType = errors.New("models: invalid character alignment type")
-	ErrInvalidRaceType      = errors.New("models: invalid character race type")
 	ErrInvalidSexType       = errors.New("models: invalid character sex type")
+	ErrInvalidRegistryKind  = errors.New("models: invalid registry kind")
+)
+
+// JSON unmarshal errors for journal data types.
+var (
+	ErrInvalidJournalVisibility = errors.New("models: invalid journal visibility")
+)
+
+// JSON unmarshal errors for NPC data types.
+var (
+	ErrInvalidNPCKind = errors.New("models: invalid NPC kind")
+)
+
+// JSON unmarshal errors for combat data types.
+var (
+	ErrInvalidCondition = errors.New("models: invalid condition")
+	ErrInvalidRest      = errors.New("models: invalid rest")
+)
+
+// JSON unmarshal errors for dice roll data types.
+var (
+	ErrInvalidAbility       = errors.New("models: invalid ability")
+	ErrInvalidRollMode      = errors.New("models: invalid roll mode")
+	ErrInvalidAbilityMethod = errors.New("models: invalid ability score generation method")
+)
+
+// JSON unmarshal errors for player data types.
+var (
+	ErrInvalidRoleType = errors.New("models: invalid player role type")
 )
 
 // JSON unmarshal errors for custom item data types.
@@ -35,19 +67,199 @@ var (
 	ErrInvalidMagicSchoolType = errors.New("models: invalid spell magic school type")
 )
 
-// JSON unmarshal errors for class attribute types.
+// Two-factor authentication errors.
+var (
+	ErrTwoFactorNotEnabled     = errors.New("models: two-factor authentication is not enabled")
+	ErrTwoFactorAlreadyEnabled = errors.New("models: two-factor authentication is already enabled")
+	ErrTOTPCodeReused          = errors.New("models: TOTP code has already been used")
+	ErrInvalidRecoveryCode     = errors.New("models: invalid recovery code")
+	ErrChallengeExpired        = errors.New("models: login challenge is invalid or has expired")
+)
+
+// Single sign-on errors.
+var (
+	ErrDuplicateIdentity = errors.New("models: external identity is already linked to a player")
+	ErrLoginStateExpired = errors.New("models: single sign-on state is invalid or has expired")
+)
+
+// Campaign lifecycle errors.
+var (
+	ErrCharacterNotInCampaign = errors.New("models: character does not belong to the campaign")
+	ErrInvalidMilestoneOrder  = errors.New("models: milestone order must list every milestone of the campaign exactly once")
+	ErrInvalidCampaignState   = errors.New("models: invalid campaign state")
+	ErrInvalidStateTransition = errors.New("models: campaign cannot move from its current state to the requested state")
+	ErrCampaignArchived       = errors.New("models: campaign is archived and cannot be modified")
+)
+
+// Multiclassing errors.
+var (
+	ErrDuplicateCharacterClass = errors.New("models: character cannot take the same class twice")
+	ErrNotEnoughLevels         = errors.New("models: character does not have enough levels for its classes")
+)
+
+// Level-up errors.
+var (
+	ErrInvalidHitPointsMethod = errors.New("models: invalid hit points method")
+	ErrNoLevelAvailable       = errors.New("models: character does not have the experience to gain a level")
+	ErrLevelChanged           = errors.New("models: character has changed since the level-up was chosen")
+)
+
+// Feature errors.
+var (
+	ErrInvalidFeatureSource      = errors.New("models: invalid feature source")
+	ErrDuplicateCharacterFeature = errors.New("models: character already has this feature")
+	ErrNoUsesRemaining           = errors.New("models: feature has no uses remaining")
+)
+
+// Ability score generation errors.
+var (
+	ErrAbilityRollUsed = errors.New("models: ability roll has already been used by another character")
+)
+
+// Hit point and death save errors.
+var (
+	ErrNotDying      = errors.New("models: character is not dying")
+	ErrCharacterDead = errors.New("models: character is dead")
+)
+
+// Proficiency errors.
+var (
+	ErrInvalidProficiencyKind = errors.New("models: invalid proficiency kind")
+)
+
+// Race, class and subclass registry errors.
+var (
+	ErrDuplicateRegistryEntry = errors.New("models: a race, class or subclass with this name already exists")
+	ErrRegistryEntryInUse     = errors.New("models: race, class or subclass is used by a character")
+)
+
+// Combat tracker errors.
+var (
+	ErrCombatInProgress   = errors.New("models: campaign already has a combat in progress")
+	ErrInitiativeMissing  = errors.New("models: every combatant needs an initiative before the first turn")
+	ErrNoActiveCombatants = errors.New("models: combat has no combatants left to take a turn")
+)
+
+// Personal access token errors.
 var (
-	ErrInvalidClassAttribute = errors.New("models: invalid class attribute type")
+	ErrDuplicateTokenName = errors.New("models: personal access token names must be unique for a given player")
+	ErrInvalidTokenScope  = errors.New("models: invalid personal access token scope")
+	ErrTokenRevoked       = errors.New("models: personal access token is invalid or has been revoked")
 )
 
+type RoleType string
+
+const (
+	RolePlayer RoleType = "player"
+	RoleAdmin  RoleType = "admin"
+)
+
+func (t *RoleType) UnmarshalJSON(b []byte) error {
+	type T RoleType
+	var r *T = (*T)(t)
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return err
+	}
+	switch *t {
+	case
+		RolePlayer,
+		RoleAdmin:
+		return nil
+	}
+	return ErrInvalidRoleType
+}
+
 // Player is the code representation of the "Player" relation in the
 // database schema.
 type Player struct {
-	Username string `json:"username" db:"username"`
-	Password string `json:"-" db:"password"`
-	Name     string `json:"name" db:"name"`
+	ID                    int      `json:"-" db:"id"` // Never changes, unlike the username
+	Username              string   `json:"username" db:"username"`
+	Password              string   `json:"-" db:"password"`
+	Name                  string   `json:"name" db:"name"`
+	AvatarURL             string   `json:"avatar_url" db:"avatar_url"`
+	Bio                   string   `json:"bio" db:"bio"`
+	Role                  RoleType `json:"role" db:"role"`
+	Disabled              bool     `json:"disabled" db:"disabled"`
+	PasswordResetRequired bool     `json:"password_reset_required" db:"password_reset_required"`
+	TOTPEnabled           bool     `json:"totp_enabled" db:"totp_enabled"`
+}
+
+// PlayerIdentity is the code representation of the "PlayerIdentity"
+// relation in the database schema.
+type PlayerIdentity struct {
+	Issuer         string    `json:"issuer" db:"issuer"`
+	Subject        string    `json:"subject" db:"subject"`
+	PlayerUsername string    `json:"player_username" db:"player_username"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// IsValidUsername reports whether `username` may be used as a player
+// username.
+func IsValidUsername(username string) bool {
+	if len(username) == 0 || len(username) > 25 {
+		return false
+	}
+	for _, r := range username {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		isDigit := r >= '0' && r <= '9'
+		if !isLetter && !isDigit && r != '.' && r != '-' && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// AuditLogEntry is the code representation of the "AuditLog" relation
+// in the database schema.
+type AuditLogEntry struct {
+	ID            int       `json:"id" db:"id"`
+	ActorUsername string    `json:"actor_username" db:"actor_username"`
+	Action        string    `json:"action" db:"action"`
+	Target        *string   `json:"target" db:"target"`
+	Details       *string   `json:"details" db:"details"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+type TokenScope string
+
+const (
+	ScopeReadOnly   TokenScope = "read-only"
+	ScopeCharacters TokenScope = "characters"
+	ScopeCampaigns  TokenScope = "campaigns"
+)
+
+func (t *TokenScope) UnmarshalJSON(b []byte) error {
+	type T TokenScope
+	var r *T = (*T)(t)
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return err
+	}
+	switch *t {
+	case
+		ScopeReadOnly,
+		ScopeCharacters,
+		ScopeCampaigns:
+		return nil
+	}
+	return ErrInvalidTokenScope
+}
+
+// PersonalAccessToken is the code representation of the
+// "PersonalAccessToken" relation in the database schema. The token
+// itself is only ever shown to the player when it is created.
+type PersonalAccessToken struct {
+	ID         int          `json:"id" db:"id"`
+	Name       string       `json:"name" db:"name"`
+	Scopes     []TokenScope `json:"scopes" db:"scopes"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time   `json:"last_used_at" db:"last_used_at"`
+	RevokedAt  *time.Time   `json:"revoked_at" db:"revoked_at"`
 }
 
+// ClassType names a class of the registry. The official classes are
+// listed below.
 type ClassType string
 
 const (
@@ -66,33 +278,6 @@ const (
 	None                = "None"
 )
 
-func (t *ClassType) UnmarshalJSON(b []byte) error {
-	type T ClassType
-	var r *T = (*T)(t)
-	err := json.Unmarshal(b, &r)
-	if err != nil {
-		return err
-	}
-	switch *t {
-	case
-		Barbarian,
-		Bard,
-		Cleric,
-		Druid,
-		Fighter,
-		Monk,
-		Paladin,
-		Ranger,
-		Rogue,
-		Sorcerer,
-		Warlock,
-		Wizard,
-		None:
-		return nil
-	}
-	return ErrInvalidClassType
-}
-
 type AlignmentType string
 
 const (
@@ -130,6 +315,8 @@ func (t *AlignmentType) UnmarshalJSON(b []byte) error {
 	return ErrInvalidAlignmentType
 }
 
+// RaceType names a race of the registry. The official races are listed
+// below.
 type RaceType string
 
 const (
@@ -144,29 +331,6 @@ const (
 	Tiefling            = "Tiefling"
 )
 
-func (t *RaceType) UnmarshalJSON(b []byte) error {
-	type T RaceType
-	var r *T = (*T)(t)
-	err := json.Unmarshal(b, &r)
-	if err != nil {
-		return err
-	}
-	switch *t {
-	case
-		Dragonborn,
-		Dwarf,
-		Elf,
-		Gnome,
-		HalfElf,
-		Halfling,
-		HalfOrc,
-		Human,
-		Tiefling:
-		return nil
-	}
-	return ErrInvalidRaceType
-}
-
 type SexType string
 
 const (
@@ -195,27 +359,251 @@ func (t *SexType) UnmarshalJSON(b []byte) error {
 // Character is the code representation of the "Character" relation in
 // the database schema.
 type Character struct {
-	ID             int                `json:"id" db:"id"`
-	Name           string             `json:"name" db:"name"`
-	Weight         int                `json:"weight" db:"weight"`
-	Height         int                `json:"height" db:"height"`
-	Alignment      AlignmentType      `json:"alignment" db:"alignment"`
-	Sex            SexType            `json:"sex" db:"sex"`
-	Background     string             `json:"background" db:"background"`
-	Race           RaceType           `json:"race" db:"race"`
-	Speed          int                `json:"speed" db:"speed"`
-	Strength       int                `json:"strength" db:"strength"`
-	Dexterity      int                `json:"dexterity" db:"dexterity"`
-	Intelligence   int                `json:"intelligence" db:"intelligence"`
-	Wisdom         int                `json:"wisdom" db:"wisdom"`
-	Charisma       int                `json:"charisma" db:"charisma"`
-	Constitution   int                `json:"constitution" db:"constitution"`
-	HPMax          int                `json:"hp_max" db:"hp_max"`
-	AbilityPoints  int                `json:"ability_points" db:"ability_points"`
-	XPPoints       int                `json:"xp_points" db:"xp_points"`
-	Class          ClassType          `json:"class" db:"class"`
-	ClassAttribute ClassAttributeType `json:"class_attribute" db:"class_attribute"`
-	PlayerUsername string             `json:"player_username" db:"player_username"`
+	ID                 int                `json:"id" db:"id"`
+	Name               string             `json:"name" db:"name"`
+	Weight             int                `json:"weight" db:"weight"`
+	Height             int                `json:"height" db:"height"`
+	Alignment          AlignmentType      `json:"alignment" db:"alignment"`
+	Sex                SexType            `json:"sex" db:"sex"`
+	Background         string             `json:"background" db:"background"`
+	Race               RaceType           `json:"race" db:"race"`
+	Speed              int                `json:"speed" db:"speed"`
+	Strength           int                `json:"strength" db:"strength"`
+	Dexterity          int                `json:"dexterity" db:"dexterity"`
+	Intelligence       int                `json:"intelligence" db:"intelligence"`
+	Wisdom             int                `json:"wisdom" db:"wisdom"`
+	Charisma           int                `json:"charisma" db:"charisma"`
+	Constitution       int                `json:"constitution" db:"constitution"`
+	HPMax              int                `json:"hp_max" db:"hp_max"`
+	HPCurrent          int                `json:"hp_current" db:"hp_current"`
+	DeathSaveSuccesses int                `json:"death_save_successes" db:"death_save_successes"`
+	DeathSaveFailures  int                `json:"death_save_failures" db:"death_save_failures"`
+	Stable             bool               `json:"stable" db:"stable"` // At 0 hit points without rolling death saves
+	Dead               bool               `json:"dead" db:"dead"`
+	AbilityMethod      AbilityMethodType  `json:"ability_method" db:"ability_method"`
+	AbilityRollID      *int               `json:"ability_roll_id" db:"ability_roll_id"` // Rolled scores, even once edited
+	AbilityPoints      int                `json:"ability_points" db:"ability_points"`
+	XPPoints           int                `json:"xp_points" db:"xp_points"`
+	Level              int                `json:"level" db:"level"` // Gained through level-ups once XPPoints allow
+	Class              ClassType          `json:"class" db:"class"`
+	ClassAttribute     ClassAttributeType `json:"class_attribute" db:"class_attribute"`
+	PlayerUsername     string             `json:"player_username" db:"player_username"`
+}
+
+// DeathSavesNeeded is the number of successful death saves which
+// stabilize a dying character, and of failed ones which kill it.
+const DeathSavesNeeded = 3
+
+type LifeStateType string
+
+const (
+	LifeConscious LifeStateType = "conscious"
+	LifeDying                   = "dying"
+	LifeStable                  = "stable"
+	LifeDead                    = "dead"
+)
+
+// LifeState returns whether the character is conscious, dying, stable at
+// 0 hit points or dead.
+func (c *Character) LifeState() LifeStateType {
+	switch {
+	case c.Dead:
+		return LifeDead
+	case c.HPCurrent > 0:
+		return LifeConscious
+	case c.Stable:
+		return LifeStable
+	}
+	return LifeDying
+}
+
+// TakeDamage reduces the character's hit points by `damage`. A character
+// dropping to 0 hit points starts dying, unless the damage left over
+// reaches its hit point maximum, which kills it outright. Damage taken
+// at 0 hit points counts as a failed death save.
+func (c *Character) TakeDamage(damage int) {
+	if damage <= 0 || c.Dead {
+		return
+	}
+
+	if c.HPCurrent == 0 {
+		if damage >= c.HPMax {
+			c.die()
+			return
+		}
+		c.Stable = false
+		c.failDeathSaves(1)
+		return
+	}
+
+	if damage < c.HPCurrent {
+		c.HPCurrent -= damage
+		return
+	}
+
+	leftOver := damage - c.HPCurrent
+	c.HPCurrent = 0
+	c.resetDeathSaves()
+	if leftOver >= c.HPMax {
+		c.die()
+	}
+}
+
+// Heal restores `healing` hit points, up to the character's maximum. A
+// dying or stable character regains consciousness and its death saves
+// are reset. The dead cannot be healed.
+func (c *Character) Heal(healing int) {
+	if healing <= 0 || c.Dead {
+		return
+	}
+
+	c.HPCurrent += healing
+	if c.HPCurrent > c.HPMax {
+		c.HPCurrent = c.HPMax
+	}
+	c.resetDeathSaves()
+}
+
+// RollDeathSave records a death save of a dying character which rolled
+// `roll` on a d20. A 10 or higher succeeds; a natural 1 counts as two
+// failures and a natural 20 brings the character back with 1 hit point.
+func (c *Character) RollDeathSave(roll int) error {
+	if c.LifeState() != LifeDying {
+		return ErrNotDying
+	}
+
+	switch {
+	case roll >= 20:
+		c.HPCurrent = 1
+		c.resetDeathSaves()
+	case roll <= 1:
+		c.failDeathSaves(2)
+	case roll >= 10:
+		c.DeathSaveSuccesses++
+		if c.DeathSaveSuccesses >= DeathSavesNeeded {
+			c.Stabilize()
+		}
+	default:
+		c.failDeathSaves(1)
+	}
+	return nil
+}
+
+// Stabilize stops a dying character from rolling death saves. It stays
+// at 0 hit points until healed.
+func (c *Character) Stabilize() error {
+	if c.LifeState() != LifeDying {
+		return ErrNotDying
+	}
+
+	c.resetDeathSaves()
+	c.Stable = true
+	return nil
+}
+
+func (c *Character) failDeathSaves(n int) {
+	c.DeathSaveFailures += n
+	if c.DeathSaveFailures >= DeathSavesNeeded {
+		c.die()
+	}
+}
+
+func (c *Character) die() {
+	c.HPCurrent = 0
+	c.DeathSaveSuccesses = 0
+	c.DeathSaveFailures = DeathSavesNeeded
+	c.Stable = false
+	c.Dead = true
+}
+
+func (c *Character) resetDeathSaves() {
+	c.DeathSaveSuccesses = 0
+	c.DeathSaveFailures = 0
+	c.Stable = false
+}
+
+// MaxXP is the experience needed to reach level 20, and the most that
+// a character may have.
+const MaxXP = 355000
+
+// XPThresholds lists the experience needed to reach each level,
+// starting with level 1.
+var XPThresholds = [20]int{
+	0, 300, 900, 2700, 6500, 14000, 23000, 34000, 48000, 64000,
+	85000, 100000, 120000, 140000, 165000, 195000, 225000, 265000, 305000, 355000,
+}
+
+// LevelForXP returns the level a character with `xp` experience has
+// reached.
+func LevelForXP(xp int) int {
+	level := 1
+	for i, threshold := range XPThresholds {
+		if xp >= threshold {
+			level = i + 1
+		}
+	}
+	return level
+}
+
+type RegistryKindType string
+
+const (
+	RegistryRace     RegistryKindType = "race"
+	RegistryClass                     = "class"
+	RegistrySubclass                  = "subclass"
+)
+
+func (t *RegistryKindType) UnmarshalJSON(b []byte) error {
+	type T RegistryKindType
+	var r *T = (*T)(t)
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return err
+	}
+	switch *t {
+	case
+		RegistryRace,
+		RegistryClass,
+		RegistrySubclass:
+		return nil
+	}
+	return ErrInvalidRegistryKind
+}
+
+// RegistryEntry is the code representation of the "RegistryEntry"
+// relation in the database schema. Official entries have neither an
+// owner nor a campaign; homebrew entries belong to a player or are
+// shared with everyone taking part in a campaign.
+type RegistryEntry struct {
+	ID                  int              `json:"id" db:"id"`
+	Kind                RegistryKindType `json:"kind" db:"kind"`
+	Name                string           `json:"name" db:"name"`
+	Description         string           `json:"description" db:"description"`
+	ClassID             *int             `json:"class_id,omitempty" db:"class_id"`                           // Subclasses only
+	ClassName           *string          `json:"class_name,omitempty" db:"class_name"`                       // Subclasses only
+	HitDie              *int             `json:"hit_die,omitempty" db:"hit_die"`                             // Classes only
+	AbilityBonuses      json.RawMessage  `json:"ability_bonuses,omitempty" db:"ability_bonuses"`             // Races only
+	AbilityBonusChoices *int             `json:"ability_bonus_choices,omitempty" db:"ability_bonus_choices"` // Races only, free +1 increases
+	OwnerUsername       *string          `json:"owner_username" db:"owner_username"`
+	CampaignID          *int             `json:"campaign_id" db:"campaign_id"`
+	CreatedAt           time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// IsOfficial reports whether the entry is part of the official rules
+// rather than homebrew.
+func (e *RegistryEntry) IsOfficial() bool {
+	return e.OwnerUsername == nil && e.CampaignID == nil
+}
+
+// RacialAbilityBonuses returns the ability score increases of a race.
+func (e *RegistryEntry) RacialAbilityBonuses() (map[AbilityType]int, error) {
+	bonuses := map[AbilityType]int{}
+	if len(e.AbilityBonuses) == 0 {
+		return bonuses, nil
+	}
+	err := json.Unmarshal(e.AbilityBonuses, &bonuses)
+	return bonuses, err
 }
 
 type ItemType string
@@ -356,18 +744,97 @@ type Spell struct {
 // Campaign is the code representation of the "Campaign" relation in the
 // database schema.
 type Campaign struct {
-	ID              int    `json:"id" db:"id"`
-	Name            string `json:"name" db:"name"`
-	CurrentLocation string `json:"current_location" db:"current_location"`
-	State           string `json:"state" db:"state"`
-	DungeonMaster   string `json:"dungeon_master" db:"dungeon_master"`
+	ID              int               `json:"id" db:"id"`
+	Name            string            `json:"name" db:"name"`
+	CurrentLocation string            `json:"current_location" db:"current_location"`
+	State           CampaignStateType `json:"state" db:"state"`
+	DungeonMaster   *string           `json:"dungeon_master" db:"dungeon_master"` // NULL once the DM's account is deleted
+	StatusNote      string            `json:"status_note" db:"status_note"`
+	StateChangedAt  time.Time         `json:"state_changed_at" db:"state_changed_at"`
+}
+
+type CampaignStateType string
+
+const (
+	CampaignPlanning   CampaignStateType = "planning"
+	CampaignRecruiting                   = "recruiting"
+	CampaignActive                       = "active"
+	CampaignOnHiatus                     = "on hiatus"
+	CampaignCompleted                    = "completed"
+	CampaignArchived                     = "archived"
+)
+
+func (t *CampaignStateType) UnmarshalJSON(b []byte) error {
+	type T CampaignStateType
+	var r *T = (*T)(t)
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return err
+	}
+	switch *t {
+	case
+		CampaignPlanning,
+		CampaignRecruiting,
+		CampaignActive,
+		CampaignOnHiatus,
+		CampaignCompleted,
+		CampaignArchived:
+		return nil
+	}
+	return ErrInvalidCampaignState
+}
+
+// CampaignStateTransitions lists the states which a campaign may move
+// to from each state. Archived campaigns are read-only, so nothing
+// leaves the archived state.
+var CampaignStateTransitions = map[CampaignStateType][]CampaignStateType{
+	CampaignPlanning:   {CampaignRecruiting, CampaignActive, CampaignArchived},
+	CampaignRecruiting: {CampaignPlanning, CampaignActive, CampaignArchived},
+	CampaignActive:     {CampaignRecruiting, CampaignOnHiatus, CampaignCompleted},
+	CampaignOnHiatus:   {CampaignActive, CampaignCompleted, CampaignArchived},
+	CampaignCompleted:  {CampaignActive, CampaignArchived},
+	CampaignArchived:   {},
+}
+
+// CanTransitionTo reports whether a campaign in state `t` may move to
+// state `next`.
+func (t CampaignStateType) CanTransitionTo(next CampaignStateType) bool {
+	for _, allowed := range CampaignStateTransitions[t] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// CampaignStateTransition is the code representation of the
+// "CampaignStateTransition" relation in the database schema. `FromState`
+// is nil for the transition recorded when the campaign was created.
+type CampaignStateTransition struct {
+	ID         int                `json:"id" db:"id"`
+	CampaignID int                `json:"campaign_id" db:"campaign_id"`
+	FromState  *CampaignStateType `json:"from_state" db:"from_state"`
+	ToState    CampaignStateType  `json:"to_state" db:"to_state"`
+	ChangedBy  *string            `json:"changed_by" db:"changed_by"`
+	ChangedAt  time.Time          `json:"changed_at" db:"changed_at"`
 }
 
 // CampaignMilestone is the code representation of the
-// "CampaignMilestones" relation in the database schema.
+// "CampaignMilestones" relation in the database schema. `OccurredOn` is
+// the real-world date formatted as YYYY-MM-DD, while `InGameDate` is
+// free text in the campaign's own calendar.
 type CampaignMilestone struct {
-	CampaignID int    `json:"campaign_id" db:"campaign_id"`
-	Milestone  string `json:"milestone" db:"milestone"`
+	ID             int       `json:"id" db:"id"`
+	CampaignID     int       `json:"campaign_id" db:"campaign_id"`
+	Sequence       int       `json:"sequence" db:"sequence"`
+	Milestone      string    `json:"milestone" db:"milestone"`
+	Description    string    `json:"description" db:"description"`
+	InGameDate     string    `json:"in_game_date" db:"in_game_date"`
+	OccurredOn     *string   `json:"occurred_on" db:"occurred_on"`
+	AuthorUsername *string   `json:"author_username" db:"author_username"`
+	XPReward       int       `json:"xp_reward" db:"xp_reward"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type CampaignParticipants struct {
@@ -377,150 +844,80 @@ type CampaignParticipants struct {
 	CharacterClass ClassType `json:"character_class"`
 }
 
-// BelongsTo is the code representation of the "BelongsTo" relation in
-// the database schema.
-type BelongsTo struct {
-	CharacterID int `json:"character_id" db:"character_id"`
-	CampaignID  int `json:"campaign_id" db:"campaign_id"`
+type RequestStatusType string
+
+const (
+	RequestPending  RequestStatusType = "pending"
+	RequestAccepted RequestStatusType = "accepted"
+	RequestDeclined RequestStatusType = "declined"
+	RequestRevoked  RequestStatusType = "revoked"
+)
+
+// CampaignInvitation is the code representation of the
+// "CampaignInvitation" relation in the database schema. Exactly one of
+// `InvitedUsername` and `Code` is set.
+type CampaignInvitation struct {
+	ID              int               `json:"id" db:"id"`
+	CampaignID      int               `json:"campaign_id" db:"campaign_id"`
+	CampaignName    string            `json:"campaign_name" db:"campaign_name"`
+	InvitedUsername *string           `json:"invited_username" db:"invited_username"`
+	Code            *string           `json:"code,omitempty" db:"code"`
+	Status          RequestStatusType `json:"status" db:"status"`
+	ExpiresAt       *time.Time        `json:"expires_at" db:"expires_at"`
+	CreatedAt       time.Time         `json:"created_at" db:"created_at"`
+	RespondedAt     *time.Time        `json:"responded_at" db:"responded_at"`
 }
 
-type ClassAttributeType string
+// CampaignJoinRequest is the code representation of the
+// "CampaignJoinRequest" relation in the database schema.
+type CampaignJoinRequest struct {
+	ID             int               `json:"id" db:"id"`
+	CampaignID     int               `json:"campaign_id" db:"campaign_id"`
+	CharacterID    int               `json:"character_id" db:"character_id"`
+	CharacterName  string            `json:"character_name" db:"character_name"`
+	PlayerUsername string            `json:"player_username" db:"player_username"`
+	Message        string            `json:"message" db:"message"`
+	Status         RequestStatusType `json:"status" db:"status"`
+	CreatedAt      time.Time         `json:"created_at" db:"created_at"`
+	RespondedAt    *time.Time        `json:"responded_at" db:"responded_at"`
+}
+
+// CampaignLogEntry is the code representation of the "CampaignLog"
+// relation in the database schema.
+type CampaignLogEntry struct {
+	ID            int       `json:"id" db:"id"`
+	CampaignID    int       `json:"campaign_id" db:"campaign_id"`
+	ActorUsername *string   `json:"actor_username" db:"actor_username"`
+	Kind          string    `json:"kind" db:"kind"`
+	Message       string    `json:"message" db:"message"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// XPAwardResult describes the experience one character received from an
+// award. `Amount` is less than was requested if the character reached
+// MaxXP.
+type XPAwardResult struct {
+	CharacterID    int    `json:"character_id" db:"character_id"`
+	CharacterName  string `json:"character_name" db:"character_name"`
+	PlayerUsername string `json:"player_username" db:"player_username"`
+	Amount         int    `json:"amount" db:"amount"`
+	XPBefore       int    `json:"xp_before" db:"xp_before"`
+	XPAfter        int    `json:"xp_after" db:"xp_after"`
+	LevelBefore    int    `json:"level_before" db:"-"`
+	LevelAfter     int    `json:"level_after" db:"-"`
+	LeveledUp      bool   `json:"leveled_up" db:"-"` // A level-up has become available
+}
+
+type JournalVisibilityType string
 
 const (
-	AncestralGuardian ClassAttributeType = "Ancestral Guardian"
-	Battlerager                          = "Battlerager"
-	Beast                                = "Beast"
-	Berserker                            = "Berserker"
-	StormHerald                          = "Storm Herald"
-	TotemWarrior                         = "Totem Warrior"
-	WildMagic                            = "Wild Magic"
-	Zealot                               = "Zealot"
-
-	Creation  = "Creation"
-	Eloquence = "Eloquence"
-	Glamour   = "Glamour"
-	Lore      = "Lore"
-	Swords    = "Swords"
-	Valor     = "Valor"
-	Whispers  = "Whispers"
-
-	Arcana    = "Arcana"
-	Death     = "Death"
-	Forge     = "Forge"
-	Grave     = "Grave"
-	Knowledge = "Knowledge"
-	Life      = "Life"
-	Light     = "Light"
-	Nature    = "Nature"
-	Order     = "Order"
-	Peace     = "Peace"
-	Tempest   = "Tempest"
-	Trickery  = "Trickery"
-	Twilight  = "Twilight"
-	War       = "War"
-
-	Dreams      = "Dreams"
-	TheLand     = "The Land"
-	TheMoon     = "The Moon"
-	TheShepherd = "The Shepherd"
-	TheSpores   = "The Spores"
-	TheStars    = "The Stars"
-	Wildfire    = "Wildfire"
-
-	ArcaneArcher   = "Arcane Archer"
-	Banneret       = "Banneret"
-	BattleMaster   = "Battle Master"
-	Cavalier       = "Cavalier"
-	Champion       = "Champion"
-	EchoKnight     = "Echo Knight"
-	EldritchKnight = "Eldritch Knight"
-	PsiWarrior     = "Psi Warrior"
-	RuneKnight     = "Rune Knight"
-	Samurai        = "Samurai"
-
-	Archery              = "Archery"
-	BlindFighting        = "Blind Fighting"
-	Defense              = "Defense"
-	DruidicWarrior       = "Druidic Warrior"
-	Dueling              = "Dueling"
-	GreatWeaponFighting  = "Great Weapon Fighting"
-	Interception         = "Interception"
-	Protection           = "Protection"
-	SuperiorTechnique    = "Superior Technique"
-	ThrownWeaponFighting = "Thrown Weapon Fighting"
-	TwoWeaponFighting    = "Two-Weapon Fighting"
-	UnarmedFighting      = "Unarmed Fighting"
-
-	AstralSelf    = "Astral Self"
-	DrunkenMaster = "Drunken Master"
-	FourElements  = "Four Elements"
-	Kensei        = "Kensei"
-	LongDeath     = "Long Death"
-	Mercy         = "Mercy"
-	OpenHand      = "Open Hand"
-	Shadow        = "Shadow"
-	SunSoul       = "Sun Soul"
-
-	TheAncients = "The Ancients"
-	Conquest    = "Conquest"
-	TheCrown    = "The Crown"
-	Devotion    = "Devotion"
-	Glory       = "Glory"
-	Redemption  = "Redemption"
-	Vengeance   = "Vengeance"
-	TheWatchers = "The Watchers"
-	Oathbreaker = "Oathbreaker"
-
-	BeastMaster   = "Beast Master"
-	FeyWanderer   = "Fey Wanderer"
-	GloomStalker  = "Gloom Stalker"
-	HorizonWalker = "Horizon Walker"
-	Hunter        = "Hunter"
-	MonsterSlayer = "Monster Slayer"
-	Swarmkeeper   = "Swarmkeeper"
-
-	ArcaneTrickster = "Arcane Trickster"
-	Assassin        = "Assassin"
-	Inquisitive     = "Inquisitive"
-	Mastermind      = "Mastermind"
-	Phantom         = "Phantom"
-	Scout           = "Scout"
-	Soulknife       = "Soulknife"
-	Swashbuckler    = "Swashbuckler"
-	Thief           = "Thief"
-
-	AberrantMind      = "Aberrant Mind"
-	ClockworkSoul     = "Clockwork Soul"
-	DraconicBloodline = "Draconic Bloodline"
-	DivineSoul        = "Divine Soul"
-	Storm             = "Storm"
-
-	Archfey     = "Archfey"
-	Celestial   = "Celestial"
-	Fathomless  = "Fathomless"
-	Fiend       = "Fiend"
-	Genie       = "Genie"
-	GreatOldOne = "Great Old One"
-	Hexblade    = "Hexblade"
-	Undying     = "Undying"
-
-	WAbjuration    = "Abjuration"
-	Bladesinging   = "Bladesinging"
-	Chronurgy      = "Chronurgy"
-	WConjuration   = "Conjuration"
-	WDivination    = "Divination"
-	WEnchantment   = "Enchantment"
-	WEvocation     = "Evocation"
-	Graviturgy     = "Graviturgy"
-	WIllusion      = "Illusion"
-	WNecromancy    = "Necromancy"
-	OrderOfScribes = "Order of Scribes"
-	Transmutation  = "Transmutation"
-	WarMagic       = "War Magic"
-)
-
-func (t *ClassAttributeType) UnmarshalJSON(b []byte) error {
-	type T ClassAttributeType
+	JournalEveryone JournalVisibilityType = "everyone"
+	JournalDMOnly                         = "dm_only"
+	JournalPlayers                        = "players"
+)
+
+func (t *JournalVisibilityType) UnmarshalJSON(b []byte) error {
+	type T JournalVisibilityType
 	var r *T = (*T)(t)
 	err := json.Unmarshal(b, &r)
 	if err != nil {
@@ -528,148 +925,1607 @@ func (t *ClassAttributeType) UnmarshalJSON(b []byte) error {
 	}
 	switch *t {
 	case
-		AncestralGuardian,
-		Battlerager,
-		Beast,
-		Berserker,
-		StormHerald,
-		TotemWarrior,
-		WildMagic,
-		Zealot,
-
-		Creation,
-		Eloquence,
-		Glamour,
-		Lore,
-		Swords,
-		Valor,
-		Whispers,
-
-		Arcana,
-		Death,
-		Forge,
-		Grave,
-		Knowledge,
-		Life,
-		Light,
-		Nature,
-		Order,
-		Peace,
-		Tempest,
-		Trickery,
-		Twilight,
-		War,
-
-		Dreams,
-		TheLand,
-		TheMoon,
-		TheShepherd,
-		TheSpores,
-		TheStars,
-		Wildfire,
-
-		ArcaneArcher,
-		Banneret,
-		BattleMaster,
-		Cavalier,
-		Champion,
-		EchoKnight,
-		EldritchKnight,
-		PsiWarrior,
-		RuneKnight,
-		Samurai,
-
-		Archery,
-		BlindFighting,
-		Defense,
-		DruidicWarrior,
-		Dueling,
-		GreatWeaponFighting,
-		Interception,
-		Protection,
-		SuperiorTechnique,
-		ThrownWeaponFighting,
-		TwoWeaponFighting,
-		UnarmedFighting,
-
-		AstralSelf,
-		DrunkenMaster,
-		FourElements,
-		Kensei,
-		LongDeath,
-		Mercy,
-		OpenHand,
-		Shadow,
-		SunSoul,
-
-		TheAncients,
-		Conquest,
-		TheCrown,
-		Devotion,
-		Glory,
-		Redemption,
-		Vengeance,
-		TheWatchers,
-		Oathbreaker,
-
-		BeastMaster,
-		FeyWanderer,
-		GloomStalker,
-		HorizonWalker,
-		Hunter,
-		MonsterSlayer,
-		Swarmkeeper,
-
-		ArcaneTrickster,
-		Assassin,
-		Inquisitive,
-		Mastermind,
-		Phantom,
-		Scout,
-		Soulknife,
-		Swashbuckler,
-		Thief,
-
-		AberrantMind,
-		ClockworkSoul,
-		DraconicBloodline,
-		DivineSoul,
-
-		Storm,
-		Archfey,
-		Celestial,
-		Fathomless,
-		Fiend,
-		Genie,
-		GreatOldOne,
-		Hexblade,
-		Undying,
-
-		WAbjuration,
-		Bladesinging,
-		Chronurgy,
-		WConjuration,
-		WDivination,
-		WEnchantment,
-		WEvocation,
-		Graviturgy,
-		WIllusion,
-		WNecromancy,
-		OrderOfScribes,
-		Transmutation,
-		WarMagic:
+		JournalEveryone,
+		JournalDMOnly,
+		JournalPlayers:
 		return nil
 	}
-	return ErrInvalidClassAttribute
+	return ErrInvalidJournalVisibility
 }
 
-/* Model for global statistics. */
-type Stats struct {
-	NumPlayersCreated    int `json:"num_player_account" db:"num_player_account"`
-	NumCharactersCreated int `json:"num_character_created" db:"num_character_created"`
-	NumCampaignsCreated  int `json:"num_campaign_created" db:"num_campaign_created"`
-	NumSpellsCreated     int `json:"num_spells_created" db:"num_spells_created"`
-	NumItemsCreated      int `json:"num_items_created" db:"num_items_created"`
+// JournalEntry is the code representation of the "JournalEntry"
+// relation in the database schema. `BodyHTML` is rendered from the
+// markdown `Body` when the entry is sent to a client.
+type JournalEntry struct {
+	ID             int                   `json:"id" db:"id"`
+	CampaignID     int                   `json:"campaign_id" db:"campaign_id"`
+	AuthorUsername *string               `json:"author_username" db:"author_username"`
+	Title          string                `json:"title" db:"title"`
+	Body           string                `json:"body" db:"body"`
+	BodyHTML       string                `json:"body_html" db:"-"`
+	Visibility     JournalVisibilityType `json:"visibility" db:"visibility"`
+	Viewers        []string              `json:"viewers" db:"-"`
+	Revision       int                   `json:"revision" db:"revision"`
+	CreatedAt      time.Time             `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time             `json:"updated_at" db:"updated_at"`
+}
+
+// JournalEntryRevision is the code representation of the
+// "JournalEntryRevision" relation in the database schema.
+type JournalEntryRevision struct {
+	EntryID    int                   `json:"entry_id" db:"entry_id"`
+	Revision   int                   `json:"revision" db:"revision"`
+	Title      string                `json:"title" db:"title"`
+	Body       string                `json:"body" db:"body"`
+	Visibility JournalVisibilityType `json:"visibility" db:"visibility"`
+	EditedBy   *string               `json:"edited_by" db:"edited_by"`
+	EditedAt   time.Time             `json:"edited_at" db:"edited_at"`
+}
+
+type NPCKindType string
+
+const (
+	NPCKindNPC     NPCKindType = "npc"
+	NPCKindMonster             = "monster"
+)
+
+func (t *NPCKindType) UnmarshalJSON(b []byte) error {
+	type T NPCKindType
+	var r *T = (*T)(t)
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return err
+	}
+	switch *t {
+	case
+		NPCKindNPC,
+		NPCKindMonster:
+		return nil
+	}
+	return ErrInvalidNPCKind
+}
+
+// ChallengeRatingXP maps each challenge rating to the experience a
+// creature of that rating is worth.
+var ChallengeRatingXP = map[string]int{
+	"0": 10, "1/8": 25, "1/4": 50, "1/2": 100,
+	"1": 200, "2": 450, "3": 700, "4": 1100, "5": 1800,
+	"6": 2300, "7": 2900, "8": 3900, "9": 5000, "10": 5900,
+	"11": 7200, "12": 8400, "13": 10000, "14": 11500, "15": 13000,
+	"16": 15000, "17": 18000, "18": 20000, "19": 22000, "20": 25000,
+	"21": 33000, "22": 41000, "23": 50000, "24": 62000, "25": 75000,
+	"26": 90000, "27": 105000, "28": 120000, "29": 135000, "30": 155000,
+}
+
+// NPCAction is a single action or trait in an NPC's stat block.
+type NPCAction struct {
+	Name        string `json:"name" db:"name"`
+	Description string `json:"description" db:"description"`
+}
+
+// CampaignNPC is the code representation of the "CampaignNPC" relation
+// in the database schema, along with its actions and tags.
+type CampaignNPC struct {
+	ID              int         `json:"id" db:"id"`
+	CampaignID      int         `json:"campaign_id" db:"campaign_id"`
+	Name            string      `json:"name" db:"name"`
+	Kind            NPCKindType `json:"kind" db:"kind"`
+	Size            string      `json:"size" db:"size"`
+	CreatureType    string      `json:"creature_type" db:"creature_type"`
+	Alignment       string      `json:"alignment" db:"alignment"`
+	Description     string      `json:"description" db:"description"`
+	ArmorClass      int         `json:"armor_class" db:"armor_class"`
+	HPMax           int         `json:"hp_max" db:"hp_max"`
+	HitDice         string      `json:"hit_dice" db:"hit_dice"`
+	Speed           int         `json:"speed" db:"speed"`
+	ChallengeRating string      `json:"challenge_rating" db:"challenge_rating"`
+	Strength        int         `json:"strength" db:"strength"`
+	Dexterity       int         `json:"dexterity" db:"dexterity"`
+	Constitution    int         `json:"constitution" db:"constitution"`
+	Intelligence    int         `json:"intelligence" db:"intelligence"`
+	Wisdom          int         `json:"wisdom" db:"wisdom"`
+	Charisma        int         `json:"charisma" db:"charisma"`
+	DMNotes         string      `json:"dm_notes,omitempty" db:"dm_notes"`
+	Hidden          bool        `json:"hidden" db:"hidden"`
+	CompendiumSlug  *string     `json:"compendium_slug" db:"compendium_slug"`
+	Actions         []NPCAction `json:"actions" db:"-"`
+	Tags            []string    `json:"tags" db:"-"`
+	CreatedAt       time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+type EncounterDifficultyType string
+
+const (
+	EncounterTrivial EncounterDifficultyType = "trivial"
+	EncounterEasy                            = "easy"
+	EncounterMedium                          = "medium"
+	EncounterHard                            = "hard"
+	EncounterDeadly                          = "deadly"
+)
+
+// EncounterXPThresholds lists the easy, medium, hard and deadly
+// experience thresholds of a single character, starting with level 1.
+var EncounterXPThresholds = [20][4]int{
+	{25, 50, 75, 100}, {50, 100, 150, 200}, {75, 150, 225, 400}, {125, 250, 375, 500},
+	{250, 500, 750, 1100}, {300, 600, 900, 1400}, {350, 750, 1100, 1700}, {450, 900, 1400, 2100},
+	{550, 1100, 1600, 2400}, {600, 1200, 1900, 2800}, {800, 1600, 2400, 3600}, {1000, 2000, 3000, 4500},
+	{1100, 2200, 3400, 5100}, {1250, 2500, 3800, 5700}, {1400, 2800, 4300, 6400}, {1600, 3200, 4800, 7200},
+	{2000, 3900, 5900, 8800}, {2100, 4200, 6300, 9500}, {2400, 4900, 7300, 10900}, {2800, 5700, 8500, 12700},
+}
+
+// encounterMultipliers lists the multipliers applied to the experience
+// of an encounter's monsters, from fewest to most monsters.
+var encounterMultipliers = []float64{0.5, 1, 1.5, 2, 2.5, 3, 4, 5}
+
+// EncounterMultiplier returns the multiplier applied to the experience of
+// `monsterCount` monsters fighting a party of `partySize` characters.
+// Small parties use the next higher multiplier and large parties the next
+// lower one.
+func EncounterMultiplier(monsterCount, partySize int) float64 {
+	var step int
+	switch {
+	case monsterCount <= 1:
+		step = 1
+	case monsterCount == 2:
+		step = 2
+	case monsterCount <= 6:
+		step = 3
+	case monsterCount <= 10:
+		step = 4
+	case monsterCount <= 14:
+		step = 5
+	default:
+		step = 6
+	}
+
+	if partySize < 3 {
+		step++
+	} else if partySize >= 6 {
+		step--
+	}
+	return encounterMultipliers[step]
+}
+
+// EncounterThresholds holds a party's experience thresholds for each
+// encounter difficulty.
+type EncounterThresholds struct {
+	Easy   int `json:"easy"`
+	Medium int `json:"medium"`
+	Hard   int `json:"hard"`
+	Deadly int `json:"deadly"`
+}
+
+// EncounterRating describes how difficult an encounter is for a party.
+type EncounterRating struct {
+	PartyLevels  []int                   `json:"party_levels"`
+	Thresholds   EncounterThresholds     `json:"thresholds"`
+	MonsterCount int                     `json:"monster_count"`
+	BaseXP       int                     `json:"base_xp"`
+	Multiplier   float64                 `json:"multiplier"`
+	AdjustedXP   int                     `json:"adjusted_xp"`
+	Difficulty   EncounterDifficultyType `json:"difficulty"`
+}
+
+// RateEncounter rates `monsters` against a party whose characters have
+// the levels `partyLevels`.
+func RateEncounter(partyLevels []int, monsters []EncounterMonster) EncounterRating {
+	rating := EncounterRating{PartyLevels: partyLevels}
+
+	for _, level := range partyLevels {
+		if level < 1 || level > len(EncounterXPThresholds) {
+			continue
+		}
+		t := EncounterXPThresholds[level-1]
+		rating.Thresholds.Easy += t[0]
+		rating.Thresholds.Medium += t[1]
+		rating.Thresholds.Hard += t[2]
+		rating.Thresholds.Deadly += t[3]
+	}
+
+	for _, m := range monsters {
+		rating.MonsterCount += m.Quantity
+		rating.BaseXP += ChallengeRatingXP[m.ChallengeRating] * m.Quantity
+	}
+
+	rating.Multiplier = EncounterMultiplier(rating.MonsterCount, len(partyLevels))
+	rating.AdjustedXP = int(float64(rating.BaseXP) * rating.Multiplier)
+
+	switch {
+	case rating.AdjustedXP >= rating.Thresholds.Deadly:
+		rating.Difficulty = EncounterDeadly
+	case rating.AdjustedXP >= rating.Thresholds.Hard:
+		rating.Difficulty = EncounterHard
+	case rating.AdjustedXP >= rating.Thresholds.Medium:
+		rating.Difficulty = EncounterMedium
+	case rating.AdjustedXP >= rating.Thresholds.Easy:
+		rating.Difficulty = EncounterEasy
+	default:
+		rating.Difficulty = EncounterTrivial
+	}
+
+	return rating
+}
+
+// EncounterMonster is the code representation of the "EncounterMonster"
+// relation in the database schema. Its name and challenge rating are
+// copied from the NPC or compendium monster it was chosen from, if any.
+type EncounterMonster struct {
+	NPCID           *int    `json:"npc_id" db:"npc_id"`
+	CompendiumSlug  *string `json:"compendium_slug" db:"compendium_slug"`
+	Name            string  `json:"name" db:"name"`
+	ChallengeRating string  `json:"challenge_rating" db:"challenge_rating"`
+	Quantity        int     `json:"quantity" db:"quantity"`
+}
+
+// Encounter is the code representation of the "Encounter" relation in
+// the database schema, along with its monsters.
+type Encounter struct {
+	ID         int                `json:"id" db:"id"`
+	CampaignID int                `json:"campaign_id" db:"campaign_id"`
+	Name       string             `json:"name" db:"name"`
+	Notes      string             `json:"notes" db:"notes"`
+	CreatedBy  *string            `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" db:"updated_at"`
+	Monsters   []EncounterMonster `json:"monsters" db:"-"`
+	Rating     *EncounterRating   `json:"rating,omitempty" db:"-"`
+}
+
+// AbilityModifier returns the modifier of an ability score, which is
+// rounded down.
+func AbilityModifier(score int) int {
+	modifier := (score - 10) / 2
+	if score < 10 && (score-10)%2 != 0 {
+		modifier--
+	}
+	return modifier
+}
+
+type ConditionType string
+
+const (
+	ConditionBlinded       ConditionType = "blinded"
+	ConditionCharmed                     = "charmed"
+	ConditionDeafened                    = "deafened"
+	ConditionExhaustion                  = "exhaustion"
+	ConditionFrightened                  = "frightened"
+	ConditionGrappled                    = "grappled"
+	ConditionIncapacitated               = "incapacitated"
+	ConditionInvisible                   = "invisible"
+	ConditionParalyzed                   = "paralyzed"
+	ConditionPetrified                   = "petrified"
+	ConditionPoisoned                    = "poisoned"
+	ConditionProne                       = "prone"
+	ConditionRestrained                  = "restrained"
+	ConditionStunned                     = "stunned"
+	ConditionUnconscious                 = "unconscious"
+)
+
+func (t *ConditionType) UnmarshalJSON(b []byte) error {
+	type T ConditionType
+	var r *T = (*T)(t)
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return err
+	}
+	switch *t {
+	case
+		ConditionBlinded,
+		ConditionCharmed,
+		ConditionDeafened,
+		ConditionExhaustion,
+		ConditionFrightened,
+		ConditionGrappled,
+		ConditionIncapacitated,
+		ConditionInvisible,
+		ConditionParalyzed,
+		ConditionPetrified,
+		ConditionPoisoned,
+		ConditionProne,
+		ConditionRestrained,
+		ConditionStunned,
+		ConditionUnconscious:
+		return nil
+	}
+	return ErrInvalidCondition
+}
+
+// MaxExhaustion is the level of exhaustion at which a creature dies.
+const MaxExhaustion = 6
+
+type RestType string
+
+const (
+	RestShort RestType = "short"
+	RestLong           = "long"
+)
+
+func (t *RestType) UnmarshalJSON(b []byte) error {
+	type T RestType
+	var r *T = (*T)(t)
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return err
+	}
+	switch *t {
+	case
+		RestShort,
+		RestLong:
+		return nil
+	}
+	return ErrInvalidRest
+}
+
+// AppliedCondition is a condition affecting a character or combatant.
+// A condition with neither RoundsRemaining nor UntilRest lasts until it
+// is removed; one lasting until a short rest also ends with a long rest.
+type AppliedCondition struct {
+	Condition       ConditionType `json:"condition" db:"condition"`
+	Level           *int          `json:"level" db:"level"` // Exhaustion only, from 1 to MaxExhaustion
+	Source          string        `json:"source" db:"source"`
+	RoundsRemaining *int          `json:"rounds_remaining" db:"rounds_remaining"` // Counted down as combat rounds start
+	UntilRest       *RestType     `json:"until_rest" db:"until_rest"`
+	AppliedAt       time.Time     `json:"applied_at" db:"applied_at"`
+}
+
+// ConditionEffects are the mechanical effects of a set of conditions.
+// Advantage and disadvantage are reported separately, since they cancel
+// each other out only on a given roll.
+type ConditionEffects struct {
+	Incapacitated            bool          `json:"incapacitated"`
+	SpeedZero                bool          `json:"speed_zero"`
+	SpeedHalved              bool          `json:"speed_halved"`
+	HPMaxHalved              bool          `json:"hp_max_halved"`
+	Dead                     bool          `json:"dead"`
+	AttackAdvantage          bool          `json:"attack_advantage"`
+	AttackDisadvantage       bool          `json:"attack_disadvantage"`
+	AttackedWithAdvantage    bool          `json:"attacked_with_advantage"`
+	AttackedWithDisadvantage bool          `json:"attacked_with_disadvantage"`
+	AbilityCheckDisadvantage bool          `json:"ability_check_disadvantage"`
+	SavingThrowDisadvantage  []AbilityType `json:"saving_throw_disadvantage"`
+	SavingThrowAutoFail      []AbilityType `json:"saving_throw_auto_fail"`
+}
+
+// CalculateConditionEffects returns the combined effects of
+// `conditions`, following the conditions appendix of the Player's
+// Handbook. Exhaustion has the effects of its level and every level
+// below it.
+func CalculateConditionEffects(conditions []AppliedCondition) ConditionEffects {
+	e := ConditionEffects{SavingThrowDisadvantage: []AbilityType{}, SavingThrowAutoFail: []AbilityType{}}
+	physicalSaves := []AbilityType{AbilityStrength, AbilityDexterity}
+
+	for _, c := range conditions {
+		switch c.Condition {
+		case ConditionBlinded:
+			e.AttackDisadvantage = true
+			e.AttackedWithAdvantage = true
+		case ConditionExhaustion:
+			level := 1
+			if c.Level != nil {
+				level = *c.Level
+			}
+			e.AbilityCheckDisadvantage = true
+			if level >= 2 {
+				e.SpeedHalved = true
+			}
+			if level >= 3 {
+				e.AttackDisadvantage = true
+				e.SavingThrowDisadvantage = append(e.SavingThrowDisadvantage, Abilities...)
+			}
+			if level >= 4 {
+				e.HPMaxHalved = true
+			}
+			if level >= 5 {
+				e.SpeedZero = true
+			}
+			if level >= MaxExhaustion {
+				e.Dead = true
+			}
+		case ConditionFrightened, ConditionPoisoned:
+			e.AttackDisadvantage = true
+			e.AbilityCheckDisadvantage = true
+		case ConditionGrappled:
+			e.SpeedZero = true
+		case ConditionIncapacitated:
+			e.Incapacitated = true
+		case ConditionInvisible:
+			e.AttackAdvantage = true
+			e.AttackedWithDisadvantage = true
+		case ConditionParalyzed, ConditionPetrified, ConditionStunned, ConditionUnconscious:
+			e.Incapacitated = true
+			e.SpeedZero = true
+			e.AttackedWithAdvantage = true
+			e.SavingThrowAutoFail = append(e.SavingThrowAutoFail, physicalSaves...)
+			if c.Condition == ConditionUnconscious {
+				e.AttackDisadvantage = true // Unconscious creatures fall prone
+			}
+		case ConditionProne:
+			e.AttackDisadvantage = true
+		case ConditionRestrained:
+			e.SpeedZero = true
+			e.AttackDisadvantage = true
+			e.AttackedWithAdvantage = true
+			e.SavingThrowDisadvantage = append(e.SavingThrowDisadvantage, AbilityDexterity)
+		}
+	}
+
+	e.SavingThrowDisadvantage = uniqueAbilities(e.SavingThrowDisadvantage)
+	e.SavingThrowAutoFail = uniqueAbilities(e.SavingThrowAutoFail)
+	return e
+}
+
+// uniqueAbilities returns `abilities` without duplicates, in the order of
+// Abilities.
+func uniqueAbilities(abilities []AbilityType) []AbilityType {
+	unique := []AbilityType{}
+	for _, a := range Abilities {
+		for _, b := range abilities {
+			if a == b {
+				unique = append(unique, a)
+				break
+			}
+		}
+	}
+	return unique
+}
+
+// EffectiveSpeed returns `speed` as changed by the effects.
+func (e ConditionEffects) EffectiveSpeed(speed int) int {
+	if e.SpeedZero || e.Dead {
+		return 0
+	}
+	if e.SpeedHalved {
+		return speed / 2
+	}
+	return speed
+}
+
+// EffectiveHPMax returns `hpMax` as changed by the effects.
+func (e ConditionEffects) EffectiveHPMax(hpMax int) int {
+	if e.HPMaxHalved {
+		return hpMax / 2
+	}
+	return hpMax
+}
+
+// Combat is the code representation of the "Combat" relation in the
+// database schema, along with its combatants in turn order.
+type Combat struct {
+	ID                 int         `json:"id" db:"id"`
+	CampaignID         int         `json:"campaign_id" db:"campaign_id"`
+	EncounterID        *int        `json:"encounter_id" db:"encounter_id"`
+	Name               string      `json:"name" db:"name"`
+	Round              int         `json:"round" db:"round"`
+	CurrentCombatantID *int        `json:"current_combatant_id" db:"current_combatant_id"`
+	Version            int         `json:"version" db:"version"`
+	StartedBy          *string     `json:"started_by" db:"started_by"`
+	StartedAt          time.Time   `json:"started_at" db:"started_at"`
+	UpdatedAt          time.Time   `json:"updated_at" db:"updated_at"`
+	EndedAt            *time.Time  `json:"ended_at" db:"ended_at"`
+	Combatants         []Combatant `json:"combatants" db:"-"`
+}
+
+// Combatant is the code representation of the "Combatant" relation in
+// the database schema, along with its conditions and the player who
+// controls it, if any. Players do not see the statistics of NPCs, in
+// which case StatsHidden is set.
+type Combatant struct {
+	ID              int                `json:"id" db:"id"`
+	CombatID        int                `json:"combat_id" db:"combat_id"`
+	CharacterID     *int               `json:"character_id" db:"character_id"`
+	NPCID           *int               `json:"npc_id" db:"npc_id"`
+	PlayerUsername  *string            `json:"player_username" db:"player_username"`
+	Name            string             `json:"name" db:"name"`
+	Initiative      *int               `json:"initiative" db:"initiative"`
+	InitiativeBonus int                `json:"initiative_bonus" db:"initiative_bonus"`
+	ArmorClass      int                `json:"armor_class" db:"armor_class"`
+	HPMax           int                `json:"hp_max" db:"hp_max"`
+	HPCurrent       int                `json:"hp_current" db:"hp_current"`
+	TempHP          int                `json:"temp_hp" db:"temp_hp"`
+	Defeated        bool               `json:"defeated" db:"defeated"`
+	CreatedAt       time.Time          `json:"created_at" db:"created_at"`
+	Conditions      []AppliedCondition `json:"conditions" db:"-"`
+	Effects         ConditionEffects   `json:"effects" db:"-"`
+	Bloodied        bool               `json:"bloodied" db:"-"` // At half of its hit points or fewer
+	StatsHidden     bool               `json:"stats_hidden" db:"-"`
+}
+
+type AbilityType string
+
+const (
+	AbilityStrength     AbilityType = "strength"
+	AbilityDexterity                = "dexterity"
+	AbilityConstitution             = "constitution"
+	AbilityIntelligence             = "intelligence"
+	AbilityWisdom                   = "wisdom"
+	AbilityCharisma                 = "charisma"
+)
+
+func (t *AbilityType) UnmarshalJSON(b []byte) error {
+	type T AbilityType
+	var r *T = (*T)(t)
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return err
+	}
+	switch *t {
+	case
+		AbilityStrength,
+		AbilityDexterity,
+		AbilityConstitution,
+		AbilityIntelligence,
+		AbilityWisdom,
+		AbilityCharisma:
+		return nil
+	}
+	return ErrInvalidAbility
+}
+
+// AbilityScore returns the character's score in `ability`.
+func (c *Character) AbilityScore(ability AbilityType) int {
+	switch ability {
+	case AbilityStrength:
+		return c.Strength
+	case AbilityDexterity:
+		return c.Dexterity
+	case AbilityConstitution:
+		return c.Constitution
+	case AbilityIntelligence:
+		return c.Intelligence
+	case AbilityWisdom:
+		return c.Wisdom
+	case AbilityCharisma:
+		return c.Charisma
+	}
+	return 10
+}
+
+// Abilities lists every ability in the order of a character sheet.
+var Abilities = []AbilityType{
+	AbilityStrength, AbilityDexterity, AbilityConstitution,
+	AbilityIntelligence, AbilityWisdom, AbilityCharisma,
+}
+
+type AbilityMethodType string
+
+const (
+	AbilityMethodManual        AbilityMethodType = "manual"
+	AbilityMethodPointBuy                        = "point_buy"
+	AbilityMethodStandardArray                   = "standard_array"
+	AbilityMethodRolled                          = "rolled"
+)
+
+func (t *AbilityMethodType) UnmarshalJSON(b []byte) error {
+	type T AbilityMethodType
+	var r *T = (*T)(t)
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return err
+	}
+	switch *t {
+	case
+		AbilityMethodManual,
+		AbilityMethodPointBuy,
+		AbilityMethodStandardArray,
+		AbilityMethodRolled:
+		return nil
+	}
+	return ErrInvalidAbilityMethod
+}
+
+// Point-buy rules: every score starts at 8 and may be raised to 15 by
+// spending from a budget of points.
+const (
+	PointBuyBudget   = 27
+	PointBuyMinScore = 8
+	PointBuyMaxScore = 15
+)
+
+// PointBuyCosts maps each score allowed by point-buy to its cost.
+var PointBuyCosts = map[int]int{8: 0, 9: 1, 10: 2, 11: 3, 12: 4, 13: 5, 14: 7, 15: 9}
+
+// StandardArray lists the scores assigned by the standard array method.
+var StandardArray = []int{15, 14, 13, 12, 10, 8}
+
+// AbilityRollExpression is rolled once per ability score by the rolled
+// method.
+const AbilityRollExpression = "4d6dl1"
+
+// AbilityRoll is the code representation of the "AbilityRoll" relation
+// in the database schema: six ability scores rolled by the server, kept
+// so that rolled characters can be checked against them.
+type AbilityRoll struct {
+	ID             int             `json:"id" db:"id"`
+	PlayerUsername string          `json:"player_username" db:"player_username"`
+	Scores         []int           `json:"scores" db:"-"`
+	Rolls          json.RawMessage `json:"rolls" db:"rolls"`
+	CharacterID    *int            `json:"character_id" db:"-"` // Character created from the roll
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+}
+
+// AbilityScores returns the six ability scores of the character in the
+// order of Abilities.
+func (c *Character) AbilityScores() []int {
+	scores := make([]int, len(Abilities))
+	for i, ability := range Abilities {
+		scores[i] = c.AbilityScore(ability)
+	}
+	return scores
+}
+
+// SetAbilityScore sets the character's score in `ability`.
+func (c *Character) SetAbilityScore(ability AbilityType, score int) {
+	switch ability {
+	case AbilityStrength:
+		c.Strength = score
+	case AbilityDexterity:
+		c.Dexterity = score
+	case AbilityConstitution:
+		c.Constitution = score
+	case AbilityIntelligence:
+		c.Intelligence = score
+	case AbilityWisdom:
+		c.Wisdom = score
+	case AbilityCharisma:
+		c.Charisma = score
+	}
+}
+
+type SkillType string
+
+const (
+	SkillAcrobatics     SkillType = "acrobatics"
+	SkillAnimalHandling           = "animal_handling"
+	SkillArcana                   = "arcana"
+	SkillAthletics                = "athletics"
+	SkillDeception                = "deception"
+	SkillHistory                  = "history"
+	SkillInsight                  = "insight"
+	SkillIntimidation             = "intimidation"
+	SkillInvestigation            = "investigation"
+	SkillMedicine                 = "medicine"
+	SkillNature                   = "nature"
+	SkillPerception               = "perception"
+	SkillPerformance              = "performance"
+	SkillPersuasion               = "persuasion"
+	SkillReligion                 = "religion"
+	SkillSleightOfHand            = "sleight_of_hand"
+	SkillStealth                  = "stealth"
+	SkillSurvival                 = "survival"
+)
+
+// Skills lists every skill in alphabetical order.
+var Skills = []SkillType{
+	SkillAcrobatics, SkillAnimalHandling, SkillArcana, SkillAthletics, SkillDeception, SkillHistory,
+	SkillInsight, SkillIntimidation, SkillInvestigation, SkillMedicine, SkillNature, SkillPerception,
+	SkillPerformance, SkillPersuasion, SkillReligion, SkillSleightOfHand, SkillStealth, SkillSurvival,
+}
+
+// SkillAbilities maps each skill to the ability it is rolled with.
+var SkillAbilities = map[SkillType]AbilityType{
+	SkillAcrobatics:     AbilityDexterity,
+	SkillAnimalHandling: AbilityWisdom,
+	SkillArcana:         AbilityIntelligence,
+	SkillAthletics:      AbilityStrength,
+	SkillDeception:      AbilityCharisma,
+	SkillHistory:        AbilityIntelligence,
+	SkillInsight:        AbilityWisdom,
+	SkillIntimidation:   AbilityCharisma,
+	SkillInvestigation:  AbilityIntelligence,
+	SkillMedicine:       AbilityWisdom,
+	SkillNature:         AbilityIntelligence,
+	SkillPerception:     AbilityWisdom,
+	SkillPerformance:    AbilityCharisma,
+	SkillPersuasion:     AbilityCharisma,
+	SkillReligion:       AbilityIntelligence,
+	SkillSleightOfHand:  AbilityDexterity,
+	SkillStealth:        AbilityDexterity,
+	SkillSurvival:       AbilityWisdom,
+}
+
+// ClassSavingThrows lists the saving throws each official class is
+// proficient in. Characters start out with those of their first class.
+var ClassSavingThrows = map[ClassType][]AbilityType{
+	Barbarian: {AbilityStrength, AbilityConstitution},
+	Bard:      {AbilityDexterity, AbilityCharisma},
+	Cleric:    {AbilityWisdom, AbilityCharisma},
+	Druid:     {AbilityIntelligence, AbilityWisdom},
+	Fighter:   {AbilityStrength, AbilityConstitution},
+	Monk:      {AbilityStrength, AbilityDexterity},
+	Paladin:   {AbilityWisdom, AbilityCharisma},
+	Ranger:    {AbilityStrength, AbilityDexterity},
+	Rogue:     {AbilityDexterity, AbilityIntelligence},
+	Sorcerer:  {AbilityConstitution, AbilityCharisma},
+	Warlock:   {AbilityWisdom, AbilityCharisma},
+	Wizard:    {AbilityIntelligence, AbilityWisdom},
+}
+
+// ProficiencyBonus returns the proficiency bonus of a character of
+// level `level`.
+func ProficiencyBonus(level int) int {
+	if level < 1 {
+		level = 1
+	}
+	return 2 + (level-1)/4
+}
+
+type ProficiencyKindType string
+
+const (
+	ProficiencySavingThrow ProficiencyKindType = "saving_throw"
+	ProficiencySkill                           = "skill"
+	ProficiencyTool                            = "tool"
+	ProficiencyLanguage                        = "language"
+)
+
+func (t *ProficiencyKindType) UnmarshalJSON(b []byte) error {
+	type T ProficiencyKindType
+	var r *T = (*T)(t)
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return err
+	}
+	switch *t {
+	case
+		ProficiencySavingThrow,
+		ProficiencySkill,
+		ProficiencyTool,
+		ProficiencyLanguage:
+		return nil
+	}
+	return ErrInvalidProficiencyKind
+}
+
+// CharacterProficiency is the code representation of the
+// "CharacterProficiency" relation in the database schema. Saving throws
+// are named after their ability and skills after SkillType; tools and
+// languages are free text. Only skills and tools can have expertise.
+type CharacterProficiency struct {
+	CharacterID int                 `json:"character_id" db:"character_id"`
+	Kind        ProficiencyKindType `json:"kind" db:"kind"`
+	Name        string              `json:"name" db:"name"`
+	Expertise   bool                `json:"expertise" db:"expertise"`
+}
+
+// SavingThrowBonus is the bonus of a character to a saving throw.
+type SavingThrowBonus struct {
+	Ability    AbilityType `json:"ability"`
+	Proficient bool        `json:"proficient"`
+	Bonus      int         `json:"bonus"`
+}
+
+// SkillBonus is the bonus of a character to a skill check.
+type SkillBonus struct {
+	Skill      SkillType   `json:"skill"`
+	Ability    AbilityType `json:"ability"`
+	Proficient bool        `json:"proficient"`
+	Expertise  bool        `json:"expertise"`
+	Bonus      int         `json:"bonus"`
+}
+
+// CalculateBonuses returns the saving throw and skill bonuses of `c`
+// given its proficiencies: the ability modifier, plus the proficiency
+// bonus when proficient, doubled with expertise.
+func CalculateBonuses(c *Character, proficiencies []CharacterProficiency) ([]SavingThrowBonus, []SkillBonus) {
+	proficiencyBonus := ProficiencyBonus(c.Level)
+
+	saves := map[string]bool{}
+	skills := map[string]bool{} // Whether the skill has expertise
+	for _, p := range proficiencies {
+		switch p.Kind {
+		case ProficiencySavingThrow:
+			saves[p.Name] = true
+		case ProficiencySkill:
+			skills[p.Name] = p.Expertise
+		}
+	}
+
+	savingThrows := []SavingThrowBonus{}
+	for _, ability := range Abilities {
+		s := SavingThrowBonus{Ability: ability, Bonus: AbilityModifier(c.AbilityScore(ability))}
+		if saves[string(ability)] {
+			s.Proficient = true
+			s.Bonus += proficiencyBonus
+		}
+		savingThrows = append(savingThrows, s)
+	}
+
+	skillBonuses := []SkillBonus{}
+	for _, skill := range Skills {
+		ability := SkillAbilities[skill]
+		s := SkillBonus{Skill: skill, Ability: ability, Bonus: AbilityModifier(c.AbilityScore(ability))}
+		if expertise, ok := skills[string(skill)]; ok {
+			s.Proficient = true
+			s.Expertise = expertise
+			s.Bonus += proficiencyBonus
+			if expertise {
+				s.Bonus += proficiencyBonus
+			}
+		}
+		skillBonuses = append(skillBonuses, s)
+	}
+
+	return savingThrows, skillBonuses
+}
+
+type RollModeType string
+
+const (
+	RollNormal       RollModeType = "normal"
+	RollAdvantage                 = "advantage"
+	RollDisadvantage              = "disadvantage"
+)
+
+func (t *RollModeType) UnmarshalJSON(b []byte) error {
+	type T RollModeType
+	var r *T = (*T)(t)
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return err
+	}
+	switch *t {
+	case
+		RollNormal,
+		RollAdvantage,
+		RollDisadvantage:
+		return nil
+	}
+	return ErrInvalidRollMode
+}
+
+// DiceRoll is the code representation of the "DiceRoll" relation in the
+// database schema. Terms holds the dice rolled for each term of the
+// expression, as returned by the dice package.
+type DiceRoll struct {
+	ID            int             `json:"id" db:"id"`
+	CampaignID    int             `json:"campaign_id" db:"campaign_id"`
+	RolledBy      *string         `json:"rolled_by" db:"rolled_by"`
+	CharacterID   *int            `json:"character_id" db:"character_id"`
+	CharacterName *string         `json:"character_name" db:"character_name"`
+	Label         string          `json:"label" db:"label"`
+	Expression    string          `json:"expression" db:"expression"`
+	Mode          RollModeType    `json:"mode" db:"mode"`
+	Ability       *AbilityType    `json:"ability" db:"ability"`
+	Modifier      int             `json:"modifier" db:"modifier"`
+	Terms         json.RawMessage `json:"terms" db:"terms"`
+	Total         int             `json:"total" db:"total"`
+	RolledAt      time.Time       `json:"rolled_at" db:"rolled_at"`
+}
+
+// BelongsTo is the code representation of the "BelongsTo" relation in
+// the database schema.
+type BelongsTo struct {
+	CharacterID int `json:"character_id" db:"character_id"`
+	CampaignID  int `json:"campaign_id" db:"campaign_id"`
+}
+
+// ClassAttributeType names a subclass of the registry. The official
+// subclasses are listed below, prefixed with their class since some
+// classes share subclass names.
+type ClassAttributeType string
+
+const (
+	BarbarianAncestralGuardian ClassAttributeType = "Ancestral Guardian"
+	BarbarianBattlerager       ClassAttributeType = "Battlerager"
+	BarbarianBeast             ClassAttributeType = "Beast"
+	BarbarianBerserker         ClassAttributeType = "Berserker"
+	BarbarianStormHerald       ClassAttributeType = "Storm Herald"
+	BarbarianTotemWarrior      ClassAttributeType = "Totem Warrior"
+	BarbarianWildMagic         ClassAttributeType = "Wild Magic"
+	BarbarianZealot            ClassAttributeType = "Zealot"
+
+	BardCreation  ClassAttributeType = "Creation"
+	BardEloquence ClassAttributeType = "Eloquence"
+	BardGlamour   ClassAttributeType = "Glamour"
+	BardLore      ClassAttributeType = "Lore"
+	BardSwords    ClassAttributeType = "Swords"
+	BardValor     ClassAttributeType = "Valor"
+	BardWhispers  ClassAttributeType = "Whispers"
+
+	ClericArcana    ClassAttributeType = "Arcana"
+	ClericDeath     ClassAttributeType = "Death"
+	ClericForge     ClassAttributeType = "Forge"
+	ClericGrave     ClassAttributeType = "Grave"
+	ClericKnowledge ClassAttributeType = "Knowledge"
+	ClericLife      ClassAttributeType = "Life"
+	ClericLight     ClassAttributeType = "Light"
+	ClericNature    ClassAttributeType = "Nature"
+	ClericOrder     ClassAttributeType = "Order"
+	ClericPeace     ClassAttributeType = "Peace"
+	ClericTempest   ClassAttributeType = "Tempest"
+	ClericTrickery  ClassAttributeType = "Trickery"
+	ClericTwilight  ClassAttributeType = "Twilight"
+	ClericWar       ClassAttributeType = "War"
+
+	DruidDreams      ClassAttributeType = "Dreams"
+	DruidTheLand     ClassAttributeType = "The Land"
+	DruidTheMoon     ClassAttributeType = "The Moon"
+	DruidTheShepherd ClassAttributeType = "The Shepherd"
+	DruidTheSpores   ClassAttributeType = "The Spores"
+	DruidTheStars    ClassAttributeType = "The Stars"
+	DruidWildfire    ClassAttributeType = "Wildfire"
+
+	FighterArcaneArcher   ClassAttributeType = "Arcane Archer"
+	FighterBanneret       ClassAttributeType = "Banneret"
+	FighterBattleMaster   ClassAttributeType = "Battle Master"
+	FighterCavalier       ClassAttributeType = "Cavalier"
+	FighterChampion       ClassAttributeType = "Champion"
+	FighterEchoKnight     ClassAttributeType = "Echo Knight"
+	FighterEldritchKnight ClassAttributeType = "Eldritch Knight"
+	FighterPsiWarrior     ClassAttributeType = "Psi Warrior"
+	FighterRuneKnight     ClassAttributeType = "Rune Knight"
+	FighterSamurai        ClassAttributeType = "Samurai"
+
+	MonkAstralSelf    ClassAttributeType = "Astral Self"
+	MonkDrunkenMaster ClassAttributeType = "Drunken Master"
+	MonkFourElements  ClassAttributeType = "Four Elements"
+	MonkKensei        ClassAttributeType = "Kensei"
+	MonkLongDeath     ClassAttributeType = "Long Death"
+	MonkMercy         ClassAttributeType = "Mercy"
+	MonkOpenHand      ClassAttributeType = "Open Hand"
+	MonkShadow        ClassAttributeType = "Shadow"
+	MonkSunSoul       ClassAttributeType = "Sun Soul"
+
+	PaladinTheAncients ClassAttributeType = "The Ancients"
+	PaladinConquest    ClassAttributeType = "Conquest"
+	PaladinTheCrown    ClassAttributeType = "The Crown"
+	PaladinDevotion    ClassAttributeType = "Devotion"
+	PaladinGlory       ClassAttributeType = "Glory"
+	PaladinRedemption  ClassAttributeType = "Redemption"
+	PaladinVengeance   ClassAttributeType = "Vengeance"
+	PaladinTheWatchers ClassAttributeType = "The Watchers"
+	PaladinOathbreaker ClassAttributeType = "Oathbreaker"
+
+	RangerBeastMaster   ClassAttributeType = "Beast Master"
+	RangerFeyWanderer   ClassAttributeType = "Fey Wanderer"
+	RangerGloomStalker  ClassAttributeType = "Gloom Stalker"
+	RangerHorizonWalker ClassAttributeType = "Horizon Walker"
+	RangerHunter        ClassAttributeType = "Hunter"
+	RangerMonsterSlayer ClassAttributeType = "Monster Slayer"
+	RangerSwarmkeeper   ClassAttributeType = "Swarmkeeper"
+
+	RogueArcaneTrickster ClassAttributeType = "Arcane Trickster"
+	RogueAssassin        ClassAttributeType = "Assassin"
+	RogueInquisitive     ClassAttributeType = "Inquisitive"
+	RogueMastermind      ClassAttributeType = "Mastermind"
+	RoguePhantom         ClassAttributeType = "Phantom"
+	RogueScout           ClassAttributeType = "Scout"
+	RogueSoulknife       ClassAttributeType = "Soulknife"
+	RogueSwashbuckler    ClassAttributeType = "Swashbuckler"
+	RogueThief           ClassAttributeType = "Thief"
+
+	SorcererAberrantMind      ClassAttributeType = "Aberrant Mind"
+	SorcererClockworkSoul     ClassAttributeType = "Clockwork Soul"
+	SorcererDraconicBloodline ClassAttributeType = "Draconic Bloodline"
+	SorcererDivineSoul        ClassAttributeType = "Divine Soul"
+	SorcererShadow            ClassAttributeType = "Shadow"
+	SorcererStorm             ClassAttributeType = "Storm"
+	SorcererWildMagic         ClassAttributeType = "Wild Magic"
+
+	WarlockArchfey     ClassAttributeType = "Archfey"
+	WarlockCelestial   ClassAttributeType = "Celestial"
+	WarlockFathomless  ClassAttributeType = "Fathomless"
+	WarlockFiend       ClassAttributeType = "Fiend"
+	WarlockGenie       ClassAttributeType = "Genie"
+	WarlockGreatOldOne ClassAttributeType = "Great Old One"
+	WarlockHexblade    ClassAttributeType = "Hexblade"
+	WarlockUndying     ClassAttributeType = "Undying"
+
+	WizardAbjuration     ClassAttributeType = "Abjuration"
+	WizardBladesinging   ClassAttributeType = "Bladesinging"
+	WizardChronurgy      ClassAttributeType = "Chronurgy"
+	WizardConjuration    ClassAttributeType = "Conjuration"
+	WizardDivination     ClassAttributeType = "Divination"
+	WizardEnchantment    ClassAttributeType = "Enchantment"
+	WizardEvocation      ClassAttributeType = "Evocation"
+	WizardGraviturgy     ClassAttributeType = "Graviturgy"
+	WizardIllusion       ClassAttributeType = "Illusion"
+	WizardNecromancy     ClassAttributeType = "Necromancy"
+	WizardOrderOfScribes ClassAttributeType = "Order of Scribes"
+	WizardTransmutation  ClassAttributeType = "Transmutation"
+	WizardWarMagic       ClassAttributeType = "War Magic"
+
+	NoSubclass ClassAttributeType = "None" // The subclass of the "None" class
+)
+
+// ClassSubclasses maps each official class to its official subclasses.
+// It matches the registry entries created by init_db.sql.
+var ClassSubclasses = map[ClassType][]ClassAttributeType{
+	Barbarian: {
+		BarbarianAncestralGuardian,
+		BarbarianBattlerager,
+		BarbarianBeast,
+		BarbarianBerserker,
+		BarbarianStormHerald,
+		BarbarianTotemWarrior,
+		BarbarianWildMagic,
+		BarbarianZealot,
+	},
+	Bard: {
+		BardCreation,
+		BardEloquence,
+		BardGlamour,
+		BardLore,
+		BardSwords,
+		BardValor,
+		BardWhispers,
+	},
+	Cleric: {
+		ClericArcana,
+		ClericDeath,
+		ClericForge,
+		ClericGrave,
+		ClericKnowledge,
+		ClericLife,
+		ClericLight,
+		ClericNature,
+		ClericOrder,
+		ClericPeace,
+		ClericTempest,
+		ClericTrickery,
+		ClericTwilight,
+		ClericWar,
+	},
+	Druid: {
+		DruidDreams,
+		DruidTheLand,
+		DruidTheMoon,
+		DruidTheShepherd,
+		DruidTheSpores,
+		DruidTheStars,
+		DruidWildfire,
+	},
+	Fighter: {
+		FighterArcaneArcher,
+		FighterBanneret,
+		FighterBattleMaster,
+		FighterCavalier,
+		FighterChampion,
+		FighterEchoKnight,
+		FighterEldritchKnight,
+		FighterPsiWarrior,
+		FighterRuneKnight,
+		FighterSamurai,
+	},
+	Monk: {
+		MonkAstralSelf,
+		MonkDrunkenMaster,
+		MonkFourElements,
+		MonkKensei,
+		MonkLongDeath,
+		MonkMercy,
+		MonkOpenHand,
+		MonkShadow,
+		MonkSunSoul,
+	},
+	Paladin: {
+		PaladinTheAncients,
+		PaladinConquest,
+		PaladinTheCrown,
+		PaladinDevotion,
+		PaladinGlory,
+		PaladinRedemption,
+		PaladinVengeance,
+		PaladinTheWatchers,
+		PaladinOathbreaker,
+	},
+	Ranger: {
+		RangerBeastMaster,
+		RangerFeyWanderer,
+		RangerGloomStalker,
+		RangerHorizonWalker,
+		RangerHunter,
+		RangerMonsterSlayer,
+		RangerSwarmkeeper,
+	},
+	Rogue: {
+		RogueArcaneTrickster,
+		RogueAssassin,
+		RogueInquisitive,
+		RogueMastermind,
+		RoguePhantom,
+		RogueScout,
+		RogueSoulknife,
+		RogueSwashbuckler,
+		RogueThief,
+	},
+	Sorcerer: {
+		SorcererAberrantMind,
+		SorcererClockworkSoul,
+		SorcererDraconicBloodline,
+		SorcererDivineSoul,
+		SorcererShadow,
+		SorcererStorm,
+		SorcererWildMagic,
+	},
+	Warlock: {
+		WarlockArchfey,
+		WarlockCelestial,
+		WarlockFathomless,
+		WarlockFiend,
+		WarlockGenie,
+		WarlockGreatOldOne,
+		WarlockHexblade,
+		WarlockUndying,
+	},
+	Wizard: {
+		WizardAbjuration,
+		WizardBladesinging,
+		WizardChronurgy,
+		WizardConjuration,
+		WizardDivination,
+		WizardEnchantment,
+		WizardEvocation,
+		WizardGraviturgy,
+		WizardIllusion,
+		WizardNecromancy,
+		WizardOrderOfScribes,
+		WizardTransmutation,
+		WizardWarMagic,
+	},
+	None: {NoSubclass},
+}
+
+// CharacterClass is the code representation of the "CharacterClass"
+// relation in the database schema. The first class of a character is the
+// one stored on the character itself.
+type CharacterClass struct {
+	CharacterID int                 `json:"character_id" db:"character_id"`
+	Position    int                 `json:"position" db:"position"`
+	Class       ClassType           `json:"class" db:"class"`
+	Subclass    *ClassAttributeType `json:"subclass" db:"subclass"`
+	Levels      int                 `json:"levels" db:"levels"`
+}
+
+// MulticlassMinimumScore is the ability score needed to meet a
+// multiclassing prerequisite.
+const MulticlassMinimumScore = 13
+
+// MulticlassPrerequisites lists the abilities a character needs at least
+// MulticlassMinimumScore in to multiclass into or out of each official
+// class. Every group must be met, by any one ability of the group.
+var MulticlassPrerequisites = map[ClassType][][]AbilityType{
+	Barbarian: {{AbilityStrength}},
+	Bard:      {{AbilityCharisma}},
+	Cleric:    {{AbilityWisdom}},
+	Druid:     {{AbilityWisdom}},
+	Fighter:   {{AbilityStrength, AbilityDexterity}},
+	Monk:      {{AbilityDexterity}, {AbilityWisdom}},
+	Paladin:   {{AbilityStrength}, {AbilityCharisma}},
+	Ranger:    {{AbilityDexterity}, {AbilityWisdom}},
+	Rogue:     {{AbilityDexterity}},
+	Sorcerer:  {{AbilityCharisma}},
+	Warlock:   {{AbilityCharisma}},
+	Wizard:    {{AbilityIntelligence}},
+}
+
+// MeetsMulticlassPrerequisites reports whether `c` has the ability
+// scores needed to multiclass with `class`. Homebrew classes have no
+// prerequisites.
+func MeetsMulticlassPrerequisites(c *Character, class ClassType) bool {
+	for _, group := range MulticlassPrerequisites[class] {
+		met := false
+		for _, ability := range group {
+			if c.AbilityScore(ability) >= MulticlassMinimumScore {
+				met = true
+			}
+		}
+		if !met {
+			return false
+		}
+	}
+	return true
+}
+
+type SpellcastingType string
+
+const (
+	SpellcastingNone  SpellcastingType = "none"
+	SpellcastingFull                   = "full"
+	SpellcastingHalf                   = "half"
+	SpellcastingThird                  = "third"
+	SpellcastingPact                   = "pact"
+)
+
+// ClassSpellcasting returns how a class, and for some classes its
+// subclass, casts spells. Homebrew classes do not cast spells.
+func ClassSpellcasting(class ClassType, subclass *ClassAttributeType) SpellcastingType {
+	switch class {
+	case Bard, Cleric, Druid, Sorcerer, Wizard:
+		return SpellcastingFull
+	case Paladin, Ranger:
+		return SpellcastingHalf
+	case Warlock:
+		return SpellcastingPact
+	case Fighter:
+		if subclass != nil && *subclass == FighterEldritchKnight {
+			return SpellcastingThird
+		}
+	case Rogue:
+		if subclass != nil && *subclass == RogueArcaneTrickster {
+			return SpellcastingThird
+		}
+	}
+	return SpellcastingNone
+}
+
+// SpellSlotsByCasterLevel lists the spell slots of each spell level,
+// starting with 1st-level spells, for each caster level starting with 1.
+var SpellSlotsByCasterLevel = [20][9]int{
+	{2}, {3}, {4, 2}, {4, 3}, {4, 3, 2},
+	{4, 3, 3}, {4, 3, 3, 1}, {4, 3, 3, 2}, {4, 3, 3, 3, 1}, {4, 3, 3, 3, 2},
+	{4, 3, 3, 3, 2, 1}, {4, 3, 3, 3, 2, 1}, {4, 3, 3, 3, 2, 1, 1}, {4, 3, 3, 3, 2, 1, 1}, {4, 3, 3, 3, 2, 1, 1, 1},
+	{4, 3, 3, 3, 2, 1, 1, 1}, {4, 3, 3, 3, 2, 1, 1, 1, 1}, {4, 3, 3, 3, 3, 1, 1, 1, 1}, {4, 3, 3, 3, 3, 2, 1, 1, 1},
+	{4, 3, 3, 3, 3, 2, 2, 1, 1},
+}
+
+// PactSlotsByLevel lists the number and level of a warlock's pact magic
+// slots for each warlock level starting with 1.
+var PactSlotsByLevel = [20][2]int{
+	{1, 1}, {2, 1}, {2, 2}, {2, 2}, {2, 3}, {2, 3}, {2, 4}, {2, 4}, {2, 5}, {2, 5},
+	{3, 5}, {3, 5}, {3, 5}, {3, 5}, {3, 5}, {3, 5}, {4, 5}, {4, 5}, {4, 5}, {4, 5},
+}
+
+// SpellSlots are the spell slots of a character across all its classes.
+// Pact magic slots are tracked apart from the others.
+type SpellSlots struct {
+	CasterLevel   int   `json:"caster_level"`
+	Slots         []int `json:"slots"` // Starting with 1st-level spells
+	PactSlots     int   `json:"pact_slots"`
+	PactSlotLevel int   `json:"pact_slot_level"`
+}
+
+// CalculateSpellSlots returns the spell slots of a character with
+// `classes`. A single spellcasting class uses its own progression, where
+// half and third casters round up once they can cast spells; several
+// spellcasting classes add up their caster levels, rounding down.
+func CalculateSpellSlots(classes []CharacterClass) SpellSlots {
+	full, half, third, casters := 0, 0, 0, 0
+	slots := SpellSlots{Slots: make([]int, 9)}
+
+	for _, c := range classes {
+		switch ClassSpellcasting(c.Class, c.Subclass) {
+		case SpellcastingFull:
+			full += c.Levels
+			casters++
+		case SpellcastingHalf:
+			half += c.Levels
+			casters++
+		case SpellcastingThird:
+			third += c.Levels
+			casters++
+		case SpellcastingPact:
+			if c.Levels >= 1 && c.Levels <= 20 {
+				slots.PactSlots = PactSlotsByLevel[c.Levels-1][0]
+				slots.PactSlotLevel = PactSlotsByLevel[c.Levels-1][1]
+			}
+		}
+	}
+
+	switch {
+	case casters > 1:
+		slots.CasterLevel = full + half/2 + third/3
+	case half >= 2:
+		slots.CasterLevel = (half + 1) / 2
+	case third >= 3:
+		slots.CasterLevel = (third + 2) / 3
+	default:
+		slots.CasterLevel = full
+	}
+	if slots.CasterLevel > 20 {
+		slots.CasterLevel = 20
+	}
+
+	if slots.CasterLevel > 0 {
+		copy(slots.Slots, SpellSlotsByCasterLevel[slots.CasterLevel-1][:])
+	}
+	return slots
+}
+
+// HitPointsMethodType is how the hit points of a new level are decided.
+type HitPointsMethodType string
+
+const (
+	HitPointsAverage HitPointsMethodType = "average"
+	HitPointsRoll                        = "roll"
+)
+
+func (t *HitPointsMethodType) UnmarshalJSON(b []byte) error {
+	type T HitPointsMethodType
+	var r *T = (*T)(t)
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return err
+	}
+	switch *t {
+	case
+		HitPointsAverage,
+		HitPointsRoll:
+		return nil
+	}
+	return ErrInvalidHitPointsMethod
+}
+
+// AverageHitDie returns the hit points taken instead of rolling a hit die
+// with `sides` sides.
+func AverageHitDie(sides int) int {
+	return sides/2 + 1
+}
+
+// MaxImprovedAbilityScore is the highest score an Ability Score
+// Improvement can raise an ability to.
+const MaxImprovedAbilityScore = 20
+
+// AbilityScoreImprovementPoints is the total increase given by an
+// Ability Score Improvement, either +2 to one ability or +1 to two.
+const AbilityScoreImprovementPoints = 2
+
+// AbilityScoreImprovementLevels lists the class levels at which each
+// official class gains an Ability Score Improvement or a feat. Homebrew
+// classes follow DefaultAbilityScoreImprovementLevels.
+var AbilityScoreImprovementLevels = map[ClassType][]int{
+	Fighter: {4, 6, 8, 12, 14, 16, 19},
+	Rogue:   {4, 8, 10, 12, 16, 19},
+}
+
+// DefaultAbilityScoreImprovementLevels lists the class levels at which
+// most classes gain an Ability Score Improvement or a feat.
+var DefaultAbilityScoreImprovementLevels = []int{4, 8, 12, 16, 19}
+
+// GrantsAbilityScoreImprovement reports whether reaching `classLevel` in
+// `class` gives an Ability Score Improvement or a feat.
+func GrantsAbilityScoreImprovement(class ClassType, classLevel int) bool {
+	levels, ok := AbilityScoreImprovementLevels[class]
+	if !ok {
+		levels = DefaultAbilityScoreImprovementLevels
+	}
+	for _, level := range levels {
+		if level == classLevel {
+			return true
+		}
+	}
+	return false
+}
+
+// SubclassLevels lists the class level at which each official class
+// chooses its subclass when it differs from DefaultSubclassLevel.
+var SubclassLevels = map[ClassType]int{
+	Cleric:   1,
+	Sorcerer: 1,
+	Warlock:  1,
+	Druid:    2,
+	Wizard:   2,
+}
+
+// DefaultSubclassLevel is the class level at which most classes choose
+// their subclass.
+const DefaultSubclassLevel = 3
+
+// SubclassLevel returns the class level at which `class` chooses its
+// subclass.
+func SubclassLevel(class ClassType) int {
+	if level, ok := SubclassLevels[class]; ok {
+		return level
+	}
+	return DefaultSubclassLevel
+}
+
+// LevelUp is the code representation of the "CharacterLevelHistory"
+// relation in the database schema: a level gained by a character and
+// the choices made for it.
+type LevelUp struct {
+	ID               int                 `json:"id" db:"id"`
+	CharacterID      int                 `json:"character_id" db:"character_id"`
+	Level            int                 `json:"level" db:"level"` // Of the character once gained
+	Class            ClassType           `json:"class" db:"class"`
+	ClassLevel       int                 `json:"class_level" db:"class_level"`
+	HitPointsMethod  HitPointsMethodType `json:"hit_points_method" db:"hit_points_method"`
+	HitDieRoll       *int                `json:"hit_die_roll" db:"hit_die_roll"` // Rolled method only
+	HPGained         int                 `json:"hp_gained" db:"hp_gained"`
+	AbilityIncreases json.RawMessage     `json:"ability_increases" db:"ability_increases"` // Such as {"strength": 2}
+	Feat             *string             `json:"feat" db:"feat"`
+	Subclass         *ClassAttributeType `json:"subclass" db:"subclass"` // Chosen at this level
+	LeveledAt        time.Time           `json:"leveled_at" db:"leveled_at"`
+}
+
+// AbilityScoreIncreases returns the increases of the Ability Score
+// Improvement taken at the level.
+func (l *LevelUp) AbilityScoreIncreases() (map[AbilityType]int, error) {
+	increases := map[AbilityType]int{}
+	if len(l.AbilityIncreases) == 0 {
+		return increases, nil
+	}
+	err := json.Unmarshal(l.AbilityIncreases, &increases)
+	return increases, err
+}
+
+// ApplyLevelUp gains the level `l` for `c`: its ability scores rise by
+// `increases` and its maximum hit points by `l.HPGained`, worked out with
+// its Constitution modifier before the increases. A higher modifier then
+// counts for the new level and every level already gained. `l.HPGained`
+// is updated to the total gain, which the character also heals unless it
+// is at 0 hit points.
+func (c *Character) ApplyLevelUp(l *LevelUp, increases map[AbilityType]int) {
+	modifierBefore := AbilityModifier(c.Constitution)
+	for ability, increase := range increases {
+		c.SetAbilityScore(ability, c.AbilityScore(ability)+increase)
+	}
+	l.HPGained += (AbilityModifier(c.Constitution) - modifierBefore) * l.Level
+
+	c.Level = l.Level
+	c.HPMax += l.HPGained
+	if c.HPCurrent > 0 {
+		c.HPCurrent += l.HPGained
+	}
+	if c.HPCurrent > c.HPMax {
+		c.HPCurrent = c.HPMax
+	}
+}
+
+// FeatureSourceType is where a feature comes from.
+type FeatureSourceType string
+
+const (
+	FeatureSourceRace     FeatureSourceType = "race"
+	FeatureSourceClass                      = "class"
+	FeatureSourceSubclass                   = "subclass"
+	FeatureSourceFeat                       = "feat"
+)
+
+func (t *FeatureSourceType) UnmarshalJSON(b []byte) error {
+	type T FeatureSourceType
+	var r *T = (*T)(t)
+	err := json.Unmarshal(b, &r)
+	if err != nil {
+		return err
+	}
+	switch *t {
+	case
+		FeatureSourceRace,
+		FeatureSourceClass,
+		FeatureSourceSubclass,
+		FeatureSourceFeat:
+		return nil
+	}
+	return ErrInvalidFeatureSource
+}
+
+// Feature is the code representation of the "Feature" relation in the
+// database schema: a racial trait, a class or subclass feature, or a
+// feat. Characters are granted the features of their race, classes and
+// subclasses once they reach Level, which is the level of the character
+// for racial traits and the level in the class otherwise.
+type Feature struct {
+	ID          int                 `json:"id" db:"id"`
+	Source      FeatureSourceType   `json:"source" db:"source"`
+	Race        *string             `json:"race,omitempty" db:"race"`         // Racial traits only
+	Class       *ClassType          `json:"class,omitempty" db:"class"`       // Class and subclass features only
+	Subclass    *ClassAttributeType `json:"subclass,omitempty" db:"subclass"` // Subclass features only
+	Level       int                 `json:"level" db:"level"`
+	Name        string              `json:"name" db:"name"`
+	Description string              `json:"description" db:"description"`
+	UsesByLevel json.RawMessage     `json:"uses_by_level,omitempty" db:"uses_by_level"` // Such as {"1": 2, "3": 3}
+	UsesAbility *AbilityType        `json:"uses_ability,omitempty" db:"uses_ability"`   // Its modifier adds to the uses
+	Recharge    *RestType           `json:"recharge" db:"recharge"`                     // Unlimited features have none
+}
+
+// IsLimited reports whether the feature can only be used a number of
+// times between rests.
+func (f *Feature) IsLimited() bool {
+	return f.Recharge != nil
+}
+
+// MaxUses returns the number of times `c` can use the limited feature
+// between rests, with `level` levels in its source: the uses listed for
+// the highest level reached, plus the modifier of UsesAbility, and at
+// least one.
+func (f *Feature) MaxUses(c *Character, level int) (int, error) {
+	usesByLevel := map[int]int{}
+	if len(f.UsesByLevel) > 0 {
+		if err := json.Unmarshal(f.UsesByLevel, &usesByLevel); err != nil {
+			return 0, err
+		}
+	}
+
+	uses, reached := 0, 0
+	for l, n := range usesByLevel {
+		if l <= level && l > reached {
+			uses, reached = n, l
+		}
+	}
+	if f.UsesAbility != nil {
+		uses += AbilityModifier(c.AbilityScore(*f.UsesAbility))
+	}
+	if uses < 1 {
+		uses = 1
+	}
+	return uses, nil
+}
+
+// SourceLevel returns the level of `c` which the feature depends on: the
+// levels in its class for class and subclass features, and the level of
+// the character otherwise.
+func (f *Feature) SourceLevel(c *Character, classes []CharacterClass) int {
+	if f.Class == nil {
+		return c.Level
+	}
+	for _, class := range classes {
+		if class.Class == *f.Class {
+			return class.Levels
+		}
+	}
+	return 0
+}
+
+// CharacterFeature is the code representation of the "CharacterFeature"
+// relation in the database schema: a feature granted to a character,
+// along with the uses of a limited feature spent since its last
+// recharge.
+type CharacterFeature struct {
+	Feature
+	CharacterID   int       `json:"character_id" db:"character_id"`
+	UsesSpent     int       `json:"uses_spent" db:"uses_spent"`
+	MaxUses       *int      `json:"max_uses" db:"-"`       // Limited features only
+	UsesRemaining *int      `json:"uses_remaining" db:"-"` // Limited features only
+	GrantedAt     time.Time `json:"granted_at" db:"granted_at"`
+}
+
+// SetUses works out MaxUses and UsesRemaining of a limited feature for
+// `c`, whose classes are `classes`.
+func (f *CharacterFeature) SetUses(c *Character, classes []CharacterClass) error {
+	if !f.IsLimited() {
+		return nil
+	}
+
+	maxUses, err := f.Feature.MaxUses(c, f.SourceLevel(c, classes))
+	if err != nil {
+		return err
+	}
+	remaining := maxUses - f.UsesSpent
+	if remaining < 0 {
+		remaining = 0
+	}
+	f.MaxUses, f.UsesRemaining = &maxUses, &remaining
+	return nil
+}
+
+// FightingStyleType is a fighting style of the fighter, paladin or
+// ranger.
+type FightingStyleType string
+
+const (
+	FightingStyleArchery              FightingStyleType = "Archery"
+	FightingStyleBlindFighting        FightingStyleType = "Blind Fighting"
+	FightingStyleDefense              FightingStyleType = "Defense"
+	FightingStyleDruidicWarrior       FightingStyleType = "Druidic Warrior"
+	FightingStyleDueling              FightingStyleType = "Dueling"
+	FightingStyleGreatWeaponFighting  FightingStyleType = "Great Weapon Fighting"
+	FightingStyleInterception         FightingStyleType = "Interception"
+	FightingStyleProtection           FightingStyleType = "Protection"
+	FightingStyleSuperiorTechnique    FightingStyleType = "Superior Technique"
+	FightingStyleThrownWeaponFighting FightingStyleType = "Thrown Weapon Fighting"
+	FightingStyleTwoWeaponFighting    FightingStyleType = "Two-Weapon Fighting"
+	FightingStyleUnarmedFighting      FightingStyleType = "Unarmed Fighting"
+)
+
+/* Model for global statistics. */
+type Stats struct {
+	NumPlayersCreated    int `json:"num_player_account" db:"num_player_account"`
+	NumCharactersCreated int `json:"num_character_created" db:"num_character_created"`
+	NumCampaignsCreated  int `json:"num_campaign_created" db:"num_campaign_created"`
+	NumSpellsCreated     int `json:"num_spells_created" db:"num_spells_created"`
+	NumItemsCreated      int `json:"num_items_created" db:"num_items_created"`
+}
+
+/* Model for statistics only visible to administrators. */
+type AdminStats struct {
+	Stats
+	NumAdmins               int `json:"num_admins" db:"num_admins"`
+	NumDisabledPlayers      int `json:"num_disabled_players" db:"num_disabled_players"`
+	NumTwoFactorPlayers     int `json:"num_two_factor_players" db:"num_two_factor_players"`
+	NumOrphanedCampaigns    int `json:"num_orphaned_campaigns" db:"num_orphaned_campaigns"`
+	NumActivePersonalTokens int `json:"num_active_personal_tokens" db:"num_active_personal_tokens"`
 }
 
 /* Model for get Count of spells per Magic School */
@@ -0,0 +1,105 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"encoding/json"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type AbilityRollModel struct {
+	DB *sqlx.DB
+}
+
+// selectAbilityRolls selects ability rolls along with the ID of the
+// character whose scores were assigned from each.
+const selectAbilityRolls = `SELECT ar.id, ar.player_username, ar.scores, ar.rolls, ch.id, ar.created_at
+		FROM AbilityRoll AS ar
+		LEFT JOIN Character AS ch
+		ON ch.ability_roll_id = ar.id`
+
+// Insert records the six ability scores `scores` rolled for `username`,
+// along with the dice behind them, and returns the ID of the new roll.
+func (m *AbilityRollModel) Insert(username string, scores []int, rolls json.RawMessage) (int, error) {
+	stmt := `INSERT INTO AbilityRoll (player_username, scores, rolls)
+		VALUES($1, $2, $3)
+		RETURNING id`
+
+	stored := make([]int64, len(scores))
+	for i, score := range scores {
+		stored[i] = int64(score)
+	}
+
+	var id int
+	if err := m.DB.QueryRowx(stmt, username, pq.Array(stored), []byte(rolls)).Scan(&id); err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// Get retrieves the ability roll identified by `id`.
+func (m *AbilityRollModel) Get(id int) (*models.AbilityRoll, error) {
+	stmt := selectAbilityRolls + " WHERE ar.id = $1"
+
+	roll, err := scanAbilityRoll(m.DB.QueryRowx(stmt, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return roll, nil
+}
+
+// GetAllForPlayer retrieves the ability rolls of the player `username`,
+// most recent first.
+func (m *AbilityRollModel) GetAllForPlayer(username string, limit, offset int) (*[]models.AbilityRoll, error) {
+	storedRolls := []models.AbilityRoll{}
+
+	stmt := selectAbilityRolls + `
+			WHERE ar.player_username = $1
+			ORDER BY ar.created_at DESC, ar.id DESC
+			LIMIT $2 OFFSET $3`
+
+	rows, err := m.DB.Queryx(stmt, username, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		roll, err := scanAbilityRoll(rows)
+		if err != nil {
+			return nil, err
+		}
+		storedRolls = append(storedRolls, *roll)
+	}
+
+	return &storedRolls, rows.Err()
+}
+
+// scanAbilityRoll reads an ability roll selected by selectAbilityRolls.
+func scanAbilityRoll(row interface{ Scan(...interface{}) error }) (*models.AbilityRoll, error) {
+	var roll models.AbilityRoll
+	var scores []int64
+	var rolls []byte
+
+	err := row.Scan(&roll.ID, &roll.PlayerUsername, pq.Array(&scores), &rolls, &roll.CharacterID, &roll.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	roll.Rolls = rolls
+	roll.Scores = make([]int, len(scores))
+	for i, score := range scores {
+		roll.Scores[i] = int(score)
+	}
+
+	return &roll, nil
+}
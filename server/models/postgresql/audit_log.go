@@ -0,0 +1,47 @@
+package postgresql
+
+import (
+	"draco/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AuditLogModel struct {
+	DB *sqlx.DB
+}
+
+// Insert records an action taken by `actor` against `target`.
+func (m *AuditLogModel) Insert(actor, action, target, details string) error {
+	stmt := `INSERT INTO AuditLog (actor_username, action, target, details)
+		VALUES($1, $2, NULLIF($3, ''), NULLIF($4, ''))`
+
+	_, err := m.DB.Exec(stmt, actor, action, target, details)
+	return err
+}
+
+// GetAll retrieves audit log entries, most recent first.
+func (m *AuditLogModel) GetAll(limit, offset int) (*[]models.AuditLogEntry, error) {
+	storedEntries := []models.AuditLogEntry{}
+
+	stmt := `SELECT *
+			FROM AuditLog
+			ORDER BY created_at DESC, id DESC
+			LIMIT $1 OFFSET $2`
+
+	rows, err := m.DB.Queryx(stmt, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.AuditLogEntry
+		err = rows.StructScan(&e)
+		if err != nil {
+			return nil, err
+		}
+		storedEntries = append(storedEntries, e)
+	}
+
+	return &storedEntries, rows.Err()
+}
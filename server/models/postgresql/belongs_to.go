@@ -84,3 +84,44 @@ func (m *BelongsToModel) GetAllCampaignCharacters(campaignID int) (*[]models.Cha
 
 	return &storedCharacters, nil
 }
+
+// Delete removes the character identified by `characterID` from the
+// campaign identified by `campaignID`.
+func (m *BelongsToModel) Delete(characterID, campaignID int) error {
+	stmt := "DELETE FROM BelongsTo WHERE character_id = $1 AND campaign_id = $2"
+
+	res, err := m.DB.Exec(stmt, characterID, campaignID)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return models.ErrNoRecord
+	}
+
+	return nil
+}
+
+// IsPlayerInCampaign reports whether any character belonging to the
+// player identified by `username` is part of the campaign identified by
+// `campaignID`.
+func (m *BelongsToModel) IsPlayerInCampaign(username string, campaignID int) (bool, error) {
+	var exists bool
+
+	stmt := `SELECT EXISTS (
+				SELECT 1
+				FROM BelongsTo
+				INNER JOIN Character
+				ON Character.id = BelongsTo.character_id
+				WHERE BelongsTo.campaign_id = $1 AND Character.player_username = $2
+			)`
+	if err := m.DB.QueryRow(stmt, campaignID, username).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
@@ -0,0 +1,146 @@
+package postgresql
+
+import (
+	"draco/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type CampaignLogModel struct {
+	DB *sqlx.DB
+}
+
+// Insert records an event of type `kind` in the log of the campaign
+// identified by `campaignID` and returns the ID of the new entry.
+func (m *CampaignLogModel) Insert(campaignID int, actor, kind, message string) (int, error) {
+	return insertCampaignLog(m.DB, campaignID, actor, kind, message)
+}
+
+// GetAllForCampaign retrieves the log of the campaign identified by
+// `campaignID`, most recent first.
+func (m *CampaignLogModel) GetAllForCampaign(campaignID, limit, offset int) (*[]models.CampaignLogEntry, error) {
+	storedEntries := []models.CampaignLogEntry{}
+
+	stmt := `SELECT *
+			FROM CampaignLog
+			WHERE campaign_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2 OFFSET $3`
+
+	rows, err := m.DB.Queryx(stmt, campaignID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.CampaignLogEntry
+		err = rows.StructScan(&e)
+		if err != nil {
+			return nil, err
+		}
+		storedEntries = append(storedEntries, e)
+	}
+
+	return &storedEntries, rows.Err()
+}
+
+// AwardXP adds experience to characters in the campaign identified by
+// `campaignID`, where `amounts` maps character IDs to the experience
+// each should receive. No character is given more than models.MaxXP.
+// The award is recorded in the campaign log with `message`.
+func (m *CampaignLogModel) AwardXP(campaignID int, actor, message string, amounts map[int]int) (*[]models.XPAwardResult, error) {
+	stmtSelect := `SELECT ch.id, ch.name, ch.player_username, ch.xp_points
+			FROM Character AS ch
+			INNER JOIN BelongsTo AS bt
+			ON bt.character_id = ch.id
+			WHERE bt.campaign_id = $1
+			ORDER BY ch.id
+			FOR UPDATE OF ch`
+	stmtUpdate := "UPDATE Character SET xp_points = $2 WHERE id = $1"
+	stmtAward := `INSERT INTO CampaignXPAward (log_id, character_id, amount, xp_before, xp_after)
+		VALUES($1, $2, $3, $4, $5)`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Queryx(stmtSelect, campaignID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	results := []models.XPAwardResult{}
+	for rows.Next() {
+		var r models.XPAwardResult
+		if err := rows.Scan(&r.CharacterID, &r.CharacterName, &r.PlayerUsername, &r.XPBefore); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return nil, err
+		}
+		if _, ok := amounts[r.CharacterID]; ok {
+			results = append(results, r)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if len(results) != len(amounts) {
+		tx.Rollback()
+		return nil, models.ErrCharacterNotInCampaign
+	}
+
+	logID, err := insertCampaignLog(tx, campaignID, actor, "xp_award", message)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for i := range results {
+		r := &results[i]
+
+		r.XPAfter = r.XPBefore + amounts[r.CharacterID]
+		if r.XPAfter > models.MaxXP {
+			r.XPAfter = models.MaxXP
+		}
+		r.Amount = r.XPAfter - r.XPBefore
+		r.LevelBefore = models.LevelForXP(r.XPBefore)
+		r.LevelAfter = models.LevelForXP(r.XPAfter)
+		r.LeveledUp = r.LevelAfter > r.LevelBefore
+
+		if _, err := tx.Exec(stmtUpdate, r.CharacterID, r.XPAfter); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+
+		if _, err := tx.Exec(stmtAward, logID, r.CharacterID, r.Amount, r.XPBefore, r.XPAfter); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &results, nil
+}
+
+// insertCampaignLog adds an entry to a campaign log using `q`, which may
+// be a database handle or an open transaction.
+func insertCampaignLog(q sqlx.Queryer, campaignID int, actor, kind, message string) (int, error) {
+	stmt := `INSERT INTO CampaignLog (campaign_id, actor_username, kind, message)
+		VALUES($1, NULLIF($2, ''), $3, $4)
+		RETURNING id`
+
+	var id int
+	if err := q.QueryRowx(stmt, campaignID, actor, kind, message).Scan(&id); err != nil {
+		return -1, err
+	}
+	return id, nil
+}
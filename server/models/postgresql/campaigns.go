@@ -4,8 +4,10 @@ import (
 	"database/sql"
 	"draco/models"
 	"errors"
+	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type CampaignModel struct {
@@ -13,29 +15,43 @@ type CampaignModel struct {
 }
 
 // Inserts a new campaign into the DB and any accompanying characters into the
-// BelongsTo relation
-func (m *CampaignModel) Insert(c models.Campaign, characterIDs []int) (int, error) {
-	stmtCampaign := `INSERT INTO Campaign (name, current_location, state, dungeon_master)
-		VALUES($1, $2, $3, $4)
+// BelongsTo relation. The campaign's initial state is recorded as its
+// first state transition, and the players `invitees` are invited to it
+// until `expiresAt`.
+func (m *CampaignModel) Insert(c models.Campaign, characterIDs []int, invitees []string, expiresAt *time.Time) (int, error) {
+	stmtCampaign := `INSERT INTO Campaign (name, current_location, state, dungeon_master, status_note)
+		VALUES($1, $2, $3, $4, $5)
 		RETURNING id`
+	stmtTransition := `INSERT INTO CampaignStateTransition (campaign_id, from_state, to_state, changed_by)
+		VALUES($1, NULL, $2, $3)`
 	stmtBelongsTo := `INSERT INTO BelongsTo (character_id, campaign_id)
 	VALUES($1, $2)`
 
 	var createdCampaignID int
 
+	if c.State == "" {
+		c.State = models.CampaignPlanning
+	}
+
 	tx, err := m.DB.Beginx()
 	if err != nil {
 		return -1, err
 	}
 
 	err = tx.QueryRowx(
-		stmtCampaign, c.Name, c.CurrentLocation, c.State, c.DungeonMaster,
+		stmtCampaign, c.Name, c.CurrentLocation, c.State, c.DungeonMaster, c.StatusNote,
 	).Scan(&createdCampaignID)
 	if err != nil {
 		tx.Rollback()
 		return -1, err
 	}
 
+	_, err = tx.Exec(stmtTransition, createdCampaignID, c.State, c.DungeonMaster)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
 	for _, id := range characterIDs {
 		_, err := tx.Exec(stmtBelongsTo, id, createdCampaignID)
 		if err != nil {
@@ -44,6 +60,15 @@ func (m *CampaignModel) Insert(c models.Campaign, characterIDs []int) (int, erro
 		}
 	}
 
+	for _, username := range invitees {
+		var invitationID int
+		err := tx.QueryRowx(insertInvitation, createdCampaignID, username, "", expiresAt).Scan(&invitationID)
+		if err != nil {
+			tx.Rollback()
+			return -1, err
+		}
+	}
+
 	err = tx.Commit()
 
 	return createdCampaignID, err
@@ -70,12 +95,13 @@ func (m *CampaignModel) Get(id int) (*models.Campaign, error) {
 	return &storedCampaign, nil
 }
 
-// Update attempts to update the state and location of the campaign
-// identified by `id`.
-func (m *CampaignModel) Update(id int, state string, location string) error {
-	stmt := "UPDATE Campaign SET state = $2, current_location = $3 WHERE id = $1"
+// Update attempts to update the status note and location of the
+// campaign identified by `id`. Archived campaigns are not modified.
+func (m *CampaignModel) Update(id int, statusNote string, location string) error {
+	stmt := `UPDATE Campaign SET status_note = $2, current_location = $3
+			WHERE id = $1 AND state <> 'archived'`
 
-	res, err := m.DB.Exec(stmt, id, state, location)
+	res, err := m.DB.Exec(stmt, id, statusNote, location)
 	if err != nil {
 		return err
 	}
@@ -235,3 +261,131 @@ func (m *CampaignModel) GetPlayersAttendedAll(dungeonMaster string) (*[]string,
 
 	return &storedUsernames, nil
 }
+
+// GetOrphaned retrieves all campaigns which no longer have a dungeon
+// master because the DM's account was deleted.
+func (m *CampaignModel) GetOrphaned() (*[]models.Campaign, error) {
+	storedCampaigns := []models.Campaign{}
+
+	stmt := `SELECT *
+			FROM Campaign
+			WHERE dungeon_master IS NULL
+			ORDER BY id`
+
+	rows, err := m.DB.Queryx(stmt)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var campaign models.Campaign
+		err = rows.StructScan(&campaign)
+		if err != nil {
+			return nil, err
+		}
+		storedCampaigns = append(storedCampaigns, campaign)
+	}
+
+	return &storedCampaigns, rows.Err()
+}
+
+// SetDungeonMaster reassigns the campaign identified by `id` to the
+// player identified by `username`.
+func (m *CampaignModel) SetDungeonMaster(id int, username string) error {
+	stmt := "UPDATE Campaign SET dungeon_master = $2 WHERE id = $1"
+
+	res, err := m.DB.Exec(stmt, id, username)
+	if err != nil {
+		var postgresError *pq.Error
+		if errors.As(err, &postgresError) {
+			if postgresError.Code.Name() == "foreign_key_violation" {
+				return models.ErrNoRecord
+			}
+		}
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return models.ErrNoRecord
+	}
+	if count != 1 {
+		return models.ErrUpdateSingleRecord
+	}
+
+	return nil
+}
+
+// Transition moves the campaign identified by `id` to the state `to`
+// on behalf of the player identified by `changedBy`, provided that the
+// move is allowed from the campaign's current state.
+func (m *CampaignModel) Transition(id int, to models.CampaignStateType, changedBy string) error {
+	stmtSelect := "SELECT state FROM Campaign WHERE id = $1 FOR UPDATE"
+	stmtUpdate := `UPDATE Campaign SET state = $2, state_changed_at = now()
+			WHERE id = $1`
+	stmtTransition := `INSERT INTO CampaignStateTransition (campaign_id, from_state, to_state, changed_by)
+		VALUES($1, $2, $3, $4)`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var from models.CampaignStateType
+	if err := tx.QueryRow(stmtSelect, id).Scan(&from); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNoRecord
+		}
+		return err
+	}
+
+	if !from.CanTransitionTo(to) {
+		tx.Rollback()
+		return models.ErrInvalidStateTransition
+	}
+
+	if _, err := tx.Exec(stmtUpdate, id, to); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if _, err := tx.Exec(stmtTransition, id, from, to, changedBy); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetStateHistory retrieves every state transition of the campaign
+// identified by `id`, oldest first.
+func (m *CampaignModel) GetStateHistory(id int) (*[]models.CampaignStateTransition, error) {
+	transitions := []models.CampaignStateTransition{}
+
+	stmt := `SELECT *
+			FROM CampaignStateTransition
+			WHERE campaign_id = $1
+			ORDER BY changed_at, id`
+
+	rows, err := m.DB.Queryx(stmt, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transition models.CampaignStateTransition
+		err = rows.StructScan(&transition)
+		if err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, transition)
+	}
+
+	return &transitions, rows.Err()
+}
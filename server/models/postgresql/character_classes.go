@@ -0,0 +1,97 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type CharacterClassModel struct {
+	DB *sqlx.DB
+}
+
+// GetAll retrieves the classes of the character identified by
+// `characterID`, first class first.
+func (m *CharacterClassModel) GetAll(characterID int) (*[]models.CharacterClass, error) {
+	storedClasses := []models.CharacterClass{}
+
+	stmt := `SELECT *
+			FROM CharacterClass
+			WHERE character_id = $1
+			ORDER BY position`
+
+	if err := m.DB.Select(&storedClasses, stmt, characterID); err != nil {
+		return nil, err
+	}
+
+	return &storedClasses, nil
+}
+
+// Replace sets the classes of the character identified by `characterID`
+// to `classes`, in order. The first class becomes the class of the
+// character itself, and the character is granted the features of its
+// classes and subclasses.
+func (m *CharacterClassModel) Replace(characterID int, classes []models.CharacterClass) error {
+	stmtLock := "SELECT id FROM Character WHERE id = $1 FOR UPDATE"
+	stmtDelete := "DELETE FROM CharacterClass WHERE character_id = $1"
+	stmtCharacter := "UPDATE Character SET class = $2, class_attribute = $3 WHERE id = $1"
+	stmtInsert := `INSERT INTO CharacterClass (character_id, position, class, subclass, levels)
+		VALUES($1, $2, $3, $4, $5)`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var id int
+	if err := tx.QueryRowx(stmtLock, characterID).Scan(&id); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNoRecord
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(stmtDelete, characterID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// With no classes stored, the trigger on Character has nothing to update
+	if _, err := tx.Exec(stmtCharacter, characterID, classes[0].Class, classes[0].Subclass); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for i, c := range classes {
+		if _, err := tx.Exec(stmtInsert, characterID, i, c.Class, c.Subclass, c.Levels); err != nil {
+			tx.Rollback()
+			return characterClassError(err)
+		}
+	}
+
+	if err := syncFeatures(tx, characterID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// characterClassError converts violations of the CharacterClass
+// constraints into the matching model errors.
+func characterClassError(err error) error {
+	var postgresError *pq.Error
+	if errors.As(err, &postgresError) {
+		switch postgresError.Constraint {
+		case "characterclass_pkey":
+			return models.ErrDuplicateCharacterClass
+		case "characterclass_levels_check":
+			return models.ErrNotEnoughLevels
+		}
+	}
+	return err
+}
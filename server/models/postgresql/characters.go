@@ -14,37 +14,65 @@ type CharacterModel struct {
 	DB *sqlx.DB
 }
 
+// Insert saves the character `c` at full hit points and at the level its
+// experience allows, and returns its ID. The character is proficient in
+// the saving throws of its class, and is granted the features of its
+// race and class.
 func (m *CharacterModel) Insert(c models.Character) (int, error) {
 	stmt := `INSERT INTO Character (name, weight, height,
 		alignment, sex, background, race,
 		speed, strength, dexterity, intelligence, wisdom, charisma, constitution,
-		hp_max, ability_points, xp_points,
-		class, class_attribute, player_username)
-		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+		hp_max, hp_current, ability_points, xp_points, level,
+		class, class_attribute, player_username, ability_method, ability_roll_id)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $15, $16, $17, level_for_xp($17),
+			$18, $19, $20, $21, $22)
 		RETURNING id`
+	stmtSave := `INSERT INTO CharacterProficiency (character_id, kind, name)
+		VALUES($1, $2, $3)`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return -1, err
+	}
 
 	var createdCharacterID int
-	err := m.DB.QueryRowx(
+	err = tx.QueryRowx(
 		stmt, c.Name, c.Weight, c.Height,
 		c.Alignment, c.Sex, c.Background, c.Race,
 		c.Speed, c.Strength, c.Dexterity, c.Intelligence, c.Wisdom, c.Charisma, c.Constitution,
 		c.HPMax, c.AbilityPoints, c.XPPoints,
-		c.Class, c.ClassAttribute, c.PlayerUsername,
+		c.Class, c.ClassAttribute, c.PlayerUsername, c.AbilityMethod, c.AbilityRollID,
 	).Scan(&createdCharacterID)
 
 	if err != nil {
+		tx.Rollback()
 		var postgresError *pq.Error
 		if errors.As(err, &postgresError) {
 			if postgresError.Code.Name() == "unique_violation" {
 				if strings.Contains(postgresError.Message, "character_name_player_username_key") {
 					return -1, models.ErrDuplicateCharacter
 				}
+				if strings.Contains(postgresError.Message, "character_ability_roll_id_key") {
+					return -1, models.ErrAbilityRollUsed
+				}
 			}
 		}
 		return -1, err
 	}
 
-	return createdCharacterID, nil
+	for _, ability := range models.ClassSavingThrows[c.Class] {
+		if _, err := tx.Exec(stmtSave, createdCharacterID, models.ProficiencySavingThrow, ability); err != nil {
+			tx.Rollback()
+			return -1, err
+		}
+	}
+
+	if err := syncFeatures(tx, createdCharacterID); err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	return createdCharacterID, tx.Commit()
 }
 
 // Get attempts to retrieve a character entity from the database with a
@@ -88,17 +116,31 @@ func (m *CharacterModel) GetAllUserCharacters(username string) (*[]models.Charac
 	return &storedCharacters, nil
 }
 
+// Update replaces the details of the character `c`. Levels are only
+// gained through level-ups, but are lost along with the experience they
+// needed, and so are the features they granted. Changing the ability
+// scores makes them manual, as they no longer come from the method they
+// were generated with, but a rolled character keeps its roll so that the
+// roll cannot be used again.
 func (m *CharacterModel) Update(c models.Character) error {
+	abilitiesChanged := `(strength, dexterity, intelligence, wisdom, charisma, constitution)
+			IS DISTINCT FROM ($10::int, $11::int, $12::int, $13::int, $14::int, $15::int)`
 	stmt := `UPDATE Character
-			SET name = $2, weight = $3, height = $4,
+			SET ability_method = CASE WHEN ` + abilitiesChanged + ` THEN 'manual' ELSE ability_method END,
+				name = $2, weight = $3, height = $4,
 				alignment = $5, sex = $6, background = $7, race = $8, speed = $9,
 				strength = $10, dexterity = $11, intelligence = $12, wisdom = $13,
-				charisma = $14, constitution = $15, hp_max = $16,
-				ability_points = $17, xp_points = $18, class = $19,
+				charisma = $14, constitution = $15, hp_max = $16, hp_current = LEAST(hp_current, $16),
+				ability_points = $17, xp_points = $18, level = LEAST(level, level_for_xp($18)), class = $19,
 				class_attribute = $20, player_username = $21
 			WHERE id = $1`
 
-	_, err := m.DB.Exec(
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(
 		stmt, c.ID, c.Name, c.Weight, c.Height,
 		c.Alignment, c.Sex, c.Background, c.Race,
 		c.Speed, c.Strength, c.Dexterity, c.Intelligence, c.Wisdom, c.Charisma, c.Constitution,
@@ -107,6 +149,7 @@ func (m *CharacterModel) Update(c models.Character) error {
 	)
 
 	if err != nil {
+		tx.Rollback()
 		var postgresError *pq.Error
 		if errors.As(err, &postgresError) {
 			if postgresError.Code.Name() == "unique_violation" {
@@ -115,10 +158,15 @@ func (m *CharacterModel) Update(c models.Character) error {
 				}
 			}
 		}
+		return characterClassError(err)
+	}
+
+	if err := syncFeatures(tx, c.ID); err != nil {
+		tx.Rollback()
 		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
 // Delete attempts to delete a character identified by `id`.
@@ -0,0 +1,689 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type CombatModel struct {
+	DB *sqlx.DB
+}
+
+// combatantColumns selects the columns of "Combatant" aliased `cb` along
+// with the player controlling it, joined from "Character" aliased `ch`.
+const combatantColumns = `cb.id, cb.combat_id, cb.character_id, cb.npc_id, ch.player_username,
+		cb.name, cb.initiative, cb.initiative_bonus, cb.armor_class, cb.hp_max, cb.hp_current,
+		cb.temp_hp, cb.defeated, cb.created_at`
+
+// combatantOrder sorts combatants aliased `cb` into turn order. Ties are
+// broken by initiative bonus, and combatants without an initiative go
+// last.
+const combatantOrder = `cb.initiative DESC NULLS LAST, cb.initiative_bonus DESC, cb.id`
+
+// Start begins a combat with `combatants` in the campaign identified by
+// `combat.CampaignID` and returns the ID of the new combat.
+// models.ErrCombatInProgress is returned if the campaign is already in a
+// combat.
+func (m *CombatModel) Start(combat models.Combat, combatants []models.Combatant) (int, error) {
+	stmt := `INSERT INTO Combat (campaign_id, encounter_id, name, started_by)
+		VALUES($1, $2, $3, $4)
+		RETURNING id`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return -1, err
+	}
+
+	var id int
+	err = tx.QueryRowx(stmt, combat.CampaignID, combat.EncounterID, combat.Name, combat.StartedBy).Scan(&id)
+	if err != nil {
+		tx.Rollback()
+		var postgresError *pq.Error
+		if errors.As(err, &postgresError) && postgresError.Code.Name() == "unique_violation" {
+			return -1, models.ErrCombatInProgress
+		}
+		return -1, err
+	}
+
+	if err := insertCombatants(tx, id, combatants); err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	return id, tx.Commit()
+}
+
+// GetActive retrieves the combat of the campaign identified by
+// `campaignID` which has not ended, along with its combatants in turn
+// order.
+func (m *CombatModel) GetActive(campaignID int) (*models.Combat, error) {
+	var storedCombat models.Combat
+
+	stmt := "SELECT * FROM Combat WHERE campaign_id = $1 AND ended_at IS NULL"
+	if err := m.DB.QueryRowx(stmt, campaignID).StructScan(&storedCombat); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	combatants, err := getCombatants(m.DB, storedCombat.ID, false)
+	if err != nil {
+		return nil, err
+	}
+	storedCombat.Combatants = combatants
+
+	return &storedCombat, nil
+}
+
+// End ends the combat identified by `id`.
+func (m *CombatModel) End(id int) error {
+	stmt := `UPDATE Combat
+			SET ended_at = now(), version = version + 1, updated_at = now()
+			WHERE id = $1 AND ended_at IS NULL`
+
+	res, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return models.ErrNoRecord
+	}
+
+	return nil
+}
+
+// AddCombatants adds `combatants` to the combat identified by `combatID`.
+func (m *CombatModel) AddCombatants(combatID int, combatants []models.Combatant) error {
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if _, err := lockCombat(tx, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := insertCombatants(tx, combatID, combatants); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := touchCombat(tx, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveCombatant removes the combatant identified by `combatantID` from
+// the combat identified by `combatID`. If it was the combatant's turn,
+// the turn passes to the next combatant after it who has not been
+// defeated, or to nobody if there is none.
+func (m *CombatModel) RemoveCombatant(combatID, combatantID int) error {
+	stmt := "DELETE FROM Combatant WHERE id = $1 AND combat_id = $2"
+	stmtNoTurn := "UPDATE Combat SET current_combatant_id = NULL WHERE id = $1"
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	combat, err := lockCombat(tx, combatID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	combatants, err := getCombatants(tx, combatID, true)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	position := -1
+	for i, c := range combatants {
+		if c.ID == combatantID {
+			position = i
+		}
+	}
+	if position < 0 {
+		tx.Rollback()
+		return models.ErrNoRecord
+	}
+
+	if _, err := tx.Exec(stmt, combatantID, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// The turn passes on from where the removed combatant stood, among
+	// the combatants left
+	if combat.CurrentCombatantID != nil && *combat.CurrentCombatantID == combatantID {
+		remaining := append(combatants[:position:position], combatants[position+1:]...)
+		err := passTurn(tx, combat, remaining, position, combat.Round)
+		if errors.Is(err, models.ErrNoActiveCombatants) {
+			_, err = tx.Exec(stmtNoTurn, combatID)
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := touchCombat(tx, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetInitiative sets the initiative of each combatant of the combat
+// identified by `combatID` whose ID is a key of `initiatives`.
+func (m *CombatModel) SetInitiative(combatID int, initiatives map[int]int) error {
+	stmt := "UPDATE Combatant SET initiative = $3 WHERE id = $1 AND combat_id = $2"
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if _, err := lockCombat(tx, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for combatantID, initiative := range initiatives {
+		res, err := tx.Exec(stmt, combatantID, combatID, initiative)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		count, err := res.RowsAffected()
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if count != 1 {
+			tx.Rollback()
+			return models.ErrNoRecord
+		}
+	}
+
+	if err := touchCombat(tx, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// NextTurn passes the turn to the next combatant of the combat identified
+// by `combatID` who has not been defeated, starting a new round after the
+// last one. The first call starts round 1, which requires every
+// combatant to have an initiative.
+func (m *CombatModel) NextTurn(combatID int) error {
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	combat, err := lockCombat(tx, combatID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := advanceTurn(tx, combat); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := touchCombat(tx, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AdjustHP applies `damage` and `healing` to the combatant identified by
+// `combatantID` and sets its temporary hit points to `tempHP` if it is
+// not nil. Temporary hit points absorb damage first. An NPC reduced to 0
+// hit points is defeated, and any combatant healed above 0 is not. The
+// hit points of a character's combatant are those of the character, who
+// starts dying at 0 hit points and is only defeated once dead.
+func (m *CombatModel) AdjustHP(combatID, combatantID, damage, healing int, tempHP *int) error {
+	stmtSelect := `SELECT hp_max, hp_current, temp_hp, defeated, npc_id IS NOT NULL
+			FROM Combatant
+			WHERE id = $1 AND combat_id = $2
+			FOR UPDATE`
+	stmtUpdate := `UPDATE Combatant
+			SET hp_current = $2, temp_hp = $3, defeated = $4
+			WHERE id = $1`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	// The combat is locked first, then characters before their
+	// combatants, as elsewhere
+	if _, err := lockCombat(tx, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	characterID, err := getCombatantCharacter(tx, combatID, combatantID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	var character *models.Character
+	if characterID != nil {
+		if character, err = lockCharacter(tx, *characterID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	var hpMax, hpCurrent, temp int
+	var defeated, isNPC bool
+	err = tx.QueryRowx(stmtSelect, combatantID, combatID).Scan(&hpMax, &hpCurrent, &temp, &defeated, &isNPC)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNoRecord
+		}
+		return err
+	}
+
+	if tempHP != nil {
+		temp = *tempHP
+	}
+
+	absorbed := damage
+	if absorbed > temp {
+		absorbed = temp
+	}
+	temp -= absorbed
+
+	if character != nil {
+		character.TakeDamage(damage - absorbed)
+		character.Heal(healing)
+		if err := saveCharacterVitals(tx, character); err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		hpCurrent = character.HPCurrent
+		if hpCurrent > hpMax {
+			hpCurrent = hpMax
+		}
+		defeated = character.Dead
+	} else {
+		hpCurrent -= damage - absorbed
+		if hpCurrent < 0 {
+			hpCurrent = 0
+		}
+
+		hpCurrent += healing
+		if hpCurrent > hpMax {
+			hpCurrent = hpMax
+		}
+
+		if hpCurrent > 0 {
+			defeated = false
+		} else if isNPC {
+			defeated = true
+		}
+	}
+	if _, err := tx.Exec(stmtUpdate, combatantID, hpCurrent, temp, defeated); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := touchCombat(tx, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetDefeated marks the combatant identified by `combatantID` as
+// defeated or not.
+func (m *CombatModel) SetDefeated(combatID, combatantID int, defeated bool) error {
+	stmt := "UPDATE Combatant SET defeated = $3 WHERE id = $1 AND combat_id = $2"
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if _, err := lockCombat(tx, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	res, err := tx.Exec(stmt, combatantID, combatID, defeated)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count != 1 {
+		tx.Rollback()
+		return models.ErrNoRecord
+	}
+
+	if err := touchCombat(tx, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AddCondition applies `condition` to the combatant identified by
+// `combatantID`, replacing it if the combatant already has it. The
+// conditions of characters are kept on the character, so that they last
+// beyond the combat.
+func (m *CombatModel) AddCondition(combatID, combatantID int, condition models.AppliedCondition) error {
+	stmt := `INSERT INTO CombatantCondition (combatant_id, condition, level, source, rounds_remaining, until_rest)
+		VALUES($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (combatant_id, condition) DO UPDATE
+		SET level = EXCLUDED.level, source = EXCLUDED.source, rounds_remaining = EXCLUDED.rounds_remaining,
+			until_rest = EXCLUDED.until_rest, applied_at = now()`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if _, err := lockCombat(tx, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	characterID, err := getCombatantCharacter(tx, combatID, combatantID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if characterID != nil {
+		_, err = tx.Exec(upsertCharacterCondition, *characterID, condition.Condition, condition.Level,
+			condition.Source, condition.RoundsRemaining, condition.UntilRest)
+	} else {
+		_, err = tx.Exec(stmt, combatantID, condition.Condition, condition.Level,
+			condition.Source, condition.RoundsRemaining, condition.UntilRest)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := touchCombat(tx, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveCondition ends `condition` on the combatant identified by
+// `combatantID`, or on its character.
+func (m *CombatModel) RemoveCondition(combatID, combatantID int, condition models.ConditionType) error {
+	stmtCombatant := "DELETE FROM CombatantCondition WHERE combatant_id = $1 AND condition = $2"
+	stmtCharacter := "DELETE FROM CharacterCondition WHERE character_id = $1 AND condition = $2"
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if _, err := lockCombat(tx, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	characterID, err := getCombatantCharacter(tx, combatID, combatantID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var res sql.Result
+	if characterID != nil {
+		res, err = tx.Exec(stmtCharacter, *characterID, condition)
+	} else {
+		res, err = tx.Exec(stmtCombatant, combatantID, condition)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count != 1 {
+		tx.Rollback()
+		return models.ErrNoRecord
+	}
+
+	if err := touchCombat(tx, combatID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// getCombatants retrieves the combatants of the combat identified by
+// `combatID` in turn order, along with their conditions. The rows are
+// locked for update if `lock` is true.
+func getCombatants(q sqlx.Queryer, combatID int, lock bool) ([]models.Combatant, error) {
+	combatants := []models.Combatant{}
+
+	stmt := `SELECT ` + combatantColumns + `
+			FROM Combatant AS cb
+			LEFT JOIN Character AS ch ON ch.id = cb.character_id
+			WHERE cb.combat_id = $1
+			ORDER BY ` + combatantOrder
+	if lock {
+		stmt += " FOR UPDATE OF cb"
+	}
+
+	if err := sqlx.Select(q, &combatants, stmt, combatID); err != nil {
+		return nil, err
+	}
+	if len(combatants) == 0 {
+		return combatants, nil
+	}
+
+	ids := make([]int64, len(combatants))
+	byID := make(map[int]*models.Combatant, len(combatants))
+	for i := range combatants {
+		ids[i] = int64(combatants[i].ID)
+		combatants[i].Conditions = []models.AppliedCondition{}
+		byID[combatants[i].ID] = &combatants[i]
+	}
+
+	stmtConditions := `SELECT cc.combatant_id, cc.condition, cc.level, cc.source,
+				cc.rounds_remaining, cc.until_rest, cc.applied_at
+			FROM CombatantCondition AS cc
+			WHERE cc.combatant_id = ANY($1)
+			UNION ALL
+			SELECT cb.id, chc.condition, chc.level, chc.source,
+				chc.rounds_remaining, chc.until_rest, chc.applied_at
+			FROM Combatant AS cb
+			INNER JOIN CharacterCondition AS chc
+			ON chc.character_id = cb.character_id
+			WHERE cb.id = ANY($1)
+			ORDER BY combatant_id, condition`
+
+	rows, err := q.Queryx(stmtConditions, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var condition struct {
+			CombatantID int `db:"combatant_id"`
+			models.AppliedCondition
+		}
+		if err := rows.StructScan(&condition); err != nil {
+			return nil, err
+		}
+		c := byID[condition.CombatantID]
+		c.Conditions = append(c.Conditions, condition.AppliedCondition)
+	}
+
+	return combatants, rows.Err()
+}
+
+// getCombatantCharacter returns the ID of the character of the
+// combatant identified by `combatantID`, or nil if it is not a character,
+// as part of the transaction `tx`.
+func getCombatantCharacter(tx *sqlx.Tx, combatID, combatantID int) (*int, error) {
+	stmt := "SELECT character_id FROM Combatant WHERE id = $1 AND combat_id = $2"
+
+	var characterID *int
+	if err := tx.QueryRowx(stmt, combatantID, combatID).Scan(&characterID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return characterID, nil
+}
+
+// lockCombat retrieves the combat identified by `id`, which must not have
+// ended, and locks it for the rest of the transaction `tx`.
+func lockCombat(tx *sqlx.Tx, id int) (*models.Combat, error) {
+	var combat models.Combat
+
+	stmt := "SELECT * FROM Combat WHERE id = $1 AND ended_at IS NULL FOR UPDATE"
+	if err := tx.QueryRowx(stmt, id).StructScan(&combat); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return &combat, nil
+}
+
+// advanceTurn passes the turn of the locked `combat` to the next
+// combatant who has not been defeated as part of the transaction `tx`.
+func advanceTurn(tx *sqlx.Tx, combat *models.Combat) error {
+	combatants, err := getCombatants(tx, combat.ID, true)
+	if err != nil {
+		return err
+	}
+
+	round := combat.Round
+	start := 0
+	if round == 0 {
+		for _, c := range combatants {
+			if c.Initiative == nil {
+				return models.ErrInitiativeMissing
+			}
+		}
+		round = 1
+	} else if combat.CurrentCombatantID != nil {
+		for i, c := range combatants {
+			if c.ID == *combat.CurrentCombatantID {
+				start = i + 1
+				break
+			}
+		}
+	}
+
+	return passTurn(tx, combat, combatants, start, round)
+}
+
+// passTurn gives the turn of the locked `combat` to the first combatant
+// of `combatants`, in turn order, from the index `start` on who has not
+// been defeated, during `round` or the next round if the turn order
+// wraps around, as part of the transaction `tx`. Conditions lasting a
+// number of rounds are counted down as each new round starts.
+// models.ErrNoActiveCombatants is returned if every combatant has been
+// defeated.
+func passTurn(tx *sqlx.Tx, combat *models.Combat, combatants []models.Combatant, start, round int) error {
+	stmt := `UPDATE Combat
+			SET round = $2, current_combatant_id = $3
+			WHERE id = $1`
+
+	for n := 0; n < len(combatants); n++ {
+		i := start + n
+		if i == len(combatants) {
+			round++
+		}
+		next := combatants[i%len(combatants)]
+		if !next.Defeated {
+			if combat.Round > 0 && round > combat.Round {
+				if err := expireConditions(tx, combat.ID); err != nil {
+					return err
+				}
+			}
+			_, err := tx.Exec(stmt, combat.ID, round, next.ID)
+			return err
+		}
+	}
+
+	return models.ErrNoActiveCombatants
+}
+
+// insertCombatants adds `combatants` to the combat identified by
+// `combatID` as part of the transaction `tx`.
+func insertCombatants(tx *sqlx.Tx, combatID int, combatants []models.Combatant) error {
+	stmt := `INSERT INTO Combatant (combat_id, character_id, npc_id, name, initiative,
+			initiative_bonus, armor_class, hp_max, hp_current)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	for _, c := range combatants {
+		_, err := tx.Exec(stmt, combatID, c.CharacterID, c.NPCID, c.Name, c.Initiative,
+			c.InitiativeBonus, c.ArmorClass, c.HPMax, c.HPCurrent)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// touchCombat records a change to the combat identified by `id` as part
+// of the transaction `tx`.
+func touchCombat(tx *sqlx.Tx, id int) error {
+	stmt := `UPDATE Combat
+			SET version = version + 1, updated_at = now()
+			WHERE id = $1`
+
+	_, err := tx.Exec(stmt, id)
+	return err
+}
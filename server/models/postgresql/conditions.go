@@ -0,0 +1,212 @@
+package postgresql
+
+import (
+	"draco/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ConditionModel struct {
+	DB *sqlx.DB
+}
+
+// upsertCharacterCondition applies a condition to a character, replacing
+// the level, source and duration of the condition if it already has it.
+const upsertCharacterCondition = `INSERT INTO CharacterCondition (character_id, condition, level, source, rounds_remaining, until_rest)
+		VALUES($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (character_id, condition) DO UPDATE
+		SET level = EXCLUDED.level, source = EXCLUDED.source, rounds_remaining = EXCLUDED.rounds_remaining,
+			until_rest = EXCLUDED.until_rest, applied_at = now()`
+
+// Set applies `condition` to the character identified by `characterID`.
+// Applying a condition the character already has replaces it, so that
+// exhaustion can be raised or lowered a level at a time. The combats the
+// character is in show its conditions, so they are changed too.
+func (m *ConditionModel) Set(characterID int, condition models.AppliedCondition) error {
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := lockCharacterCombats(tx, characterID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	_, err = tx.Exec(upsertCharacterCondition, characterID, condition.Condition, condition.Level,
+		condition.Source, condition.RoundsRemaining, condition.UntilRest)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := touchCharacterCombats(tx, characterID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetAll retrieves the conditions of the character identified by
+// `characterID`.
+func (m *ConditionModel) GetAll(characterID int) (*[]models.AppliedCondition, error) {
+	storedConditions := []models.AppliedCondition{}
+
+	stmt := `SELECT condition, level, source, rounds_remaining, until_rest, applied_at
+			FROM CharacterCondition
+			WHERE character_id = $1
+			ORDER BY condition`
+
+	if err := m.DB.Select(&storedConditions, stmt, characterID); err != nil {
+		return nil, err
+	}
+
+	return &storedConditions, nil
+}
+
+// Delete ends `condition` on the character identified by `characterID`,
+// changing the combats the character is in along with it.
+func (m *ConditionModel) Delete(characterID int, condition models.ConditionType) error {
+	stmt := "DELETE FROM CharacterCondition WHERE character_id = $1 AND condition = $2"
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := lockCharacterCombats(tx, characterID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	res, err := tx.Exec(stmt, characterID, condition)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count != 1 {
+		tx.Rollback()
+		return models.ErrNoRecord
+	}
+
+	if err := touchCharacterCombats(tx, characterID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Rest ends the conditions of the character identified by `characterID`
+// which last until a rest of kind `rest`, and recharges the features
+// which recharge with it. A long rest also removes a level of exhaustion
+// and restores every hit point.
+func (m *ConditionModel) Rest(characterID int, rest models.RestType) error {
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := lockCharacterCombats(tx, characterID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	character, err := lockCharacter(tx, characterID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := restConditions(tx, characterID, rest); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := restFeatures(tx, characterID, rest); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if rest == models.RestLong {
+		character.Heal(character.HPMax)
+		if err := saveCharacterVitals(tx, character); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := touchCharacterCombats(tx, characterID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// restConditions ends the conditions of the character identified by
+// `characterID` which last until a rest of kind `rest`, and removes a
+// level of exhaustion after a long rest, as part of the transaction `tx`.
+func restConditions(tx *sqlx.Tx, characterID int, rest models.RestType) error {
+	stmtEnd := `DELETE FROM CharacterCondition
+			WHERE character_id = $1
+			AND (until_rest = 'short' OR (until_rest = 'long' AND $2 = 'long'))`
+	stmtRecover := `DELETE FROM CharacterCondition
+			WHERE character_id = $1 AND condition = 'exhaustion' AND level = 1`
+	stmtExhaustion := `UPDATE CharacterCondition
+			SET level = level - 1
+			WHERE character_id = $1 AND condition = 'exhaustion'`
+
+	if _, err := tx.Exec(stmtEnd, characterID, rest); err != nil {
+		return err
+	}
+
+	if rest == models.RestLong {
+		if _, err := tx.Exec(stmtRecover, characterID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(stmtExhaustion, characterID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// expireConditions counts down the conditions lasting a number of rounds
+// of every combatant of the combat identified by `combatID`, ending those
+// which run out, as part of the transaction `tx`. Characters' conditions
+// count down along with the combat they are in.
+func expireConditions(tx *sqlx.Tx, combatID int) error {
+	stmts := []string{
+		`DELETE FROM CombatantCondition
+			WHERE rounds_remaining = 1
+			AND combatant_id IN (SELECT id FROM Combatant WHERE combat_id = $1)`,
+		`UPDATE CombatantCondition
+			SET rounds_remaining = rounds_remaining - 1
+			WHERE rounds_remaining IS NOT NULL
+			AND combatant_id IN (SELECT id FROM Combatant WHERE combat_id = $1)`,
+		`DELETE FROM CharacterCondition
+			WHERE rounds_remaining = 1
+			AND character_id IN (SELECT character_id FROM Combatant WHERE combat_id = $1)`,
+		`UPDATE CharacterCondition
+			SET rounds_remaining = rounds_remaining - 1
+			WHERE rounds_remaining IS NOT NULL
+			AND character_id IN (SELECT character_id FROM Combatant WHERE combat_id = $1)`,
+	}
+
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt, combatID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
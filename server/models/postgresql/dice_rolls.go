@@ -0,0 +1,71 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type DiceRollModel struct {
+	DB *sqlx.DB
+}
+
+// selectDiceRolls selects dice rolls along with the name of the
+// character they were made for.
+const selectDiceRolls = `SELECT dr.*, ch.name AS character_name
+		FROM DiceRoll AS dr
+		LEFT JOIN Character AS ch
+		ON ch.id = dr.character_id`
+
+// Insert records `roll` and returns the ID of the new roll.
+func (m *DiceRollModel) Insert(roll models.DiceRoll) (int, error) {
+	stmt := `INSERT INTO DiceRoll (campaign_id, rolled_by, character_id, label, expression, mode, ability, modifier, terms, total)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id`
+
+	var id int
+	err := m.DB.QueryRowx(stmt, roll.CampaignID, roll.RolledBy, roll.CharacterID, roll.Label, roll.Expression,
+		roll.Mode, roll.Ability, roll.Modifier, []byte(roll.Terms), roll.Total).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// Get retrieves the dice roll identified by `id`.
+func (m *DiceRollModel) Get(id int) (*models.DiceRoll, error) {
+	var storedRoll models.DiceRoll
+
+	stmt := selectDiceRolls + " WHERE dr.id = $1"
+	if err := m.DB.QueryRowx(stmt, id).StructScan(&storedRoll); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return &storedRoll, nil
+}
+
+// GetAllForCampaign retrieves the dice rolls of the campaign identified
+// by `campaignID`, most recent first. Only the rolls made for the
+// character identified by `characterID` are retrieved if it is set.
+func (m *DiceRollModel) GetAllForCampaign(campaignID int, characterID *int, limit, offset int) (*[]models.DiceRoll, error) {
+	storedRolls := []models.DiceRoll{}
+
+	stmt := selectDiceRolls + `
+			WHERE dr.campaign_id = $1
+			AND ($2::int IS NULL OR dr.character_id = $2)
+			ORDER BY dr.rolled_at DESC, dr.id DESC
+			LIMIT $3 OFFSET $4`
+
+	if err := m.DB.Select(&storedRolls, stmt, campaignID, characterID, limit, offset); err != nil {
+		return nil, err
+	}
+
+	return &storedRolls, nil
+}
@@ -0,0 +1,204 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type EncounterModel struct {
+	DB *sqlx.DB
+}
+
+// Insert saves `encounter` along with its monsters and returns the ID of
+// the new encounter.
+func (m *EncounterModel) Insert(encounter models.Encounter) (int, error) {
+	stmt := `INSERT INTO Encounter (campaign_id, name, notes, created_by)
+		VALUES($1, $2, $3, $4)
+		RETURNING id`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return -1, err
+	}
+
+	var id int
+	err = tx.QueryRowx(stmt, encounter.CampaignID, encounter.Name, encounter.Notes, encounter.CreatedBy).Scan(&id)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	if err := writeEncounterMonsters(tx, id, encounter.Monsters); err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	return id, tx.Commit()
+}
+
+// Get retrieves the encounter identified by `id` along with its monsters.
+func (m *EncounterModel) Get(id int) (*models.Encounter, error) {
+	var storedEncounter models.Encounter
+
+	stmt := "SELECT * FROM Encounter WHERE id = $1"
+	if err := m.DB.QueryRowx(stmt, id).StructScan(&storedEncounter); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	encounters := []models.Encounter{storedEncounter}
+	if err := m.loadMonsters(encounters); err != nil {
+		return nil, err
+	}
+
+	return &encounters[0], nil
+}
+
+// GetAllForCampaign retrieves the encounters of the campaign identified
+// by `campaignID`, most recently updated first.
+func (m *EncounterModel) GetAllForCampaign(campaignID int) (*[]models.Encounter, error) {
+	storedEncounters := []models.Encounter{}
+
+	stmt := `SELECT *
+			FROM Encounter
+			WHERE campaign_id = $1
+			ORDER BY updated_at DESC, id DESC`
+
+	if err := m.DB.Select(&storedEncounters, stmt, campaignID); err != nil {
+		return nil, err
+	}
+
+	if err := m.loadMonsters(storedEncounters); err != nil {
+		return nil, err
+	}
+
+	return &storedEncounters, nil
+}
+
+// Update replaces the name, notes and monsters of the encounter
+// identified by `encounter.ID`.
+func (m *EncounterModel) Update(encounter models.Encounter) error {
+	stmt := `UPDATE Encounter
+			SET name = $2, notes = $3, updated_at = now()
+			WHERE id = $1`
+	stmtDeleteMonsters := "DELETE FROM EncounterMonster WHERE encounter_id = $1"
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(stmt, encounter.ID, encounter.Name, encounter.Notes)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count != 1 {
+		tx.Rollback()
+		return models.ErrNoRecord
+	}
+
+	if _, err := tx.Exec(stmtDeleteMonsters, encounter.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := writeEncounterMonsters(tx, encounter.ID, encounter.Monsters); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes the encounter identified by `id`.
+func (m *EncounterModel) Delete(id int) error {
+	stmt := "DELETE FROM Encounter WHERE id = $1"
+
+	res, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return models.ErrNoRecord
+	}
+	if count > 1 {
+		return models.ErrDeleteSingleRecord
+	}
+
+	return nil
+}
+
+// loadMonsters fills in the monsters of every encounter in `encounters`.
+func (m *EncounterModel) loadMonsters(encounters []models.Encounter) error {
+	if len(encounters) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(encounters))
+	byID := make(map[int]*models.Encounter, len(encounters))
+	for i := range encounters {
+		ids[i] = int64(encounters[i].ID)
+		encounters[i].Monsters = []models.EncounterMonster{}
+		byID[encounters[i].ID] = &encounters[i]
+	}
+
+	stmt := `SELECT encounter_id, npc_id, compendium_slug, name, challenge_rating, quantity
+			FROM EncounterMonster
+			WHERE encounter_id = ANY($1)
+			ORDER BY encounter_id, position`
+
+	rows, err := m.DB.Query(stmt, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var encounterID int
+		var monster models.EncounterMonster
+		err := rows.Scan(&encounterID, &monster.NPCID, &monster.CompendiumSlug, &monster.Name,
+			&monster.ChallengeRating, &monster.Quantity)
+		if err != nil {
+			return err
+		}
+		byID[encounterID].Monsters = append(byID[encounterID].Monsters, monster)
+	}
+
+	return rows.Err()
+}
+
+// writeEncounterMonsters stores `monsters` in order for the encounter
+// identified by `encounterID` as part of the transaction `tx`.
+func writeEncounterMonsters(tx *sqlx.Tx, encounterID int, monsters []models.EncounterMonster) error {
+	stmt := `INSERT INTO EncounterMonster (encounter_id, position, npc_id, compendium_slug, name, challenge_rating, quantity)
+		VALUES($1, $2, $3, $4, $5, $6, $7)`
+
+	for i, monster := range monsters {
+		_, err := tx.Exec(stmt, encounterID, i, monster.NPCID, monster.CompendiumSlug, monster.Name,
+			monster.ChallengeRating, monster.Quantity)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type FeatureModel struct {
+	DB *sqlx.DB
+}
+
+// selectCharacterFeatures selects the features granted to characters.
+const selectCharacterFeatures = `SELECT f.*, cf.character_id, cf.uses_spent, cf.granted_at
+		FROM CharacterFeature AS cf
+		INNER JOIN Feature AS f
+		ON f.id = cf.feature_id`
+
+// GetAll retrieves the features of the catalog, restricted to the source
+// `source`, the race `race` and the class `class` when they are set.
+func (m *FeatureModel) GetAll(source *models.FeatureSourceType, race, class string) (*[]models.Feature, error) {
+	storedFeatures := []models.Feature{}
+
+	stmt := `SELECT *
+			FROM Feature
+			WHERE ($1::e_feature_source IS NULL OR source = $1)
+			AND ($2 = '' OR lower(race) = lower($2))
+			AND ($3 = '' OR lower(class) = lower($3))
+			ORDER BY source, race, class, subclass, level, name`
+
+	if err := m.DB.Select(&storedFeatures, stmt, source, race, class); err != nil {
+		return nil, err
+	}
+
+	return &storedFeatures, nil
+}
+
+// FindFeat retrieves the feat named `name`, ignoring case.
+func (m *FeatureModel) FindFeat(name string) (*models.Feature, error) {
+	var storedFeature models.Feature
+
+	stmt := "SELECT * FROM Feature WHERE source = 'feat' AND lower(name) = lower($1)"
+	if err := m.DB.QueryRowx(stmt, name).StructScan(&storedFeature); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return &storedFeature, nil
+}
+
+// GetForCharacter retrieves the feature identified by `featureID` granted
+// to the character identified by `characterID`.
+func (m *FeatureModel) GetForCharacter(characterID, featureID int) (*models.CharacterFeature, error) {
+	var storedFeature models.CharacterFeature
+
+	stmt := selectCharacterFeatures + " WHERE cf.character_id = $1 AND cf.feature_id = $2"
+	if err := m.DB.QueryRowx(stmt, characterID, featureID).StructScan(&storedFeature); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return &storedFeature, nil
+}
+
+// GetAllForCharacter retrieves the features granted to the character
+// identified by `characterID`, racial traits first.
+func (m *FeatureModel) GetAllForCharacter(characterID int) (*[]models.CharacterFeature, error) {
+	storedFeatures := []models.CharacterFeature{}
+
+	stmt := selectCharacterFeatures + `
+			WHERE cf.character_id = $1
+			ORDER BY f.source, f.class, f.level, f.name`
+
+	if err := m.DB.Select(&storedFeatures, stmt, characterID); err != nil {
+		return nil, err
+	}
+
+	return &storedFeatures, nil
+}
+
+// AddFeat grants the feat named `name` to the character identified by
+// `characterID`.
+func (m *FeatureModel) AddFeat(characterID int, name string) error {
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := grantFeat(tx, characterID, name); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RemoveFeat takes the feat identified by `featureID` away from the
+// character identified by `characterID`. Features granted by a race or
+// class cannot be removed.
+func (m *FeatureModel) RemoveFeat(characterID, featureID int) error {
+	stmt := `DELETE FROM CharacterFeature
+			WHERE character_id = $1 AND feature_id = $2
+			AND feature_id IN (SELECT id FROM Feature WHERE source = 'feat')`
+
+	res, err := m.DB.Exec(stmt, characterID, featureID)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return models.ErrNoRecord
+	}
+
+	return nil
+}
+
+// Use spends `uses` uses of the limited feature identified by `featureID`
+// of the character identified by `characterID`, or regains them when
+// `uses` is negative, and returns the uses spent since its last recharge.
+// models.ErrNoUsesRemaining is returned if more than `maxUses` would be
+// spent.
+func (m *FeatureModel) Use(characterID, featureID, uses, maxUses int) (int, error) {
+	stmt := `UPDATE CharacterFeature
+			SET uses_spent = GREATEST(uses_spent + $3, 0)
+			WHERE character_id = $1 AND feature_id = $2
+			AND uses_spent + $3 <= $4
+			RETURNING uses_spent`
+
+	var spent int
+	if err := m.DB.QueryRowx(stmt, characterID, featureID, uses, maxUses).Scan(&spent); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return -1, models.ErrNoUsesRemaining
+		}
+		return -1, err
+	}
+
+	return spent, nil
+}
+
+// grantFeat grants the feat named `name`, ignoring case, to the
+// character identified by `characterID`, as part of the transaction
+// `tx`.
+func grantFeat(tx *sqlx.Tx, characterID int, name string) error {
+	stmt := `INSERT INTO CharacterFeature (character_id, feature_id)
+		SELECT $1, id FROM Feature WHERE source = 'feat' AND lower(name) = lower($2)`
+
+	res, err := tx.Exec(stmt, characterID, name)
+	if err != nil {
+		var postgresError *pq.Error
+		if errors.As(err, &postgresError) && postgresError.Code.Name() == "unique_violation" {
+			return models.ErrDuplicateCharacterFeature
+		}
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return models.ErrNoRecord
+	}
+
+	return nil
+}
+
+// syncFeatures grants the character identified by `characterID` the
+// features of its race, classes and subclasses which it has the levels
+// for, and takes away those it no longer qualifies for, as part of the
+// transaction `tx`. Feats are left alone.
+func syncFeatures(tx *sqlx.Tx, characterID int) error {
+	eligible := `SELECT f.id
+			FROM Feature AS f
+			INNER JOIN Character AS ch
+			ON ch.id = $1
+			WHERE (f.source = 'race' AND f.race = ch.race AND f.level <= ch.level)
+			OR (f.source IN ('class', 'subclass') AND EXISTS (
+				SELECT 1
+				FROM CharacterClass AS cc
+				WHERE cc.character_id = ch.id
+				AND cc.class = f.class
+				AND cc.levels >= f.level
+				AND (f.source = 'class' OR cc.subclass = f.subclass)))`
+	stmtRevoke := `DELETE FROM CharacterFeature
+			WHERE character_id = $1
+			AND feature_id IN (SELECT id FROM Feature WHERE source <> 'feat')
+			AND feature_id NOT IN (` + eligible + `)`
+	stmtGrant := `INSERT INTO CharacterFeature (character_id, feature_id)
+		SELECT $1, id FROM (` + eligible + `) AS e
+		ON CONFLICT DO NOTHING`
+
+	if _, err := tx.Exec(stmtRevoke, characterID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(stmtGrant, characterID)
+	return err
+}
+
+// restFeatures restores the uses of the limited features of the
+// character identified by `characterID` which recharge with a rest of
+// kind `rest`, as part of the transaction `tx`. Features recharging with
+// a short rest also recharge with a long rest.
+func restFeatures(tx *sqlx.Tx, characterID int, rest models.RestType) error {
+	stmt := `UPDATE CharacterFeature
+			SET uses_spent = 0
+			WHERE character_id = $1 AND uses_spent > 0
+			AND feature_id IN (
+				SELECT id
+				FROM Feature
+				WHERE recharge = 'short' OR (recharge = 'long' AND $2 = 'long'))`
+
+	_, err := tx.Exec(stmt, characterID, rest)
+	return err
+}
@@ -0,0 +1,155 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type HitPointModel struct {
+	DB *sqlx.DB
+}
+
+// AdjustHP applies `damage`, then `healing`, to the character identified
+// by `characterID` and returns the character as it ends up.
+// models.ErrCharacterDead is returned for a dead character.
+func (m *HitPointModel) AdjustHP(characterID, damage, healing int) (*models.Character, error) {
+	return m.change(characterID, func(c *models.Character) error {
+		if c.Dead {
+			return models.ErrCharacterDead
+		}
+		c.TakeDamage(damage)
+		c.Heal(healing)
+		return nil
+	})
+}
+
+// DeathSave records a death save of `roll` on a d20 for the dying
+// character identified by `characterID` and returns the character as it
+// ends up.
+func (m *HitPointModel) DeathSave(characterID, roll int) (*models.Character, error) {
+	return m.change(characterID, func(c *models.Character) error {
+		return c.RollDeathSave(roll)
+	})
+}
+
+// Stabilize stabilizes the dying character identified by `characterID`
+// and returns the character as it ends up.
+func (m *HitPointModel) Stabilize(characterID int) (*models.Character, error) {
+	return m.change(characterID, func(c *models.Character) error {
+		return c.Stabilize()
+	})
+}
+
+// change applies `apply` to the character identified by `characterID`
+// while it is locked, then saves its hit points and death saves.
+func (m *HitPointModel) change(characterID int, apply func(c *models.Character) error) (*models.Character, error) {
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockCharacterCombats(tx, characterID); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	character, err := lockCharacter(tx, characterID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := apply(character); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := saveCharacterVitals(tx, character); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return character, tx.Commit()
+}
+
+// lockCharacter retrieves the character identified by `id` and locks it
+// for the rest of the transaction `tx`.
+func lockCharacter(tx *sqlx.Tx, id int) (*models.Character, error) {
+	var character models.Character
+
+	stmt := "SELECT * FROM Character WHERE id = $1 FOR UPDATE"
+	if err := tx.QueryRowx(stmt, id).StructScan(&character); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		}
+		return nil, err
+	}
+
+	return &character, nil
+}
+
+// lockCharacterCombats locks the combats which have not ended that the
+// character identified by `characterID` takes part in, for the rest of
+// the transaction `tx`. Combats are locked before characters, so this
+// comes before lockCharacter wherever the character's vitals are saved.
+func lockCharacterCombats(tx *sqlx.Tx, characterID int) error {
+	stmt := `SELECT id
+			FROM Combat
+			WHERE ended_at IS NULL
+			AND id IN (SELECT combat_id FROM Combatant WHERE character_id = $1)
+			ORDER BY id
+			FOR UPDATE`
+
+	var ids []int
+	return tx.Select(&ids, stmt, characterID)
+}
+
+// saveCharacterVitals saves the hit points and death saves of `c` as part
+// of the transaction `tx`. The character's combatants in combats which
+// have not ended follow its hit points, so those combats must have been
+// locked first.
+func saveCharacterVitals(tx *sqlx.Tx, c *models.Character) error {
+	stmtCharacter := `UPDATE Character
+			SET hp_current = $2, death_save_successes = $3, death_save_failures = $4, stable = $5, dead = $6
+			WHERE id = $1`
+	stmtCombatants := `UPDATE Combatant
+			SET hp_current = LEAST($2, hp_max), defeated = $3
+			WHERE character_id = $1
+			AND combat_id IN (SELECT id FROM Combat WHERE ended_at IS NULL)`
+	_, err := tx.Exec(stmtCharacter, c.ID, c.HPCurrent, c.DeathSaveSuccesses, c.DeathSaveFailures, c.Stable, c.Dead)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(stmtCombatants, c.ID, c.HPCurrent, c.Dead)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return touchCharacterCombats(tx, c.ID)
+	}
+
+	return nil
+}
+
+// touchCharacterCombats records a change to the combats which have not
+// ended that the character identified by `characterID` takes part in, as
+// part of the transaction `tx`. Those combats must have been locked
+// first.
+func touchCharacterCombats(tx *sqlx.Tx, characterID int) error {
+	stmt := `UPDATE Combat
+			SET version = version + 1, updated_at = now()
+			WHERE ended_at IS NULL
+			AND id IN (SELECT combat_id FROM Combatant WHERE character_id = $1)`
+
+	_, err := tx.Exec(stmt, characterID)
+	return err
+}
@@ -0,0 +1,190 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type IdentityModel struct {
+	DB *sqlx.DB
+}
+
+// CreateLoginState stores a pending single sign-on request. Only the
+// hashes of the state parameter and of the session key given to the
+// client which started the request are stored. `linkUsername` is empty
+// for logins.
+func (m *IdentityModel) CreateLoginState(stateHash, sessionKeyHash, nonce, codeVerifier, linkUsername string, expiresAt time.Time) error {
+	stmt := `INSERT INTO OIDCLoginState (state_hash, session_key_hash, nonce, code_verifier, link_username, expires_at)
+		VALUES($1, $2, $3, $4, NULLIF($5, ''), $6)`
+
+	_, err := m.DB.Exec(stmt, stateHash, sessionKeyHash, nonce, codeVerifier, linkUsername, expiresAt)
+	return err
+}
+
+// ConsumeLoginState removes and returns the pending single sign-on
+// request identified by `stateHash`, provided it has not expired and was
+// started with the session key hashing to `sessionKeyHash`.
+func (m *IdentityModel) ConsumeLoginState(stateHash, sessionKeyHash string) (string, string, string, error) {
+	var nonce, codeVerifier string
+	var linkUsername sql.NullString
+	var expiresAt time.Time
+
+	stmt := `DELETE FROM OIDCLoginState
+			WHERE state_hash = $1 AND session_key_hash = $2
+			RETURNING nonce, code_verifier, link_username, expires_at`
+
+	row := m.DB.QueryRow(stmt, stateHash, sessionKeyHash)
+	if err := row.Scan(&nonce, &codeVerifier, &linkUsername, &expiresAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", "", "", models.ErrLoginStateExpired
+		} else {
+			return "", "", "", err
+		}
+	}
+
+	if time.Now().After(expiresAt) {
+		return "", "", "", models.ErrLoginStateExpired
+	}
+
+	// Expired requests are never consumed, so clean them up here.
+	if _, err := m.DB.Exec("DELETE FROM OIDCLoginState WHERE expires_at <= now()"); err != nil {
+		return "", "", "", err
+	}
+
+	return nonce, codeVerifier, linkUsername.String, nil
+}
+
+// GetPlayer retrieves the username of the player linked to `subject` at
+// the identity provider `issuer`.
+func (m *IdentityModel) GetPlayer(issuer, subject string) (string, error) {
+	var username string
+
+	stmt := "SELECT player_username FROM PlayerIdentity WHERE issuer = $1 AND subject = $2"
+	row := m.DB.QueryRow(stmt, issuer, subject)
+	if err := row.Scan(&username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", models.ErrNoRecord
+		} else {
+			return "", err
+		}
+	}
+
+	return username, nil
+}
+
+// GetAllForPlayer retrieves all external identities linked to the
+// player identified by `username`.
+func (m *IdentityModel) GetAllForPlayer(username string) (*[]models.PlayerIdentity, error) {
+	storedIdentities := []models.PlayerIdentity{}
+
+	stmt := "SELECT * FROM PlayerIdentity WHERE player_username = $1 ORDER BY created_at"
+	rows, err := m.DB.Queryx(stmt, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i models.PlayerIdentity
+		err = rows.StructScan(&i)
+		if err != nil {
+			return nil, err
+		}
+		storedIdentities = append(storedIdentities, i)
+	}
+
+	return &storedIdentities, rows.Err()
+}
+
+// Link associates `subject` at the identity provider `issuer` with the
+// player identified by `username`.
+func (m *IdentityModel) Link(issuer, subject, username string) error {
+	stmt := `INSERT INTO PlayerIdentity (issuer, subject, player_username)
+		VALUES($1, $2, $3)`
+
+	_, err := m.DB.Exec(stmt, issuer, subject, username)
+	if err != nil {
+		var postgresError *pq.Error
+		if errors.As(err, &postgresError) {
+			if postgresError.Code.Name() == "unique_violation" {
+				return models.ErrDuplicateIdentity
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Provision creates the player `username` with `password` and `name`,
+// linked to the account `subject` at the identity provider `issuer`. The
+// player is only created along with the link, so models.ErrDuplicateUsername
+// or models.ErrDuplicateIdentity leaves nothing behind.
+func (m *IdentityModel) Provision(issuer, subject, username, password, name string) error {
+	stmtPlayer := `INSERT INTO Player (username, password, name)
+	VALUES($1, $2, $3)`
+	stmtIdentity := `INSERT INTO PlayerIdentity (issuer, subject, player_username)
+		VALUES($1, $2, $3)`
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(stmtPlayer, username, string(hashedPassword), name); err != nil {
+		tx.Rollback()
+		var postgresError *pq.Error
+		if errors.As(err, &postgresError) {
+			if postgresError.Code.Name() == "unique_violation" && strings.Contains(postgresError.Message, "player_pkey") {
+				return models.ErrDuplicateUsername
+			}
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(stmtIdentity, issuer, subject, username); err != nil {
+		tx.Rollback()
+		var postgresError *pq.Error
+		if errors.As(err, &postgresError) {
+			if postgresError.Code.Name() == "unique_violation" {
+				return models.ErrDuplicateIdentity
+			}
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Unlink removes the link between the player identified by `username`
+// and their account at the identity provider `issuer`.
+func (m *IdentityModel) Unlink(username, issuer string) error {
+	stmt := "DELETE FROM PlayerIdentity WHERE player_username = $1 AND issuer = $2"
+
+	res, err := m.DB.Exec(stmt, username, issuer)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return models.ErrNoRecord
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type InvitationModel struct {
+	DB *sqlx.DB
+}
+
+const selectInvitations = `SELECT i.*, ca.name AS campaign_name
+	FROM CampaignInvitation AS i
+	INNER JOIN Campaign AS ca
+	ON ca.id = i.campaign_id`
+
+// insertInvitation creates an invitation to a campaign, either of a
+// player or with a code.
+const insertInvitation = `INSERT INTO CampaignInvitation (campaign_id, invited_username, code, expires_at)
+		VALUES($1, NULLIF($2, ''), NULLIF($3, ''), $4)
+		RETURNING id`
+
+// Insert creates an invitation to the campaign identified by
+// `campaignID`. Exactly one of `invitedUsername` and `code` should be
+// non-empty.
+func (m *InvitationModel) Insert(campaignID int, invitedUsername, code string, expiresAt *time.Time) (int, error) {
+	var createdInvitationID int
+	err := m.DB.QueryRowx(insertInvitation, campaignID, invitedUsername, code, expiresAt).Scan(&createdInvitationID)
+	if err != nil {
+		var postgresError *pq.Error
+		if errors.As(err, &postgresError) {
+			if postgresError.Code.Name() == "foreign_key_violation" {
+				return -1, models.ErrNoRecord
+			}
+		}
+		return -1, err
+	}
+
+	return createdInvitationID, nil
+}
+
+// Get attempts to retrieve the invitation identified by `id`.
+func (m *InvitationModel) Get(id int) (*models.CampaignInvitation, error) {
+	var storedInvitation models.CampaignInvitation
+
+	stmt := selectInvitations + " WHERE i.id = $1"
+	row := m.DB.QueryRowx(stmt, id)
+
+	if err := row.StructScan(&storedInvitation); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return &storedInvitation, nil
+}
+
+// GetAllForCampaign retrieves every invitation to the campaign
+// identified by `campaignID`, most recent first.
+func (m *InvitationModel) GetAllForCampaign(campaignID int) (*[]models.CampaignInvitation, error) {
+	stmt := selectInvitations + " WHERE i.campaign_id = $1 ORDER BY i.created_at DESC"
+	return m.query(stmt, campaignID)
+}
+
+// GetPendingForPlayer retrieves the unexpired invitations which are
+// addressed to the player identified by `username` and awaiting a
+// response.
+func (m *InvitationModel) GetPendingForPlayer(username string) (*[]models.CampaignInvitation, error) {
+	stmt := selectInvitations + `
+		WHERE i.invited_username = $1
+			AND i.status = 'pending'
+			AND (i.expires_at IS NULL OR i.expires_at > now())
+		ORDER BY i.created_at DESC`
+	return m.query(stmt, username)
+}
+
+func (m *InvitationModel) query(stmt string, args ...interface{}) (*[]models.CampaignInvitation, error) {
+	storedInvitations := []models.CampaignInvitation{}
+
+	rows, err := m.DB.Queryx(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i models.CampaignInvitation
+		err = rows.StructScan(&i)
+		if err != nil {
+			return nil, err
+		}
+		storedInvitations = append(storedInvitations, i)
+	}
+
+	return &storedInvitations, rows.Err()
+}
+
+// Respond marks the pending invitation identified by `id` as declined
+// or revoked.
+func (m *InvitationModel) Respond(id int, status models.RequestStatusType) error {
+	stmt := `UPDATE CampaignInvitation
+			SET status = $2, responded_at = now()
+			WHERE id = $1 AND status = 'pending'`
+
+	res, err := m.DB.Exec(stmt, id, status)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return models.ErrInvitationExpired
+	}
+
+	return nil
+}
+
+// Accept marks the invitation identified by `id` as accepted and adds
+// the character identified by `characterID` to the campaign.
+func (m *InvitationModel) Accept(id, characterID int) error {
+	stmt := `UPDATE CampaignInvitation
+			SET status = 'accepted', responded_at = now()
+			WHERE id = $1 AND status = 'pending'
+				AND (expires_at IS NULL OR expires_at > now())
+			RETURNING campaign_id`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var campaignID int
+	if err := tx.QueryRowx(stmt, id).Scan(&campaignID); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrInvitationExpired
+		}
+		return err
+	}
+
+	if err := insertBelongsTo(tx, characterID, campaignID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Redeem adds the character identified by `characterID` to the campaign
+// which the shareable invitation `code` belongs to. Shareable codes
+// remain valid until they expire or are revoked.
+func (m *InvitationModel) Redeem(code string, characterID int) (int, error) {
+	stmt := `SELECT campaign_id
+			FROM CampaignInvitation
+			WHERE code = $1 AND status = 'pending'
+				AND (expires_at IS NULL OR expires_at > now())`
+
+	var campaignID int
+	if err := m.DB.QueryRowx(stmt, code).Scan(&campaignID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return -1, models.ErrInvitationExpired
+		}
+		return -1, err
+	}
+
+	if err := insertBelongsTo(m.DB, characterID, campaignID); err != nil {
+		return -1, err
+	}
+
+	return campaignID, nil
+}
+
+// insertBelongsTo adds a character to a campaign using `e`, which may be
+// a database handle or an open transaction. Characters cannot join
+// archived campaigns.
+func insertBelongsTo(e sqlx.Execer, characterID, campaignID int) error {
+	stmt := `INSERT INTO BelongsTo (character_id, campaign_id)
+		SELECT $1, id FROM Campaign WHERE id = $2 AND state <> 'archived'`
+
+	res, err := e.Exec(stmt, characterID, campaignID)
+	if err != nil {
+		var postgresError *pq.Error
+		if errors.As(err, &postgresError) {
+			if postgresError.Code.Name() == "unique_violation" {
+				return models.ErrDuplicateBelongsTo
+			}
+		}
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return models.ErrCampaignArchived
+	}
+	return nil
+}
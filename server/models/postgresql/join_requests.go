@@ -0,0 +1,143 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type JoinRequestModel struct {
+	DB *sqlx.DB
+}
+
+const selectJoinRequests = `SELECT r.*, ch.name AS character_name, ch.player_username
+	FROM CampaignJoinRequest AS r
+	INNER JOIN Character AS ch
+	ON ch.id = r.character_id`
+
+// Insert creates a request for the character identified by
+// `characterID` to join the campaign identified by `campaignID`.
+func (m *JoinRequestModel) Insert(campaignID, characterID int, message string) (int, error) {
+	stmt := `INSERT INTO CampaignJoinRequest (campaign_id, character_id, message)
+		VALUES($1, $2, $3)
+		RETURNING id`
+
+	var createdRequestID int
+	err := m.DB.QueryRowx(stmt, campaignID, characterID, message).Scan(&createdRequestID)
+	if err != nil {
+		var postgresError *pq.Error
+		if errors.As(err, &postgresError) {
+			switch postgresError.Code.Name() {
+			case "unique_violation":
+				return -1, models.ErrDuplicateJoinRequest
+			case "foreign_key_violation":
+				return -1, models.ErrNoRecord
+			}
+		}
+		return -1, err
+	}
+
+	return createdRequestID, nil
+}
+
+// Get attempts to retrieve the join request identified by `id`.
+func (m *JoinRequestModel) Get(id int) (*models.CampaignJoinRequest, error) {
+	var storedRequest models.CampaignJoinRequest
+
+	stmt := selectJoinRequests + " WHERE r.id = $1"
+	row := m.DB.QueryRowx(stmt, id)
+
+	if err := row.StructScan(&storedRequest); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return &storedRequest, nil
+}
+
+// GetAllForCampaign retrieves the join requests for the campaign
+// identified by `campaignID`, most recent first. Only requests with
+// `status` are returned unless it is empty.
+func (m *JoinRequestModel) GetAllForCampaign(campaignID int, status models.RequestStatusType) (*[]models.CampaignJoinRequest, error) {
+	storedRequests := []models.CampaignJoinRequest{}
+
+	stmt := selectJoinRequests + `
+		WHERE r.campaign_id = $1 AND ($2 = '' OR r.status::text = $2)
+		ORDER BY r.created_at DESC`
+
+	rows, err := m.DB.Queryx(stmt, campaignID, string(status))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.CampaignJoinRequest
+		err = rows.StructScan(&r)
+		if err != nil {
+			return nil, err
+		}
+		storedRequests = append(storedRequests, r)
+	}
+
+	return &storedRequests, rows.Err()
+}
+
+// Accept marks the pending join request identified by `id` as accepted
+// and adds its character to the campaign.
+func (m *JoinRequestModel) Accept(id int) error {
+	stmt := `UPDATE CampaignJoinRequest
+			SET status = 'accepted', responded_at = now()
+			WHERE id = $1 AND status = 'pending'
+			RETURNING character_id, campaign_id`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var characterID, campaignID int
+	if err := tx.QueryRowx(stmt, id).Scan(&characterID, &campaignID); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrInvitationExpired
+		}
+		return err
+	}
+
+	if err := insertBelongsTo(tx, characterID, campaignID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Decline marks the pending join request identified by `id` as
+// declined.
+func (m *JoinRequestModel) Decline(id int) error {
+	stmt := `UPDATE CampaignJoinRequest
+			SET status = 'declined', responded_at = now()
+			WHERE id = $1 AND status = 'pending'`
+
+	res, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return models.ErrInvitationExpired
+	}
+
+	return nil
+}
@@ -0,0 +1,218 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type JournalModel struct {
+	DB *sqlx.DB
+}
+
+// journalVisibleTo restricts a query over "JournalEntry" aliased `je` to
+// the entries which the player named by parameter $2 may read, unless
+// parameter $3 is true because the player is the dungeon master.
+const journalVisibleTo = `($3 OR je.visibility = 'everyone' OR je.author_username = $2
+		OR (je.visibility = 'players' AND EXISTS (
+			SELECT 1 FROM JournalEntryViewer v
+			WHERE v.entry_id = je.id AND v.player_username = $2)))`
+
+// Insert creates a journal entry along with its first revision and
+// returns the ID of the new entry. `entry.Viewers` is only stored when
+// the entry's visibility is models.JournalPlayers.
+func (m *JournalModel) Insert(entry models.JournalEntry) (int, error) {
+	stmt := `INSERT INTO JournalEntry (campaign_id, author_username, title, body, visibility)
+		VALUES($1, $2, $3, $4, $5)
+		RETURNING id`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return -1, err
+	}
+
+	var id int
+	err = tx.QueryRowx(
+		stmt, entry.CampaignID, entry.AuthorUsername, entry.Title, entry.Body, entry.Visibility,
+	).Scan(&id)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	entry.ID = id
+	entry.Revision = 1
+	if err := writeJournalDetails(tx, entry, entry.AuthorUsername); err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	return id, tx.Commit()
+}
+
+// Get retrieves the journal entry identified by `id` along with the
+// players it is shared with.
+func (m *JournalModel) Get(id int) (*models.JournalEntry, error) {
+	var storedEntry models.JournalEntry
+
+	stmt := "SELECT * FROM JournalEntry WHERE id = $1"
+	if err := m.DB.QueryRowx(stmt, id).StructScan(&storedEntry); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	stmtViewers := `SELECT player_username
+			FROM JournalEntryViewer
+			WHERE entry_id = $1
+			ORDER BY player_username`
+	storedEntry.Viewers = []string{}
+	if err := m.DB.Select(&storedEntry.Viewers, stmtViewers, id); err != nil {
+		return nil, err
+	}
+
+	return &storedEntry, nil
+}
+
+// GetAllForCampaign retrieves the journal entries of the campaign
+// identified by `campaignID` which `username` may read, most recent
+// first. When `query` is not empty only entries matching it are
+// returned, best matches first.
+func (m *JournalModel) GetAllForCampaign(campaignID int, username string, isDungeonMaster bool, query string, limit, offset int) (*[]models.JournalEntry, error) {
+	storedEntries := []models.JournalEntry{}
+
+	stmt := `SELECT je.*
+			FROM JournalEntry AS je
+			WHERE je.campaign_id = $1 AND ` + journalVisibleTo + `
+			ORDER BY je.created_at DESC, je.id DESC
+			LIMIT $4 OFFSET $5`
+	args := []interface{}{campaignID, username, isDungeonMaster, limit, offset}
+
+	if query != "" {
+		stmt = `SELECT je.*
+			FROM JournalEntry AS je, plainto_tsquery('english', $6) AS q
+			WHERE je.campaign_id = $1 AND ` + journalVisibleTo + `
+				AND to_tsvector('english', je.title || ' ' || je.body) @@ q
+			ORDER BY ts_rank(to_tsvector('english', je.title || ' ' || je.body), q) DESC, je.id DESC
+			LIMIT $4 OFFSET $5`
+		args = append(args, query)
+	}
+
+	rows, err := m.DB.Queryx(stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.JournalEntry
+		err = rows.StructScan(&entry)
+		if err != nil {
+			return nil, err
+		}
+		storedEntries = append(storedEntries, entry)
+	}
+
+	return &storedEntries, rows.Err()
+}
+
+// Update replaces the title, body, visibility and viewers of the
+// journal entry identified by `entry.ID` and records a new revision
+// edited by `editor`.
+func (m *JournalModel) Update(entry models.JournalEntry, editor string) error {
+	stmt := `UPDATE JournalEntry
+			SET title = $2, body = $3, visibility = $4, revision = revision + 1, updated_at = now()
+			WHERE id = $1
+			RETURNING revision`
+	stmtDeleteViewers := "DELETE FROM JournalEntryViewer WHERE entry_id = $1"
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowx(stmt, entry.ID, entry.Title, entry.Body, entry.Visibility).Scan(&entry.Revision)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNoRecord
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(stmtDeleteViewers, entry.ID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := writeJournalDetails(tx, entry, &editor); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes the journal entry identified by `id` and its history.
+func (m *JournalModel) Delete(id int) error {
+	stmt := "DELETE FROM JournalEntry WHERE id = $1"
+
+	res, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return models.ErrNoRecord
+	}
+	if count > 1 {
+		return models.ErrDeleteSingleRecord
+	}
+
+	return nil
+}
+
+// GetRevisions retrieves every revision of the journal entry identified
+// by `id`, newest first.
+func (m *JournalModel) GetRevisions(id int) (*[]models.JournalEntryRevision, error) {
+	storedRevisions := []models.JournalEntryRevision{}
+
+	stmt := `SELECT *
+			FROM JournalEntryRevision
+			WHERE entry_id = $1
+			ORDER BY revision DESC`
+
+	if err := m.DB.Select(&storedRevisions, stmt, id); err != nil {
+		return nil, err
+	}
+
+	return &storedRevisions, nil
+}
+
+// writeJournalDetails stores the viewers and the current revision of
+// `entry` as part of the transaction `tx`.
+func writeJournalDetails(tx *sqlx.Tx, entry models.JournalEntry, editor *string) error {
+	stmtViewers := `INSERT INTO JournalEntryViewer (entry_id, player_username)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT DO NOTHING`
+	stmtRevision := `INSERT INTO JournalEntryRevision (entry_id, revision, title, body, visibility, edited_by)
+		VALUES($1, $2, $3, $4, $5, $6)`
+
+	if entry.Visibility == models.JournalPlayers && len(entry.Viewers) > 0 {
+		if _, err := tx.Exec(stmtViewers, entry.ID, pq.Array(entry.Viewers)); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec(stmtRevision, entry.ID, entry.Revision, entry.Title, entry.Body, entry.Visibility, editor)
+	return err
+}
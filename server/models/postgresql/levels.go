@@ -0,0 +1,158 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type LevelModel struct {
+	DB *sqlx.DB
+}
+
+// LevelUp gains the level `l` for the character identified by
+// `l.CharacterID`, in the class `l.Class`, and records it in the level
+// history, granting the features of the new level and the feat
+// `l.Feat`. The character must be one level below `l.Level` and have the
+// experience for it, and the class one level below `l.ClassLevel`, or
+// models.ErrLevelChanged or models.ErrNoLevelAvailable is returned.
+// The character and the recorded level are returned as they end up.
+func (m *LevelModel) LevelUp(l models.LevelUp) (*models.Character, *models.LevelUp, error) {
+	stmtClass := `SELECT position, levels
+			FROM CharacterClass
+			WHERE character_id = $1 AND class = $2`
+	stmtUpdateClass := `UPDATE CharacterClass
+			SET levels = levels + 1, subclass = COALESCE($3, subclass)
+			WHERE character_id = $1 AND class = $2`
+	stmtInsertClass := `INSERT INTO CharacterClass (character_id, position, class, subclass, levels)
+		SELECT $1, max(position) + 1, $2, $3, 1
+		FROM CharacterClass
+		WHERE character_id = $1`
+	stmtCharacter := `UPDATE Character
+			SET level = $2, strength = $3, dexterity = $4, constitution = $5,
+				intelligence = $6, wisdom = $7, charisma = $8, hp_max = $9
+			WHERE id = $1`
+	stmtCombatants := `UPDATE Combatant
+			SET hp_max = $2
+			WHERE character_id = $1
+			AND combat_id IN (SELECT id FROM Combat WHERE ended_at IS NULL)`
+	stmtHistory := `INSERT INTO CharacterLevelHistory (character_id, level, class, class_level,
+			hit_points_method, hit_die_roll, hp_gained, ability_increases, feat, subclass)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, leveled_at`
+
+	increases, err := l.AbilityScoreIncreases()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := lockCharacterCombats(tx, l.CharacterID); err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	character, err := lockCharacter(tx, l.CharacterID)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	if character.Dead {
+		tx.Rollback()
+		return nil, nil, models.ErrCharacterDead
+	}
+	if character.Level != l.Level-1 {
+		tx.Rollback()
+		return nil, nil, models.ErrLevelChanged
+	}
+	if models.LevelForXP(character.XPPoints) < l.Level {
+		tx.Rollback()
+		return nil, nil, models.ErrNoLevelAvailable
+	}
+
+	var position, levels int
+	err = tx.QueryRowx(stmtClass, l.CharacterID, l.Class).Scan(&position, &levels)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	if levels != l.ClassLevel-1 {
+		tx.Rollback()
+		return nil, nil, models.ErrLevelChanged
+	}
+
+	// The first class takes up the new level through the trigger on
+	// Character, so only other classes are changed here
+	if levels == 0 {
+		_, err = tx.Exec(stmtInsertClass, l.CharacterID, l.Class, l.Subclass)
+	} else if position > 0 {
+		_, err = tx.Exec(stmtUpdateClass, l.CharacterID, l.Class, l.Subclass)
+	}
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, characterClassError(err)
+	}
+
+	character.ApplyLevelUp(&l, increases)
+
+	_, err = tx.Exec(stmtCharacter, character.ID, character.Level,
+		character.Strength, character.Dexterity, character.Constitution,
+		character.Intelligence, character.Wisdom, character.Charisma, character.HPMax)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, characterClassError(err)
+	}
+
+	if _, err := tx.Exec(stmtCombatants, character.ID, character.HPMax); err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	if err := saveCharacterVitals(tx, character); err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	if err := syncFeatures(tx, character.ID); err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	if l.Feat != nil {
+		if err := grantFeat(tx, character.ID, *l.Feat); err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+	}
+
+	err = tx.QueryRowx(stmtHistory, l.CharacterID, l.Level, l.Class, l.ClassLevel,
+		l.HitPointsMethod, l.HitDieRoll, l.HPGained, []byte(l.AbilityIncreases), l.Feat, l.Subclass,
+	).Scan(&l.ID, &l.LeveledAt)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+
+	return character, &l, tx.Commit()
+}
+
+// GetHistory retrieves the levels gained by the character identified by
+// `characterID`, in the order they were gained.
+func (m *LevelModel) GetHistory(characterID int) (*[]models.LevelUp, error) {
+	storedLevels := []models.LevelUp{}
+
+	stmt := `SELECT *
+			FROM CharacterLevelHistory
+			WHERE character_id = $1
+			ORDER BY leveled_at, id`
+
+	if err := m.DB.Select(&storedLevels, stmt, characterID); err != nil {
+		return nil, err
+	}
+
+	return &storedLevels, nil
+}
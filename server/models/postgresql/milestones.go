@@ -1,6 +1,18 @@
 package postgresql
 
-import "github.com/jmoiron/sqlx"
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// milestoneColumns selects every column of "CampaignMilestones" with the
+// real-world date formatted as YYYY-MM-DD.
+const milestoneColumns = `id, campaign_id, sequence, milestone, description, in_game_date,
+		to_char(occurred_on, 'YYYY-MM-DD') AS occurred_on, author_username, xp_reward,
+		created_at, updated_at`
 
 type MilestoneModel struct {
 	DB *sqlx.DB
@@ -9,22 +21,69 @@ type MilestoneModel struct {
 // Insert creates a new milestone corresponding with a campaign
 // identified by `campaignID`.
 func (m *MilestoneModel) Insert(campaignID int, milestone string) error {
-	stmt := "INSERT INTO CampaignMilestones (campaign_id, milestone) VALUES ($1, $2)"
+	_, err := m.Create(models.CampaignMilestone{CampaignID: campaignID, Milestone: milestone})
+	return err
+}
 
-	_, err := m.DB.Exec(stmt, campaignID, milestone)
+// Create adds `milestone` to the end of its campaign's milestones and
+// returns the ID of the new milestone.
+func (m *MilestoneModel) Create(milestone models.CampaignMilestone) (int, error) {
+	stmt := `INSERT INTO CampaignMilestones
+			(campaign_id, sequence, milestone, description, in_game_date, occurred_on, author_username, xp_reward)
+		SELECT $1, COALESCE(MAX(sequence), 0) + 1, $2, $3, $4, $5, $6, $7
+		FROM CampaignMilestones
+		WHERE campaign_id = $1
+		RETURNING id`
+
+	tx, err := m.DB.Beginx()
 	if err != nil {
-		return err
+		return -1, err
 	}
 
-	return nil
+	if err := lockMilestones(tx, milestone.CampaignID); err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	var id int
+	err = tx.QueryRow(
+		stmt, milestone.CampaignID, milestone.Milestone, milestone.Description,
+		milestone.InGameDate, milestone.OccurredOn, milestone.AuthorUsername, milestone.XPReward,
+	).Scan(&id)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	return id, tx.Commit()
+}
+
+// Get retrieves the milestone identified by `id`.
+func (m *MilestoneModel) Get(id int) (*models.CampaignMilestone, error) {
+	var storedMilestone models.CampaignMilestone
+
+	stmt := "SELECT " + milestoneColumns + " FROM CampaignMilestones WHERE id = $1"
+	row := m.DB.QueryRowx(stmt, id)
+
+	if err := row.StructScan(&storedMilestone); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return &storedMilestone, nil
 }
 
 // GetAllForCampaign retrieves all milestone belonging to a campaign
-// identified by `campaignID`.
-func (m *MilestoneModel) GetAllForCampaign(campaignID int) (*[]string, error) {
-	var storedMilestones []string
+// identified by `campaignID`, in order.
+func (m *MilestoneModel) GetAllForCampaign(campaignID int) (*[]models.CampaignMilestone, error) {
+	storedMilestones := []models.CampaignMilestone{}
 
-	stmt := "SELECT milestone FROM CampaignMilestones WHERE campaign_id = $1"
+	stmt := "SELECT " + milestoneColumns + ` FROM CampaignMilestones
+			WHERE campaign_id = $1
+			ORDER BY sequence`
 
 	rows, err := m.DB.Queryx(stmt, campaignID)
 	if err != nil {
@@ -32,8 +91,8 @@ func (m *MilestoneModel) GetAllForCampaign(campaignID int) (*[]string, error) {
 	}
 
 	for rows.Next() {
-		var milestone string
-		err = rows.Scan(&milestone)
+		var milestone models.CampaignMilestone
+		err = rows.StructScan(&milestone)
 		if err != nil {
 			return nil, err
 		}
@@ -42,3 +101,145 @@ func (m *MilestoneModel) GetAllForCampaign(campaignID int) (*[]string, error) {
 
 	return &storedMilestones, nil
 }
+
+// Update replaces the editable fields of the milestone identified by
+// `milestone.ID`. Its position and author are left unchanged.
+func (m *MilestoneModel) Update(milestone models.CampaignMilestone) error {
+	stmt := `UPDATE CampaignMilestones
+			SET milestone = $2, description = $3, in_game_date = $4, occurred_on = $5,
+				xp_reward = $6, updated_at = now()
+			WHERE id = $1`
+
+	res, err := m.DB.Exec(
+		stmt, milestone.ID, milestone.Milestone, milestone.Description,
+		milestone.InGameDate, milestone.OccurredOn, milestone.XPReward,
+	)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return models.ErrNoRecord
+	}
+	if count != 1 {
+		return models.ErrUpdateSingleRecord
+	}
+
+	return nil
+}
+
+// Delete removes the milestone identified by `id` and closes the gap it
+// leaves in its campaign's sequence.
+func (m *MilestoneModel) Delete(id int) error {
+	stmtCampaign := "SELECT campaign_id FROM CampaignMilestones WHERE id = $1"
+	stmtDelete := `DELETE FROM CampaignMilestones
+			WHERE id = $1
+			RETURNING campaign_id, sequence`
+	stmtShift := `UPDATE CampaignMilestones
+			SET sequence = sequence - 1
+			WHERE campaign_id = $1 AND sequence > $2`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var campaignID, sequence int
+	if err := tx.QueryRow(stmtCampaign, id).Scan(&campaignID); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNoRecord
+		}
+		return err
+	}
+
+	if err := lockMilestones(tx, campaignID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.QueryRow(stmtDelete, id).Scan(&campaignID, &sequence); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNoRecord
+		}
+		return err
+	}
+
+	if _, err := tx.Exec(stmtShift, campaignID, sequence); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Reorder renumbers the milestones of the campaign identified by
+// `campaignID` so that they follow the order of `milestoneIDs`, which
+// must list every milestone of the campaign exactly once.
+func (m *MilestoneModel) Reorder(campaignID int, milestoneIDs []int) error {
+	stmtSelect := "SELECT id FROM CampaignMilestones WHERE campaign_id = $1 FOR UPDATE"
+	stmtUpdate := "UPDATE CampaignMilestones SET sequence = $2 WHERE id = $1"
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	if err := lockMilestones(tx, campaignID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var storedIDs []int
+	if err := tx.Select(&storedIDs, stmtSelect, campaignID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	remaining := make(map[int]bool, len(storedIDs))
+	for _, id := range storedIDs {
+		remaining[id] = true
+	}
+	if len(milestoneIDs) != len(storedIDs) {
+		tx.Rollback()
+		return models.ErrInvalidMilestoneOrder
+	}
+	for _, id := range milestoneIDs {
+		if !remaining[id] {
+			tx.Rollback()
+			return models.ErrInvalidMilestoneOrder
+		}
+		delete(remaining, id)
+	}
+
+	for i, id := range milestoneIDs {
+		if _, err := tx.Exec(stmtUpdate, id, i+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// lockMilestones locks the campaign identified by `campaignID` for the
+// rest of the transaction `tx`, so that its milestones can be renumbered
+// without racing other changes to their sequence.
+func lockMilestones(tx *sqlx.Tx, campaignID int) error {
+	var id int
+
+	stmt := "SELECT id FROM Campaign WHERE id = $1 FOR UPDATE"
+	if err := tx.QueryRowx(stmt, campaignID).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNoRecord
+		}
+		return err
+	}
+
+	return nil
+}
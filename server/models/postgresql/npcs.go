@@ -0,0 +1,256 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type NPCModel struct {
+	DB *sqlx.DB
+}
+
+// Insert adds `npc` along with its actions and tags to its campaign and
+// returns the ID of the new NPC.
+func (m *NPCModel) Insert(npc models.CampaignNPC) (int, error) {
+	stmt := `INSERT INTO CampaignNPC (campaign_id, name, kind, size, creature_type, alignment,
+			description, armor_class, hp_max, hit_dice, speed, challenge_rating, strength,
+			dexterity, constitution, intelligence, wisdom, charisma, dm_notes, hidden, compendium_slug)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+		RETURNING id`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return -1, err
+	}
+
+	var id int
+	err = tx.QueryRowx(stmt, npc.CampaignID, npc.Name, npc.Kind, npc.Size, npc.CreatureType,
+		npc.Alignment, npc.Description, npc.ArmorClass, npc.HPMax, npc.HitDice, npc.Speed,
+		npc.ChallengeRating, npc.Strength, npc.Dexterity, npc.Constitution, npc.Intelligence,
+		npc.Wisdom, npc.Charisma, npc.DMNotes, npc.Hidden, npc.CompendiumSlug,
+	).Scan(&id)
+	if err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	npc.ID = id
+	if err := writeNPCDetails(tx, npc); err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	return id, tx.Commit()
+}
+
+// Get retrieves the NPC identified by `id` along with its actions and
+// tags.
+func (m *NPCModel) Get(id int) (*models.CampaignNPC, error) {
+	var storedNPC models.CampaignNPC
+
+	stmt := "SELECT * FROM CampaignNPC WHERE id = $1"
+	if err := m.DB.QueryRowx(stmt, id).StructScan(&storedNPC); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	npcs := []models.CampaignNPC{storedNPC}
+	if err := m.loadDetails(npcs); err != nil {
+		return nil, err
+	}
+
+	return &npcs[0], nil
+}
+
+// GetAllForCampaign retrieves the NPCs of the campaign identified by
+// `campaignID` in alphabetical order. Hidden NPCs are only included if
+// `includeHidden` is true. `kind` and `tag` filter the results when they
+// are not empty.
+func (m *NPCModel) GetAllForCampaign(campaignID int, includeHidden bool, kind models.NPCKindType, tag string) (*[]models.CampaignNPC, error) {
+	storedNPCs := []models.CampaignNPC{}
+
+	stmt := `SELECT *
+			FROM CampaignNPC AS n
+			WHERE n.campaign_id = $1
+				AND ($2 OR NOT n.hidden)
+				AND ($3 = '' OR n.kind::text = $3)
+				AND ($4 = '' OR EXISTS (
+					SELECT 1 FROM CampaignNPCTag t
+					WHERE t.npc_id = n.id AND t.tag = $4))
+			ORDER BY n.name, n.id`
+
+	if err := m.DB.Select(&storedNPCs, stmt, campaignID, includeHidden, string(kind), tag); err != nil {
+		return nil, err
+	}
+
+	if err := m.loadDetails(storedNPCs); err != nil {
+		return nil, err
+	}
+
+	return &storedNPCs, nil
+}
+
+// Update replaces every editable field of the NPC identified by
+// `npc.ID`, including its actions and tags.
+func (m *NPCModel) Update(npc models.CampaignNPC) error {
+	stmt := `UPDATE CampaignNPC
+			SET name = $2, kind = $3, size = $4, creature_type = $5, alignment = $6,
+				description = $7, armor_class = $8, hp_max = $9, hit_dice = $10, speed = $11,
+				challenge_rating = $12, strength = $13, dexterity = $14, constitution = $15,
+				intelligence = $16, wisdom = $17, charisma = $18, dm_notes = $19, hidden = $20,
+				updated_at = now()
+			WHERE id = $1`
+	stmtDeleteActions := "DELETE FROM CampaignNPCAction WHERE npc_id = $1"
+	stmtDeleteTags := "DELETE FROM CampaignNPCTag WHERE npc_id = $1"
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(stmt, npc.ID, npc.Name, npc.Kind, npc.Size, npc.CreatureType,
+		npc.Alignment, npc.Description, npc.ArmorClass, npc.HPMax, npc.HitDice, npc.Speed,
+		npc.ChallengeRating, npc.Strength, npc.Dexterity, npc.Constitution, npc.Intelligence,
+		npc.Wisdom, npc.Charisma, npc.DMNotes, npc.Hidden,
+	)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count != 1 {
+		tx.Rollback()
+		return models.ErrNoRecord
+	}
+
+	for _, stmt := range []string{stmtDeleteActions, stmtDeleteTags} {
+		if _, err := tx.Exec(stmt, npc.ID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := writeNPCDetails(tx, npc); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes the NPC identified by `id`.
+func (m *NPCModel) Delete(id int) error {
+	stmt := "DELETE FROM CampaignNPC WHERE id = $1"
+
+	res, err := m.DB.Exec(stmt, id)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return models.ErrNoRecord
+	}
+	if count > 1 {
+		return models.ErrDeleteSingleRecord
+	}
+
+	return nil
+}
+
+// loadDetails fills in the actions and tags of every NPC in `npcs`.
+func (m *NPCModel) loadDetails(npcs []models.CampaignNPC) error {
+	if len(npcs) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(npcs))
+	byID := make(map[int]*models.CampaignNPC, len(npcs))
+	for i := range npcs {
+		ids[i] = int64(npcs[i].ID)
+		npcs[i].Actions = []models.NPCAction{}
+		npcs[i].Tags = []string{}
+		byID[npcs[i].ID] = &npcs[i]
+	}
+
+	stmtActions := `SELECT npc_id, name, description
+			FROM CampaignNPCAction
+			WHERE npc_id = ANY($1)
+			ORDER BY npc_id, position`
+	rows, err := m.DB.Query(stmtActions, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var npcID int
+		var action models.NPCAction
+		if err := rows.Scan(&npcID, &action.Name, &action.Description); err != nil {
+			rows.Close()
+			return err
+		}
+		byID[npcID].Actions = append(byID[npcID].Actions, action)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	stmtTags := `SELECT npc_id, tag
+			FROM CampaignNPCTag
+			WHERE npc_id = ANY($1)
+			ORDER BY npc_id, tag`
+	rows, err = m.DB.Query(stmtTags, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var npcID int
+		var tag string
+		if err := rows.Scan(&npcID, &tag); err != nil {
+			return err
+		}
+		byID[npcID].Tags = append(byID[npcID].Tags, tag)
+	}
+
+	return rows.Err()
+}
+
+// writeNPCDetails stores the actions and tags of `npc` as part of the
+// transaction `tx`.
+func writeNPCDetails(tx *sqlx.Tx, npc models.CampaignNPC) error {
+	stmtAction := `INSERT INTO CampaignNPCAction (npc_id, position, name, description)
+		VALUES($1, $2, $3, $4)`
+	stmtTag := `INSERT INTO CampaignNPCTag (npc_id, tag)
+		VALUES($1, $2)
+		ON CONFLICT DO NOTHING`
+
+	for i, action := range npc.Actions {
+		if _, err := tx.Exec(stmtAction, npc.ID, i, action.Name, action.Description); err != nil {
+			return err
+		}
+	}
+
+	for _, tag := range npc.Tags {
+		if _, err := tx.Exec(stmtTag, npc.ID, tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type PersonalTokenModel struct {
+	DB *sqlx.DB
+}
+
+// Insert stores a new personal access token belonging to `username`.
+// Only the hash of the token is stored.
+func (m *PersonalTokenModel) Insert(username, name, tokenHash string, scopes []models.TokenScope) (int, error) {
+	stmt := `INSERT INTO PersonalAccessToken (player_username, name, token_hash, scopes)
+		VALUES($1, $2, $3, $4)
+		RETURNING id`
+
+	var createdTokenID int
+	err := m.DB.QueryRowx(
+		stmt, username, name, tokenHash, pq.Array(scopesToStrings(scopes)),
+	).Scan(&createdTokenID)
+	if err != nil {
+		var postgresError *pq.Error
+		if errors.As(err, &postgresError) {
+			if postgresError.Code.Name() == "unique_violation" {
+				return -1, models.ErrDuplicateTokenName
+			}
+		}
+		return -1, err
+	}
+
+	return createdTokenID, nil
+}
+
+// GetAllForPlayer retrieves all personal access tokens, including
+// revoked ones, which belong to `username`.
+func (m *PersonalTokenModel) GetAllForPlayer(username string) (*[]models.PersonalAccessToken, error) {
+	storedTokens := []models.PersonalAccessToken{}
+
+	stmt := `SELECT id, name, scopes, created_at, last_used_at, revoked_at
+			FROM PersonalAccessToken
+			WHERE player_username = $1
+			ORDER BY created_at`
+
+	rows, err := m.DB.Query(stmt, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t models.PersonalAccessToken
+		var scopes []string
+		err = rows.Scan(&t.ID, &t.Name, pq.Array(&scopes), &t.CreatedAt, &t.LastUsedAt, &t.RevokedAt)
+		if err != nil {
+			return nil, err
+		}
+		t.Scopes = stringsToScopes(scopes)
+		storedTokens = append(storedTokens, t)
+	}
+
+	return &storedTokens, rows.Err()
+}
+
+// Revoke marks the token identified by `id` and belonging to
+// `username` as revoked. Revoked tokens can no longer be used.
+func (m *PersonalTokenModel) Revoke(username string, id int) error {
+	stmt := `UPDATE PersonalAccessToken
+			SET revoked_at = now()
+			WHERE id = $1 AND player_username = $2 AND revoked_at IS NULL`
+
+	res, err := m.DB.Exec(stmt, id, username)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return models.ErrNoRecord
+	}
+
+	return nil
+}
+
+// Authenticate looks up an unrevoked token by its hash, records that it
+// has been used, and returns its owner and scopes.
+func (m *PersonalTokenModel) Authenticate(tokenHash string) (string, []models.TokenScope, error) {
+	var username string
+	var scopes []string
+
+	stmt := `UPDATE PersonalAccessToken
+			SET last_used_at = now()
+			WHERE token_hash = $1 AND revoked_at IS NULL
+			RETURNING player_username, scopes`
+
+	row := m.DB.QueryRow(stmt, tokenHash)
+	if err := row.Scan(&username, pq.Array(&scopes)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil, models.ErrTokenRevoked
+		} else {
+			return "", nil, err
+		}
+	}
+
+	return username, stringsToScopes(scopes), nil
+}
+
+func scopesToStrings(scopes []models.TokenScope) []string {
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = string(scope)
+	}
+	return s
+}
+
+func stringsToScopes(s []string) []models.TokenScope {
+	scopes := make([]models.TokenScope, len(s))
+	for i, scope := range s {
+		scopes[i] = models.TokenScope(scope)
+	}
+	return scopes
+}
@@ -45,10 +45,11 @@ func (m *PlayerModel) Insert(username, password, name string) error {
 func (m *PlayerModel) Authenticate(username, password string) (string, error) {
 	var storedUsername string
 	var hashedPassword []byte
+	var disabled bool
 
-	stmt := "SELECT username, password FROM Player WHERE username = $1"
+	stmt := "SELECT username, password, disabled FROM Player WHERE username = $1"
 	row := m.DB.QueryRow(stmt, username)
-	if err := row.Scan(&storedUsername, &hashedPassword); err != nil {
+	if err := row.Scan(&storedUsername, &hashedPassword, &disabled); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return "", models.ErrNoRecord
 		} else {
@@ -64,18 +65,23 @@ func (m *PlayerModel) Authenticate(username, password string) (string, error) {
 		}
 	}
 
+	if disabled {
+		return "", models.ErrAccountDisabled
+	}
+
 	return storedUsername, nil
 }
 
 // Get attempts to retrieve a player entity from the database with a
 // username equal to `username`. Does not return the player's password.
 func (m *PlayerModel) Get(username string) (*models.Player, error) {
-	var storedUsername string
-	var storedName string
+	var p models.Player
 
-	stmt := "SELECT username, name FROM Player WHERE username = $1"
-	row := m.DB.QueryRow(stmt, username)
-	if err := row.Scan(&storedUsername, &storedName); err != nil {
+	stmt := `SELECT id, username, name, avatar_url, bio, role, disabled, password_reset_required, totp_enabled
+			FROM Player
+			WHERE username = $1`
+	row := m.DB.QueryRowx(stmt, username)
+	if err := row.StructScan(&p); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return &models.Player{}, models.ErrNoRecord
 		} else {
@@ -83,12 +89,78 @@ func (m *PlayerModel) Get(username string) (*models.Player, error) {
 		}
 	}
 
-	p := &models.Player{
-		Username: storedUsername,
-		Name:     storedName,
+	return &p, nil
+}
+
+// Search retrieves players whose username or name contains `query`,
+// ordered by username. An empty query matches every player.
+func (m *PlayerModel) Search(query string, limit, offset int) (*[]models.Player, error) {
+	storedPlayers := []models.Player{}
+
+	stmt := `SELECT id, username, name, avatar_url, bio, role, disabled, password_reset_required, totp_enabled
+			FROM Player
+			WHERE username ILIKE '%' || $1 || '%' OR name ILIKE '%' || $1 || '%'
+			ORDER BY username
+			LIMIT $2 OFFSET $3`
+
+	rows, err := m.DB.Queryx(stmt, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.Player
+		err = rows.StructScan(&p)
+		if err != nil {
+			return nil, err
+		}
+		storedPlayers = append(storedPlayers, p)
 	}
 
-	return p, nil
+	return &storedPlayers, rows.Err()
+}
+
+// SetDisabled enables or disables the account of the player identified
+// by `username`. Disabled players cannot log in or use existing tokens.
+func (m *PlayerModel) SetDisabled(username string, disabled bool) error {
+	stmt := "UPDATE Player SET disabled = $2 WHERE username = $1"
+	return m.updateSingle(stmt, username, disabled)
+}
+
+// SetRole changes the role of the player identified by `username`.
+func (m *PlayerModel) SetRole(username string, role models.RoleType) error {
+	stmt := "UPDATE Player SET role = $2 WHERE username = $1"
+	return m.updateSingle(stmt, username, role)
+}
+
+// RequirePasswordReset forces the player identified by `username` to
+// change their password before they can use the rest of the API.
+func (m *PlayerModel) RequirePasswordReset(username string) error {
+	stmt := "UPDATE Player SET password_reset_required = true WHERE username = $1"
+	return m.updateSingle(stmt, username)
+}
+
+// updateSingle executes `stmt` and verifies that exactly one player
+// was updated.
+func (m *PlayerModel) updateSingle(stmt string, args ...interface{}) error {
+	res, err := m.DB.Exec(stmt, args...)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return models.ErrNoRecord
+	}
+	if count != 1 {
+		return models.ErrUpdateSingleRecord
+	}
+
+	return nil
 }
 
 // UpdatePassword attempts to update the password belonging to the
@@ -99,7 +171,9 @@ func (m *PlayerModel) UpdatePassword(username, newPassword string) error {
 		return err
 	}
 
-	stmt := "UPDATE Player SET password = $2 WHERE username = $1"
+	stmt := `UPDATE Player
+			SET password = $2, password_reset_required = false
+			WHERE username = $1`
 	res, err := m.DB.Exec(stmt, username, hashedPassword)
 	if err != nil {
 		return err
@@ -116,6 +190,37 @@ func (m *PlayerModel) UpdatePassword(username, newPassword string) error {
 	return nil
 }
 
+// UpdateProfile updates the display name, avatar and bio of the player
+// identified by `username`. Nil values are left unchanged.
+func (m *PlayerModel) UpdateProfile(username string, name, avatarURL, bio *string) error {
+	stmt := `UPDATE Player
+			SET name = COALESCE($2, name),
+				avatar_url = COALESCE($3, avatar_url),
+				bio = COALESCE($4, bio)
+			WHERE username = $1`
+	return m.updateSingle(stmt, username, name, avatarURL, bio)
+}
+
+// ChangeUsername renames the player identified by `username`. Foreign
+// keys referencing the player are declared with ON UPDATE CASCADE, so
+// characters, campaigns and tokens follow the player automatically.
+func (m *PlayerModel) ChangeUsername(username, newUsername string) error {
+	stmt := "UPDATE Player SET username = $2 WHERE username = $1"
+
+	err := m.updateSingle(stmt, username, newUsername)
+	if err != nil {
+		var postgresError *pq.Error
+		if errors.As(err, &postgresError) {
+			if postgresError.Code.Name() == "unique_violation" {
+				return models.ErrDuplicateUsername
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
 // Delete attempts to delete a player identified by `username`.
 func (m *PlayerModel) Delete(username string) error {
 	stmt := "DELETE FROM Player WHERE username = $1"
@@ -0,0 +1,60 @@
+package postgresql
+
+import (
+	"draco/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type ProficiencyModel struct {
+	DB *sqlx.DB
+}
+
+// Set makes the character identified by `p.CharacterID` proficient in
+// `p`, or updates its expertise if it already is.
+func (m *ProficiencyModel) Set(p models.CharacterProficiency) error {
+	stmt := `INSERT INTO CharacterProficiency (character_id, kind, name, expertise)
+		VALUES($1, $2, $3, $4)
+		ON CONFLICT (character_id, kind, name) DO UPDATE SET expertise = EXCLUDED.expertise`
+
+	_, err := m.DB.Exec(stmt, p.CharacterID, p.Kind, p.Name, p.Expertise)
+	return err
+}
+
+// GetAll retrieves the proficiencies of the character identified by
+// `characterID`, grouped by kind.
+func (m *ProficiencyModel) GetAll(characterID int) (*[]models.CharacterProficiency, error) {
+	storedProficiencies := []models.CharacterProficiency{}
+
+	stmt := `SELECT *
+			FROM CharacterProficiency
+			WHERE character_id = $1
+			ORDER BY kind, name`
+
+	if err := m.DB.Select(&storedProficiencies, stmt, characterID); err != nil {
+		return nil, err
+	}
+
+	return &storedProficiencies, nil
+}
+
+// Delete removes the proficiency of kind `kind` named `name` from the
+// character identified by `characterID`.
+func (m *ProficiencyModel) Delete(characterID int, kind models.ProficiencyKindType, name string) error {
+	stmt := "DELETE FROM CharacterProficiency WHERE character_id = $1 AND kind = $2 AND name = $3"
+
+	res, err := m.DB.Exec(stmt, characterID, kind, name)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return models.ErrNoRecord
+	}
+
+	return nil
+}
@@ -0,0 +1,272 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type RegistryModel struct {
+	DB *sqlx.DB
+}
+
+// selectRegistryEntries selects registry entries along with the name of
+// the class of each subclass.
+const selectRegistryEntries = `SELECT e.*, c.name AS class_name
+		FROM RegistryEntry AS e
+		LEFT JOIN RegistryEntry AS c
+		ON c.id = e.class_id`
+
+// visibleRegistryEntries restricts registry entries to the official ones
+// and the homebrew which the player `$1` owns or whose campaign they take
+// part in.
+const visibleRegistryEntries = `((e.owner_username IS NULL AND e.campaign_id IS NULL)
+			OR e.owner_username = $1
+			OR e.campaign_id IN (SELECT id FROM Campaign WHERE dungeon_master = $1)
+			OR e.campaign_id IN (
+				SELECT bt.campaign_id
+				FROM BelongsTo AS bt
+				INNER JOIN Character AS ch
+				ON ch.id = bt.character_id
+				WHERE ch.player_username = $1))`
+
+// Insert saves the homebrew entry `entry` and returns its ID. Homebrew
+// may not reuse the name of an official entry, nor of another entry of
+// the same owner or campaign.
+func (m *RegistryModel) Insert(entry models.RegistryEntry) (int, error) {
+	stmt := `INSERT INTO RegistryEntry (kind, name, description, class_id, hit_die,
+			ability_bonuses, ability_bonus_choices, owner_username, campaign_id)
+		VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return -1, err
+	}
+
+	if err := checkRegistryName(tx, entry); err != nil {
+		tx.Rollback()
+		return -1, err
+	}
+
+	var id int
+	err = tx.QueryRowx(stmt, entry.Kind, entry.Name, entry.Description, entry.ClassID, entry.HitDie,
+		[]byte(entry.AbilityBonuses), entry.AbilityBonusChoices, entry.OwnerUsername, entry.CampaignID).Scan(&id)
+	if err != nil {
+		tx.Rollback()
+		return -1, registryError(err)
+	}
+
+	return id, tx.Commit()
+}
+
+// Get retrieves the registry entry identified by `id`.
+func (m *RegistryModel) Get(id int) (*models.RegistryEntry, error) {
+	var storedEntry models.RegistryEntry
+
+	stmt := selectRegistryEntries + " WHERE e.id = $1"
+	if err := m.DB.QueryRowx(stmt, id).StructScan(&storedEntry); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return &storedEntry, nil
+}
+
+// GetAllVisible retrieves the entries of kind `kind` which `username` may
+// choose for a character, official entries first.
+func (m *RegistryModel) GetAllVisible(username string, kind models.RegistryKindType) (*[]models.RegistryEntry, error) {
+	storedEntries := []models.RegistryEntry{}
+
+	stmt := selectRegistryEntries + `
+			WHERE e.kind = $2
+			AND ` + visibleRegistryEntries + `
+			ORDER BY (e.owner_username IS NOT NULL OR e.campaign_id IS NOT NULL), c.name, e.name, e.id`
+
+	if err := m.DB.Select(&storedEntries, stmt, username, kind); err != nil {
+		return nil, err
+	}
+
+	return &storedEntries, nil
+}
+
+// FindVisible retrieves the entry of kind `kind` named `name`, ignoring
+// case, which `username` may choose for a character. Subclasses are
+// only looked up within the class identified by `classID` if it is set.
+func (m *RegistryModel) FindVisible(username string, kind models.RegistryKindType, name string, classID *int) (*models.RegistryEntry, error) {
+	var storedEntry models.RegistryEntry
+
+	stmt := selectRegistryEntries + `
+			WHERE e.kind = $2
+			AND lower(e.name) = lower($3)
+			AND ($4::int IS NULL OR e.class_id = $4)
+			AND ` + visibleRegistryEntries + `
+			ORDER BY (e.owner_username IS NOT NULL OR e.campaign_id IS NOT NULL), e.id
+			LIMIT 1`
+
+	if err := m.DB.QueryRowx(stmt, username, kind, name, classID).StructScan(&storedEntry); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return &storedEntry, nil
+}
+
+// Update replaces the name, description, hit die and ability bonuses of
+// the homebrew entry identified by `entry.ID`. An entry cannot be
+// renamed while a character uses it.
+func (m *RegistryModel) Update(entry models.RegistryEntry) error {
+	stmt := `UPDATE RegistryEntry
+			SET name = $2, description = $3, hit_die = $4,
+				ability_bonuses = $5, ability_bonus_choices = $6, updated_at = now()
+			WHERE id = $1`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var stored models.RegistryEntry
+	if err := tx.QueryRowx(selectRegistryEntries+" WHERE e.id = $1 FOR UPDATE OF e", entry.ID).StructScan(&stored); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNoRecord
+		}
+		return err
+	}
+
+	if stored.Name != entry.Name {
+		inUse, err := isRegistryEntryInUse(tx, stored)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if inUse {
+			tx.Rollback()
+			return models.ErrRegistryEntryInUse
+		}
+
+		if err := checkRegistryName(tx, entry); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	_, err = tx.Exec(stmt, entry.ID, entry.Name, entry.Description, entry.HitDie,
+		[]byte(entry.AbilityBonuses), entry.AbilityBonusChoices)
+	if err != nil {
+		tx.Rollback()
+		return registryError(err)
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes the homebrew entry identified by `id`, along with the
+// subclasses of a class, unless a character uses it.
+func (m *RegistryModel) Delete(id int) error {
+	stmt := "DELETE FROM RegistryEntry WHERE id = $1"
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var stored models.RegistryEntry
+	if err := tx.QueryRowx(selectRegistryEntries+" WHERE e.id = $1 FOR UPDATE OF e", id).StructScan(&stored); err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.ErrNoRecord
+		}
+		return err
+	}
+
+	inUse, err := isRegistryEntryInUse(tx, stored)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if inUse {
+		tx.Rollback()
+		return models.ErrRegistryEntryInUse
+	}
+
+	if _, err := tx.Exec(stmt, id); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkRegistryName returns models.ErrDuplicateRegistryEntry if an
+// official entry, or an entry of the same owner or campaign, already has
+// the name of `entry`.
+func checkRegistryName(tx *sqlx.Tx, entry models.RegistryEntry) error {
+	stmt := `SELECT EXISTS (
+			SELECT 1
+			FROM RegistryEntry
+			WHERE kind = $1
+			AND lower(name) = lower($2)
+			AND COALESCE(class_id, 0) = COALESCE($3::int, 0)
+			AND id <> $4
+			AND ((owner_username IS NULL AND campaign_id IS NULL)
+				OR owner_username = $5
+				OR campaign_id = $6))`
+
+	var exists bool
+	err := tx.QueryRowx(stmt, entry.Kind, entry.Name, entry.ClassID, entry.ID,
+		entry.OwnerUsername, entry.CampaignID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return models.ErrDuplicateRegistryEntry
+	}
+
+	return nil
+}
+
+// isRegistryEntryInUse reports whether a character which can see the
+// homebrew entry `entry` has chosen it, as its race or as any of its
+// classes and subclasses: for player homebrew, one of the owner's
+// characters, and for campaign homebrew, a character of the campaign.
+func isRegistryEntryInUse(tx *sqlx.Tx, entry models.RegistryEntry) (bool, error) {
+	stmt := `SELECT EXISTS (
+			SELECT 1
+			FROM Character AS ch
+			WHERE (ch.player_username = $1
+				OR ch.id IN (SELECT character_id FROM BelongsTo WHERE campaign_id = $2))
+			AND CASE $3::e_registry_kind
+				WHEN 'race' THEN ch.race = $4
+				WHEN 'class' THEN ch.class = $4 OR EXISTS (
+					SELECT 1 FROM CharacterClass AS cc
+					WHERE cc.character_id = ch.id AND cc.class = $4)
+				ELSE (ch.class_attribute = $4 AND ch.class = $5) OR EXISTS (
+					SELECT 1 FROM CharacterClass AS cc
+					WHERE cc.character_id = ch.id AND cc.subclass = $4 AND cc.class = $5)
+			END)`
+
+	var inUse bool
+	err := tx.QueryRowx(stmt, entry.OwnerUsername, entry.CampaignID, entry.Kind, entry.Name, entry.ClassName).Scan(&inUse)
+	return inUse, err
+}
+
+// registryError converts a unique violation of a registry entry's name
+// into models.ErrDuplicateRegistryEntry.
+func registryError(err error) error {
+	var postgresError *pq.Error
+	if errors.As(err, &postgresError) && postgresError.Code.Name() == "unique_violation" {
+		return models.ErrDuplicateRegistryEntry
+	}
+	return err
+}
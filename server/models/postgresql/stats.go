@@ -29,3 +29,28 @@ func (m *StatsModel) GetAll() (*models.Stats, error) {
 
 	return &storedStats, nil
 }
+
+// Retrieve global statistics along with statistics which are only
+// visible to administrators.
+func (m *StatsModel) GetAdmin() (*models.AdminStats, error) {
+	var storedStats models.AdminStats
+
+	stmt := `SELECT s.*,
+				(SELECT COUNT(*) FROM Player WHERE role = 'admin') AS num_admins,
+				(SELECT COUNT(*) FROM Player WHERE disabled) AS num_disabled_players,
+				(SELECT COUNT(*) FROM Player WHERE totp_enabled) AS num_two_factor_players,
+				(SELECT COUNT(*) FROM Campaign WHERE dungeon_master IS NULL) AS num_orphaned_campaigns,
+				(SELECT COUNT(*) FROM PersonalAccessToken WHERE revoked_at IS NULL) AS num_active_personal_tokens
+			FROM Stats s`
+	row := m.DB.QueryRowx(stmt)
+
+	if err := row.StructScan(&storedStats); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, models.ErrNoRecord
+		} else {
+			return nil, err
+		}
+	}
+
+	return &storedStats, nil
+}
@@ -0,0 +1,281 @@
+package postgresql
+
+import (
+	"database/sql"
+	"draco/models"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxChallengeAttempts is the number of incorrect codes which may be
+// submitted against a single login challenge before it is rejected.
+const maxChallengeAttempts = 5
+
+type TwoFactorModel struct {
+	DB *sqlx.DB
+}
+
+// GetSecret retrieves the TOTP secret stored for the player identified
+// by `username`, and whether two-factor authentication has been
+// confirmed. The secret is empty if enrolment has not been started.
+func (m *TwoFactorModel) GetSecret(username string) (string, bool, error) {
+	var secret sql.NullString
+	var enabled bool
+
+	stmt := "SELECT totp_secret, totp_enabled FROM Player WHERE username = $1"
+	row := m.DB.QueryRow(stmt, username)
+	if err := row.Scan(&secret, &enabled); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, models.ErrNoRecord
+		} else {
+			return "", false, err
+		}
+	}
+
+	return secret.String, enabled, nil
+}
+
+// SetPendingSecret stores `secret` for the player identified by
+// `username`. The secret is not used for logins until enrolment has
+// been confirmed with Enable.
+func (m *TwoFactorModel) SetPendingSecret(username, secret string) error {
+	stmt := `UPDATE Player
+			SET totp_secret = $2, totp_last_counter = 0
+			WHERE username = $1 AND totp_enabled = false`
+
+	res, err := m.DB.Exec(stmt, username, secret)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return models.ErrTwoFactorAlreadyEnabled
+	}
+
+	return nil
+}
+
+// Enable confirms two-factor enrolment for the player identified by
+// `username` and replaces any existing recovery codes with hashed
+// copies of `recoveryCodes`.
+func (m *TwoFactorModel) Enable(username string, recoveryCodes []string) error {
+	stmtEnable := `UPDATE Player
+			SET totp_enabled = true
+			WHERE username = $1 AND totp_secret IS NOT NULL`
+	stmtDeleteCodes := "DELETE FROM PlayerRecoveryCode WHERE player_username = $1"
+	stmtInsertCode := `INSERT INTO PlayerRecoveryCode (player_username, code_hash)
+		VALUES($1, $2)`
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(stmtEnable, username)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if count != 1 {
+		tx.Rollback()
+		return models.ErrUpdateSingleRecord
+	}
+
+	if _, err := tx.Exec(stmtDeleteCodes, username); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, code := range recoveryCodes {
+		hashedCode, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if _, err := tx.Exec(stmtInsertCode, username, string(hashedCode)); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Disable removes the TOTP secret, recovery codes and any outstanding
+// login challenges belonging to the player identified by `username`.
+func (m *TwoFactorModel) Disable(username string) error {
+	stmtDisable := `UPDATE Player
+			SET totp_secret = NULL, totp_enabled = false, totp_last_counter = 0
+			WHERE username = $1`
+	stmtDeleteCodes := "DELETE FROM PlayerRecoveryCode WHERE player_username = $1"
+	stmtDeleteChallenges := "DELETE FROM LoginChallenge WHERE player_username = $1"
+
+	tx, err := m.DB.Beginx()
+	if err != nil {
+		return err
+	}
+
+	for _, stmt := range []string{stmtDisable, stmtDeleteCodes, stmtDeleteChallenges} {
+		if _, err := tx.Exec(stmt, username); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ClaimCounter records that the TOTP code for time step `counter` has
+// been used by the player identified by `username`. Codes for the same
+// or an earlier time step are rejected so that a code cannot be
+// replayed.
+func (m *TwoFactorModel) ClaimCounter(username string, counter int64) error {
+	stmt := `UPDATE Player
+			SET totp_last_counter = $2
+			WHERE username = $1 AND totp_last_counter < $2`
+
+	res, err := m.DB.Exec(stmt, username, counter)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return models.ErrTOTPCodeReused
+	}
+
+	return nil
+}
+
+// UseRecoveryCode verifies `code` against the unused recovery codes
+// belonging to the player identified by `username`. A matching code is
+// marked as used.
+func (m *TwoFactorModel) UseRecoveryCode(username, code string) error {
+	stmt := `SELECT id, code_hash
+			FROM PlayerRecoveryCode
+			WHERE player_username = $1 AND used_at IS NULL`
+
+	rows, err := m.DB.Queryx(stmt, username)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	matchedID := -1
+	for rows.Next() {
+		var id int
+		var hashedCode []byte
+		if err := rows.Scan(&id, &hashedCode); err != nil {
+			return err
+		}
+
+		if bcrypt.CompareHashAndPassword(hashedCode, []byte(code)) == nil {
+			matchedID = id
+			break
+		}
+	}
+	rows.Close()
+
+	if matchedID == -1 {
+		return models.ErrInvalidRecoveryCode
+	}
+
+	stmtUse := "UPDATE PlayerRecoveryCode SET used_at = now() WHERE id = $1 AND used_at IS NULL"
+	res, err := m.DB.Exec(stmtUse, matchedID)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if count != 1 {
+		return models.ErrInvalidRecoveryCode
+	}
+
+	return nil
+}
+
+// CountUnusedRecoveryCodes returns the number of recovery codes which
+// the player identified by `username` has not yet used.
+func (m *TwoFactorModel) CountUnusedRecoveryCodes(username string) (int, error) {
+	var count int
+
+	stmt := `SELECT COUNT(*)
+			FROM PlayerRecoveryCode
+			WHERE player_username = $1 AND used_at IS NULL`
+	if err := m.DB.QueryRow(stmt, username).Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// CreateChallenge stores a login challenge for the player identified
+// by `username`. Only the hash of the challenge is stored.
+func (m *TwoFactorModel) CreateChallenge(username, challengeHash string, expiresAt time.Time) error {
+	stmt := `INSERT INTO LoginChallenge (challenge_hash, player_username, expires_at)
+		VALUES($1, $2, $3)`
+
+	_, err := m.DB.Exec(stmt, challengeHash, username, expiresAt)
+	return err
+}
+
+// GetChallenge returns the username of the player that a login
+// challenge identified by `challengeHash` was issued to, provided that
+// the challenge has neither expired nor been guessed against too often.
+func (m *TwoFactorModel) GetChallenge(challengeHash string) (string, error) {
+	var username string
+
+	stmt := `SELECT player_username
+			FROM LoginChallenge
+			WHERE challenge_hash = $1 AND expires_at > now() AND failed_attempts < $2`
+	row := m.DB.QueryRow(stmt, challengeHash, maxChallengeAttempts)
+	if err := row.Scan(&username); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", models.ErrChallengeExpired
+		} else {
+			return "", err
+		}
+	}
+
+	return username, nil
+}
+
+// RecordFailedAttempt increments the number of incorrect codes which
+// were submitted against the challenge identified by `challengeHash`.
+func (m *TwoFactorModel) RecordFailedAttempt(challengeHash string) error {
+	stmt := `UPDATE LoginChallenge
+			SET failed_attempts = failed_attempts + 1
+			WHERE challenge_hash = $1`
+
+	_, err := m.DB.Exec(stmt, challengeHash)
+	return err
+}
+
+// DeleteChallenge removes the challenge identified by `challengeHash`,
+// along with any expired challenges.
+func (m *TwoFactorModel) DeleteChallenge(challengeHash string) error {
+	stmt := "DELETE FROM LoginChallenge WHERE challenge_hash = $1 OR expires_at <= now()"
+
+	_, err := m.DB.Exec(stmt, challengeHash)
+	return err
+}
@@ -0,0 +1,96 @@
+package models
+
+import "testing"
+
+func TestMeetsMulticlassPrerequisites(t *testing.T) {
+	tests := []struct {
+		name      string
+		class     ClassType
+		character Character
+		want      bool
+	}{
+		{"single ability met", Wizard, Character{Intelligence: 13}, true},
+		{"single ability missed", Wizard, Character{Intelligence: 12, Wisdom: 18}, false},
+		{"either ability, first", Fighter, Character{Strength: 13}, true},
+		{"either ability, second", Fighter, Character{Dexterity: 15}, true},
+		{"either ability, neither", Fighter, Character{Strength: 12, Dexterity: 12}, false},
+		{"both abilities", Monk, Character{Dexterity: 13, Wisdom: 13}, true},
+		{"both abilities, one missed", Monk, Character{Dexterity: 18, Wisdom: 12}, false},
+		{"both abilities, other missed", Paladin, Character{Strength: 12, Charisma: 18}, false},
+		{"homebrew class", ClassType("Blood Hunter"), Character{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MeetsMulticlassPrerequisites(&tt.character, tt.class); got != tt.want {
+				t.Errorf("MeetsMulticlassPrerequisites(%+v, %q) = %v, want %v", tt.character, tt.class, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateSpellSlots(t *testing.T) {
+	eldritchKnight, arcaneTrickster := FighterEldritchKnight, RogueArcaneTrickster
+	class := func(class ClassType, levels int) CharacterClass {
+		return CharacterClass{Class: class, Levels: levels}
+	}
+	subclass := func(class ClassType, subclass *ClassAttributeType, levels int) CharacterClass {
+		return CharacterClass{Class: class, Subclass: subclass, Levels: levels}
+	}
+
+	tests := []struct {
+		name          string
+		classes       []CharacterClass
+		casterLevel   int
+		slots         []int
+		pactSlots     int
+		pactSlotLevel int
+	}{
+		{"no class", nil, 0, nil, 0, 0},
+		{"not a caster", []CharacterClass{class(Barbarian, 20)}, 0, nil, 0, 0},
+		{"full caster", []CharacterClass{class(Wizard, 5)}, 5, []int{4, 3, 2}, 0, 0},
+		{"full caster at 20", []CharacterClass{class(Cleric, 20)}, 20, []int{4, 3, 3, 3, 3, 2, 2, 1, 1}, 0, 0},
+		{"half caster at 1", []CharacterClass{class(Paladin, 1)}, 0, nil, 0, 0},
+		{"half caster at 2", []CharacterClass{class(Paladin, 2)}, 1, []int{2}, 0, 0},
+		{"half caster rounds up", []CharacterClass{class(Ranger, 5)}, 3, []int{4, 2}, 0, 0},
+		{"third caster at 2", []CharacterClass{subclass(Fighter, &eldritchKnight, 2)}, 0, nil, 0, 0},
+		{"third caster at 3", []CharacterClass{subclass(Fighter, &eldritchKnight, 3)}, 1, []int{2}, 0, 0},
+		{"third caster rounds up", []CharacterClass{subclass(Rogue, &arcaneTrickster, 7)}, 3, []int{4, 2}, 0, 0},
+		{"fighter without a casting subclass", []CharacterClass{class(Fighter, 7)}, 0, nil, 0, 0},
+		{"full casters add up", []CharacterClass{class(Wizard, 3), class(Cleric, 2)}, 5, []int{4, 3, 2}, 0, 0},
+		{"multiclass half caster rounds down", []CharacterClass{class(Paladin, 3), class(Sorcerer, 3)}, 4, []int{4, 3}, 0, 0},
+		{"multiclass half caster at 1", []CharacterClass{class(Ranger, 1), class(Wizard, 1)}, 1, []int{2}, 0, 0},
+		{"multiclass third caster rounds down",
+			[]CharacterClass{subclass(Rogue, &arcaneTrickster, 5), class(Bard, 2)}, 3, []int{4, 2}, 0, 0},
+		{"one caster among other classes", []CharacterClass{class(Fighter, 5), class(Wizard, 1)}, 1, []int{2}, 0, 0},
+		{"caster level capped at 20", []CharacterClass{class(Wizard, 15), class(Druid, 10)}, 20, []int{4, 3, 3, 3, 3, 2, 2, 1, 1}, 0, 0},
+		{"pact magic", []CharacterClass{class(Warlock, 5)}, 0, nil, 2, 3},
+		{"pact magic at 20", []CharacterClass{class(Warlock, 20)}, 0, nil, 4, 5},
+		{"pact magic kept apart", []CharacterClass{class(Warlock, 2), class(Wizard, 3)}, 3, []int{4, 2}, 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CalculateSpellSlots(tt.classes)
+			want := make([]int, 9)
+			copy(want, tt.slots)
+
+			if got.CasterLevel != tt.casterLevel {
+				t.Errorf("caster level = %d, want %d", got.CasterLevel, tt.casterLevel)
+			}
+			if len(got.Slots) != len(want) {
+				t.Fatalf("slots = %v, want %v", got.Slots, want)
+			}
+			for i := range want {
+				if got.Slots[i] != want[i] {
+					t.Errorf("slots = %v, want %v", got.Slots, want)
+					break
+				}
+			}
+			if got.PactSlots != tt.pactSlots || got.PactSlotLevel != tt.pactSlotLevel {
+				t.Errorf("pact slots = %d of level %d, want %d of level %d",
+					got.PactSlots, got.PactSlotLevel, tt.pactSlots, tt.pactSlotLevel)
+			}
+		})
+	}
+}
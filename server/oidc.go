@@ -0,0 +1,358 @@
+package main
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// oidcStateTTL is how long a player has to complete a single sign-on
+// login at the identity provider.
+const oidcStateTTL = 10 * time.Minute
+
+var (
+	errOIDCDiscovery    = errors.New("oidc: could not load provider configuration")
+	errOIDCTokenRequest = errors.New("oidc: token request failed")
+	errOIDCInvalidToken = errors.New("oidc: invalid ID token")
+	errOIDCUnknownKey   = errors.New("oidc: ID token signed with an unknown key")
+)
+
+// oidcProvider implements the parts of OpenID Connect needed for an
+// authorization code login with PKCE against a single provider.
+type oidcProvider struct {
+	issuer        string
+	clientID      string
+	clientSecret  string
+	redirectURL   string
+	scopes        []string
+	autoProvision bool
+	client        *http.Client
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+}
+
+// oidcDiscovery holds the fields used from the provider's
+// `/.well-known/openid-configuration` document.
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// oidcClaims holds the verified claims of an ID token which are used to
+// identify and provision players.
+type oidcClaims struct {
+	Issuer            string
+	Subject           string
+	PreferredUsername string
+	Email             string
+	Name              string
+}
+
+func newOIDCProvider(cfg *Config) *oidcProvider {
+	scopes := cfg.OIDC.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+
+	return &oidcProvider{
+		issuer:        strings.TrimSuffix(cfg.OIDC.Issuer, "/"),
+		clientID:      cfg.OIDC.ClientID,
+		clientSecret:  cfg.OIDC.ClientSecret,
+		redirectURL:   cfg.OIDC.RedirectURL,
+		scopes:        scopes,
+		autoProvision: cfg.OIDC.AutoProvision,
+		client:        &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// getDiscovery fetches and caches the provider configuration.
+func (p *oidcProvider) getDiscovery() (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var d oidcDiscovery
+	if err := p.getJSON(p.issuer+"/.well-known/openid-configuration", &d); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(d.Issuer, "/") != p.issuer || d.AuthorizationEndpoint == "" ||
+		d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errOIDCDiscovery
+	}
+
+	p.discovery = &d
+	return p.discovery, nil
+}
+
+func (p *oidcProvider) getJSON(uri string, v interface{}) error {
+	resp, err := p.client.Get(uri)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned %s", errOIDCDiscovery, uri, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// pkceChallenge derives the S256 code challenge for `verifier`.
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// authorizationURL returns the URL the player must visit to log in at
+// the identity provider.
+func (p *oidcProvider) authorizationURL(state, nonce, codeVerifier string) (string, error) {
+	d, err := p.getDiscovery()
+	if err != nil {
+		return "", err
+	}
+
+	v := url.Values{}
+	v.Set("response_type", "code")
+	v.Set("client_id", p.clientID)
+	v.Set("redirect_uri", p.redirectURL)
+	v.Set("scope", strings.Join(p.scopes, " "))
+	v.Set("state", state)
+	v.Set("nonce", nonce)
+	v.Set("code_challenge", pkceChallenge(codeVerifier))
+	v.Set("code_challenge_method", "S256")
+
+	sep := "?"
+	if strings.Contains(d.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return d.AuthorizationEndpoint + sep + v.Encode(), nil
+}
+
+// exchangeCode redeems an authorization code at the token endpoint and
+// returns the verified claims of the resulting ID token.
+func (p *oidcProvider) exchangeCode(code, codeVerifier, nonce string) (*oidcClaims, error) {
+	d, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.redirectURL)
+	form.Set("client_id", p.clientID)
+	form.Set("code_verifier", codeVerifier)
+
+	req, err := http.NewRequest(http.MethodPost, d.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.clientID), url.QueryEscape(p.clientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", errOIDCTokenRequest, resp.Status)
+	}
+
+	tokenResp := struct {
+		IDToken string `json:"id_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, err
+	}
+	if tokenResp.IDToken == "" {
+		return nil, fmt.Errorf("%w: response did not contain an ID token", errOIDCTokenRequest)
+	}
+
+	return p.verifyIDToken(tokenResp.IDToken, nonce)
+}
+
+// verifyIDToken checks the signature, issuer, audience, expiry and
+// nonce of `rawToken`.
+func (p *oidcProvider) verifyIDToken(rawToken, nonce string) (*oidcClaims, error) {
+	token, err := jwt.Parse(rawToken, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("%w: unexpected signing method %v", errOIDCInvalidToken, t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return p.publicKey(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errOIDCInvalidToken
+	}
+
+	// Expiry is verified by the parser, but the provider must set it.
+	if _, ok := claims["exp"]; !ok {
+		return nil, fmt.Errorf("%w: missing expiry", errOIDCInvalidToken)
+	}
+
+	iss, _ := claims["iss"].(string)
+	if strings.TrimSuffix(iss, "/") != p.issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", errOIDCInvalidToken, iss)
+	}
+
+	if !audienceContains(claims["aud"], p.clientID) {
+		return nil, fmt.Errorf("%w: unexpected audience", errOIDCInvalidToken)
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", errOIDCInvalidToken)
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("%w: missing subject", errOIDCInvalidToken)
+	}
+
+	c := &oidcClaims{Issuer: p.issuer, Subject: sub}
+	c.PreferredUsername, _ = claims["preferred_username"].(string)
+	c.Email, _ = claims["email"].(string)
+	c.Name, _ = claims["name"].(string)
+	return c, nil
+}
+
+// audienceContains reports whether the `aud` claim, which may be a
+// string or an array of strings, contains `clientID`.
+func audienceContains(aud interface{}, clientID string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == clientID
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// publicKey returns the provider's signing key identified by `kid`. The
+// key set is refetched when an unknown key is requested so that key
+// rotation at the provider is picked up.
+func (p *oidcProvider) publicKey(kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	d, err := p.getDiscovery()
+	if err != nil {
+		return nil, err
+	}
+
+	jwks := struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}{}
+	if err := p.getJSON(d.JWKSURI, &jwks); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	key, ok = keys[kid]
+	if !ok {
+		// Providers with a single key may omit `kid` from the token.
+		if kid == "" && len(keys) == 1 {
+			for _, k := range keys {
+				return k, nil
+			}
+		}
+		return nil, errOIDCUnknownKey
+	}
+	return key, nil
+}
+
+// provisionUsername derives a candidate username for a new player from
+// the claims of their ID token.
+func provisionUsername(c *oidcClaims) string {
+	candidate := c.PreferredUsername
+	if candidate == "" && c.Email != "" {
+		candidate = strings.SplitN(c.Email, "@", 2)[0]
+	}
+	if candidate == "" {
+		candidate = "player"
+	}
+
+	var b strings.Builder
+	for _, r := range candidate {
+		isLetter := (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+		isDigit := r >= '0' && r <= '9'
+		if isLetter || isDigit || r == '.' || r == '-' || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+
+	username := b.String()
+	if username == "" {
+		username = "player"
+	}
+	// Leave room for a numeric suffix should the name be taken.
+	if len(username) > 20 {
+		username = username[:20]
+	}
+	return username
+}
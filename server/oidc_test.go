@@ -0,0 +1,579 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"draco/models"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	testClientID     = "draco"
+	testCode         = "authorization-code"
+	testCodeVerifier = "code-verifier"
+	testNonce        = "nonce"
+	testKeyID        = "key-1"
+)
+
+// mockIdentityProvider is an OpenID Connect provider serving discovery,
+// a key set and a token endpoint which redeems `testCode` for `claims`
+// signed by `key`.
+type mockIdentityProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+	sign   func(claims jwt.MapClaims) string
+
+	// codeChallenge is the PKCE challenge the code was issued for.
+	codeChallenge string
+}
+
+func newMockIdentityProvider(t *testing.T) *mockIdentityProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &mockIdentityProvider{key: key, codeChallenge: pkceChallenge(testCodeVerifier)}
+	idp.sign = func(claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = testKeyID
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                idp.server.URL,
+			AuthorizationEndpoint: idp.server.URL + "/authorize",
+			TokenEndpoint:         idp.server.URL + "/token",
+			JWKSURI:               idp.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		e := big.NewInt(int64(key.PublicKey.E)).Bytes()
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": testKeyID,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(e),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil ||
+			r.PostForm.Get("grant_type") != "authorization_code" ||
+			r.PostForm.Get("code") != testCode ||
+			pkceChallenge(r.PostForm.Get("code_verifier")) != idp.codeChallenge ||
+			r.PostForm.Get("client_id") != testClientID {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idp.sign(idp.claims)})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// validClaims returns the claims of an ID token a client of `idp`
+// accepts.
+func (idp *mockIdentityProvider) validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":                idp.server.URL,
+		"aud":                testClientID,
+		"sub":                "subject-1",
+		"nonce":              testNonce,
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"preferred_username": "aragorn",
+		"name":               "Aragorn",
+	}
+}
+
+func (idp *mockIdentityProvider) provider() *oidcProvider {
+	cfg := &Config{}
+	cfg.OIDC.Issuer = idp.server.URL + "/"
+	cfg.OIDC.ClientID = testClientID
+	cfg.OIDC.RedirectURL = "https://draco.example/callback"
+	cfg.OIDC.AutoProvision = true
+	return newOIDCProvider(cfg)
+}
+
+func TestOIDCAuthorizationURL(t *testing.T) {
+	idp := newMockIdentityProvider(t)
+
+	authURL, err := idp.provider().authorizationURL("state", testNonce, testCodeVerifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != idp.server.URL+"/authorize" {
+		t.Errorf("endpoint = %q, want %q", got, idp.server.URL+"/authorize")
+	}
+
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"state":                 "state",
+		"nonce":                 testNonce,
+		"scope":                 "openid profile email",
+		"code_challenge":        pkceChallenge(testCodeVerifier),
+		"code_challenge_method": "S256",
+	}
+	for param, value := range want {
+		if got := u.Query().Get(param); got != value {
+			t.Errorf("%s = %q, want %q", param, got, value)
+		}
+	}
+}
+
+func TestOIDCDiscoveryRejectsOtherIssuer(t *testing.T) {
+	idp := newMockIdentityProvider(t)
+
+	p := idp.provider()
+	p.issuer = idp.server.URL + "/tenant"
+	if _, err := p.getDiscovery(); err == nil {
+		t.Fatal("expected discovery of another issuer to fail")
+	}
+}
+
+func TestOIDCExchangeCode(t *testing.T) {
+	idp := newMockIdentityProvider(t)
+
+	tests := []struct {
+		name    string
+		modify  func(claims jwt.MapClaims)
+		nonce   string
+		code    string
+		wantErr bool
+	}{
+		{"valid", func(claims jwt.MapClaims) {}, testNonce, testCode, false},
+		{"audience list", func(claims jwt.MapClaims) { claims["aud"] = []string{"other", testClientID} }, testNonce, testCode, false},
+		{"wrong issuer", func(claims jwt.MapClaims) { claims["iss"] = "https://evil.example" }, testNonce, testCode, true},
+		{"wrong audience", func(claims jwt.MapClaims) { claims["aud"] = "other" }, testNonce, testCode, true},
+		{"wrong nonce", func(claims jwt.MapClaims) {}, "another nonce", testCode, true},
+		{"missing nonce", func(claims jwt.MapClaims) { delete(claims, "nonce") }, testNonce, testCode, true},
+		{"expired", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-time.Minute).Unix() }, testNonce, testCode, true},
+		{"missing expiry", func(claims jwt.MapClaims) { delete(claims, "exp") }, testNonce, testCode, true},
+		{"missing subject", func(claims jwt.MapClaims) { delete(claims, "sub") }, testNonce, testCode, true},
+		{"rejected code", func(claims jwt.MapClaims) {}, testNonce, "stolen code", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp.claims = idp.validClaims()
+			tt.modify(idp.claims)
+
+			claims, err := idp.provider().exchangeCode(tt.code, testCodeVerifier, tt.nonce)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Issuer != idp.server.URL || claims.Subject != "subject-1" || claims.PreferredUsername != "aragorn" {
+				t.Errorf("unexpected claims %+v", claims)
+			}
+		})
+	}
+}
+
+func TestOIDCVerifyIDTokenRejectsForeignKeys(t *testing.T) {
+	idp := newMockIdentityProvider(t)
+	p := idp.provider()
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.validClaims())
+	forged.Header["kid"] = testKeyID
+	rawForged, err := forged.SignedString(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.verifyIDToken(rawForged, testNonce); err == nil {
+		t.Error("accepted a token signed with another key")
+	}
+
+	unknown := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.validClaims())
+	unknown.Header["kid"] = "key-2"
+	rawUnknown, err := unknown.SignedString(idp.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// jwt-go wraps the key function's error without unwrapping support.
+	if _, err := p.verifyIDToken(rawUnknown, testNonce); err == nil || !strings.Contains(err.Error(), errOIDCUnknownKey.Error()) {
+		t.Errorf("err = %v, want %v", err, errOIDCUnknownKey)
+	}
+
+	symmetric := jwt.NewWithClaims(jwt.SigningMethodHS256, idp.validClaims())
+	rawSymmetric, err := symmetric.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.verifyIDToken(rawSymmetric, testNonce); err == nil {
+		t.Error("accepted a token signed with HS256")
+	}
+}
+
+func TestProvisionUsername(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims oidcClaims
+		want   string
+	}{
+		{"preferred username", oidcClaims{PreferredUsername: "legolas", Email: "elf@example.com"}, "legolas"},
+		{"email local part", oidcClaims{Email: "gimli.son-of-gloin@example.com"}, "gimli.son-of-gloin"},
+		{"invalid characters", oidcClaims{PreferredUsername: "Frodo Baggins!"}, "FrodoBaggins"},
+		{"nothing usable", oidcClaims{PreferredUsername: "ñ€"}, "player"},
+		{"no claims", oidcClaims{}, "player"},
+		{"too long", oidcClaims{PreferredUsername: strings.Repeat("a", 30)}, strings.Repeat("a", 20)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := provisionUsername(&tt.claims); got != tt.want {
+				t.Errorf("provisionUsername() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeLoginState is a pending single sign-on request held by
+// fakeIdentities.
+type fakeLoginState struct {
+	sessionKeyHash, nonce, codeVerifier, linkUsername string
+}
+
+type fakeIdentities struct {
+	states  map[string]fakeLoginState
+	links   map[string]string
+	players *fakePlayers
+}
+
+func (f *fakeIdentities) CreateLoginState(stateHash, sessionKeyHash, nonce, codeVerifier, linkUsername string, expiresAt time.Time) error {
+	f.states[stateHash] = fakeLoginState{sessionKeyHash, nonce, codeVerifier, linkUsername}
+	return nil
+}
+
+func (f *fakeIdentities) ConsumeLoginState(stateHash, sessionKeyHash string) (string, string, string, error) {
+	s, ok := f.states[stateHash]
+	if !ok || s.sessionKeyHash != sessionKeyHash {
+		return "", "", "", models.ErrLoginStateExpired
+	}
+	delete(f.states, stateHash)
+	return s.nonce, s.codeVerifier, s.linkUsername, nil
+}
+
+func (f *fakeIdentities) GetPlayer(issuer, subject string) (string, error) {
+	username, ok := f.links[issuer+" "+subject]
+	if !ok {
+		return "", models.ErrNoRecord
+	}
+	return username, nil
+}
+
+func (f *fakeIdentities) GetAllForPlayer(username string) (*[]models.PlayerIdentity, error) {
+	return &[]models.PlayerIdentity{}, nil
+}
+
+func (f *fakeIdentities) Link(issuer, subject, username string) error {
+	if _, ok := f.links[issuer+" "+subject]; ok {
+		return models.ErrDuplicateIdentity
+	}
+	f.links[issuer+" "+subject] = username
+	return nil
+}
+
+func (f *fakeIdentities) Provision(issuer, subject, username, password, name string) error {
+	if _, ok := f.players.players[username]; ok {
+		return models.ErrDuplicateUsername
+	}
+	if err := f.Link(issuer, subject, username); err != nil {
+		return err
+	}
+	return f.players.Insert(username, password, name)
+}
+
+func (f *fakeIdentities) Unlink(username, issuer string) error {
+	return models.ErrNoRecord
+}
+
+type fakePlayers struct {
+	players map[string]*models.Player
+}
+
+func (f *fakePlayers) Insert(username string, password string, name string) error {
+	if _, ok := f.players[username]; ok {
+		return models.ErrDuplicateUsername
+	}
+	f.players[username] = &models.Player{ID: len(f.players) + 1, Username: username, Name: name, Role: models.RolePlayer}
+	return nil
+}
+
+func (f *fakePlayers) Authenticate(username string, password string) (string, error) {
+	return "", models.ErrNoRecord
+}
+
+func (f *fakePlayers) Get(username string) (*models.Player, error) {
+	p, ok := f.players[username]
+	if !ok {
+		return nil, models.ErrNoRecord
+	}
+	return p, nil
+}
+
+func (f *fakePlayers) UpdatePassword(username string, newPassword string) error { return nil }
+func (f *fakePlayers) Delete(username string) error                             { return nil }
+func (f *fakePlayers) Search(query string, limit, offset int) (*[]models.Player, error) {
+	return &[]models.Player{}, nil
+}
+func (f *fakePlayers) SetDisabled(username string, disabled bool) error    { return nil }
+func (f *fakePlayers) SetRole(username string, role models.RoleType) error { return nil }
+func (f *fakePlayers) RequirePasswordReset(username string) error          { return nil }
+func (f *fakePlayers) ChangeUsername(username, newUsername string) error   { return nil }
+func (f *fakePlayers) UpdateProfile(username string, name, avatarURL, bio *string) error {
+	return nil
+}
+
+type fakeTwoFactor struct {
+	challenges map[string]string
+}
+
+func (f *fakeTwoFactor) GetSecret(username string) (string, bool, error)      { return "", false, nil }
+func (f *fakeTwoFactor) SetPendingSecret(username, secret string) error       { return nil }
+func (f *fakeTwoFactor) Enable(username string, recoveryCodes []string) error { return nil }
+func (f *fakeTwoFactor) Disable(username string) error                        { return nil }
+func (f *fakeTwoFactor) ClaimCounter(username string, counter int64) error    { return nil }
+func (f *fakeTwoFactor) UseRecoveryCode(username, code string) error          { return nil }
+func (f *fakeTwoFactor) CountUnusedRecoveryCodes(username string) (int, error) {
+	return 0, nil
+}
+func (f *fakeTwoFactor) CreateChallenge(username, challengeHash string, expiresAt time.Time) error {
+	f.challenges[challengeHash] = username
+	return nil
+}
+func (f *fakeTwoFactor) GetChallenge(challengeHash string) (string, error) {
+	return f.challenges[challengeHash], nil
+}
+func (f *fakeTwoFactor) RecordFailedAttempt(challengeHash string) error { return nil }
+func (f *fakeTwoFactor) DeleteChallenge(challengeHash string) error     { return nil }
+
+// newOIDCTestApplication returns an application logging in against `idp`
+// with in-memory players and identities.
+func newOIDCTestApplication(idp *mockIdentityProvider) (*application, *fakePlayers, *fakeIdentities) {
+	players := &fakePlayers{players: make(map[string]*models.Player)}
+	identities := &fakeIdentities{states: make(map[string]fakeLoginState), links: make(map[string]string), players: players}
+
+	app := &application{
+		echoInstance:  echo.New(),
+		jwtSigningKey: "test",
+		oidc:          idp.provider(),
+		players:       players,
+		identities:    identities,
+		twoFactor:     &fakeTwoFactor{challenges: make(map[string]string)},
+	}
+	app.echoInstance.GET("/login/oidc", app.startOIDCLogin)
+	app.echoInstance.POST("/login/oidc/callback", app.completeOIDCLogin)
+
+	return app, players, identities
+}
+
+// oidcTestResponse is the body of a response to a single sign-on request.
+type oidcTestResponse struct {
+	Message string `json:"message"`
+	Data    struct {
+		AuthorizationURL  string `json:"authorization_url"`
+		SessionKey        string `json:"session_key"`
+		Username          string `json:"username"`
+		Token             string `json:"token"`
+		TwoFactorRequired bool   `json:"two_factor_required"`
+		Challenge         string `json:"challenge"`
+	} `json:"data"`
+}
+
+func serveOIDCTestRequest(t *testing.T, app *application, method, target string, body interface{}) (int, oidcTestResponse) {
+	t.Helper()
+
+	var reader *strings.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = strings.NewReader(string(b))
+	} else {
+		reader = strings.NewReader("")
+	}
+
+	req := httptest.NewRequest(method, target, reader)
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	app.echoInstance.ServeHTTP(rec, req)
+
+	var resp oidcTestResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("could not decode %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+// startOIDCTestLogin starts a login and returns the state and session
+// key. The mock provider is set up to answer the request like a player
+// logging in as "subject-1".
+func startOIDCTestLogin(t *testing.T, app *application, idp *mockIdentityProvider) (string, string) {
+	t.Helper()
+
+	code, resp := serveOIDCTestRequest(t, app, http.MethodGet, "/login/oidc", nil)
+	if code != http.StatusOK {
+		t.Fatalf("start returned %d", code)
+	}
+
+	u, err := url.Parse(resp.Data.AuthorizationURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp.claims = idp.validClaims()
+	idp.claims["nonce"] = u.Query().Get("nonce")
+	idp.codeChallenge = u.Query().Get("code_challenge")
+	return u.Query().Get("state"), resp.Data.SessionKey
+}
+
+// completeOIDCTestLogin redeems the authorization code of the mock
+// provider.
+func completeOIDCTestLogin(t *testing.T, app *application, state, sessionKey string) (int, oidcTestResponse) {
+	t.Helper()
+
+	return serveOIDCTestRequest(t, app, http.MethodPost, "/login/oidc/callback", map[string]string{
+		"code":        testCode,
+		"state":       state,
+		"session_key": sessionKey,
+	})
+}
+
+func TestCompleteOIDCLoginProvisionsPlayers(t *testing.T) {
+	idp := newMockIdentityProvider(t)
+	app, players, identities := newOIDCTestApplication(idp)
+	players.Insert("aragorn", "", "Strider")
+
+	state, sessionKey := startOIDCTestLogin(t, app, idp)
+	code, resp := completeOIDCTestLogin(t, app, state, sessionKey)
+	if code != http.StatusOK {
+		t.Fatalf("callback returned %d: %s", code, resp.Message)
+	}
+
+	// The preferred username is taken, so a suffix is added.
+	if resp.Data.Username != "aragorn1" || resp.Data.Token == "" {
+		t.Errorf("logged in as %q with token %q, want a token for aragorn1", resp.Data.Username, resp.Data.Token)
+	}
+	if p, err := players.Get("aragorn1"); err != nil || p.Name != "Aragorn" {
+		t.Errorf("provisioned player = %+v, %v", p, err)
+	}
+	if username, _ := identities.GetPlayer(idp.server.URL, "subject-1"); username != "aragorn1" {
+		t.Errorf("identity linked to %q, want aragorn1", username)
+	}
+
+	// The second login finds the linked player instead of provisioning.
+	state, sessionKey = startOIDCTestLogin(t, app, idp)
+	code, resp = completeOIDCTestLogin(t, app, state, sessionKey)
+	if code != http.StatusOK || resp.Data.Username != "aragorn1" {
+		t.Errorf("second login returned %d as %q", code, resp.Data.Username)
+	}
+	if len(players.players) != 2 {
+		t.Errorf("%d players exist, want 2", len(players.players))
+	}
+}
+
+func TestProvisionOIDCPlayerLinkedMeanwhile(t *testing.T) {
+	idp := newMockIdentityProvider(t)
+	app, players, identities := newOIDCTestApplication(idp)
+	players.Insert("legolas", "", "Legolas")
+	identities.Link(idp.server.URL, "subject-1", "legolas")
+
+	claims := oidcClaims{PreferredUsername: "greenleaf"}
+	claims.Issuer, claims.Subject = idp.server.URL, "subject-1"
+	username, err := app.provisionOIDCPlayer(&claims)
+	if err != nil || username != "legolas" {
+		t.Errorf("provisionOIDCPlayer() = %q, %v, want legolas", username, err)
+	}
+	if len(players.players) != 1 {
+		t.Errorf("%d players exist, want 1", len(players.players))
+	}
+}
+
+func TestCompleteOIDCLoginWithoutAutoProvisioning(t *testing.T) {
+	idp := newMockIdentityProvider(t)
+	app, players, _ := newOIDCTestApplication(idp)
+	app.oidc.autoProvision = false
+
+	state, sessionKey := startOIDCTestLogin(t, app, idp)
+	code, _ := completeOIDCTestLogin(t, app, state, sessionKey)
+	if code != http.StatusUnauthorized {
+		t.Errorf("callback returned %d, want %d", code, http.StatusUnauthorized)
+	}
+	if len(players.players) != 0 {
+		t.Errorf("%d players were provisioned", len(players.players))
+	}
+}
+
+func TestCompleteOIDCLoginRequiresSessionKey(t *testing.T) {
+	idp := newMockIdentityProvider(t)
+	app, _, _ := newOIDCTestApplication(idp)
+
+	state, _ := startOIDCTestLogin(t, app, idp)
+	_, otherSessionKey := startOIDCTestLogin(t, app, idp)
+
+	for _, sessionKey := range []string{"", otherSessionKey} {
+		code, resp := completeOIDCTestLogin(t, app, state, sessionKey)
+		if code != http.StatusUnauthorized || resp.Data.Token != "" {
+			t.Errorf("callback with session key %q returned %d", sessionKey, code)
+		}
+	}
+}
+
+func TestCompleteOIDCLoginRequiresSecondFactor(t *testing.T) {
+	idp := newMockIdentityProvider(t)
+	app, players, identities := newOIDCTestApplication(idp)
+	players.Insert("boromir", "", "Boromir")
+	players.players["boromir"].TOTPEnabled = true
+	identities.Link(idp.server.URL, "subject-1", "boromir")
+
+	state, sessionKey := startOIDCTestLogin(t, app, idp)
+	code, resp := completeOIDCTestLogin(t, app, state, sessionKey)
+	if code != http.StatusOK {
+		t.Fatalf("callback returned %d: %s", code, resp.Message)
+	}
+	if resp.Data.Token != "" || !resp.Data.TwoFactorRequired || resp.Data.Challenge == "" {
+		t.Errorf("expected a login challenge instead of a token, got %+v", resp.Data)
+	}
+}
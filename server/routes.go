@@ -15,27 +15,85 @@ func (app *application) registerMiddleware() {
 
 func (app *application) registerRoutes() {
 	app.echoInstance.POST("/login", app.loginPlayer)
+	app.echoInstance.POST("/login/2fa", app.completeTwoFactorLogin)
+	app.echoInstance.GET("/login/oidc", app.startOIDCLogin)
+	app.echoInstance.GET("/login/oidc/callback", app.completeOIDCLogin)
+	app.echoInstance.POST("/login/oidc/callback", app.completeOIDCLogin)
 	app.echoInstance.POST("/register", app.createPlayer)
 
 	// Unprotected character endpoints
 	app.echoInstance.GET("/character/:id", app.retrieveCharacter)
 
+	// Unprotected rule endpoints
+	app.echoInstance.GET("/meta", app.getMetadata)
+	app.echoInstance.GET("/meta/subclasses", app.getClassSubclasses)
+
 	// Unprotected stat endpoints
 	app.echoInstance.GET("/stat", app.retrieveAllStats)
 
 	// All routes which require JWT-based authentication
 	r := app.echoInstance.Group("/auth")
+	r.Use(app.personalTokenAuth)
 	r.Use(middleware.JWTWithConfig(app.getJWTConfig()))
+	r.Use(app.requireActivePlayer)
 	r.GET("/player/:username", app.retrievePlayer)
 	r.PUT("/player/me/password", app.changePlayerPassword)
 	r.DELETE("/player/me", app.deletePlayerSelf)
+	r.PATCH("/player/me", app.updatePlayerProfile)
+	r.PUT("/player/me/username", app.changePlayerUsername)
+
+	// Protected two-factor authentication endpoints
+	r.GET("/player/me/2fa", app.retrieveTwoFactorStatus)
+	r.POST("/player/me/2fa", app.enrolTwoFactor)
+	r.POST("/player/me/2fa/confirm", app.confirmTwoFactor)
+	r.POST("/player/me/2fa/recovery-codes", app.regenerateRecoveryCodes)
+	r.DELETE("/player/me/2fa", app.disableTwoFactor)
+
+	// Protected single sign-on endpoints
+	r.GET("/player/me/oidc", app.retrieveOIDCIdentities)
+	r.POST("/player/me/oidc", app.startOIDCLink)
+	r.DELETE("/player/me/oidc", app.unlinkOIDCIdentity)
+
+	// Protected personal access token endpoints
+	r.POST("/player/me/token", app.createPersonalToken)
+	r.GET("/player/me/token", app.retrievePersonalTokens)
+	r.DELETE("/player/me/token/:id", app.revokePersonalToken)
 
 	// Protected character endpoints
 	r.POST("/character", app.createCharacter)
 	r.GET("/character/me", app.retrieveUserCharacters)
+	r.POST("/character/ability-roll", app.createAbilityRoll)
+	r.GET("/character/ability-roll", app.getAbilityRolls)
 	r.GET("/character/:id", app.retrieveCharacter)
 	r.PUT("/character/:id", app.updateCharacter)
 	r.DELETE("/character/:id", app.deleteCharacter)
+	r.GET("/character/:id/class", app.getCharacterClasses)
+	r.PUT("/character/:id/class", app.updateCharacterClasses)
+	r.GET("/character/:id/level", app.getCharacterLevel)
+	r.POST("/character/:id/level-up", app.levelUpCharacter)
+
+	// Protected proficiency endpoints
+	r.GET("/character/:id/proficiency", app.getCharacterProficiencies)
+	r.PUT("/character/:id/proficiency/:kind/:name", app.setCharacterProficiency)
+	r.DELETE("/character/:id/proficiency/:kind/:name", app.deleteCharacterProficiency)
+
+	// Protected condition endpoints
+	r.GET("/character/:id/condition", app.getCharacterConditions)
+	r.POST("/character/:id/condition", app.addCharacterCondition)
+	r.DELETE("/character/:id/condition/:condition", app.removeCharacterCondition)
+	r.POST("/character/:id/rest", app.restCharacter)
+
+	// Protected feature endpoints
+	r.GET("/feature", app.getFeatures)
+	r.GET("/character/:id/feature", app.getCharacterFeatures)
+	r.POST("/character/:id/feature", app.addCharacterFeat)
+	r.DELETE("/character/:id/feature/:featureID", app.removeCharacterFeat)
+	r.POST("/character/:id/feature/:featureID/use", app.useCharacterFeature)
+
+	// Protected hit point and death save endpoints
+	r.POST("/character/:id/hp", app.adjustCharacterHP)
+	r.POST("/character/:id/death-save", app.rollDeathSave)
+	r.POST("/character/:id/stabilize", app.stabilizeCharacter)
 
 	// Protected spell endpoints
 	r.POST("/character/:id/spell", app.createSpell)
@@ -51,15 +109,109 @@ func (app *application) registerRoutes() {
 	r.DELETE("/character/:id/item/:name", app.deleteItem)
 	r.GET("/character/:id/item/stats", app.getItemStats)
 
+	// Race, class and subclass registry endpoints
+	r.GET("/registry/:kind", app.getRegistryEntries)
+	r.POST("/registry/:kind", app.createRegistryEntry)
+	r.PUT("/registry/:kind/:entryID", app.updateRegistryEntry)
+	r.DELETE("/registry/:kind/:entryID", app.deleteRegistryEntry)
+
 	// Protected campaign endpoints
 	r.POST("/campaign", app.createCampaign)
 	r.PUT("/campaign/:id", app.updateCampaign)
+	r.POST("/campaign/:id/state", app.changeCampaignState)
+	r.GET("/campaign/:id/state", app.getCampaignStateHistory)
+	r.POST("/campaign/:id/award-xp", app.awardCampaignXP)
+	r.GET("/campaign/:id/log", app.getCampaignLog)
+
+	// Protected campaign journal endpoints
+	r.POST("/campaign/:id/journal", app.createJournalEntry)
+	r.GET("/campaign/:id/journal", app.getJournalEntries)
+	r.GET("/campaign/:id/journal/:entryID", app.getJournalEntry)
+	r.PUT("/campaign/:id/journal/:entryID", app.updateJournalEntry)
+	r.DELETE("/campaign/:id/journal/:entryID", app.deleteJournalEntry)
+	r.GET("/campaign/:id/journal/:entryID/revisions", app.getJournalEntryRevisions)
+
+	// Protected campaign NPC and compendium endpoints
+	r.POST("/campaign/:id/npc", app.createCampaignNPC)
+	r.GET("/campaign/:id/npc", app.getCampaignNPCs)
+	r.POST("/campaign/:id/npc/from-compendium", app.createNPCFromCompendium)
+	r.GET("/campaign/:id/npc/:npcID", app.getCampaignNPC)
+	r.PUT("/campaign/:id/npc/:npcID", app.updateCampaignNPC)
+	r.DELETE("/campaign/:id/npc/:npcID", app.deleteCampaignNPC)
+	r.POST("/campaign/:id/npc/:npcID/duplicate", app.duplicateCampaignNPC)
+	r.GET("/compendium/monster", app.getCompendiumMonsters)
+	r.GET("/compendium/monster/:slug", app.getCompendiumMonster)
+
+	// Protected encounter builder endpoints
+	r.POST("/campaign/:id/encounter/evaluate", app.evaluateEncounter)
+	r.POST("/campaign/:id/encounter", app.createEncounter)
+	r.GET("/campaign/:id/encounter", app.getCampaignEncounters)
+	r.GET("/campaign/:id/encounter/:encounterID", app.getCampaignEncounter)
+	r.PUT("/campaign/:id/encounter/:encounterID", app.updateCampaignEncounter)
+	r.DELETE("/campaign/:id/encounter/:encounterID", app.deleteCampaignEncounter)
+
+	// Protected combat tracker endpoints
+	r.POST("/campaign/:id/combat", app.startCombat)
+	r.GET("/campaign/:id/combat", app.getCombat)
+	r.DELETE("/campaign/:id/combat", app.endCombat)
+	r.POST("/campaign/:id/combat/next", app.nextCombatTurn)
+	r.PUT("/campaign/:id/combat/initiative", app.setCombatInitiative)
+	r.POST("/campaign/:id/combat/initiative/roll", app.rollCombatInitiative)
+	r.POST("/campaign/:id/combat/combatant", app.addCombatants)
+	r.DELETE("/campaign/:id/combat/combatant/:combatantID", app.removeCombatant)
+	r.POST("/campaign/:id/combat/combatant/:combatantID/hp", app.updateCombatantHP)
+	r.POST("/campaign/:id/combat/combatant/:combatantID/condition", app.addCombatantCondition)
+	r.DELETE("/campaign/:id/combat/combatant/:combatantID/condition/:condition", app.removeCombatantCondition)
+
 	r.DELETE("/campaign/:id", app.deleteCampaign)
 	r.POST("/campaign/milestone", app.createMilestone)
 	r.GET("/campaign/:id/milestone", app.getAllMilestonesForCampaign)
+	r.POST("/campaign/:id/milestone", app.createCampaignMilestone)
+	r.PUT("/campaign/:id/milestone/order", app.reorderCampaignMilestones)
+	r.GET("/campaign/:id/milestone/:milestoneID", app.getCampaignMilestone)
+	r.PUT("/campaign/:id/milestone/:milestoneID", app.updateCampaignMilestone)
+	r.DELETE("/campaign/:id/milestone/:milestoneID", app.deleteCampaignMilestone)
 	r.GET("/campaign/:id/participants", app.getCampaignParticipants)
 	r.GET("/campaign/me/stats/player-attendance", app.getPlayersAttendedAll)
 	r.GET("/campaign/me", app.getsPlayersCreatedCampaigns)
 	r.GET("/character/:id/campaign", app.getAllCharacterCampaigns)
+	r.DELETE("/campaign/:id/character/:characterID", app.removeCampaignCharacter)
+
+	// Protected campaign membership endpoints
+	r.POST("/campaign/:id/invitation", app.createCampaignInvitation)
+	r.GET("/campaign/:id/invitation", app.getCampaignInvitations)
+	r.DELETE("/campaign/:id/invitation/:invitationID", app.revokeCampaignInvitation)
+	r.GET("/invitation/me", app.getPlayerInvitations)
+	r.POST("/invitation/:id/accept", app.acceptInvitation)
+	r.POST("/invitation/:id/decline", app.declineInvitation)
+	r.POST("/campaign/join", app.joinCampaignWithCode)
+	r.POST("/campaign/:id/join-request", app.createJoinRequest)
+	r.GET("/campaign/:id/join-request", app.getCampaignJoinRequests)
+	r.POST("/campaign/:id/join-request/:requestID/accept", app.acceptJoinRequest)
+	r.POST("/campaign/:id/join-request/:requestID/decline", app.declineJoinRequest)
+	r.POST("/campaign/:id/events/ticket", app.createCampaignEventTicket)
+	r.POST("/campaign/:id/roll", app.createCampaignDiceRoll)
+	r.GET("/campaign/:id/roll", app.getCampaignDiceRolls)
+
+	// Campaign event streams, authenticated with a stream ticket
+	s := app.echoInstance.Group("/stream")
+	s.Use(middleware.JWTWithConfig(app.getStreamJWTConfig()))
+	s.Use(app.requireActivePlayer)
+	s.GET("/campaign/:id/events", app.streamCampaignEvents)
+	s.GET("/campaign/:id/ws", app.streamCampaignEventsWebSocket)
 
+	// All routes which require an administrator
+	a := app.echoInstance.Group("/admin")
+	a.Use(middleware.JWTWithConfig(app.getJWTConfig()))
+	a.Use(app.requireActivePlayer)
+	a.Use(app.requireAdmin)
+	a.GET("/player", app.adminSearchPlayers)
+	a.GET("/player/:username", app.adminRetrievePlayer)
+	a.PUT("/player/:username/disabled", app.adminSetPlayerDisabled)
+	a.PUT("/player/:username/role", app.adminSetPlayerRole)
+	a.POST("/player/:username/password-reset", app.adminForcePasswordReset)
+	a.GET("/campaign/orphaned", app.adminRetrieveOrphanedCampaigns)
+	a.PUT("/campaign/:id/dungeon-master", app.adminReassignCampaign)
+	a.GET("/stat", app.adminRetrieveStats)
+	a.GET("/audit", app.adminRetrieveAuditLog)
 }
@@ -1,26 +1,107 @@
 -- Initialize DB schema
 -- Only needs to be run once per server
 
+CREATE TYPE e_role AS ENUM (
+    'player',
+    'admin'
+);
+
 CREATE TABLE Player (
     username        varchar(25) PRIMARY KEY,
+    id              serial UNIQUE, -- Never changes, unlike the username
     password        text NOT NULL,
-    name            varchar(50) NOT NULL
+    name            varchar(50) NOT NULL,
+    avatar_url      varchar(2048) NOT NULL DEFAULT '',
+    bio             varchar(2000) NOT NULL DEFAULT '',
+    role            e_role NOT NULL DEFAULT 'player',
+    disabled        bool NOT NULL DEFAULT false,
+    password_reset_required bool NOT NULL DEFAULT false,
+    totp_secret     text,
+    totp_enabled    bool NOT NULL DEFAULT false,
+    totp_last_counter bigint NOT NULL DEFAULT 0 -- Last accepted TOTP time step
+);
+
+-- Recovery codes allow a player to log in when their authenticator app
+-- is unavailable. Codes are stored hashed and may only be used once.
+CREATE TABLE PlayerRecoveryCode (
+    id                  serial PRIMARY KEY,
+    player_username     varchar(25) NOT NULL,
+    code_hash           text NOT NULL,
+    used_at             timestamptz,
+    FOREIGN KEY (player_username) REFERENCES Player(username)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+-- Short-lived challenges issued after a successful password check for
+-- players with two-factor authentication enabled.
+CREATE TABLE LoginChallenge (
+    challenge_hash      text PRIMARY KEY,
+    player_username     varchar(25) NOT NULL,
+    expires_at          timestamptz NOT NULL,
+    failed_attempts     int NOT NULL DEFAULT 0,
+    FOREIGN KEY (player_username) REFERENCES Player(username)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
 );
 
-CREATE TYPE e_class AS ENUM (
-    'Barbarian',
-    'Bard',
-    'Cleric',
-    'Druid',
-    'Fighter',
-    'Monk',
-    'Paladin',
-    'Ranger',
-    'Rogue',
-    'Sorcerer',
-    'Warlock',
-    'Wizard',
-    'None'
+-- Personal access tokens let players authenticate scripts without
+-- storing their password. Tokens are stored hashed.
+CREATE TABLE PersonalAccessToken (
+    id                  serial PRIMARY KEY,
+    player_username     varchar(25) NOT NULL,
+    name                varchar(50) CHECK (length(name) > 0) NOT NULL,
+    token_hash          text UNIQUE NOT NULL,
+    scopes              text[] NOT NULL CHECK (
+        cardinality(scopes) > 0 AND
+        scopes <@ ARRAY['read-only', 'characters', 'campaigns']::text[]
+    ),
+    created_at          timestamptz NOT NULL DEFAULT now(),
+    last_used_at        timestamptz,
+    revoked_at          timestamptz,
+    UNIQUE (player_username, name),
+    FOREIGN KEY (player_username) REFERENCES Player(username)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+-- Links accounts at an external OpenID Connect provider to players.
+CREATE TABLE PlayerIdentity (
+    issuer              text NOT NULL,
+    subject             text NOT NULL,
+    player_username     varchar(25) NOT NULL,
+    created_at          timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (issuer, subject),
+    UNIQUE (player_username, issuer),
+    FOREIGN KEY (player_username) REFERENCES Player(username)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+-- Pending OpenID Connect authorization requests. `link_username` is set
+-- when a logged-in player is linking their account rather than logging
+-- in.
+CREATE TABLE OIDCLoginState (
+    state_hash          text PRIMARY KEY,
+    session_key_hash    text NOT NULL,
+    nonce               text NOT NULL,
+    code_verifier       text NOT NULL,
+    link_username       varchar(25),
+    expires_at          timestamptz NOT NULL,
+    FOREIGN KEY (link_username) REFERENCES Player(username)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+-- Record of every action taken through the administrator API. The actor
+-- is not a foreign key so that entries outlive deleted accounts.
+CREATE TABLE AuditLog (
+    id                  serial PRIMARY KEY,
+    actor_username      varchar(25) NOT NULL,
+    action              text NOT NULL,
+    target              text,
+    details             text,
+    created_at          timestamptz NOT NULL DEFAULT now()
 );
 
 CREATE TYPE e_alignment AS ENUM (
@@ -35,24 +116,33 @@ CREATE TYPE e_alignment AS ENUM (
     'Chaotic Evil'
 );
 
-CREATE TYPE e_race AS ENUM (
-    'Dragonborn',
-    'Dwarf',
-    'Elf',
-    'Gnome',
-    'Half-Elf',
-    'Halfling',
-    'Half-Orc',
-    'Human',
-    'Tiefling'
-);
-
 CREATE TYPE e_sex AS ENUM (
     'Male',
     'Female',
     'Other'
 );
 
+CREATE TYPE e_ability_method AS ENUM (
+    'manual',
+    'point_buy',
+    'standard_array',
+    'rolled'
+);
+
+-- Ability scores rolled by the server with 4d6, dropping the lowest die,
+-- kept so that characters of the rolled method can be checked against
+-- them and players cannot reroll unnoticed.
+CREATE TABLE AbilityRoll (
+    id                  serial PRIMARY KEY,
+    player_username     varchar(25) NOT NULL,
+    scores              int[] NOT NULL CHECK (array_length(scores, 1) = 6),
+    rolls               jsonb NOT NULL, -- Every die rolled
+    created_at          timestamptz NOT NULL DEFAULT now(),
+    FOREIGN KEY (player_username) REFERENCES Player(username)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
 CREATE TABLE Character (
     id                  serial PRIMARY KEY,
     name                text CHECK (length(name) > 0) NOT NULL,
@@ -61,7 +151,7 @@ CREATE TABLE Character (
     alignment           e_alignment NOT NULL,
     sex                 e_sex NOT NULL,
     background          text,
-    race                e_race NOT NULL,
+    race                varchar(50) NOT NULL, -- Name of a RegistryEntry
     speed               int NOT NULL CHECK (speed > 0 AND speed <= 1280),
     strength            int NOT NULL CHECK (strength > 0 AND strength <= 30),
     dexterity           int NOT NULL CHECK (dexterity > 0 AND dexterity <= 30),
@@ -70,13 +160,88 @@ CREATE TABLE Character (
     charisma            int NOT NULL CHECK (charisma > 0 AND charisma <= 30),
     constitution        int NOT NULL CHECK (constitution > 0 AND constitution <= 30),
     hp_max              int NOT NULL CHECK (hp_max > 0 AND hp_max <= 440),
+    hp_current          int NOT NULL CHECK (hp_current >= 0 AND hp_current <= hp_max),
+    death_save_successes int NOT NULL DEFAULT 0 CHECK (death_save_successes >= 0 AND death_save_successes < 3),
+    death_save_failures int NOT NULL DEFAULT 0 CHECK (death_save_failures >= 0 AND death_save_failures <= 3),
+    stable              bool NOT NULL DEFAULT false, -- At 0 hit points without rolling death saves
+    dead                bool NOT NULL DEFAULT false,
+    ability_method      e_ability_method NOT NULL DEFAULT 'manual',
+    ability_roll_id     int UNIQUE, -- Roll the scores were assigned from
     ability_points      int NOT NULL CHECK (ability_points >= 0 AND ability_points <= 180), -- Unused ability points
     xp_points           int NOT NULL CHECK (xp_points >= 0 AND xp_points <= 355000),
-    class               e_class NOT NULL,
+    level               int NOT NULL CHECK (level >= 1 AND level <= 20), -- Gained through level-ups once xp_points allow
+    class               varchar(50) NOT NULL, -- Name of a RegistryEntry
     class_attribute     text CHECK (length(class_attribute) > 0) NOT NULL,
     player_username     text NOT NULL,
     UNIQUE (name, player_username), -- No player should have multiple characters of the same name
     FOREIGN KEY (player_username) REFERENCES Player(username)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (ability_roll_id) REFERENCES AbilityRoll(id)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+);
+
+-- The classes a character has levels in. The first class, at position 0,
+-- mirrors Character.class and Character.class_attribute, and takes up the
+-- levels gained or lost by the character, so that the levels of all
+-- classes add up to the level of the character.
+CREATE TABLE CharacterClass (
+    character_id        int NOT NULL,
+    position            int NOT NULL CHECK (position >= 0),
+    class               varchar(50) NOT NULL, -- Name of a RegistryEntry
+    subclass            varchar(50), -- Name of a RegistryEntry
+    levels              int NOT NULL CHECK (levels >= 1 AND levels <= 20),
+    PRIMARY KEY (character_id, class),
+    UNIQUE (character_id, position),
+    FOREIGN KEY (character_id) REFERENCES Character(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE TYPE e_hit_points_method AS ENUM (
+    'average',
+    'roll'
+);
+
+-- Every level a character has gained through a level-up, along with the
+-- choices made for it.
+CREATE TABLE CharacterLevelHistory (
+    id                  serial PRIMARY KEY,
+    character_id        int NOT NULL,
+    level               int NOT NULL CHECK (level >= 2 AND level <= 20), -- Of the character once gained
+    class               varchar(50) NOT NULL, -- Name of a RegistryEntry
+    class_level         int NOT NULL CHECK (class_level >= 1 AND class_level <= 20),
+    hit_points_method   e_hit_points_method NOT NULL,
+    hit_die_roll        int CHECK (hit_die_roll >= 1), -- Rolled method only
+    hp_gained           int NOT NULL CHECK (hp_gained >= 1),
+    ability_increases   jsonb NOT NULL DEFAULT '{}', -- Such as {"strength": 2}
+    feat                varchar(100), -- Taken instead of an Ability Score Improvement
+    subclass            varchar(50), -- Chosen at this level
+    leveled_at          timestamptz NOT NULL DEFAULT now(),
+    CHECK ((hit_points_method = 'roll') = (hit_die_roll IS NOT NULL)),
+    CHECK (feat IS NULL OR ability_increases = '{}'),
+    FOREIGN KEY (character_id) REFERENCES Character(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE TYPE e_proficiency_kind AS ENUM (
+    'saving_throw',
+    'skill',
+    'tool',
+    'language'
+);
+
+-- Saving throws, skills, tools and languages a character is proficient in
+CREATE TABLE CharacterProficiency (
+    character_id        int NOT NULL,
+    kind                e_proficiency_kind NOT NULL,
+    name                varchar(50) NOT NULL,
+    expertise           boolean NOT NULL DEFAULT false,
+    PRIMARY KEY (character_id, kind, name),
+    CHECK (NOT expertise OR kind IN ('skill', 'tool')),
+    FOREIGN KEY (character_id) REFERENCES Character(id)
         ON DELETE CASCADE
         ON UPDATE CASCADE
 );
@@ -144,23 +309,66 @@ CREATE TABLE Spells (
         ON UPDATE CASCADE
 );
 
+CREATE TYPE e_campaign_state AS ENUM (
+    'planning',
+    'recruiting',
+    'active',
+    'on hiatus',
+    'completed',
+    'archived'
+);
+
 CREATE TABLE Campaign (
     id                  serial PRIMARY KEY,
     name                varchar(50) NOT NULL,
     current_location    varchar(50) NOT NULL,
-    state               varchar(1024),
+    state               e_campaign_state NOT NULL DEFAULT 'planning',
     dungeon_master      varchar(25),
+    status_note         varchar(1024) NOT NULL DEFAULT '',
+    state_changed_at    timestamptz NOT NULL DEFAULT now(),
     FOREIGN KEY (dungeon_master) REFERENCES Player(username)
         ON DELETE SET NULL
         ON UPDATE CASCADE
 );
 
+-- Every change to a campaign's state. `from_state` is NULL for the row
+-- recorded when the campaign is created.
+CREATE TABLE CampaignStateTransition (
+    id                  serial PRIMARY KEY,
+    campaign_id         int NOT NULL,
+    from_state          e_campaign_state,
+    to_state            e_campaign_state NOT NULL,
+    changed_by          varchar(25),
+    changed_at          timestamptz NOT NULL DEFAULT now(),
+    FOREIGN KEY (campaign_id) REFERENCES Campaign(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (changed_by) REFERENCES Player(username)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+);
+
+-- `sequence` orders the milestones of a campaign starting from 1. The
+-- uniqueness check is deferred so that milestones can be reordered in
+-- a single transaction.
 CREATE TABLE CampaignMilestones (
+    id                  serial PRIMARY KEY,
     campaign_id         int NOT NULL,
+    sequence            int NOT NULL CHECK (sequence > 0),
     milestone           text CHECK (length(milestone) > 0) NOT NULL,
-    PRIMARY KEY (campaign_id, milestone),
+    description         text NOT NULL DEFAULT '',
+    in_game_date        varchar(100) NOT NULL DEFAULT '',
+    occurred_on         date,
+    author_username     varchar(25),
+    xp_reward           int NOT NULL DEFAULT 0 CHECK (xp_reward >= 0),
+    created_at          timestamptz NOT NULL DEFAULT now(),
+    updated_at          timestamptz NOT NULL DEFAULT now(),
+    UNIQUE (campaign_id, sequence) DEFERRABLE INITIALLY DEFERRED,
     FOREIGN KEY (campaign_id) REFERENCES Campaign(id)
         ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (author_username) REFERENCES Player(username)
+        ON DELETE SET NULL
         ON UPDATE CASCADE
 );
 
@@ -176,6 +384,828 @@ CREATE TABLE BelongsTo (
         ON UPDATE CASCADE
 );
 
+CREATE TYPE e_request_status AS ENUM (
+    'pending',
+    'accepted',
+    'declined',
+    'revoked'
+);
+
+-- Invitations to join a campaign. An invitation is either addressed to a
+-- single player, or is a shareable code which any player may redeem
+-- until it expires or is revoked.
+CREATE TABLE CampaignInvitation (
+    id                  serial PRIMARY KEY,
+    campaign_id         int NOT NULL,
+    invited_username    varchar(25),
+    code                text UNIQUE,
+    status              e_request_status NOT NULL DEFAULT 'pending',
+    expires_at          timestamptz,
+    created_at          timestamptz NOT NULL DEFAULT now(),
+    responded_at        timestamptz,
+    CHECK ((invited_username IS NULL) <> (code IS NULL)),
+    FOREIGN KEY (campaign_id) REFERENCES Campaign(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (invited_username) REFERENCES Player(username)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+-- Requests from players to have one of their characters join a
+-- campaign, which the dungeon master may accept or decline.
+CREATE TABLE CampaignJoinRequest (
+    id                  serial PRIMARY KEY,
+    campaign_id         int NOT NULL,
+    character_id        int NOT NULL,
+    message             varchar(500) NOT NULL DEFAULT '',
+    status              e_request_status NOT NULL DEFAULT 'pending',
+    created_at          timestamptz NOT NULL DEFAULT now(),
+    responded_at        timestamptz,
+    FOREIGN KEY (campaign_id) REFERENCES Campaign(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (character_id) REFERENCES Character(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+-- A character may only have one pending request per campaign.
+CREATE UNIQUE INDEX campaignjoinrequest_pending_key
+    ON CampaignJoinRequest (campaign_id, character_id)
+    WHERE status = 'pending';
+
+-- A record of notable events in a campaign, such as experience awards.
+CREATE TABLE CampaignLog (
+    id                  serial PRIMARY KEY,
+    campaign_id         int NOT NULL,
+    actor_username      varchar(25),
+    kind                varchar(50) NOT NULL,
+    message             text NOT NULL,
+    created_at          timestamptz NOT NULL DEFAULT now(),
+    FOREIGN KEY (campaign_id) REFERENCES Campaign(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (actor_username) REFERENCES Player(username)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+);
+
+-- The experience each character received from an award in the log.
+CREATE TABLE CampaignXPAward (
+    log_id              int NOT NULL,
+    character_id        int NOT NULL,
+    amount              int NOT NULL CHECK (amount >= 0),
+    xp_before           int NOT NULL,
+    xp_after            int NOT NULL,
+    PRIMARY KEY (log_id, character_id),
+    FOREIGN KEY (log_id) REFERENCES CampaignLog(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (character_id) REFERENCES Character(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE TYPE e_journal_visibility AS ENUM (
+    'everyone',
+    'dm_only',
+    'players'
+);
+
+-- Markdown journal entries written by the participants of a campaign.
+-- Entries with the 'players' visibility are shown to the players listed
+-- in JournalEntryViewer, and the DM and the author can always see an
+-- entry.
+CREATE TABLE JournalEntry (
+    id                  serial PRIMARY KEY,
+    campaign_id         int NOT NULL,
+    author_username     varchar(25),
+    title               varchar(200) CHECK (length(title) > 0) NOT NULL,
+    body                text NOT NULL,
+    visibility          e_journal_visibility NOT NULL DEFAULT 'everyone',
+    revision            int NOT NULL DEFAULT 1,
+    created_at          timestamptz NOT NULL DEFAULT now(),
+    updated_at          timestamptz NOT NULL DEFAULT now(),
+    FOREIGN KEY (campaign_id) REFERENCES Campaign(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (author_username) REFERENCES Player(username)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+);
+
+CREATE INDEX journalentry_search_idx ON JournalEntry
+    USING GIN (to_tsvector('english', title || ' ' || body));
+
+CREATE TABLE JournalEntryViewer (
+    entry_id            int NOT NULL,
+    player_username     varchar(25) NOT NULL,
+    PRIMARY KEY (entry_id, player_username),
+    FOREIGN KEY (entry_id) REFERENCES JournalEntry(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (player_username) REFERENCES Player(username)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+-- Every version of a journal entry, including the current one.
+CREATE TABLE JournalEntryRevision (
+    entry_id            int NOT NULL,
+    revision            int NOT NULL,
+    title               varchar(200) NOT NULL,
+    body                text NOT NULL,
+    visibility          e_journal_visibility NOT NULL,
+    edited_by           varchar(25),
+    edited_at           timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (entry_id, revision),
+    FOREIGN KEY (entry_id) REFERENCES JournalEntry(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (edited_by) REFERENCES Player(username)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+);
+
+CREATE TYPE e_npc_kind AS ENUM (
+    'npc',
+    'monster'
+);
+
+-- Non-player characters and monsters kept by a campaign's dungeon
+-- master. Hidden NPCs and `dm_notes` are never shown to players.
+CREATE TABLE CampaignNPC (
+    id                  serial PRIMARY KEY,
+    campaign_id         int NOT NULL,
+    name                varchar(100) CHECK (length(name) > 0) NOT NULL,
+    kind                e_npc_kind NOT NULL DEFAULT 'npc',
+    size                varchar(20) NOT NULL DEFAULT 'Medium',
+    creature_type       varchar(50) NOT NULL DEFAULT '',
+    alignment           varchar(50) NOT NULL DEFAULT '',
+    description         text NOT NULL DEFAULT '',
+    armor_class         int NOT NULL CHECK (armor_class > 0 AND armor_class <= 30),
+    hp_max              int NOT NULL CHECK (hp_max > 0 AND hp_max <= 1000),
+    hit_dice            varchar(20) NOT NULL DEFAULT '',
+    speed               int NOT NULL CHECK (speed >= 0 AND speed <= 1280),
+    challenge_rating    varchar(5) NOT NULL DEFAULT '0',
+    strength            int NOT NULL CHECK (strength > 0 AND strength <= 30),
+    dexterity           int NOT NULL CHECK (dexterity > 0 AND dexterity <= 30),
+    constitution        int NOT NULL CHECK (constitution > 0 AND constitution <= 30),
+    intelligence        int NOT NULL CHECK (intelligence > 0 AND intelligence <= 30),
+    wisdom              int NOT NULL CHECK (wisdom > 0 AND wisdom <= 30),
+    charisma            int NOT NULL CHECK (charisma > 0 AND charisma <= 30),
+    dm_notes            text NOT NULL DEFAULT '',
+    hidden              bool NOT NULL DEFAULT false,
+    compendium_slug     varchar(50), -- Set when copied from the monster compendium
+    created_at          timestamptz NOT NULL DEFAULT now(),
+    updated_at          timestamptz NOT NULL DEFAULT now(),
+    FOREIGN KEY (campaign_id) REFERENCES Campaign(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+-- The actions and traits of an NPC's stat block, in order.
+CREATE TABLE CampaignNPCAction (
+    npc_id              int NOT NULL,
+    position            int NOT NULL CHECK (position >= 0),
+    name                varchar(100) CHECK (length(name) > 0) NOT NULL,
+    description         text NOT NULL DEFAULT '',
+    PRIMARY KEY (npc_id, position),
+    FOREIGN KEY (npc_id) REFERENCES CampaignNPC(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE TABLE CampaignNPCTag (
+    npc_id              int NOT NULL,
+    tag                 varchar(30) CHECK (length(tag) > 0) NOT NULL,
+    PRIMARY KEY (npc_id, tag),
+    FOREIGN KEY (npc_id) REFERENCES CampaignNPC(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+-- Encounters planned by the dungeon master of a campaign.
+CREATE TABLE Encounter (
+    id                  serial PRIMARY KEY,
+    campaign_id         int NOT NULL,
+    name                varchar(100) CHECK (length(name) > 0) NOT NULL,
+    notes               text NOT NULL DEFAULT '',
+    created_by          varchar(25),
+    created_at          timestamptz NOT NULL DEFAULT now(),
+    updated_at          timestamptz NOT NULL DEFAULT now(),
+    FOREIGN KEY (campaign_id) REFERENCES Campaign(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES Player(username)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+);
+
+-- The monsters of an encounter. The name and challenge rating are copied
+-- so that the encounter can still be rated after its NPC is deleted.
+CREATE TABLE EncounterMonster (
+    encounter_id        int NOT NULL,
+    position            int NOT NULL CHECK (position >= 0),
+    npc_id              int,
+    compendium_slug     varchar(50),
+    name                varchar(100) CHECK (length(name) > 0) NOT NULL,
+    challenge_rating    varchar(5) NOT NULL,
+    quantity            int NOT NULL CHECK (quantity > 0 AND quantity <= 100),
+    PRIMARY KEY (encounter_id, position),
+    FOREIGN KEY (encounter_id) REFERENCES Encounter(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (npc_id) REFERENCES CampaignNPC(id)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+);
+
+CREATE TYPE e_condition AS ENUM (
+    'blinded',
+    'charmed',
+    'deafened',
+    'exhaustion',
+    'frightened',
+    'grappled',
+    'incapacitated',
+    'invisible',
+    'paralyzed',
+    'petrified',
+    'poisoned',
+    'prone',
+    'restrained',
+    'stunned',
+    'unconscious'
+);
+
+-- A fight tracked round by round. A campaign has at most one combat which
+-- has not ended. `version` increases with every change so that clients
+-- polling the combat can tell whether anything happened.
+CREATE TABLE Combat (
+    id                      serial PRIMARY KEY,
+    campaign_id             int NOT NULL,
+    encounter_id            int,
+    name                    varchar(100) NOT NULL DEFAULT '',
+    round                   int NOT NULL DEFAULT 0 CHECK (round >= 0), -- 0 until the first turn
+    current_combatant_id    int,
+    version                 int NOT NULL DEFAULT 1,
+    started_by              varchar(25),
+    started_at              timestamptz NOT NULL DEFAULT now(),
+    updated_at              timestamptz NOT NULL DEFAULT now(),
+    ended_at                timestamptz,
+    FOREIGN KEY (campaign_id) REFERENCES Campaign(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (encounter_id) REFERENCES Encounter(id)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE,
+    FOREIGN KEY (started_by) REFERENCES Player(username)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+);
+
+CREATE UNIQUE INDEX combat_one_active_per_campaign ON Combat (campaign_id) WHERE ended_at IS NULL;
+
+-- A character or NPC taking part in a combat. Hit points are tracked per
+-- combatant, so several copies of the same NPC can fight at once.
+CREATE TABLE Combatant (
+    id                  serial PRIMARY KEY,
+    combat_id           int NOT NULL,
+    character_id        int,
+    npc_id              int,
+    name                varchar(100) CHECK (length(name) > 0) NOT NULL,
+    initiative          int,
+    initiative_bonus    int NOT NULL DEFAULT 0,
+    armor_class         int NOT NULL CHECK (armor_class >= 0),
+    hp_max              int NOT NULL CHECK (hp_max > 0),
+    hp_current          int NOT NULL CHECK (hp_current >= 0),
+    temp_hp             int NOT NULL DEFAULT 0 CHECK (temp_hp >= 0),
+    defeated            bool NOT NULL DEFAULT false,
+    created_at          timestamptz NOT NULL DEFAULT now(),
+    FOREIGN KEY (combat_id) REFERENCES Combat(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (character_id) REFERENCES Character(id)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE,
+    FOREIGN KEY (npc_id) REFERENCES CampaignNPC(id)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+);
+
+CREATE TYPE e_rest AS ENUM (
+    'short',
+    'long'
+);
+
+-- Conditions of combatants which are not characters. Those of characters
+-- are kept in CharacterCondition so that they outlast the combat.
+-- `rounds_remaining` is counted down as each round starts, and conditions
+-- lasting until a short rest also end with a long rest.
+CREATE TABLE CombatantCondition (
+    combatant_id        int NOT NULL,
+    condition           e_condition NOT NULL,
+    level               int CHECK (level >= 1 AND level <= 6), -- Exhaustion only
+    source              varchar(100) NOT NULL DEFAULT '',
+    rounds_remaining    int CHECK (rounds_remaining > 0),
+    until_rest          e_rest,
+    applied_at          timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (combatant_id, condition),
+    CHECK ((condition = 'exhaustion') = (level IS NOT NULL)),
+    FOREIGN KEY (combatant_id) REFERENCES Combatant(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE TABLE CharacterCondition (
+    character_id        int NOT NULL,
+    condition           e_condition NOT NULL,
+    level               int CHECK (level >= 1 AND level <= 6), -- Exhaustion only
+    source              varchar(100) NOT NULL DEFAULT '',
+    rounds_remaining    int CHECK (rounds_remaining > 0),
+    until_rest          e_rest,
+    applied_at          timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (character_id, condition),
+    CHECK ((condition = 'exhaustion') = (level IS NOT NULL)),
+    FOREIGN KEY (character_id) REFERENCES Character(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE TYPE e_ability AS ENUM (
+    'strength',
+    'dexterity',
+    'constitution',
+    'intelligence',
+    'wisdom',
+    'charisma'
+);
+
+CREATE TYPE e_roll_mode AS ENUM (
+    'normal',
+    'advantage',
+    'disadvantage'
+);
+
+-- A roll made by the server and shown to everyone in the campaign, so that
+-- results cannot be fudged. `terms` holds every die rolled.
+CREATE TABLE DiceRoll (
+    id                  serial PRIMARY KEY,
+    campaign_id         int NOT NULL,
+    rolled_by           varchar(25),
+    character_id        int,
+    label               varchar(100) NOT NULL DEFAULT '',
+    expression          varchar(200) NOT NULL,
+    mode                e_roll_mode NOT NULL DEFAULT 'normal',
+    ability             e_ability,
+    modifier            int NOT NULL DEFAULT 0,
+    terms               jsonb NOT NULL,
+    total               int NOT NULL,
+    rolled_at           timestamptz NOT NULL DEFAULT now(),
+    FOREIGN KEY (campaign_id) REFERENCES Campaign(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (rolled_by) REFERENCES Player(username)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE,
+    FOREIGN KEY (character_id) REFERENCES Character(id)
+        ON DELETE SET NULL
+        ON UPDATE CASCADE
+);
+
+CREATE INDEX diceroll_campaign_idx ON DiceRoll (campaign_id, rolled_at DESC);
+
+CREATE TYPE e_registry_kind AS ENUM (
+    'race',
+    'class',
+    'subclass'
+);
+
+-- Races, classes and subclasses which characters can choose from. Entries
+-- with neither an owner nor a campaign are the official ones. Homebrew
+-- entries either belong to a player or are shared with everyone taking
+-- part in a campaign. Characters refer to entries by name.
+CREATE TABLE RegistryEntry (
+    id                  serial PRIMARY KEY,
+    kind                e_registry_kind NOT NULL,
+    name                varchar(50) CHECK (length(name) > 0) NOT NULL,
+    description         text NOT NULL DEFAULT '',
+    class_id            int, -- Class of a subclass
+    hit_die             int CHECK (hit_die IN (6, 8, 10, 12)), -- Of a class
+    ability_bonuses     jsonb NOT NULL DEFAULT '{}', -- Of a race, such as {"strength": 2}
+    ability_bonus_choices int CHECK (ability_bonus_choices >= 0 AND ability_bonus_choices <= 3), -- Of a race, free +1 increases
+    owner_username      varchar(25),
+    campaign_id         int,
+    created_at          timestamptz NOT NULL DEFAULT now(),
+    updated_at          timestamptz NOT NULL DEFAULT now(),
+    CHECK ((kind = 'subclass') = (class_id IS NOT NULL)),
+    CHECK ((kind = 'class') = (hit_die IS NOT NULL)),
+    CHECK (kind = 'race' OR ability_bonuses = '{}'),
+    CHECK ((kind = 'race') = (ability_bonus_choices IS NOT NULL)),
+    CHECK (owner_username IS NULL OR campaign_id IS NULL),
+    FOREIGN KEY (class_id) REFERENCES RegistryEntry(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (owner_username) REFERENCES Player(username)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (campaign_id) REFERENCES Campaign(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
+CREATE UNIQUE INDEX registryentry_name_idx ON RegistryEntry
+    (kind, COALESCE(class_id, 0), lower(name), COALESCE(owner_username, ''), COALESCE(campaign_id, 0));
+
+INSERT INTO RegistryEntry (kind, name, ability_bonuses, ability_bonus_choices) VALUES
+    ('race', 'Dragonborn', '{"strength": 2, "charisma": 1}', 0),
+    ('race', 'Dwarf', '{"constitution": 2}', 0),
+    ('race', 'Elf', '{"dexterity": 2}', 0),
+    ('race', 'Gnome', '{"intelligence": 2}', 0),
+    ('race', 'Half-Elf', '{"charisma": 2}', 2),
+    ('race', 'Halfling', '{"dexterity": 2}', 0),
+    ('race', 'Half-Orc', '{"strength": 2, "constitution": 1}', 0),
+    ('race', 'Human', '{"strength": 1, "dexterity": 1, "constitution": 1, "intelligence": 1, "wisdom": 1, "charisma": 1}', 0),
+    ('race', 'Tiefling', '{"charisma": 2, "intelligence": 1}', 0);
+
+INSERT INTO RegistryEntry (kind, name, hit_die) VALUES
+    ('class', 'Barbarian', 12),
+    ('class', 'Bard', 8),
+    ('class', 'Cleric', 8),
+    ('class', 'Druid', 8),
+    ('class', 'Fighter', 10),
+    ('class', 'Monk', 8),
+    ('class', 'Paladin', 10),
+    ('class', 'Ranger', 10),
+    ('class', 'Rogue', 8),
+    ('class', 'Sorcerer', 6),
+    ('class', 'Warlock', 8),
+    ('class', 'Wizard', 6),
+    ('class', 'None', 8);
+
+INSERT INTO RegistryEntry (kind, name, class_id)
+SELECT 'subclass', s.name, c.id
+FROM (VALUES
+    ('Barbarian', 'Ancestral Guardian'),
+    ('Barbarian', 'Battlerager'),
+    ('Barbarian', 'Beast'),
+    ('Barbarian', 'Berserker'),
+    ('Barbarian', 'Storm Herald'),
+    ('Barbarian', 'Totem Warrior'),
+    ('Barbarian', 'Wild Magic'),
+    ('Barbarian', 'Zealot'),
+    ('Bard', 'Creation'),
+    ('Bard', 'Eloquence'),
+    ('Bard', 'Glamour'),
+    ('Bard', 'Lore'),
+    ('Bard', 'Swords'),
+    ('Bard', 'Valor'),
+    ('Bard', 'Whispers'),
+    ('Cleric', 'Arcana'),
+    ('Cleric', 'Death'),
+    ('Cleric', 'Forge'),
+    ('Cleric', 'Grave'),
+    ('Cleric', 'Knowledge'),
+    ('Cleric', 'Life'),
+    ('Cleric', 'Light'),
+    ('Cleric', 'Nature'),
+    ('Cleric', 'Order'),
+    ('Cleric', 'Peace'),
+    ('Cleric', 'Tempest'),
+    ('Cleric', 'Trickery'),
+    ('Cleric', 'Twilight'),
+    ('Cleric', 'War'),
+    ('Druid', 'Dreams'),
+    ('Druid', 'The Land'),
+    ('Druid', 'The Moon'),
+    ('Druid', 'The Shepherd'),
+    ('Druid', 'The Spores'),
+    ('Druid', 'The Stars'),
+    ('Druid', 'Wildfire'),
+    ('Fighter', 'Arcane Archer'),
+    ('Fighter', 'Banneret'),
+    ('Fighter', 'Battle Master'),
+    ('Fighter', 'Cavalier'),
+    ('Fighter', 'Champion'),
+    ('Fighter', 'Echo Knight'),
+    ('Fighter', 'Eldritch Knight'),
+    ('Fighter', 'Psi Warrior'),
+    ('Fighter', 'Rune Knight'),
+    ('Fighter', 'Samurai'),
+    ('Monk', 'Astral Self'),
+    ('Monk', 'Drunken Master'),
+    ('Monk', 'Four Elements'),
+    ('Monk', 'Kensei'),
+    ('Monk', 'Long Death'),
+    ('Monk', 'Mercy'),
+    ('Monk', 'Open Hand'),
+    ('Monk', 'Shadow'),
+    ('Monk', 'Sun Soul'),
+    ('Paladin', 'The Ancients'),
+    ('Paladin', 'Conquest'),
+    ('Paladin', 'The Crown'),
+    ('Paladin', 'Devotion'),
+    ('Paladin', 'Glory'),
+    ('Paladin', 'Redemption'),
+    ('Paladin', 'Vengeance'),
+    ('Paladin', 'The Watchers'),
+    ('Paladin', 'Oathbreaker'),
+    ('Ranger', 'Beast Master'),
+    ('Ranger', 'Fey Wanderer'),
+    ('Ranger', 'Gloom Stalker'),
+    ('Ranger', 'Horizon Walker'),
+    ('Ranger', 'Hunter'),
+    ('Ranger', 'Monster Slayer'),
+    ('Ranger', 'Swarmkeeper'),
+    ('Rogue', 'Arcane Trickster'),
+    ('Rogue', 'Assassin'),
+    ('Rogue', 'Inquisitive'),
+    ('Rogue', 'Mastermind'),
+    ('Rogue', 'Phantom'),
+    ('Rogue', 'Scout'),
+    ('Rogue', 'Soulknife'),
+    ('Rogue', 'Swashbuckler'),
+    ('Rogue', 'Thief'),
+    ('Sorcerer', 'Aberrant Mind'),
+    ('Sorcerer', 'Clockwork Soul'),
+    ('Sorcerer', 'Draconic Bloodline'),
+    ('Sorcerer', 'Divine Soul'),
+    ('Sorcerer', 'Shadow'),
+    ('Sorcerer', 'Storm'),
+    ('Sorcerer', 'Wild Magic'),
+    ('Warlock', 'Archfey'),
+    ('Warlock', 'Celestial'),
+    ('Warlock', 'Fathomless'),
+    ('Warlock', 'Fiend'),
+    ('Warlock', 'Genie'),
+    ('Warlock', 'Great Old One'),
+    ('Warlock', 'Hexblade'),
+    ('Warlock', 'Undying'),
+    ('Wizard', 'Abjuration'),
+    ('Wizard', 'Bladesinging'),
+    ('Wizard', 'Chronurgy'),
+    ('Wizard', 'Conjuration'),
+    ('Wizard', 'Divination'),
+    ('Wizard', 'Enchantment'),
+    ('Wizard', 'Evocation'),
+    ('Wizard', 'Graviturgy'),
+    ('Wizard', 'Illusion'),
+    ('Wizard', 'Necromancy'),
+    ('Wizard', 'Order of Scribes'),
+    ('Wizard', 'Transmutation'),
+    ('Wizard', 'War Magic'),
+    ('None', 'None')
+) AS s (class, name)
+INNER JOIN RegistryEntry AS c
+ON c.kind = 'class' AND c.name = s.class;
+
+CREATE TYPE e_feature_source AS ENUM (
+    'race',
+    'class',
+    'subclass',
+    'feat'
+);
+
+-- Racial traits, class and subclass features, and feats. Characters are
+-- granted the features of their race, classes and subclasses once they
+-- reach `level`, which is the level of the character for racial traits
+-- and the level in the class otherwise. Feats are taken by choice.
+-- Limited features can be used `uses_by_level` times, as of the highest
+-- listed level reached, plus the modifier of `uses_ability`, and regain
+-- their uses with a `recharge` rest.
+CREATE TABLE Feature (
+    id                  serial PRIMARY KEY,
+    source              e_feature_source NOT NULL,
+    race                varchar(50), -- Name of a RegistryEntry
+    class               varchar(50), -- Name of a RegistryEntry
+    subclass            varchar(50), -- Name of a RegistryEntry
+    level               int NOT NULL DEFAULT 1 CHECK (level >= 1 AND level <= 20),
+    name                varchar(100) CHECK (length(name) > 0) NOT NULL,
+    description         text NOT NULL DEFAULT '',
+    uses_by_level       jsonb NOT NULL DEFAULT '{}', -- Such as {"1": 2, "3": 3}
+    uses_ability        e_ability,
+    recharge            e_rest, -- Unlimited features have none
+    CHECK ((source = 'race') = (race IS NOT NULL)),
+    CHECK ((source IN ('class', 'subclass')) = (class IS NOT NULL)),
+    CHECK ((source = 'subclass') = (subclass IS NOT NULL)),
+    CHECK (source <> 'feat' OR level = 1),
+    CHECK ((recharge IS NULL) = (uses_by_level = '{}' AND uses_ability IS NULL))
+);
+
+CREATE UNIQUE INDEX feature_name_idx ON Feature
+    (source, COALESCE(race, ''), COALESCE(class, ''), COALESCE(subclass, ''), lower(name));
+
+-- Taken from the System Reference Document 5.1 and the Player's Handbook
+INSERT INTO Feature (source, race, level, name, description, uses_by_level, uses_ability, recharge) VALUES
+    ('race', 'Dragonborn', 1, 'Draconic Ancestry', 'Choose a type of dragon, which sets the damage type of your breath weapon and damage resistance.', '{}', NULL, NULL),
+    ('race', 'Dragonborn', 1, 'Breath Weapon', 'Exhale destructive energy in an area set by your draconic ancestry. Creatures in the area make a saving throw against 2d6 damage, increasing with level.', '{"1": 1}', NULL, 'short'),
+    ('race', 'Dragonborn', 1, 'Damage Resistance', 'Resistance to the damage type of your draconic ancestry.', '{}', NULL, NULL),
+    ('race', 'Dwarf', 1, 'Darkvision', 'See in dim light within 60 feet as if it were bright light, and in darkness as if it were dim light.', '{}', NULL, NULL),
+    ('race', 'Dwarf', 1, 'Dwarven Resilience', 'Advantage on saving throws against poison, and resistance against poison damage.', '{}', NULL, NULL),
+    ('race', 'Dwarf', 1, 'Dwarven Combat Training', 'Proficiency with the battleaxe, handaxe, light hammer and warhammer.', '{}', NULL, NULL),
+    ('race', 'Dwarf', 1, 'Stonecunning', 'Double proficiency bonus on History checks related to the origin of stonework.', '{}', NULL, NULL),
+    ('race', 'Elf', 1, 'Darkvision', 'See in dim light within 60 feet as if it were bright light, and in darkness as if it were dim light.', '{}', NULL, NULL),
+    ('race', 'Elf', 1, 'Keen Senses', 'Proficiency in the Perception skill.', '{}', NULL, NULL),
+    ('race', 'Elf', 1, 'Fey Ancestry', 'Advantage on saving throws against being charmed, and magic cannot put you to sleep.', '{}', NULL, NULL),
+    ('race', 'Elf', 1, 'Trance', 'Meditate deeply for 4 hours instead of sleeping to gain the benefit of a long rest.', '{}', NULL, NULL),
+    ('race', 'Gnome', 1, 'Darkvision', 'See in dim light within 60 feet as if it were bright light, and in darkness as if it were dim light.', '{}', NULL, NULL),
+    ('race', 'Gnome', 1, 'Gnome Cunning', 'Advantage on Intelligence, Wisdom and Charisma saving throws against magic.', '{}', NULL, NULL),
+    ('race', 'Half-Elf', 1, 'Darkvision', 'See in dim light within 60 feet as if it were bright light, and in darkness as if it were dim light.', '{}', NULL, NULL),
+    ('race', 'Half-Elf', 1, 'Fey Ancestry', 'Advantage on saving throws against being charmed, and magic cannot put you to sleep.', '{}', NULL, NULL),
+    ('race', 'Half-Elf', 1, 'Skill Versatility', 'Proficiency in two skills of your choice.', '{}', NULL, NULL),
+    ('race', 'Halfling', 1, 'Lucky', 'When you roll a 1 on an attack roll, ability check or saving throw, reroll the die and use the new roll.', '{}', NULL, NULL),
+    ('race', 'Halfling', 1, 'Brave', 'Advantage on saving throws against being frightened.', '{}', NULL, NULL),
+    ('race', 'Halfling', 1, 'Halfling Nimbleness', 'Move through the space of any creature that is of a size larger than yours.', '{}', NULL, NULL),
+    ('race', 'Half-Orc', 1, 'Darkvision', 'See in dim light within 60 feet as if it were bright light, and in darkness as if it were dim light.', '{}', NULL, NULL),
+    ('race', 'Half-Orc', 1, 'Menacing', 'Proficiency in the Intimidation skill.', '{}', NULL, NULL),
+    ('race', 'Half-Orc', 1, 'Relentless Endurance', 'When reduced to 0 hit points but not killed outright, drop to 1 hit point instead.', '{"1": 1}', NULL, 'long'),
+    ('race', 'Half-Orc', 1, 'Savage Attacks', 'Roll one of the weapon''s damage dice an additional time on a melee critical hit.', '{}', NULL, NULL),
+    ('race', 'Tiefling', 1, 'Darkvision', 'See in dim light within 60 feet as if it were bright light, and in darkness as if it were dim light.', '{}', NULL, NULL),
+    ('race', 'Tiefling', 1, 'Hellish Resistance', 'Resistance to fire damage.', '{}', NULL, NULL),
+    ('race', 'Tiefling', 1, 'Infernal Legacy', 'Know the thaumaturgy cantrip, and later cast hellish rebuke and darkness once each per long rest.', '{}', NULL, NULL);
+
+INSERT INTO Feature (source, class, level, name, description, uses_by_level, uses_ability, recharge) VALUES
+    ('class', 'Barbarian', 1, 'Rage', 'In battle, fight with primal ferocity for 1 minute: advantage on Strength checks and saving throws, bonus melee damage and resistance to physical damage.', '{"1": 2, "3": 3, "6": 4, "12": 5, "17": 6}', NULL, 'long'),
+    ('class', 'Barbarian', 1, 'Unarmored Defense', 'Without armor, your Armor Class equals 10 + your Dexterity modifier + your Constitution modifier.', '{}', NULL, NULL),
+    ('class', 'Barbarian', 2, 'Reckless Attack', 'Gain advantage on Strength melee attacks this turn, but attacks against you have advantage until your next turn.', '{}', NULL, NULL),
+    ('class', 'Barbarian', 2, 'Danger Sense', 'Advantage on Dexterity saving throws against effects that you can see.', '{}', NULL, NULL),
+    ('class', 'Barbarian', 5, 'Extra Attack', 'Attack twice, instead of once, whenever you take the Attack action on your turn.', '{}', NULL, NULL),
+    ('class', 'Barbarian', 5, 'Fast Movement', 'Your speed increases by 10 feet while you are not wearing heavy armor.', '{}', NULL, NULL),
+    ('class', 'Barbarian', 7, 'Feral Instinct', 'Advantage on initiative rolls, and act normally on your first turn when surprised if you rage.', '{}', NULL, NULL),
+    ('class', 'Barbarian', 9, 'Brutal Critical', 'Roll one additional weapon damage die on a critical hit with a melee attack.', '{}', NULL, NULL),
+    ('class', 'Barbarian', 11, 'Relentless Rage', 'While raging, a DC 10 Constitution saving throw drops you to 1 hit point instead of 0.', '{}', NULL, NULL),
+    ('class', 'Barbarian', 15, 'Persistent Rage', 'Your rage only ends early if you fall unconscious or choose to end it.', '{}', NULL, NULL),
+    ('class', 'Barbarian', 18, 'Indomitable Might', 'Use your Strength score in place of a lower total on Strength checks.', '{}', NULL, NULL),
+    ('class', 'Barbarian', 20, 'Primal Champion', 'Your Strength and Constitution scores increase by 4, to a maximum of 24.', '{}', NULL, NULL),
+    ('class', 'Bard', 1, 'Bardic Inspiration', 'As a bonus action, give a creature within 60 feet a Bardic Inspiration die to add to one ability check, attack roll or saving throw.', '{}', 'charisma', 'long'),
+    ('class', 'Bard', 2, 'Jack of All Trades', 'Add half your proficiency bonus to any ability check that does not already include it.', '{}', NULL, NULL),
+    ('class', 'Bard', 2, 'Song of Rest', 'Creatures that spend Hit Dice during a short rest while hearing you perform regain extra hit points.', '{}', NULL, NULL),
+    ('class', 'Bard', 3, 'Expertise', 'Double your proficiency bonus for two skills of your choice.', '{}', NULL, NULL),
+    ('class', 'Bard', 5, 'Font of Inspiration', 'Regain all expended uses of Bardic Inspiration after a short or long rest.', '{}', NULL, NULL),
+    ('class', 'Bard', 6, 'Countercharm', 'Give friendly creatures within 30 feet advantage on saving throws against being frightened or charmed.', '{}', NULL, NULL),
+    ('class', 'Bard', 10, 'Magical Secrets', 'Learn two spells of your choice from any class.', '{}', NULL, NULL),
+    ('class', 'Bard', 20, 'Superior Inspiration', 'Regain one use of Bardic Inspiration when you roll initiative with none left.', '{}', NULL, NULL),
+    ('class', 'Cleric', 2, 'Channel Divinity', 'Channel divine energy directly from your deity to fuel magical effects, such as turning undead.', '{"2": 1, "6": 2, "18": 3}', NULL, 'short'),
+    ('class', 'Cleric', 5, 'Destroy Undead', 'Undead of a low enough challenge rating that fail against Turn Undead are destroyed.', '{}', NULL, NULL),
+    ('class', 'Cleric', 10, 'Divine Intervention', 'Call on your deity to intervene on your behalf; succeed if a d100 roll is no higher than your cleric level.', '{"10": 1}', NULL, 'long'),
+    ('class', 'Druid', 1, 'Druidic', 'Know Druidic, the secret language of druids.', '{}', NULL, NULL),
+    ('class', 'Druid', 2, 'Wild Shape', 'Magically assume the shape of a beast that you have seen before.', '{"2": 2}', NULL, 'short'),
+    ('class', 'Druid', 18, 'Timeless Body', 'Age only 1 year for every 10 years that pass.', '{}', NULL, NULL),
+    ('class', 'Druid', 18, 'Beast Spells', 'Cast many of your druid spells in any shape you assume using Wild Shape.', '{}', NULL, NULL),
+    ('class', 'Druid', 20, 'Archdruid', 'Use Wild Shape an unlimited number of times, and ignore the components of druid spells.', '{}', NULL, NULL),
+    ('class', 'Fighter', 1, 'Fighting Style', 'Adopt a particular style of fighting as your specialty.', '{}', NULL, NULL),
+    ('class', 'Fighter', 1, 'Second Wind', 'As a bonus action, regain 1d10 + your fighter level hit points.', '{"1": 1}', NULL, 'short'),
+    ('class', 'Fighter', 2, 'Action Surge', 'Take one additional action on your turn.', '{"2": 1, "17": 2}', NULL, 'short'),
+    ('class', 'Fighter', 5, 'Extra Attack', 'Attack twice, instead of once, whenever you take the Attack action on your turn. The number of attacks increases to three at 11th level and four at 20th level.', '{}', NULL, NULL),
+    ('class', 'Fighter', 9, 'Indomitable', 'Reroll a saving throw that you fail, and use the new roll.', '{"9": 1, "13": 2, "17": 3}', NULL, 'long'),
+    ('class', 'Monk', 1, 'Unarmored Defense', 'Without armor or a shield, your Armor Class equals 10 + your Dexterity modifier + your Wisdom modifier.', '{}', NULL, NULL),
+    ('class', 'Monk', 1, 'Martial Arts', 'Use Dexterity for unarmed strikes and monk weapons, roll a Martial Arts die for their damage and make an unarmed strike as a bonus action.', '{}', NULL, NULL),
+    ('class', 'Monk', 2, 'Ki', 'Spend ki points to fuel Flurry of Blows, Patient Defense and Step of the Wind.', '{"2": 2, "3": 3, "4": 4, "5": 5, "6": 6, "7": 7, "8": 8, "9": 9, "10": 10, "11": 11, "12": 12, "13": 13, "14": 14, "15": 15, "16": 16, "17": 17, "18": 18, "19": 19, "20": 20}', NULL, 'short'),
+    ('class', 'Monk', 2, 'Unarmored Movement', 'Your speed increases by 10 feet while you are not wearing armor or wielding a shield, rising with your monk level.', '{}', NULL, NULL),
+    ('class', 'Monk', 3, 'Deflect Missiles', 'Use your reaction to reduce the damage of a ranged weapon attack, and catch the missile if it drops to 0.', '{}', NULL, NULL),
+    ('class', 'Monk', 4, 'Slow Fall', 'Use your reaction to reduce falling damage by five times your monk level.', '{}', NULL, NULL),
+    ('class', 'Monk', 5, 'Extra Attack', 'Attack twice, instead of once, whenever you take the Attack action on your turn.', '{}', NULL, NULL),
+    ('class', 'Monk', 5, 'Stunning Strike', 'Spend 1 ki point when you hit with a melee weapon attack to try to stun the target.', '{}', NULL, NULL),
+    ('class', 'Monk', 6, 'Ki-Empowered Strikes', 'Your unarmed strikes count as magical.', '{}', NULL, NULL),
+    ('class', 'Monk', 7, 'Evasion', 'Take no damage when you succeed on a Dexterity saving throw for half damage, and only half when you fail.', '{}', NULL, NULL),
+    ('class', 'Monk', 7, 'Stillness of Mind', 'Use your action to end one effect on yourself that is causing you to be charmed or frightened.', '{}', NULL, NULL),
+    ('class', 'Monk', 10, 'Purity of Body', 'Immunity to disease and poison.', '{}', NULL, NULL),
+    ('class', 'Monk', 14, 'Diamond Soul', 'Proficiency in all saving throws, and spend 1 ki point to reroll a failed one.', '{}', NULL, NULL),
+    ('class', 'Monk', 15, 'Timeless Body', 'Suffer none of the frailty of old age, and no longer need food or water.', '{}', NULL, NULL),
+    ('class', 'Monk', 18, 'Empty Body', 'Spend ki points to become invisible, or to cast astral projection.', '{}', NULL, NULL),
+    ('class', 'Monk', 20, 'Perfect Self', 'Regain 4 ki points when you roll initiative with none left.', '{}', NULL, NULL),
+    ('class', 'Paladin', 1, 'Divine Sense', 'Know the location of any celestial, fiend or undead within 60 feet until the end of your next turn.', '{"1": 1}', 'charisma', 'long'),
+    ('class', 'Paladin', 1, 'Lay on Hands', 'Restore hit points from a pool of healing power, or spend 5 of them to cure a disease or neutralize a poison.', '{"1": 5, "2": 10, "3": 15, "4": 20, "5": 25, "6": 30, "7": 35, "8": 40, "9": 45, "10": 50, "11": 55, "12": 60, "13": 65, "14": 70, "15": 75, "16": 80, "17": 85, "18": 90, "19": 95, "20": 100}', NULL, 'long'),
+    ('class', 'Paladin', 2, 'Fighting Style', 'Adopt a particular style of fighting as your specialty.', '{}', NULL, NULL),
+    ('class', 'Paladin', 2, 'Divine Smite', 'Expend a spell slot when you hit with a melee weapon attack to deal extra radiant damage.', '{}', NULL, NULL),
+    ('class', 'Paladin', 3, 'Divine Health', 'Immunity to disease.', '{}', NULL, NULL),
+    ('class', 'Paladin', 3, 'Channel Divinity', 'Channel divine energy to fuel the magical effects of your oath.', '{"3": 1}', NULL, 'short'),
+    ('class', 'Paladin', 5, 'Extra Attack', 'Attack twice, instead of once, whenever you take the Attack action on your turn.', '{}', NULL, NULL),
+    ('class', 'Paladin', 6, 'Aura of Protection', 'You and friendly creatures within 10 feet add your Charisma modifier to saving throws.', '{}', NULL, NULL),
+    ('class', 'Paladin', 10, 'Aura of Courage', 'You and friendly creatures within 10 feet cannot be frightened while you are conscious.', '{}', NULL, NULL),
+    ('class', 'Paladin', 11, 'Improved Divine Smite', 'Your melee weapon hits deal an extra 1d8 radiant damage.', '{}', NULL, NULL),
+    ('class', 'Paladin', 14, 'Cleansing Touch', 'Use your action to end one spell on yourself or on a willing creature that you touch.', '{}', 'charisma', 'long'),
+    ('class', 'Ranger', 1, 'Favored Enemy', 'Advantage on Survival checks to track, and Intelligence checks to recall information about, a chosen type of enemy.', '{}', NULL, NULL),
+    ('class', 'Ranger', 1, 'Natural Explorer', 'Expertise in a chosen type of terrain, where travel is easier and you remain alert to danger.', '{}', NULL, NULL),
+    ('class', 'Ranger', 2, 'Fighting Style', 'Adopt a particular style of fighting as your specialty.', '{}', NULL, NULL),
+    ('class', 'Ranger', 3, 'Primeval Awareness', 'Expend a spell slot to sense whether certain types of creatures are nearby.', '{}', NULL, NULL),
+    ('class', 'Ranger', 5, 'Extra Attack', 'Attack twice, instead of once, whenever you take the Attack action on your turn.', '{}', NULL, NULL),
+    ('class', 'Ranger', 8, 'Land''s Stride', 'Moving through nonmagical difficult terrain costs no extra movement, and you have advantage against magical plants.', '{}', NULL, NULL),
+    ('class', 'Ranger', 10, 'Hide in Plain Sight', 'Spend 1 minute creating camouflage to gain +10 to Dexterity (Stealth) checks while you remain still.', '{}', NULL, NULL),
+    ('class', 'Ranger', 14, 'Vanish', 'Use the Hide action as a bonus action, and you cannot be tracked by nonmagical means.', '{}', NULL, NULL),
+    ('class', 'Ranger', 18, 'Feral Senses', 'Aware of the location of any invisible creature within 30 feet, and attack creatures you cannot see without disadvantage.', '{}', NULL, NULL),
+    ('class', 'Ranger', 20, 'Foe Slayer', 'Once on each of your turns, add your Wisdom modifier to an attack or damage roll against a favored enemy.', '{}', NULL, NULL),
+    ('class', 'Rogue', 1, 'Expertise', 'Double your proficiency bonus for two skill proficiencies of your choice, or one and thieves'' tools.', '{}', NULL, NULL),
+    ('class', 'Rogue', 1, 'Sneak Attack', 'Once per turn, deal extra damage to a creature you hit with advantage, or when another enemy of the target is within 5 feet of it.', '{}', NULL, NULL),
+    ('class', 'Rogue', 1, 'Thieves'' Cant', 'Know thieves'' cant, a secret mix of dialect, jargon and code.', '{}', NULL, NULL),
+    ('class', 'Rogue', 2, 'Cunning Action', 'Take the Dash, Disengage or Hide action as a bonus action.', '{}', NULL, NULL),
+    ('class', 'Rogue', 5, 'Uncanny Dodge', 'Use your reaction to halve the damage of an attack from an attacker that you can see.', '{}', NULL, NULL),
+    ('class', 'Rogue', 7, 'Evasion', 'Take no damage when you succeed on a Dexterity saving throw for half damage, and only half when you fail.', '{}', NULL, NULL),
+    ('class', 'Rogue', 11, 'Reliable Talent', 'Treat a d20 roll of 9 or lower as a 10 on ability checks using your proficiency bonus.', '{}', NULL, NULL),
+    ('class', 'Rogue', 14, 'Blindsense', 'Aware of the location of any hidden or invisible creature within 10 feet if you can hear.', '{}', NULL, NULL),
+    ('class', 'Rogue', 15, 'Slippery Mind', 'Proficiency in Wisdom saving throws.', '{}', NULL, NULL),
+    ('class', 'Rogue', 18, 'Elusive', 'No attack roll has advantage against you while you are not incapacitated.', '{}', NULL, NULL),
+    ('class', 'Rogue', 20, 'Stroke of Luck', 'Turn a missed attack into a hit, or treat a failed ability check as a 20.', '{"20": 1}', NULL, 'short'),
+    ('class', 'Sorcerer', 2, 'Font of Magic', 'Convert spell slots into sorcery points and sorcery points into spell slots.', '{}', NULL, NULL),
+    ('class', 'Sorcerer', 2, 'Sorcery Points', 'Spend sorcery points to create spell slots or fuel Metamagic.', '{"2": 2, "3": 3, "4": 4, "5": 5, "6": 6, "7": 7, "8": 8, "9": 9, "10": 10, "11": 11, "12": 12, "13": 13, "14": 14, "15": 15, "16": 16, "17": 17, "18": 18, "19": 19, "20": 20}', NULL, 'long'),
+    ('class', 'Sorcerer', 3, 'Metamagic', 'Twist your spells to suit your needs with two Metamagic options of your choice.', '{}', NULL, NULL),
+    ('class', 'Sorcerer', 20, 'Sorcerous Restoration', 'Regain 4 expended sorcery points whenever you finish a short rest.', '{}', NULL, NULL),
+    ('class', 'Warlock', 2, 'Eldritch Invocations', 'Learn fragments of forbidden knowledge that give an abiding magical ability.', '{}', NULL, NULL),
+    ('class', 'Warlock', 3, 'Pact Boon', 'Your otherworldly patron bestows a gift upon you: the Pact of the Chain, Blade or Tome.', '{}', NULL, NULL),
+    ('class', 'Warlock', 11, 'Mystic Arcanum', 'Choose a 6th-level spell as an arcanum and cast it without expending a spell slot, adding more at higher levels.', '{"11": 1, "13": 2, "15": 3, "17": 4}', NULL, 'long'),
+    ('class', 'Warlock', 20, 'Eldritch Master', 'Spend 1 minute entreating your patron to regain all your expended Pact Magic spell slots.', '{"20": 1}', NULL, 'long'),
+    ('class', 'Wizard', 1, 'Arcane Recovery', 'During a short rest, recover expended spell slots with a combined level of up to half your wizard level.', '{"1": 1}', NULL, 'long'),
+    ('class', 'Wizard', 18, 'Spell Mastery', 'Cast a chosen 1st-level and 2nd-level wizard spell at their lowest level without expending a spell slot.', '{}', NULL, NULL),
+    ('class', 'Wizard', 20, 'Signature Spells', 'Cast each of two chosen 3rd-level wizard spells once without expending a spell slot.', '{"20": 2}', NULL, 'short');
+
+INSERT INTO Feature (source, class, subclass, level, name, description, uses_by_level, uses_ability, recharge) VALUES
+    ('subclass', 'Barbarian', 'Berserker', 3, 'Frenzy', 'Make a single melee weapon attack as a bonus action on each turn of a frenzied rage, then suffer a level of exhaustion.', '{}', NULL, NULL),
+    ('subclass', 'Barbarian', 'Berserker', 6, 'Mindless Rage', 'You cannot be charmed or frightened while raging.', '{}', NULL, NULL),
+    ('subclass', 'Barbarian', 'Berserker', 10, 'Intimidating Presence', 'Use your action to frighten a creature within 30 feet that can see or hear you.', '{}', NULL, NULL),
+    ('subclass', 'Barbarian', 'Berserker', 14, 'Retaliation', 'Use your reaction to make a melee weapon attack against a creature within 5 feet that damages you.', '{}', NULL, NULL),
+    ('subclass', 'Bard', 'Lore', 3, 'Bonus Proficiencies', 'Proficiency with three skills of your choice.', '{}', NULL, NULL),
+    ('subclass', 'Bard', 'Lore', 3, 'Cutting Words', 'Use your reaction and a Bardic Inspiration die to reduce an attack roll, ability check or damage roll of a creature within 60 feet.', '{}', NULL, NULL),
+    ('subclass', 'Bard', 'Lore', 6, 'Additional Magical Secrets', 'Learn two spells of your choice from any class.', '{}', NULL, NULL),
+    ('subclass', 'Bard', 'Lore', 14, 'Peerless Skill', 'Expend a Bardic Inspiration die to add it to one of your own ability checks.', '{}', NULL, NULL),
+    ('subclass', 'Cleric', 'Life', 1, 'Disciple of Life', 'Healing spells of 1st level or higher restore an additional 2 + the spell''s level hit points.', '{}', NULL, NULL),
+    ('subclass', 'Cleric', 'Life', 2, 'Channel Divinity: Preserve Life', 'Restore a number of hit points equal to five times your cleric level, divided among creatures within 30 feet.', '{}', NULL, NULL),
+    ('subclass', 'Cleric', 'Life', 6, 'Blessed Healer', 'Regain 2 + the spell''s level hit points when you heal another creature with a spell of 1st level or higher.', '{}', NULL, NULL),
+    ('subclass', 'Cleric', 'Life', 8, 'Divine Strike', 'Once on each of your turns, deal an extra 1d8 radiant damage with a weapon attack.', '{}', NULL, NULL),
+    ('subclass', 'Cleric', 'Life', 17, 'Supreme Healing', 'Use the highest number possible for each die instead of rolling when restoring hit points with a spell.', '{}', NULL, NULL),
+    ('subclass', 'Druid', 'The Land', 2, 'Bonus Cantrip', 'Learn one additional druid cantrip of your choice.', '{}', NULL, NULL),
+    ('subclass', 'Druid', 'The Land', 2, 'Natural Recovery', 'During a short rest, recover expended spell slots with a combined level of up to half your druid level.', '{"2": 1}', NULL, 'long'),
+    ('subclass', 'Druid', 'The Land', 6, 'Land''s Stride', 'Moving through nonmagical difficult terrain costs no extra movement, and you have advantage against magical plants.', '{}', NULL, NULL),
+    ('subclass', 'Druid', 'The Land', 10, 'Nature''s Ward', 'You cannot be charmed or frightened by elementals or fey, and are immune to poison and disease.', '{}', NULL, NULL),
+    ('subclass', 'Druid', 'The Land', 14, 'Nature''s Sanctuary', 'Beasts and plants must make a Wisdom saving throw to attack you.', '{}', NULL, NULL),
+    ('subclass', 'Fighter', 'Champion', 3, 'Improved Critical', 'Your weapon attacks score a critical hit on a roll of 19 or 20.', '{}', NULL, NULL),
+    ('subclass', 'Fighter', 'Champion', 7, 'Remarkable Athlete', 'Add half your proficiency bonus to Strength, Dexterity and Constitution checks that do not already use it.', '{}', NULL, NULL),
+    ('subclass', 'Fighter', 'Champion', 10, 'Additional Fighting Style', 'Choose a second option from the Fighting Style class feature.', '{}', NULL, NULL),
+    ('subclass', 'Fighter', 'Champion', 15, 'Superior Critical', 'Your weapon attacks score a critical hit on a roll of 18 to 20.', '{}', NULL, NULL),
+    ('subclass', 'Fighter', 'Champion', 18, 'Survivor', 'At the start of each of your turns, regain hit points if you have no more than half of your hit points left.', '{}', NULL, NULL),
+    ('subclass', 'Monk', 'Open Hand', 3, 'Open Hand Technique', 'Knock a creature hit by Flurry of Blows prone, push it away or stop it from taking reactions.', '{}', NULL, NULL),
+    ('subclass', 'Monk', 'Open Hand', 6, 'Wholeness of Body', 'As an action, regain hit points equal to three times your monk level.', '{"6": 1}', NULL, 'long'),
+    ('subclass', 'Monk', 'Open Hand', 11, 'Tranquility', 'At the end of a long rest, gain the effect of a sanctuary spell that lasts until your next long rest.', '{}', NULL, NULL),
+    ('subclass', 'Monk', 'Open Hand', 17, 'Quivering Palm', 'Spend 3 ki points to set up vibrations in a creature you hit, which you can later end to reduce it to 0 hit points.', '{}', NULL, NULL),
+    ('subclass', 'Paladin', 'Devotion', 3, 'Sacred Weapon', 'Use your Channel Divinity to imbue a weapon with positive energy, adding your Charisma modifier to attack rolls.', '{}', NULL, NULL),
+    ('subclass', 'Paladin', 'Devotion', 7, 'Aura of Devotion', 'You and friendly creatures within 10 feet cannot be charmed while you are conscious.', '{}', NULL, NULL),
+    ('subclass', 'Paladin', 'Devotion', 15, 'Purity of Spirit', 'You are always under the effects of a protection from evil and good spell.', '{}', NULL, NULL),
+    ('subclass', 'Paladin', 'Devotion', 20, 'Holy Nimbus', 'Emanate an aura of sunlight for 1 minute that damages enemies and protects you from fiends and undead.', '{"20": 1}', NULL, 'long'),
+    ('subclass', 'Ranger', 'Hunter', 3, 'Hunter''s Prey', 'Choose Colossus Slayer, Giant Killer or Horde Breaker.', '{}', NULL, NULL),
+    ('subclass', 'Ranger', 'Hunter', 7, 'Defensive Tactics', 'Choose Escape the Horde, Multiattack Defense or Steel Will.', '{}', NULL, NULL),
+    ('subclass', 'Ranger', 'Hunter', 11, 'Multiattack', 'Choose Volley or Whirlwind Attack.', '{}', NULL, NULL),
+    ('subclass', 'Ranger', 'Hunter', 15, 'Superior Hunter''s Defense', 'Choose Evasion, Stand Against the Tide or Uncanny Dodge.', '{}', NULL, NULL),
+    ('subclass', 'Rogue', 'Thief', 3, 'Fast Hands', 'Use Cunning Action to make a Sleight of Hand check, use thieves'' tools or take the Use an Object action.', '{}', NULL, NULL),
+    ('subclass', 'Rogue', 'Thief', 3, 'Second-Story Work', 'Climbing costs no extra movement, and your running jumps go further.', '{}', NULL, NULL),
+    ('subclass', 'Rogue', 'Thief', 9, 'Supreme Sneak', 'Advantage on Dexterity (Stealth) checks if you move no more than half your speed on the same turn.', '{}', NULL, NULL),
+    ('subclass', 'Rogue', 'Thief', 13, 'Use Magic Device', 'Ignore all class, race and level requirements on the use of magic items.', '{}', NULL, NULL),
+    ('subclass', 'Rogue', 'Thief', 17, 'Thief''s Reflexes', 'Take two turns during the first round of any combat.', '{}', NULL, NULL),
+    ('subclass', 'Sorcerer', 'Draconic Bloodline', 1, 'Dragon Ancestor', 'Choose a type of dragon as your ancestor, and speak, read and write Draconic.', '{}', NULL, NULL),
+    ('subclass', 'Sorcerer', 'Draconic Bloodline', 1, 'Draconic Resilience', 'Your hit point maximum increases by 1 for each sorcerer level, and your Armor Class is 13 + your Dexterity modifier without armor.', '{}', NULL, NULL),
+    ('subclass', 'Sorcerer', 'Draconic Bloodline', 6, 'Elemental Affinity', 'Add your Charisma modifier to the damage of spells of your ancestor''s damage type.', '{}', NULL, NULL),
+    ('subclass', 'Sorcerer', 'Draconic Bloodline', 14, 'Dragon Wings', 'Sprout a pair of dragon wings, gaining a flying speed equal to your current speed.', '{}', NULL, NULL),
+    ('subclass', 'Sorcerer', 'Draconic Bloodline', 18, 'Draconic Presence', 'Spend 5 sorcery points to exude an aura of awe or fear.', '{}', NULL, NULL),
+    ('subclass', 'Warlock', 'Fiend', 1, 'Dark One''s Blessing', 'Gain temporary hit points when you reduce a hostile creature to 0 hit points.', '{}', NULL, NULL),
+    ('subclass', 'Warlock', 'Fiend', 6, 'Dark One''s Own Luck', 'Add a d10 to an ability check or saving throw.', '{"6": 1}', NULL, 'short'),
+    ('subclass', 'Warlock', 'Fiend', 10, 'Fiendish Resilience', 'Choose a damage type to gain resistance to after each short or long rest.', '{}', NULL, NULL),
+    ('subclass', 'Warlock', 'Fiend', 14, 'Hurl Through Hell', 'Send a creature you hit through the lower planes, dealing 10d10 psychic damage.', '{"14": 1}', NULL, 'long'),
+    ('subclass', 'Wizard', 'Evocation', 2, 'Evocation Savant', 'The gold and time you spend to copy an evocation spell into your spellbook is halved.', '{}', NULL, NULL),
+    ('subclass', 'Wizard', 'Evocation', 2, 'Sculpt Spells', 'Protect chosen creatures from the full force of your evocation spells.', '{}', NULL, NULL),
+    ('subclass', 'Wizard', 'Evocation', 6, 'Potent Cantrip', 'Creatures that succeed on a saving throw against your damaging cantrips still take half damage.', '{}', NULL, NULL),
+    ('subclass', 'Wizard', 'Evocation', 10, 'Empowered Evocation', 'Add your Intelligence modifier to one damage roll of any wizard evocation spell you cast.', '{}', NULL, NULL),
+    ('subclass', 'Wizard', 'Evocation', 14, 'Overchannel', 'Deal maximum damage with a wizard spell of 5th level or lower, at the cost of necrotic damage when used again before a long rest.', '{}', NULL, NULL);
+
+INSERT INTO Feature (source, name, description, uses_by_level, uses_ability, recharge) VALUES
+    ('feat', 'Alert', '+5 to initiative, you cannot be surprised while conscious, and hidden attackers gain no advantage against you.', '{}', NULL, NULL),
+    ('feat', 'Grappler', 'Advantage on attack rolls against a creature you are grappling, and you can try to pin it.', '{}', NULL, NULL),
+    ('feat', 'Great Weapon Master', 'Make a bonus melee attack after a critical hit or a kill, and take -5 to hit with heavy weapons for +10 damage.', '{}', NULL, NULL),
+    ('feat', 'Lucky', 'Spend a luck point to roll an additional d20 for an attack roll, ability check or saving throw, or for an attack against you.', '{"1": 3}', NULL, 'long'),
+    ('feat', 'Mobile', 'Your speed increases by 10 feet, and creatures you attack in melee cannot make opportunity attacks against you that turn.', '{}', NULL, NULL),
+    ('feat', 'Observant', '+1 to Intelligence or Wisdom, and +5 to passive Perception and passive Investigation.', '{}', NULL, NULL),
+    ('feat', 'Resilient', '+1 to an ability of your choice, and proficiency in its saving throws.', '{}', NULL, NULL),
+    ('feat', 'Sentinel', 'Creatures you hit with opportunity attacks stop moving, and you can attack creatures that attack your allies.', '{}', NULL, NULL),
+    ('feat', 'Sharpshooter', 'Ignore long range disadvantage and cover, and take -5 to hit with ranged weapons for +10 damage.', '{}', NULL, NULL),
+    ('feat', 'Skilled', 'Proficiency in any combination of three skills or tools.', '{}', NULL, NULL),
+    ('feat', 'Tough', 'Your hit point maximum increases by 2 for every level you have.', '{}', NULL, NULL),
+    ('feat', 'War Caster', 'Advantage on Constitution saving throws to keep concentration, and cast a spell as an opportunity attack.', '{}', NULL, NULL);
+
+-- The features a character has been granted, and the uses of limited
+-- ones spent since their last recharge.
+CREATE TABLE CharacterFeature (
+    character_id        int NOT NULL,
+    feature_id          int NOT NULL,
+    uses_spent          int NOT NULL DEFAULT 0 CHECK (uses_spent >= 0),
+    granted_at          timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (character_id, feature_id),
+    FOREIGN KEY (character_id) REFERENCES Character(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE,
+    FOREIGN KEY (feature_id) REFERENCES Feature(id)
+        ON DELETE CASCADE
+        ON UPDATE CASCADE
+);
+
 CREATE TABLE Stats (
     num_player_account int DEFAULT 0,
     num_character_created int DEFAULT 0,
@@ -245,3 +1275,42 @@ CREATE TRIGGER item_creation
 AFTER INSERT ON Items
 FOR EACH ROW
 EXECUTE PROCEDURE increment_item_count();
+
+-- Level which `xp` experience allows. Matches models.LevelForXP.
+CREATE FUNCTION level_for_xp(xp int) RETURNS int AS $_$
+SELECT greatest(count(*)::int, 1)
+FROM unnest(ARRAY[0, 300, 900, 2700, 6500, 14000, 23000, 34000, 48000, 64000,
+    85000, 100000, 120000, 140000, 165000, 195000, 225000, 265000, 305000, 355000]) AS threshold
+WHERE xp >= threshold
+$_$ LANGUAGE SQL IMMUTABLE;
+
+-- Give new characters all their levels in their first class
+CREATE FUNCTION insert_first_character_class() RETURNS trigger AS $_$
+BEGIN
+INSERT INTO CharacterClass (character_id, position, class, subclass, levels)
+VALUES (NEW.id, 0, NEW.class, NEW.class_attribute, NEW.level);
+RETURN NEW;
+END $_$ LANGUAGE 'plpgsql';
+
+CREATE TRIGGER character_first_class_creation
+AFTER INSERT ON Character
+FOR EACH ROW
+EXECUTE PROCEDURE insert_first_character_class();
+
+-- Keep the first class in step with the class and level of the character
+CREATE FUNCTION update_first_character_class() RETURNS trigger AS $_$
+BEGIN
+UPDATE CharacterClass
+SET class = NEW.class, subclass = NEW.class_attribute,
+    levels = NEW.level - (
+        SELECT COALESCE(sum(levels), 0)
+        FROM CharacterClass
+        WHERE character_id = NEW.id AND position > 0)
+WHERE character_id = NEW.id AND position = 0;
+RETURN NEW;
+END $_$ LANGUAGE 'plpgsql';
+
+CREATE TRIGGER character_first_class_update
+AFTER UPDATE OF class, class_attribute, level ON Character
+FOR EACH ROW
+EXECUTE PROCEDURE update_first_character_class();
@@ -30,7 +30,12 @@ INSERT INTO Player (username, password, name) VALUES
     'New User'
 );
 
-INSERT INTO Character VALUES
+UPDATE Player SET role = 'admin' WHERE username = 'kjerome';
+
+INSERT INTO Character (id, name, weight, height, alignment, sex, background, race,
+    speed, strength, dexterity, intelligence, wisdom, charisma, constitution,
+    hp_max, hp_current, ability_points, xp_points, level,
+    class, class_attribute, player_username) VALUES
 (
     DEFAULT,
     'Korgoth',
@@ -48,8 +53,10 @@ INSERT INTO Character VALUES
     10,
     14,
     14,
+    14,
     0,
     0,
+    1,
     'Barbarian',
     'Berserker',
     'kjerome'
@@ -71,8 +78,10 @@ INSERT INTO Character VALUES
     10,
     9,
     6,
+    6,
     0,
     300,
+    2,
     'Wizard',
     'Abjuration',
     'drowsell'
@@ -94,8 +103,10 @@ INSERT INTO Character VALUES
     18,
     10,
     10,
+    10,
     0,
     300,
+    2,
     'Fighter',
     'Psi Warrior',
     'ksmolko'
@@ -117,8 +128,10 @@ INSERT INTO Character VALUES
     10,
     8,
     7,
+    7,
     0,
     400,
+    2,
     'Rogue',
     'Assassin',
     'agervacio'
@@ -140,8 +153,10 @@ INSERT INTO Character VALUES
     10,
     8,
     7,
+    7,
     0,
     400,
+    2,
     'Monk',
     'Astral Self',
     'newuser'
@@ -296,63 +311,81 @@ INSERT INTO Spells VALUES
     60
 );
 
-INSERT INTO Campaign VALUES
+INSERT INTO Campaign (id, name, current_location, state, dungeon_master, status_note) VALUES
 (
     1,
     'Campaign 1',
     'Baldur''s Gate',
-    'Doing nothing of substance',
-    'kjerome'
+    'active',
+    'kjerome',
+    'Doing nothing of substance'
 ),
 (
     2,
     'Campaign 2',
     'Icewind Dale',
-    'Fighting a troll',
-    'drowsell'
+    'active',
+    'drowsell',
+    'Fighting a troll'
 ),
 (
     3,
     'Campaign 3',
     'Nowhere',
-    'Not started',
-    'ksmolko'
+    'planning',
+    'ksmolko',
+    'Not started'
 ),
 (
     4,
     'Campaign 4',
     'Baldur''s Gate',
-    'Following Campaign 1',
-    'agervacio'
+    'on hiatus',
+    'agervacio',
+    'Following Campaign 1'
 ),
 (
     5,
     'Campaign 5',
     'The Void',
-    'Not started',
-    'newuser'
+    'recruiting',
+    'newuser',
+    'Not started'
 );
 
-INSERT INTO CampaignMilestones VALUES
+INSERT INTO CampaignStateTransition (campaign_id, from_state, to_state, changed_by)
+SELECT id, NULL, state, dungeon_master FROM Campaign;
+
+INSERT INTO CampaignMilestones (campaign_id, sequence, milestone, author_username) VALUES
 (
     1,
-    'Started your wonderful journey'
+    1,
+    'Started your wonderful journey',
+    'kjerome'
 ),
 (
     2,
-    'Defeated the big bad Dragon'
+    1,
+    'Defeated the big bad Dragon',
+    'drowsell'
 ),
 (
     3,
-    'Started your wonderful journey'
+    1,
+    'Started your wonderful journey',
+    'ksmolko'
 ),
 (
     4,
-    'Started your wonderful journey'
+    1,
+    'Started your wonderful journey',
+    'agervacio'
 ),
 (
     5,
-    'Found an orphan'
+    1,
+    'Found an orphan',
+    'newuser'
 );
 
 INSERT INTO BelongsTo VALUES
@@ -390,6 +423,21 @@ INSERT INTO BelongsTo VALUES
 );
 
 
+-- Grant the sample characters the features of their race, classes and subclasses
+INSERT INTO CharacterFeature (character_id, feature_id)
+SELECT ch.id, f.id
+FROM Character AS ch
+INNER JOIN Feature AS f
+ON (f.source = 'race' AND f.race = ch.race AND f.level <= ch.level)
+OR (f.source IN ('class', 'subclass') AND EXISTS (
+    SELECT 1
+    FROM CharacterClass AS cc
+    WHERE cc.character_id = ch.id
+    AND cc.class = f.class
+    AND cc.levels >= f.level
+    AND (f.source = 'class' OR cc.subclass = f.subclass)));
+
+
 -- Manually reset primary key indeces so that "serial" values continue from correct location
 SELECT setval(pg_get_serial_sequence('Character', 'id'), (SELECT MAX(id) FROM Character));
 SELECT setval(pg_get_serial_sequence('Campaign', 'id'), (SELECT MAX(id) FROM Campaign));
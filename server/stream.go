@@ -0,0 +1,238 @@
+package main
+
+import (
+	"draco/events"
+	"draco/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/labstack/gommon/log"
+)
+
+// Browsers cannot set headers on EventSource and WebSocket connections,
+// so event streams are authenticated with a short-lived ticket passed in
+// the query string instead of the player's JWT. A ticket is only valid
+// for the event stream of a single campaign.
+const streamTicketTTL = time.Minute
+
+// eventHistorySize is how many events are kept for subscribers resuming
+// after a dropped connection.
+const eventHistorySize = 1024
+
+// streamHeartbeat is how often idle streams are pinged. The requestor's
+// access to the campaign is checked again on every heartbeat.
+const streamHeartbeat = 25 * time.Second
+
+func (app *application) getStreamJWTConfig() middleware.JWTConfig {
+	return middleware.JWTConfig{
+		SigningKey:  []byte(app.jwtSigningKey),
+		ContextKey:  "token",
+		TokenLookup: "query:ticket",
+	}
+}
+
+// createStreamTicket returns a ticket which allows `player` to follow
+// the events of the campaign identified by `campaignID`.
+func (app *application) createStreamTicket(player *models.Player, campaignID int) (string, time.Time, error) {
+	token := jwt.New(jwt.SigningMethodHS256)
+	expiresAt := time.Now().Add(streamTicketTTL)
+
+	claims := token.Claims.(jwt.MapClaims)
+	claims["username"] = player.Username
+	claims["player_id"] = player.ID
+	claims["stream"] = campaignID
+	claims["exp"] = expiresAt.Unix()
+
+	signed, err := token.SignedString([]byte(app.jwtSigningKey))
+	return signed, expiresAt, err
+}
+
+// getStreamCampaignFromToken returns the ID of the campaign which the
+// request's stream ticket is valid for, or false if the request was not
+// authenticated with a stream ticket.
+func getStreamCampaignFromToken(c echo.Context) (int, bool) {
+	token, ok := c.Get("token").(*jwt.Token)
+	if !ok {
+		return 0, false
+	}
+	claims := token.Claims.(jwt.MapClaims)
+	campaignID, ok := claims["stream"].(float64)
+	return int(campaignID), ok
+}
+
+// isStreamPath reports whether `path` is the route of an event stream.
+func isStreamPath(path string) bool {
+	return strings.HasPrefix(path, "/stream/")
+}
+
+// publish sends an event of type `eventType` to the subscribers of the
+// campaign identified by `campaignID`.
+func (app *application) publish(campaignID int, eventType string, data interface{}) {
+	if app.events == nil {
+		return
+	}
+	app.events.Publish(events.Event{CampaignID: campaignID, Type: eventType, Data: data})
+}
+
+// publishNPCEvent sends an event of type `eventType` about the NPC
+// identified by `npcID` to the subscribers of the campaign identified by
+// `campaignID`. Players are not told about NPCs `hidden` from them.
+func (app *application) publishNPCEvent(campaignID int, eventType string, npcID int, hidden bool) {
+	if app.events == nil {
+		return
+	}
+	app.events.Publish(events.Event{
+		CampaignID:        campaignID,
+		Type:              eventType,
+		Data:              map[string]int{"npc_id": npcID},
+		DungeonMasterOnly: hidden,
+	})
+}
+
+// publishCharacterUpdated tells every campaign the character identified
+// by `characterID` plays in that the character has changed.
+func (app *application) publishCharacterUpdated(characterID int) {
+	campaigns, err := app.belongsTo.GetAllCharacterCampaigns(characterID)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	for _, campaign := range *campaigns {
+		app.publish(campaign.ID, events.CharacterUpdated, map[string]int{"character_id": characterID})
+	}
+}
+
+// publishCombat sends an event of type `eventType` about `combat` to the
+// subscribers of its campaign.
+func (app *application) publishCombat(eventType string, combat *models.Combat) {
+	app.publish(combat.CampaignID, eventType,
+		struct {
+			CombatID           int  `json:"combat_id"`
+			Version            int  `json:"version"`
+			Round              int  `json:"round"`
+			CurrentCombatantID *int `json:"current_combatant_id"`
+		}{
+			combat.ID,
+			combat.Version,
+			combat.Round,
+			combat.CurrentCombatantID,
+		})
+}
+
+// publishCharacterCombatsUpdated tells every campaign whose combat the
+// character identified by `characterID` takes part in that the combat
+// has changed.
+func (app *application) publishCharacterCombatsUpdated(characterID int) {
+	campaigns, err := app.belongsTo.GetAllCharacterCampaigns(characterID)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	for _, campaign := range *campaigns {
+		combat, err := app.combats.GetActive(campaign.ID)
+		if err != nil {
+			if !errors.Is(err, models.ErrNoRecord) {
+				log.Error(err)
+			}
+			continue
+		}
+
+		for _, combatant := range combat.Combatants {
+			if combatant.CharacterID != nil && *combatant.CharacterID == characterID {
+				app.publishCombat(events.CombatUpdated, combat)
+				break
+			}
+		}
+	}
+}
+
+// parseLastEventID reads the ID of the last event a reconnecting client
+// received, from either the Last-Event-ID header sent by EventSource or
+// the `last_event_id` query parameter.
+func parseLastEventID(c echo.Context) uint64 {
+	raw := c.Request().Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = c.QueryParam("last_event_id")
+	}
+
+	id, err := strconv.ParseUint(strings.TrimSpace(raw), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// followCampaign subscribes to the events of `campaign` and passes them to
+// `send` until the client goes away, the subscription is dropped for
+// falling behind or the requestor loses access to the campaign. Missed
+// events are replayed first when the client is resuming.
+func (app *application) followCampaign(c echo.Context, campaign *models.Campaign, send func(events.Event) error, ping func() error, done <-chan struct{}) error {
+	username := getUsernameFromToken(c)
+	sub, missed, resumable := app.events.Subscribe(campaign.ID, isDungeonMaster(campaign, username), parseLastEventID(c))
+	defer app.events.Unsubscribe(sub)
+
+	if !resumable {
+		if err := send(events.Event{CampaignID: campaign.ID, Type: events.Resync, Time: time.Now()}); err != nil {
+			return err
+		}
+	}
+	for _, e := range missed {
+		if err := send(e); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(streamHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-sub.Events():
+			if !ok {
+				return nil
+			}
+			if err := send(e); err != nil {
+				return err
+			}
+
+		case <-ticker.C:
+			if _, err := app.authorizeParticipant(c, campaign.ID); err != nil {
+				return err
+			}
+			if err := ping(); err != nil {
+				return err
+			}
+
+		case <-done:
+			return nil
+		}
+	}
+}
+
+// writeServerSentEvent writes `e` to the response of `c` in the
+// text/event-stream format.
+func writeServerSentEvent(c echo.Context, e events.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	w := c.Response()
+	if e.ID != 0 {
+		fmt.Fprintf(w, "id: %d\n", e.ID)
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+		return err
+	}
+	w.Flush()
+	return nil
+}
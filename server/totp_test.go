@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 key of the RFC 6238 test vectors,
+// "12345678901234567890", encoded in base32.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// The last six digits of the RFC 6238 appendix B SHA-1 vectors.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := totpCode(rfc6238Secret, tt.unix/totpPeriod)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("totpCode at %d = %q, want %q", tt.unix, got, tt.want)
+		}
+	}
+
+	if got, err := totpCode(strings.ToLower(rfc6238Secret), 1); err != nil || got == "" {
+		t.Errorf("lower-case secret: %q, %v", got, err)
+	}
+	if _, err := totpCode("not base32!", 1); err == nil {
+		t.Error("expected an invalid secret to fail")
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	current := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", "050471", current, true},
+		{"spaces", " 050 471 ", current, true},
+		{"previous step", mustTOTPCode(t, current-1), current - 1, true},
+		{"next step", mustTOTPCode(t, current+1), current + 1, true},
+		{"too old", mustTOTPCode(t, current-2), 0, false},
+		{"too new", mustTOTPCode(t, current+2), 0, false},
+		{"wrong code", "000000", 0, false},
+		{"too short", "05047", 0, false},
+		{"too long", "0504711", 0, false},
+		{"empty", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := validateTOTPCode(rfc6238Secret, tt.code, now)
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("validateTOTPCode(%q) = %d, %v, want %d, %v", tt.code, step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+func mustTOTPCode(t *testing.T, counter int64) string {
+	t.Helper()
+
+	code, err := totpCode(rfc6238Secret, counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	u, err := url.Parse(totpProvisioningURI("frodo", rfc6238Secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/"+totpIssuer+":frodo" {
+		t.Errorf("unexpected URI %v", u)
+	}
+	for param, want := range map[string]string{
+		"secret": rfc6238Secret,
+		"issuer": totpIssuer,
+		"digits": "6",
+		"period": "30",
+	} {
+		if got := u.Query().Get(param); got != want {
+			t.Errorf("%s = %q, want %q", param, got, want)
+		}
+	}
+}
+
+func TestGenerateSecrets(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key, err := totpEncoding.DecodeString(secret); err != nil || len(key) != totpSecretSize {
+		t.Errorf("secret %q decodes to %d bytes, %v", secret, len(key), err)
+	}
+
+	codes, err := generateRecoveryCodes(numRecoveryCodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' || code != strings.ToLower(code) {
+			t.Errorf("malformed recovery code %q", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate recovery code %q", code)
+		}
+		seen[code] = true
+	}
+	if len(codes) != numRecoveryCodes {
+		t.Errorf("%d recovery codes, want %d", len(codes), numRecoveryCodes)
+	}
+}